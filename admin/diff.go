@@ -0,0 +1,348 @@
+package admin
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// luaDescribeSpaces returns the definitions of the existing spaces of the given names,
+// or of all the user spaces sorted by name if there are none
+const luaDescribeSpaces = luaDescribe + `
+local names = ...
+local spaces = {}
+if #names == 0 then
+    for id, s in pairs(box.space) do
+        if type(id) == 'number' and id >= 512 then
+            table.insert(spaces, describe(s))
+        end
+    end
+    table.sort(spaces, function(a, b) return a.name < b.name end)
+    return spaces
+end
+for _, name in ipairs(names) do
+    local s = box.space[name]
+    if s ~= nil then
+        table.insert(spaces, describe(s))
+    end
+end
+return spaces
+`
+
+const luaSetFormat = `
+local name, format = ...
+box.space[name]:format(format)
+`
+
+// DiffKind is the kind of a Difference.
+type DiffKind int
+
+const (
+	// MissingSpace means the space doesn't exist.
+	MissingSpace DiffKind = iota + 1
+	// SpaceChanged means the engine or an option of the space differs, Name is the option.
+	SpaceChanged
+	// MissingField means the format lacks the field.
+	MissingField
+	// FieldChanged means the field at the position differs by name, type or nullability.
+	FieldChanged
+	// ExtraField means the format has the field which is not defined.
+	ExtraField
+	// MissingIndex means the index doesn't exist.
+	MissingIndex
+	// IndexChanged means the type, the uniqueness or the parts of the index differ.
+	IndexChanged
+	// ExtraIndex means the space has the index which is not defined.
+	ExtraIndex
+)
+
+// Difference is a difference between the definition of a space and the live schema.
+type Difference struct {
+	Kind  DiffKind
+	Space string
+	// Name is the name of the field, the index or the option.
+	Name string
+	// Want is the defined value and Got is the live one: the option values,
+	// Field or *IndexDef. Either is nil if the object is missing.
+	Want interface{}
+	Got  interface{}
+}
+
+func (d *Difference) String() string {
+	switch d.Kind {
+	case MissingSpace:
+		return fmt.Sprintf("space %s is missing", d.Space)
+	case SpaceChanged:
+		return fmt.Sprintf("space %s %s is %v, not %v", d.Space, d.Name, d.Got, d.Want)
+	case MissingField:
+		return fmt.Sprintf("space %s field %v is missing", d.Space, d.Want)
+	case FieldChanged:
+		return fmt.Sprintf("space %s field %v, not %v", d.Space, d.Got, d.Want)
+	case ExtraField:
+		return fmt.Sprintf("space %s field %v is not defined", d.Space, d.Got)
+	case MissingIndex:
+		return fmt.Sprintf("index %s.%s is missing", d.Space, d.Name)
+	case IndexChanged:
+		return fmt.Sprintf("index %s.%s is %v, not %v", d.Space, d.Name, d.Got, d.Want)
+	case ExtraIndex:
+		return fmt.Sprintf("index %s.%s is not defined", d.Space, d.Name)
+	}
+	return fmt.Sprintf("space %s %s differs", d.Space, d.Name)
+}
+
+// Diff compares the definitions of the spaces with the live schema and returns
+// the differences, grouped by space in the order of defs. The indexes of a missing
+// space are not reported separately.
+func Diff(ctx context.Context, conn tarantool.Executor, defs []*SpaceDef) ([]Difference, error) {
+	if len(defs) == 0 {
+		return nil, nil
+	}
+	names := make([]string, len(defs))
+	for i, def := range defs {
+		if def.Name == "" {
+			return nil, ErrEmptyName
+		}
+		names[i] = def.Name
+	}
+
+	spaces, err := Describe(ctx, conn, names...)
+	if err != nil {
+		return nil, err
+	}
+	live := make(map[string]*SpaceDef, len(spaces))
+	for _, def := range spaces {
+		live[def.Name] = def
+	}
+
+	var diff []Difference
+	for _, def := range defs {
+		got := live[def.Name]
+		if got == nil {
+			diff = append(diff, Difference{Kind: MissingSpace, Space: def.Name, Want: def})
+			continue
+		}
+		diff = append(diff, diffOptions(def, got)...)
+		diff = append(diff, diffFormat(def, got)...)
+		diff = append(diff, diffIndexes(def, got)...)
+	}
+	return diff, nil
+}
+
+// Describe returns the live definitions of the existing spaces of the names,
+// or of all the user spaces sorted by name if no names are given.
+func Describe(ctx context.Context, conn tarantool.Executor, names ...string) ([]*SpaceDef, error) {
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = name
+	}
+
+	res := conn.Exec(ctx, &tarantool.Eval{Expression: luaDescribeSpaces, Tuple: []interface{}{args}})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if len(res.Data) == 0 {
+		return nil, nil
+	}
+
+	spaces := make([]*SpaceDef, 0, len(res.Data[0]))
+	for _, v := range res.Data[0] {
+		def, err := parseSpace(v)
+		if err != nil {
+			return nil, err
+		}
+		spaces = append(spaces, def)
+	}
+	return spaces, nil
+}
+
+// Apply applies the differences found by Diff for defs: creates the missing spaces
+// with their indexes and the missing indexes, and replaces the formats differing
+// from the definitions. The server rejects a format the stored tuples don't match.
+// The differences of the engine, the options and the existing indexes require
+// rebuilding the data, so they are not applied but returned.
+func Apply(ctx context.Context, conn tarantool.Executor, defs []*SpaceDef, diff []Difference) ([]Difference, error) {
+	byName := make(map[string]*SpaceDef, len(defs))
+	for _, def := range defs {
+		byName[def.Name] = def
+	}
+	formatted := make(map[string]bool)
+
+	var rest []Difference
+	for _, d := range diff {
+		def := byName[d.Space]
+		if def == nil {
+			rest = append(rest, d)
+			continue
+		}
+
+		switch d.Kind {
+		case MissingSpace:
+			if _, err := EnsureSpace(ctx, conn, def); err != nil {
+				return nil, err
+			}
+			for i := range def.Indexes {
+				if _, err := EnsureIndex(ctx, conn, def.Name, &def.Indexes[i]); err != nil {
+					return nil, err
+				}
+			}
+		case MissingField, FieldChanged, ExtraField:
+			if formatted[def.Name] {
+				continue
+			}
+			format := make([]interface{}, len(def.Format))
+			for i, f := range def.Format {
+				format[i] = map[string]interface{}{"name": f.Name, "type": f.Type, "is_nullable": f.IsNullable}
+			}
+			if _, err := eval(ctx, conn, luaSetFormat, def.Name, format); err != nil {
+				return nil, err
+			}
+			formatted[def.Name] = true
+		case MissingIndex:
+			for i := range def.Indexes {
+				if def.Indexes[i].Name != d.Name {
+					continue
+				}
+				if _, err := EnsureIndex(ctx, conn, def.Name, &def.Indexes[i]); err != nil {
+					return nil, err
+				}
+			}
+		default:
+			rest = append(rest, d)
+		}
+	}
+	return rest, nil
+}
+
+func diffOptions(want, got *SpaceDef) []Difference {
+	var diff []Difference
+	option := func(name string, w, g interface{}) {
+		if w != g {
+			diff = append(diff, Difference{Kind: SpaceChanged, Space: want.Name, Name: name, Want: w, Got: g})
+		}
+	}
+	option("engine", engine(want), engine(got))
+	option("temporary", want.Temporary, got.Temporary)
+	option("is_sync", want.IsSync, got.IsSync)
+	return diff
+}
+
+func diffFormat(want, got *SpaceDef) []Difference {
+	var diff []Difference
+	for i, f := range want.Format {
+		switch {
+		case i >= len(got.Format):
+			diff = append(diff, Difference{Kind: MissingField, Space: want.Name, Name: f.Name, Want: f})
+		case f != got.Format[i]:
+			diff = append(diff, Difference{Kind: FieldChanged, Space: want.Name, Name: f.Name, Want: f, Got: got.Format[i]})
+		}
+	}
+	for i := len(want.Format); i < len(got.Format); i++ {
+		f := got.Format[i]
+		diff = append(diff, Difference{Kind: ExtraField, Space: want.Name, Name: f.Name, Got: f})
+	}
+	return diff
+}
+
+func diffIndexes(want, got *SpaceDef) []Difference {
+	live := make(map[string]*IndexDef, len(got.Indexes))
+	for i := range got.Indexes {
+		live[got.Indexes[i].Name] = &got.Indexes[i]
+	}
+
+	var diff []Difference
+	defined := make(map[string]bool, len(want.Indexes))
+	for i := range want.Indexes {
+		w := &want.Indexes[i]
+		defined[w.Name] = true
+		switch g := live[w.Name]; {
+		case g == nil:
+			diff = append(diff, Difference{Kind: MissingIndex, Space: want.Name, Name: w.Name, Want: w})
+		case !equalIndex(w, g):
+			diff = append(diff, Difference{Kind: IndexChanged, Space: want.Name, Name: w.Name, Want: w, Got: g})
+		}
+	}
+	for i := range got.Indexes {
+		if g := &got.Indexes[i]; !defined[g.Name] {
+			diff = append(diff, Difference{Kind: ExtraIndex, Space: want.Name, Name: g.Name, Got: g})
+		}
+	}
+	return diff
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// FormatOf returns the space format for the struct type of model, which is a struct
+// or a pointer to struct. The fields are named the way tarantool.TupleDecoder
+// matches them: by the tarantool tag or the Go name, and the fields tagged with
+// `tarantool:"-"` are skipped. The pointer fields are nullable.
+func FormatOf(model interface{}) ([]Field, error) {
+	typ := reflect.TypeOf(model)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a struct", model)
+	}
+
+	var format []Field
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Tag.Get("tarantool")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		field := Field{Name: name}
+		t := f.Type
+		if t.Kind() == reflect.Ptr {
+			field.IsNullable = true
+			t = t.Elem()
+		}
+		field.Type = fieldType(t)
+		if field.Type == "" {
+			return nil, fmt.Errorf("field %s of %s: %s has no tarantool type", f.Name, typ, f.Type)
+		}
+		format = append(format, field)
+	}
+	return format, nil
+}
+
+func fieldType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "unsigned"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "varbinary"
+		}
+		return "array"
+	case reflect.Map:
+		return "map"
+	case reflect.Struct:
+		// time.Time is encoded as an extension, not as a map
+		if t == timeType {
+			return "any"
+		}
+		return "map"
+	case reflect.Interface:
+		return "any"
+	}
+	return ""
+}
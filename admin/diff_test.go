@@ -0,0 +1,191 @@
+package admin
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{spaces: map[string]fakeSpace{}}
+	conn := newTestConn(t, s)
+	ctx := context.Background()
+
+	orders := &SpaceDef{
+		Name: "orders",
+		Format: []Field{
+			{Name: "id", Type: "unsigned"},
+			{Name: "customer", Type: "string"},
+			{Name: "extra", Type: "map"},
+		},
+		Indexes: []IndexDef{
+			{Name: "primary", Parts: []Part{{Field: "id"}}},
+			{Name: "customer", NonUnique: true, Parts: []Part{{Field: "customer"}}},
+		},
+	}
+	_, err := EnsureSpace(ctx, conn, orders)
+	require.NoError(err)
+	for i := range orders.Indexes {
+		_, err = EnsureIndex(ctx, conn, "orders", &orders.Indexes[i])
+		require.NoError(err)
+	}
+	_, err = EnsureIndex(ctx, conn, "orders", &IndexDef{Name: "legacy", Parts: []Part{{Field: "extra"}}})
+	require.NoError(err)
+
+	users := &SpaceDef{Name: "users", Format: []Field{{Name: "id", Type: "unsigned"}}}
+
+	want := *orders
+	want.Format = []Field{
+		{Name: "id", Type: "unsigned"},
+		{Name: "customer", Type: "integer"},
+	}
+	want.Indexes = []IndexDef{
+		{Name: "primary", Parts: []Part{{Field: "id"}}},
+		{Name: "customer", Parts: []Part{{Field: "customer"}}},
+		{Name: "created", Parts: []Part{{Field: "created"}}},
+	}
+	want.Engine = "vinyl"
+
+	diff, err := Diff(ctx, conn, []*SpaceDef{&want, users})
+	require.NoError(err)
+
+	var report []string
+	for i := range diff {
+		report = append(report, diff[i].String())
+	}
+	assert.Equal([]string{
+		"space orders engine is memtx, not vinyl",
+		"space orders field customer string, not customer integer",
+		"space orders field extra map is not defined",
+		"index orders.customer is TREE non-unique on customer string, not TREE on customer",
+		"index orders.created is missing",
+		"index orders.legacy is not defined",
+		"space users is missing",
+	}, report)
+
+	diff, err = Diff(ctx, conn, []*SpaceDef{orders})
+	require.NoError(err)
+	assert.Equal([]Difference{{Kind: ExtraIndex, Space: "orders", Name: "legacy", Got: &IndexDef{
+		Name:  "legacy",
+		Type:  DefaultIndexType,
+		Parts: []Part{{Field: "extra", Type: "map"}},
+	}}}, diff)
+
+	_, err = Diff(ctx, conn, []*SpaceDef{{}})
+	assert.Equal(ErrEmptyName, err)
+}
+
+func TestDescribe(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{spaces: map[string]fakeSpace{}}
+	conn := newTestConn(t, s)
+	ctx := context.Background()
+
+	for _, name := range []string{"users", "orders"} {
+		_, err := EnsureSpace(ctx, conn, &SpaceDef{Name: name, Format: []Field{{Name: "id", Type: "unsigned"}}})
+		require.NoError(err)
+	}
+	_, err := EnsureIndex(ctx, conn, "users", &IndexDef{Name: "primary", Parts: []Part{{Field: "id"}}})
+	require.NoError(err)
+
+	spaces, err := Describe(ctx, conn)
+	require.NoError(err)
+	require.Len(spaces, 2)
+	assert.Equal("orders", spaces[0].Name)
+	assert.Equal(&SpaceDef{
+		Name:    "users",
+		Engine:  DefaultEngine,
+		Format:  []Field{{Name: "id", Type: "unsigned"}},
+		Indexes: []IndexDef{{Name: "primary", Type: DefaultIndexType, Parts: []Part{{Field: "id", Type: "unsigned"}}}},
+	}, spaces[1])
+
+	spaces, err = Describe(ctx, conn, "users", "missing")
+	require.NoError(err)
+	require.Len(spaces, 1)
+	assert.Equal("users", spaces[0].Name)
+}
+
+func TestApply(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{spaces: map[string]fakeSpace{}}
+	conn := newTestConn(t, s)
+	ctx := context.Background()
+
+	orders := &SpaceDef{
+		Name:    "orders",
+		Format:  []Field{{Name: "id", Type: "unsigned"}},
+		Indexes: []IndexDef{{Name: "primary", Parts: []Part{{Field: "id"}}}},
+	}
+	defs := []*SpaceDef{orders}
+
+	diff, err := Diff(ctx, conn, defs)
+	require.NoError(err)
+	rest, err := Apply(ctx, conn, defs, diff)
+	require.NoError(err)
+	assert.Empty(rest)
+
+	diff, err = Diff(ctx, conn, defs)
+	require.NoError(err)
+	assert.Empty(diff)
+
+	orders.Format = append(orders.Format, Field{Name: "customer", Type: "string", IsNullable: true}, Field{Name: "total", Type: "number", IsNullable: true})
+	orders.Indexes = append(orders.Indexes, IndexDef{Name: "customer", NonUnique: true, Parts: []Part{{Field: "customer", IsNullable: true}}})
+	orders.IsSync = true
+
+	diff, err = Diff(ctx, conn, defs)
+	require.NoError(err)
+	require.Len(diff, 4)
+
+	rest, err = Apply(ctx, conn, defs, diff)
+	require.NoError(err)
+	assert.Equal([]Difference{{Kind: SpaceChanged, Space: "orders", Name: "is_sync", Want: true, Got: false}}, rest)
+
+	diff, err = Diff(ctx, conn, defs)
+	require.NoError(err)
+	assert.Equal(rest, diff)
+}
+
+func TestFormatOf(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	type order struct {
+		ID       uint64 `tarantool:"id"`
+		Customer string `tarantool:"customer"`
+		Total    *float64
+		Items    []interface{}
+		Payload  []byte
+		Meta     map[string]string
+		Created  time.Time
+		Ignored  int `tarantool:"-"`
+		internal int
+	}
+
+	format, err := FormatOf(&order{})
+	require.NoError(err)
+	assert.Equal([]Field{
+		{Name: "id", Type: "unsigned"},
+		{Name: "customer", Type: "string"},
+		{Name: "Total", Type: "number", IsNullable: true},
+		{Name: "Items", Type: "array"},
+		{Name: "Payload", Type: "varbinary"},
+		{Name: "Meta", Type: "map"},
+		{Name: "Created", Type: "any"},
+	}, format)
+
+	_, err = FormatOf(1)
+	assert.Error(err)
+
+	_, err = FormatOf(struct{ C chan int }{})
+	assert.Error(err)
+}
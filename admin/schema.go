@@ -0,0 +1,310 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/viciious/go-tarantool"
+)
+
+const (
+	// DefaultEngine is the engine of a space if SpaceDef.Engine is empty.
+	DefaultEngine = "memtx"
+	// DefaultIndexType is the type of an index if IndexDef.Type is empty.
+	DefaultIndexType = "TREE"
+)
+
+// ErrSchemaMismatch is returned by EnsureSpace and EnsureIndex if the object
+// exists, but differs from the definition.
+var ErrSchemaMismatch = errors.New("schema mismatch")
+
+// luaDescribe defines the function returning the definition of a space
+// with its indexes in the form parsed by parseSpace
+const luaDescribe = `
+local function describe(s)
+    local format = {}
+    for _, f in ipairs(s:format()) do
+        table.insert(format, {name = f.name, type = f.type, is_nullable = f.is_nullable == true})
+    end
+    local indexes = {}
+    for id, i in pairs(s.index) do
+        if type(id) == 'number' then
+            local parts = {}
+            for _, p in ipairs(i.parts) do
+                local field = format[p.fieldno] and format[p.fieldno].name or tostring(p.fieldno)
+                table.insert(parts, {field = field, type = p.type, is_nullable = p.is_nullable == true})
+            end
+            table.insert(indexes, {id = id, name = i.name, type = i.type, unique = i.unique == true, parts = parts})
+        end
+    end
+    table.sort(indexes, function(a, b) return a.id < b.id end)
+    return {
+        name = s.name,
+        engine = s.engine,
+        format = format,
+        temporary = s.temporary == true,
+        is_sync = s.is_sync == true,
+        indexes = indexes,
+    }
+end
+`
+
+// luaEnsureSpace creates the space, or returns the definition of the existing one
+const luaEnsureSpace = luaDescribe + `
+local name, def = ...
+local s = box.space[name]
+if s == nil then
+    box.schema.space.create(name, def)
+    return
+end
+return describe(s)
+`
+
+// luaEnsureIndex creates the index, or returns the definition of its space
+const luaEnsureIndex = luaDescribe + `
+local space, name, def = ...
+local s = box.space[space]
+if s == nil then
+    box.error(box.error.NO_SUCH_SPACE, space)
+end
+if s.index[name] == nil then
+    s:create_index(name, def)
+    return
+end
+return describe(s)
+`
+
+// Field is a field of a space format.
+type Field struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	IsNullable bool   `json:"is_nullable,omitempty"`
+}
+
+func (f Field) String() string {
+	if f.IsNullable {
+		return f.Name + " " + f.Type + " nullable"
+	}
+	return f.Name + " " + f.Type
+}
+
+// SpaceDef defines a space. It is encoded to JSON with the keys of box.schema,
+// which makes a schema dump of the spaces returned by Describe.
+type SpaceDef struct {
+	Name string `json:"name"`
+	// Engine is memtx or vinyl, DefaultEngine if empty.
+	Engine    string  `json:"engine,omitempty"`
+	Format    []Field `json:"format"`
+	Temporary bool    `json:"temporary,omitempty"`
+	IsSync    bool    `json:"is_sync,omitempty"`
+	// Indexes are compared by Diff and created by Apply,
+	// EnsureSpace ignores them.
+	Indexes []IndexDef `json:"indexes,omitempty"`
+}
+
+// Part is a part of an index key.
+type Part struct {
+	// Field is the name of the field in the space format.
+	Field string `json:"field"`
+	// Type may be empty for the fields typed by the format, then it is not compared.
+	Type       string `json:"type,omitempty"`
+	IsNullable bool   `json:"is_nullable,omitempty"`
+}
+
+// IndexDef defines an index.
+type IndexDef struct {
+	Name string `json:"name"`
+	// Type is TREE, HASH, BITSET or RTREE, DefaultIndexType if empty.
+	Type string `json:"type,omitempty"`
+	// NonUnique is set for the secondary indexes allowing duplicate keys,
+	// the primary index is always unique.
+	NonUnique bool   `json:"non_unique,omitempty"`
+	Parts     []Part `json:"parts"`
+}
+
+func (d *IndexDef) String() string {
+	var b strings.Builder
+	b.WriteString(indexType(d))
+	if d.NonUnique {
+		b.WriteString(" non-unique")
+	}
+	for i, p := range d.Parts {
+		if i == 0 {
+			b.WriteString(" on ")
+		} else {
+			b.WriteString(", ")
+		}
+		b.WriteString(p.Field)
+		if p.Type != "" {
+			b.WriteString(" " + p.Type)
+		}
+		if p.IsNullable {
+			b.WriteString(" nullable")
+		}
+	}
+	return b.String()
+}
+
+// EnsureSpace creates the space if it doesn't exist and reports whether it has been created.
+// If the space exists, but its engine, format or options differ from def, an error
+// matching ErrSchemaMismatch is returned. The indexes are created by EnsureIndex.
+func EnsureSpace(ctx context.Context, conn tarantool.Executor, def *SpaceDef) (bool, error) {
+	format := make([]interface{}, len(def.Format))
+	for i, f := range def.Format {
+		format[i] = map[string]interface{}{"name": f.Name, "type": f.Type, "is_nullable": f.IsNullable}
+	}
+	opts := map[string]interface{}{"engine": engine(def), "format": format}
+	if def.Temporary {
+		opts["temporary"] = true
+	}
+	if def.IsSync {
+		opts["is_sync"] = true
+	}
+
+	res, err := eval(ctx, conn, luaEnsureSpace, def.Name, opts)
+	if err != nil {
+		return false, err
+	}
+	existing := result(res)
+	if existing == nil {
+		return true, nil
+	}
+
+	live, err := parseSpace(existing)
+	if err != nil {
+		return false, err
+	}
+	if diff := append(diffOptions(def, live), diffFormat(def, live)...); len(diff) != 0 {
+		return false, fmt.Errorf("%w: %s", ErrSchemaMismatch, &diff[0])
+	}
+	return false, nil
+}
+
+// EnsureIndex creates the index of the space if it doesn't exist and reports whether
+// it has been created. If the index exists, but its type, uniqueness or parts differ
+// from def, an error matching ErrSchemaMismatch is returned.
+func EnsureIndex(ctx context.Context, conn tarantool.Executor, space string, def *IndexDef) (bool, error) {
+	if def.Name == "" {
+		return false, ErrEmptyName
+	}
+
+	parts := make([]interface{}, len(def.Parts))
+	for i, p := range def.Parts {
+		part := map[string]interface{}{"field": p.Field, "is_nullable": p.IsNullable}
+		if p.Type != "" {
+			part["type"] = p.Type
+		}
+		parts[i] = part
+	}
+	opts := map[string]interface{}{"type": indexType(def), "unique": !def.NonUnique, "parts": parts}
+
+	res, err := eval(ctx, conn, luaEnsureIndex, space, def.Name, opts)
+	if err != nil {
+		return false, err
+	}
+	existing := result(res)
+	if existing == nil {
+		return true, nil
+	}
+
+	live, err := parseSpace(existing)
+	if err != nil {
+		return false, err
+	}
+	for i := range live.Indexes {
+		if got := &live.Indexes[i]; got.Name == def.Name && !equalIndex(def, got) {
+			d := Difference{Kind: IndexChanged, Space: space, Name: def.Name, Want: def, Got: got}
+			return false, fmt.Errorf("%w: %s", ErrSchemaMismatch, &d)
+		}
+	}
+	return false, nil
+}
+
+func engine(def *SpaceDef) string {
+	if def.Engine == "" {
+		return DefaultEngine
+	}
+	return def.Engine
+}
+
+func indexType(def *IndexDef) string {
+	if def.Type == "" {
+		return DefaultIndexType
+	}
+	return strings.ToUpper(def.Type)
+}
+
+// parseSpace parses the space definition returned by describe
+func parseSpace(v interface{}) (*SpaceDef, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, tarantool.ErrBadResult
+	}
+
+	def := &SpaceDef{}
+	def.Name, _ = m["name"].(string)
+	def.Engine, _ = m["engine"].(string)
+	def.Temporary, _ = m["temporary"].(bool)
+	def.IsSync, _ = m["is_sync"].(bool)
+
+	for _, f := range list(m["format"]) {
+		fm, ok := f.(map[string]interface{})
+		if !ok {
+			return nil, tarantool.ErrBadResult
+		}
+		var field Field
+		field.Name, _ = fm["name"].(string)
+		field.Type, _ = fm["type"].(string)
+		field.IsNullable, _ = fm["is_nullable"].(bool)
+		def.Format = append(def.Format, field)
+	}
+
+	for _, i := range list(m["indexes"]) {
+		im, ok := i.(map[string]interface{})
+		if !ok {
+			return nil, tarantool.ErrBadResult
+		}
+		var index IndexDef
+		index.Name, _ = im["name"].(string)
+		index.Type, _ = im["type"].(string)
+		unique, _ := im["unique"].(bool)
+		index.NonUnique = !unique
+		for _, p := range list(im["parts"]) {
+			pm, ok := p.(map[string]interface{})
+			if !ok {
+				return nil, tarantool.ErrBadResult
+			}
+			var part Part
+			part.Field, _ = pm["field"].(string)
+			part.Type, _ = pm["type"].(string)
+			part.IsNullable, _ = pm["is_nullable"].(bool)
+			index.Parts = append(index.Parts, part)
+		}
+		def.Indexes = append(def.Indexes, index)
+	}
+	return def, nil
+}
+
+// list returns the elements of an array, an empty Lua table is
+// encoded as an empty map, so anything else is an empty list
+func list(v interface{}) []interface{} {
+	l, _ := v.([]interface{})
+	return l
+}
+
+func equalIndex(want, got *IndexDef) bool {
+	if indexType(want) != indexType(got) || want.NonUnique != got.NonUnique || len(want.Parts) != len(got.Parts) {
+		return false
+	}
+	for i, p := range want.Parts {
+		if p.Type == "" {
+			p.Type = got.Parts[i].Type
+		}
+		if p != got.Parts[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,185 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+)
+
+// fakeSpace is the live definition of a space as returned by describe
+type fakeSpace map[string]interface{}
+
+// handleSchema executes the DDL scripts against the in-memory spaces
+func (s *fakeServer) handleSchema(eval *tarantool.Eval) *tarantool.Result {
+	if eval.Expression == luaDescribeSpaces {
+		spaces := []interface{}{}
+		names := eval.Tuple[0].([]interface{})
+		if len(names) == 0 {
+			for name := range s.spaces {
+				names = append(names, name)
+			}
+			sort.Slice(names, func(i, j int) bool { return names[i].(string) < names[j].(string) })
+		}
+		for _, name := range names {
+			if space := s.spaces[name.(string)]; space != nil {
+				spaces = append(spaces, map[string]interface{}(space))
+			}
+		}
+		return &tarantool.Result{Data: [][]interface{}{spaces}}
+	}
+
+	name := eval.Tuple[0].(string)
+	space := s.spaces[name]
+	describe := &tarantool.Result{Data: [][]interface{}{{map[string]interface{}(space)}}}
+
+	switch eval.Expression {
+	case luaEnsureSpace:
+		if space != nil {
+			return describe
+		}
+		def := eval.Tuple[1].(map[string]interface{})
+		s.spaces[name] = fakeSpace{
+			"name":      name,
+			"engine":    def["engine"],
+			"format":    def["format"],
+			"temporary": def["temporary"] == true,
+			"is_sync":   def["is_sync"] == true,
+			"indexes":   []interface{}{},
+		}
+		return &tarantool.Result{}
+	case luaSetFormat:
+		space["format"] = eval.Tuple[1]
+		return &tarantool.Result{}
+	}
+
+	if space == nil {
+		return &tarantool.Result{ErrorCode: tarantool.ErrNoSuchSpace, Error: tarantool.NewQueryError(tarantool.ErrNoSuchSpace, "Space '"+name+"' does not exist")}
+	}
+	index := eval.Tuple[1].(string)
+	indexes := space["indexes"].([]interface{})
+	for _, i := range indexes {
+		if i.(map[string]interface{})["name"] == index {
+			return describe
+		}
+	}
+
+	// the server takes the types of the parts from the format
+	def := eval.Tuple[2].(map[string]interface{})
+	for _, p := range def["parts"].([]interface{}) {
+		part := p.(map[string]interface{})
+		for _, f := range space["format"].([]interface{}) {
+			if field := f.(map[string]interface{}); part["type"] == nil && field["name"] == part["field"] {
+				part["type"] = field["type"]
+			}
+		}
+	}
+	def["name"] = index
+	def["id"] = int64(len(indexes))
+	space["indexes"] = append(indexes, def)
+	return &tarantool.Result{}
+}
+
+func TestEnsureSpace(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{spaces: map[string]fakeSpace{}}
+	conn := newTestConn(t, s)
+	ctx := context.Background()
+
+	def := &SpaceDef{
+		Name: "orders",
+		Format: []Field{
+			{Name: "id", Type: "unsigned"},
+			{Name: "customer", Type: "string"},
+			{Name: "note", Type: "string", IsNullable: true},
+		},
+	}
+
+	created, err := EnsureSpace(ctx, conn, def)
+	require.NoError(err)
+	assert.True(created)
+	assert.Equal(DefaultEngine, s.spaces["orders"]["engine"])
+
+	created, err = EnsureSpace(ctx, conn, def)
+	require.NoError(err)
+	assert.False(created)
+
+	changed := *def
+	changed.Format = def.Format[:2]
+	_, err = EnsureSpace(ctx, conn, &changed)
+	assert.True(errors.Is(err, ErrSchemaMismatch))
+	assert.EqualError(err, "schema mismatch: space orders field note string nullable is not defined")
+
+	changed = *def
+	changed.Engine = "vinyl"
+	_, err = EnsureSpace(ctx, conn, &changed)
+	assert.True(errors.Is(err, ErrSchemaMismatch))
+
+	changed = *def
+	changed.IsSync = true
+	_, err = EnsureSpace(ctx, conn, &changed)
+	assert.True(errors.Is(err, ErrSchemaMismatch))
+
+	_, err = EnsureSpace(ctx, conn, &SpaceDef{})
+	assert.Equal(ErrEmptyName, err)
+}
+
+func TestEnsureIndex(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{spaces: map[string]fakeSpace{}}
+	conn := newTestConn(t, s)
+	ctx := context.Background()
+
+	_, err := EnsureIndex(ctx, conn, "orders", &IndexDef{Name: "primary", Parts: []Part{{Field: "id"}}})
+	var qerr *tarantool.QueryError
+	require.ErrorAs(err, &qerr)
+	assert.Equal(tarantool.ErrNoSuchSpace, qerr.Code)
+
+	_, err = EnsureSpace(ctx, conn, &SpaceDef{
+		Name:   "orders",
+		Format: []Field{{Name: "id", Type: "unsigned"}, {Name: "customer", Type: "string"}},
+	})
+	require.NoError(err)
+
+	primary := &IndexDef{Name: "primary", Parts: []Part{{Field: "id"}}}
+	secondary := &IndexDef{Name: "customer", Type: "tree", NonUnique: true, Parts: []Part{{Field: "customer", Type: "string"}}}
+
+	for _, def := range []*IndexDef{primary, secondary} {
+		created, err := EnsureIndex(ctx, conn, "orders", def)
+		require.NoError(err)
+		assert.True(created)
+		created, err = EnsureIndex(ctx, conn, "orders", def)
+		require.NoError(err)
+		assert.False(created)
+	}
+	indexes := s.spaces["orders"]["indexes"].([]interface{})
+	require.Len(indexes, 2)
+	assert.Equal(true, indexes[0].(map[string]interface{})["unique"])
+	assert.Equal(DefaultIndexType, indexes[1].(map[string]interface{})["type"])
+
+	// the type taken from the format is compared if given
+	created, err := EnsureIndex(ctx, conn, "orders", &IndexDef{Name: "primary", Parts: []Part{{Field: "id", Type: "unsigned"}}})
+	require.NoError(err)
+	assert.False(created)
+
+	for _, def := range []*IndexDef{
+		{Name: "primary", Type: "HASH", Parts: []Part{{Field: "id"}}},
+		{Name: "primary", NonUnique: true, Parts: []Part{{Field: "id"}}},
+		{Name: "primary", Parts: []Part{{Field: "id", Type: "string"}}},
+		{Name: "primary", Parts: []Part{{Field: "id"}, {Field: "customer"}}},
+	} {
+		_, err = EnsureIndex(ctx, conn, "orders", def)
+		assert.True(errors.Is(err, ErrSchemaMismatch), "%v", err)
+	}
+
+	_, err = EnsureIndex(ctx, conn, "orders", &IndexDef{})
+	assert.Equal(ErrEmptyName, err)
+}
@@ -0,0 +1,221 @@
+// Package admin manages the users, the roles and their privileges,
+// and bootstraps the spaces and the indexes.
+//
+// The names, the passwords and the definitions are passed to box.schema as
+// the arguments of the evaluated scripts, so they need no escaping. The Ensure
+// and the Grant helpers are idempotent, which lets the provisioning tools and
+// the applications apply the desired state repeatedly.
+package admin
+
+import (
+	"context"
+	"errors"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// The object types a privilege is granted on.
+const (
+	ObjectUniverse = "universe"
+	ObjectSpace    = "space"
+	ObjectFunction = "function"
+	ObjectSequence = "sequence"
+	ObjectRole     = "role"
+	ObjectUser     = "user"
+)
+
+// ErrEmptyName is returned if the name of a user, a role, a space or an index is empty.
+var ErrEmptyName = errors.New("empty name")
+
+const luaUserExists = `
+return box.schema.user.exists(...)
+`
+
+const luaCreateUser = `
+local name, password = ...
+box.schema.user.create(name, {password = password ~= '' and password or nil})
+`
+
+// luaEnsureUser creates the user if it doesn't exist or updates its password
+const luaEnsureUser = `
+local name, password = ...
+if not box.schema.user.exists(name) then
+    box.schema.user.create(name, {password = password ~= '' and password or nil})
+    return true
+end
+if password ~= '' then
+    box.schema.user.passwd(name, password)
+end
+return false
+`
+
+const luaDropUser = `
+box.schema.user.drop(..., {if_exists = true})
+`
+
+const luaPasswd = `
+box.schema.user.passwd(...)
+`
+
+// luaEnsureRole creates the role if it doesn't exist
+const luaEnsureRole = `
+local name = ...
+if box.schema.role.exists(name) then
+    return false
+end
+box.schema.role.create(name)
+return true
+`
+
+const luaDropRole = `
+box.schema.role.drop(..., {if_exists = true})
+`
+
+const luaGrant = `
+local user, privs, otype, oname = ...
+box.schema.user.grant(user, privs, otype, oname ~= '' and oname or nil, {if_not_exists = true})
+`
+
+const luaRevoke = `
+local user, privs, otype, oname = ...
+box.schema.user.revoke(user, privs, otype, oname ~= '' and oname or nil, {if_exists = true})
+`
+
+const luaUserInfo = `
+local info = box.schema.user.info(...)
+local privs = {}
+for _, p in ipairs(info) do
+    table.insert(privs, {p[1], p[2], p[3] or ''})
+end
+return privs
+`
+
+// Privilege is a set of privileges on an object.
+type Privilege struct {
+	// Privileges is a comma separated list, e.g. "read,write".
+	Privileges string
+	// ObjectType is one of the Object constants.
+	ObjectType string
+	// ObjectName is empty for the universe.
+	ObjectName string
+}
+
+// UserExists reports whether the user exists.
+func UserExists(ctx context.Context, conn tarantool.Executor, name string) (bool, error) {
+	res, err := eval(ctx, conn, luaUserExists, name)
+	if err != nil {
+		return false, err
+	}
+	return result(res) == true, nil
+}
+
+// CreateUser creates the user, it fails if the user already exists.
+// An empty password creates the user which can only connect as a guest.
+func CreateUser(ctx context.Context, conn tarantool.Executor, name, password string) error {
+	_, err := eval(ctx, conn, luaCreateUser, name, password)
+	return err
+}
+
+// EnsureUser creates the user if it doesn't exist and reports whether it has
+// been created. The password of the existing user is updated unless it is empty.
+func EnsureUser(ctx context.Context, conn tarantool.Executor, name, password string) (bool, error) {
+	res, err := eval(ctx, conn, luaEnsureUser, name, password)
+	if err != nil {
+		return false, err
+	}
+	return result(res) == true, nil
+}
+
+// DropUser drops the user with its objects. Dropping a missing user is not an error.
+func DropUser(ctx context.Context, conn tarantool.Executor, name string) error {
+	_, err := eval(ctx, conn, luaDropUser, name)
+	return err
+}
+
+// SetPassword changes the password of the user.
+func SetPassword(ctx context.Context, conn tarantool.Executor, name, password string) error {
+	_, err := eval(ctx, conn, luaPasswd, name, password)
+	return err
+}
+
+// EnsureRole creates the role if it doesn't exist and reports whether it has been created.
+func EnsureRole(ctx context.Context, conn tarantool.Executor, name string) (bool, error) {
+	res, err := eval(ctx, conn, luaEnsureRole, name)
+	if err != nil {
+		return false, err
+	}
+	return result(res) == true, nil
+}
+
+// DropRole drops the role. Dropping a missing role is not an error.
+func DropRole(ctx context.Context, conn tarantool.Executor, name string) error {
+	_, err := eval(ctx, conn, luaDropRole, name)
+	return err
+}
+
+// Grant grants the privileges to the user or the role.
+// Granting the privileges it already has is not an error.
+func Grant(ctx context.Context, conn tarantool.Executor, user string, p Privilege) error {
+	_, err := eval(ctx, conn, luaGrant, user, p.Privileges, p.ObjectType, p.ObjectName)
+	return err
+}
+
+// Revoke revokes the privileges from the user or the role.
+// Revoking the privileges it doesn't have is not an error.
+func Revoke(ctx context.Context, conn tarantool.Executor, user string, p Privilege) error {
+	_, err := eval(ctx, conn, luaRevoke, user, p.Privileges, p.ObjectType, p.ObjectName)
+	return err
+}
+
+// GrantRole grants the role to the user or another role.
+func GrantRole(ctx context.Context, conn tarantool.Executor, user, role string) error {
+	return Grant(ctx, conn, user, Privilege{Privileges: "execute", ObjectType: ObjectRole, ObjectName: role})
+}
+
+// RevokeRole revokes the role from the user or another role.
+func RevokeRole(ctx context.Context, conn tarantool.Executor, user, role string) error {
+	return Revoke(ctx, conn, user, Privilege{Privileges: "execute", ObjectType: ObjectRole, ObjectName: role})
+}
+
+// Privileges returns the privileges of the user or the role, including the granted roles.
+func Privileges(ctx context.Context, conn tarantool.Executor, user string) ([]Privilege, error) {
+	res, err := eval(ctx, conn, luaUserInfo, user)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.Data) == 0 {
+		return nil, nil
+	}
+
+	privs := make([]Privilege, 0, len(res.Data[0]))
+	for _, row := range res.Data[0] {
+		fields, ok := row.([]interface{})
+		if !ok || len(fields) != 3 {
+			return nil, tarantool.ErrBadResult
+		}
+		var p Privilege
+		p.Privileges, _ = fields[0].(string)
+		p.ObjectType, _ = fields[1].(string)
+		p.ObjectName, _ = fields[2].(string)
+		privs = append(privs, p)
+	}
+	return privs, nil
+}
+
+func eval(ctx context.Context, conn tarantool.Executor, expr, name string, args ...interface{}) (*tarantool.Result, error) {
+	if name == "" {
+		return nil, ErrEmptyName
+	}
+	res := conn.Exec(ctx, &tarantool.Eval{Expression: expr, Tuple: append([]interface{}{name}, args...)})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return res, nil
+}
+
+func result(res *tarantool.Result) interface{} {
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 {
+		return nil
+	}
+	return res.Data[0][0]
+}
@@ -0,0 +1,213 @@
+package admin
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+type fakeUser struct {
+	password string
+	role     bool
+	privs    []Privilege
+}
+
+// fakeServer executes the admin scripts against the in-memory users
+type fakeServer struct {
+	sync.Mutex
+	users  map[string]*fakeUser
+	spaces map[string]fakeSpace
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	eval, ok := q.(*tarantool.Eval)
+	if !ok {
+		return &tarantool.Result{}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	switch eval.Expression {
+	case luaEnsureSpace, luaEnsureIndex, luaDescribeSpaces, luaSetFormat:
+		return s.handleSchema(eval)
+	}
+
+	arg := func(i int) string {
+		return eval.Tuple[i].(string)
+	}
+	fail := func(msg string) *tarantool.Result {
+		return &tarantool.Result{ErrorCode: tarantool.ErrProcLua, Error: tarantool.NewQueryError(tarantool.ErrProcLua, msg)}
+	}
+	ret := func(v interface{}) *tarantool.Result {
+		return &tarantool.Result{Data: [][]interface{}{{v}}}
+	}
+
+	name := arg(0)
+	u := s.users[name]
+
+	switch eval.Expression {
+	case luaUserExists:
+		return ret(u != nil && !u.role)
+	case luaCreateUser:
+		if u != nil {
+			return fail("User '" + name + "' already exists")
+		}
+		s.users[name] = &fakeUser{password: arg(1)}
+	case luaEnsureUser:
+		if u == nil {
+			s.users[name] = &fakeUser{password: arg(1)}
+			return ret(true)
+		}
+		if arg(1) != "" {
+			u.password = arg(1)
+		}
+		return ret(false)
+	case luaDropUser, luaDropRole:
+		delete(s.users, name)
+	case luaPasswd:
+		if u == nil {
+			return fail("User '" + name + "' is not found")
+		}
+		u.password = arg(1)
+	case luaEnsureRole:
+		if u != nil {
+			return ret(false)
+		}
+		s.users[name] = &fakeUser{role: true}
+		return ret(true)
+	case luaGrant, luaRevoke:
+		if u == nil {
+			return fail("User '" + name + "' is not found")
+		}
+		p := Privilege{Privileges: arg(1), ObjectType: arg(2), ObjectName: arg(3)}
+		privs := u.privs[:0]
+		granted := false
+		for _, have := range u.privs {
+			if have == p {
+				granted = true
+				if eval.Expression == luaRevoke {
+					continue
+				}
+			}
+			privs = append(privs, have)
+		}
+		if eval.Expression == luaGrant && !granted {
+			privs = append(privs, p)
+		}
+		u.privs = privs
+	case luaUserInfo:
+		if u == nil {
+			return fail("User '" + name + "' is not found")
+		}
+		rows := []interface{}{}
+		for _, p := range u.privs {
+			rows = append(rows, []interface{}{p.Privileges, p.ObjectType, p.ObjectName})
+		}
+		return &tarantool.Result{Data: [][]interface{}{rows}}
+	}
+	return &tarantool.Result{}
+}
+
+func newTestConn(t *testing.T, s *fakeServer) *tarantool.Connection {
+	addr := tnttest.NewServer(t, s.handle)
+
+	conn := tnttest.Connect(t, addr, nil)
+	return conn
+}
+
+func TestUsers(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{users: map[string]*fakeUser{}}
+	conn := newTestConn(t, s)
+	ctx := context.Background()
+
+	exists, err := UserExists(ctx, conn, "app")
+	require.NoError(err)
+	assert.False(exists)
+
+	created, err := EnsureUser(ctx, conn, "app", `pa"ss'\`)
+	require.NoError(err)
+	assert.True(created)
+	assert.Equal(`pa"ss'\`, s.users["app"].password)
+
+	exists, err = UserExists(ctx, conn, "app")
+	require.NoError(err)
+	assert.True(exists)
+
+	// ensuring again keeps the user and updates the password
+	created, err = EnsureUser(ctx, conn, "app", "new")
+	require.NoError(err)
+	assert.False(created)
+	assert.Equal("new", s.users["app"].password)
+
+	created, err = EnsureUser(ctx, conn, "app", "")
+	require.NoError(err)
+	assert.False(created)
+	assert.Equal("new", s.users["app"].password)
+
+	err = CreateUser(ctx, conn, "app", "x")
+	var qerr *tarantool.QueryError
+	require.ErrorAs(err, &qerr)
+	assert.Contains(qerr.Error(), "already exists")
+
+	require.NoError(SetPassword(ctx, conn, "app", "other"))
+	assert.Equal("other", s.users["app"].password)
+
+	require.NoError(DropUser(ctx, conn, "app"))
+	require.NoError(DropUser(ctx, conn, "app"))
+	assert.Empty(s.users)
+
+	_, err = EnsureUser(ctx, conn, "", "x")
+	assert.Equal(ErrEmptyName, err)
+}
+
+func TestGrant(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{users: map[string]*fakeUser{}}
+	conn := newTestConn(t, s)
+	ctx := context.Background()
+
+	created, err := EnsureRole(ctx, conn, "reader")
+	require.NoError(err)
+	assert.True(created)
+	created, err = EnsureRole(ctx, conn, "reader")
+	require.NoError(err)
+	assert.False(created)
+
+	read := Privilege{Privileges: "read", ObjectType: ObjectSpace, ObjectName: "orders"}
+	require.NoError(Grant(ctx, conn, "reader", read))
+	require.NoError(CreateUser(ctx, conn, "app", ""))
+	require.NoError(GrantRole(ctx, conn, "app", "reader"))
+	require.NoError(Grant(ctx, conn, "app", Privilege{Privileges: "execute", ObjectType: ObjectUniverse}))
+	require.NoError(GrantRole(ctx, conn, "app", "reader"))
+
+	privs, err := Privileges(ctx, conn, "app")
+	require.NoError(err)
+	assert.Equal([]Privilege{
+		{Privileges: "execute", ObjectType: ObjectRole, ObjectName: "reader"},
+		{Privileges: "execute", ObjectType: ObjectUniverse},
+	}, privs)
+
+	require.NoError(RevokeRole(ctx, conn, "app", "reader"))
+	privs, err = Privileges(ctx, conn, "app")
+	require.NoError(err)
+	assert.Equal([]Privilege{{Privileges: "execute", ObjectType: ObjectUniverse}}, privs)
+
+	require.NoError(Revoke(ctx, conn, "reader", read))
+	privs, err = Privileges(ctx, conn, "reader")
+	require.NoError(err)
+	assert.Empty(privs)
+
+	require.NoError(DropRole(ctx, conn, "reader"))
+	assert.NotContains(s.users, "reader")
+}
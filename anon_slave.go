@@ -232,12 +232,12 @@ func (s *AnonSlave) nextSnap() (p *Packet, err error) {
 	case InsertCommand:
 		q := p.Request.(*Insert)
 		if q.Space == SpaceSchema {
-			key := q.Tuple[0].(string)
+			key := tupleString(q.Tuple, 0)
 			if key == SchemaKeyClusterUUID {
-				if s.ReplicaSet.UUID != "" && s.ReplicaSet.UUID != q.Tuple[1].(string) {
-					return nil, NewUnexpectedReplicaSetUUIDError(s.ReplicaSet.UUID, q.Tuple[1].(string))
+				if s.ReplicaSet.UUID != "" && s.ReplicaSet.UUID != tupleString(q.Tuple, 1) {
+					return nil, NewUnexpectedReplicaSetUUIDError(s.ReplicaSet.UUID, tupleString(q.Tuple, 1))
 				}
-				s.ReplicaSet.UUID = q.Tuple[1].(string)
+				s.ReplicaSet.UUID = tupleString(q.Tuple, 1)
 			}
 		}
 	case OKCommand:
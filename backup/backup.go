@@ -0,0 +1,287 @@
+// Package backup orchestrates the hot backups of an instance with
+// box.backup.start and box.backup.stop: the files of a checkpoint are
+// listed and kept by the server until the backup is stopped, so they can be
+// copied while it is running.
+//
+// Run copies the files over a Transport, reading them through the connection,
+// so the client doesn't need an access to the file system of the server,
+// and always stops the backup:
+//
+//	files, err := backup.Run(ctx, conn, backup.Dir("/backups/2021-06-01"), nil)
+//
+// Start, Backup.Open and Backup.Stop are the steps of it to copy the files
+// differently, e.g. with rsync on the server host.
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+const (
+	// DefaultChunkSize is the number of the bytes of a file read at once.
+	DefaultChunkSize = 1 << 20
+	// DefaultStopTimeout limits the stop of the backup by Run.
+	DefaultStopTimeout = 10 * time.Second
+)
+
+// luaStart starts the backup marked with the id and returns the absolute path,
+// the path relative to the working directory and the size of every file,
+// the backup is stopped if they can't be listed
+const luaStart = `
+local checkpoint, id = ...
+local fio = require('fio')
+local files = box.backup.start(checkpoint)
+rawset(_G, '__backup_id', id)
+local ok, res = pcall(function()
+    local cwd = fio.cwd() .. '/'
+    local res = setmetatable({}, {__serialize = 'array'})
+    for i, path in ipairs(files) do
+        path = fio.abspath(path)
+        local name = path
+        if name:sub(1, #cwd) == cwd then
+            name = name:sub(#cwd + 1)
+        end
+        local st, err = fio.stat(path)
+        if st == nil then
+            error(err, 0)
+        end
+        res[i] = {path, name, st.size}
+    end
+    return res
+end)
+if not ok then
+    rawset(_G, '__backup_id', nil)
+    box.backup.stop()
+    error(res, 0)
+end
+return res
+`
+
+const luaStop = `
+rawset(_G, '__backup_id', nil)
+box.backup.stop()
+`
+
+// luaStopStarted stops the backup only if it has been started with the id
+const luaStopStarted = `
+local id = ...
+if rawget(_G, '__backup_id') ~= id then
+    return false
+end
+rawset(_G, '__backup_id', nil)
+box.backup.stop()
+return true
+`
+
+// luaRead reads the chunk of the file
+const luaRead = `
+local path, offset, size = ...
+local fio = require('fio')
+local f, err = fio.open(path, {'O_RDONLY'})
+if f == nil then
+    error(err, 0)
+end
+local data
+data, err = f:pread(size, offset)
+f:close()
+if data == nil then
+    error(err, 0)
+end
+return data
+`
+
+// ErrUnknownState is returned by Start if neither the start of the backup
+// nor its stop reached the server, so the backup may be left running:
+// it is stopped with box.backup.stop() on the server.
+var ErrUnknownState = errors.New("backup may be left running")
+
+// File is a file of the backup.
+type File struct {
+	// Path is the absolute path of the file on the server.
+	Path string
+	// Name is the path relative to the working directory of the server,
+	// or the absolute one if the file is outside of it.
+	Name string
+	Size int64
+}
+
+// Options are the options of the backup, the zero value is the backup of
+// the last checkpoint.
+type Options struct {
+	// Checkpoint is the number of the checkpoint before the last one to back up.
+	Checkpoint int
+	// ChunkSize is the number of the bytes of a file read at once,
+	// DefaultChunkSize is used if it is 0.
+	ChunkSize int
+	// StopTimeout limits the stop of the backup by Run,
+	// DefaultStopTimeout is used if it is 0.
+	StopTimeout time.Duration
+}
+
+// Backup is the running backup of the instance.
+type Backup struct {
+	conn      tarantool.Executor
+	chunkSize int
+	stopped   bool
+	// Files are the files to copy.
+	Files []File
+}
+
+// Start starts the backup of the checkpoint, the files of it are kept by the
+// server until Stop is called. Only one backup may run at a time.
+// If no reply to the start arrives, e.g. ctx is done, the backup is stopped
+// only if it has been started by this call. opts may be nil.
+func Start(ctx context.Context, conn tarantool.Executor, opts *Options) (*Backup, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	id := uuid.New().String()
+	res := conn.Exec(ctx, &tarantool.Eval{Expression: luaStart, Tuple: []interface{}{opts.Checkpoint, id}})
+	if res.Error != nil {
+		var qe *tarantool.QueryError
+		if errors.As(res.Error, &qe) {
+			return nil, res.Error
+		}
+		// the backup may have been started by the request,
+		// the one started by somebody else is left running
+		stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout(opts))
+		stop := conn.Exec(stopCtx, &tarantool.Eval{Expression: luaStopStarted, Tuple: []interface{}{id}})
+		cancel()
+		if stop.Error != nil {
+			return nil, fmt.Errorf("%w: %v", ErrUnknownState, res.Error)
+		}
+		return nil, res.Error
+	}
+
+	b := &Backup{conn: conn, chunkSize: opts.ChunkSize}
+	if b.chunkSize <= 0 {
+		b.chunkSize = DefaultChunkSize
+	}
+	if len(res.Data) > 0 {
+		for _, f := range res.Data[0] {
+			t, _ := f.([]interface{})
+			if len(t) < 3 {
+				continue
+			}
+			file := File{}
+			file.Path, _ = t[0].(string)
+			file.Name, _ = t[1].(string)
+			file.Size, _ = typeconv.IntfToInt64(t[2])
+			b.Files = append(b.Files, file)
+		}
+	}
+	return b, nil
+}
+
+func stopTimeout(opts *Options) time.Duration {
+	if opts.StopTimeout <= 0 {
+		return DefaultStopTimeout
+	}
+	return opts.StopTimeout
+}
+
+// Stop stops the backup, the files which aren't needed anymore may be
+// deleted by the server after it. Only the first call has an effect.
+func (b *Backup) Stop(ctx context.Context) error {
+	if b.stopped {
+		return nil
+	}
+	res := b.conn.Exec(ctx, &tarantool.Eval{Expression: luaStop})
+	if res.Error != nil {
+		return res.Error
+	}
+	b.stopped = true
+	return nil
+}
+
+// Open returns the reader of the file, which is read in chunks through the connection.
+func (b *Backup) Open(ctx context.Context, f File) io.Reader {
+	return &fileReader{ctx: ctx, backup: b, file: f}
+}
+
+type fileReader struct {
+	ctx    context.Context
+	backup *Backup
+	file   File
+	offset int64
+	buf    []byte
+}
+
+func (r *fileReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.offset >= r.file.Size {
+			return 0, io.EOF
+		}
+		if err := r.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *fileReader) fill() error {
+	size := int64(r.backup.chunkSize)
+	if rest := r.file.Size - r.offset; rest < size {
+		size = rest
+	}
+
+	res := r.backup.conn.Exec(r.ctx, &tarantool.Eval{
+		Expression: luaRead,
+		Tuple:      []interface{}{r.file.Path, r.offset, size},
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if len(res.Data) > 0 && len(res.Data[0]) > 0 {
+		switch data := res.Data[0][0].(type) {
+		case string:
+			r.buf = []byte(data)
+		case []byte:
+			r.buf = data
+		}
+	}
+	if len(r.buf) == 0 {
+		return io.ErrUnexpectedEOF
+	}
+	r.offset += int64(len(r.buf))
+	return nil
+}
+
+// Run starts the backup, sends its files over the transport one by one
+// and stops it, even if ctx is done or the transport fails.
+// opts may be nil.
+func Run(ctx context.Context, conn tarantool.Executor, t Transport, opts *Options) (files []File, err error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	b, err := Start(ctx, conn, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		stopCtx, cancel := context.WithTimeout(context.Background(), stopTimeout(opts))
+		defer cancel()
+		if serr := b.Stop(stopCtx); serr != nil && err == nil {
+			err = serr
+		}
+	}()
+
+	for _, f := range b.Files {
+		if err = t.Send(ctx, f, b.Open(ctx, f)); err != nil {
+			return b.Files, err
+		}
+	}
+	return b.Files, nil
+}
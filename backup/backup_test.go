@@ -0,0 +1,256 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// fakeServer backs up the files of its working directory
+type fakeServer struct {
+	sync.Mutex
+	dir     string
+	files   []string
+	started bool
+	// owner is the id of the backup started by Start
+	owner string
+	stops int
+	reads int
+	// delay delays the replies to the start and its cleanup
+	delay time.Duration
+}
+
+func newFakeServer(t *testing.T, files map[string]string) (*fakeServer, *tarantool.Connection) {
+	s := &fakeServer{dir: t.TempDir()}
+	for name, data := range files {
+		path := filepath.Join(s.dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(data), 0644))
+		s.files = append(s.files, name)
+	}
+
+	addr := tnttest.NewServer(t, s.handle)
+
+	conn := tnttest.Connect(t, addr, nil)
+	return s, conn
+}
+
+func queryError(msg string) *tarantool.Result {
+	return &tarantool.Result{ErrorCode: tarantool.ErrProcLua, Error: tarantool.NewQueryError(tarantool.ErrProcLua, msg)}
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	eval, ok := q.(*tarantool.Eval)
+	if !ok {
+		return &tarantool.Result{}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	switch eval.Expression {
+	case luaStart:
+		time.Sleep(s.delay)
+		if s.started {
+			return queryError("Backup is already in progress")
+		}
+		s.started = true
+		s.owner = eval.Tuple[1].(string)
+		var files []interface{}
+		for _, name := range s.files {
+			path := filepath.Join(s.dir, name)
+			st, err := os.Stat(path)
+			if err != nil {
+				return queryError(err.Error())
+			}
+			files = append(files, []interface{}{path, name, st.Size()})
+		}
+		return &tarantool.Result{Data: [][]interface{}{files}}
+	case luaStop:
+		s.started = false
+		s.owner = ""
+		s.stops++
+		return &tarantool.Result{}
+	case luaStopStarted:
+		time.Sleep(s.delay)
+		if !s.started || s.owner != eval.Tuple[0].(string) {
+			return &tarantool.Result{Data: [][]interface{}{{false}}}
+		}
+		s.started = false
+		s.owner = ""
+		s.stops++
+		return &tarantool.Result{Data: [][]interface{}{{true}}}
+	case luaRead:
+		s.reads++
+		path := eval.Tuple[0].(string)
+		offset, _ := typeconv.IntfToInt64(eval.Tuple[1])
+		size, _ := typeconv.IntfToInt64(eval.Tuple[2])
+		f, err := os.Open(path)
+		if err != nil {
+			return queryError(err.Error())
+		}
+		defer f.Close()
+		buf := make([]byte, size)
+		n, err := f.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return queryError(err.Error())
+		}
+		return &tarantool.Result{Data: [][]interface{}{{string(buf[:n])}}}
+	}
+	return queryError("unexpected eval")
+}
+
+func (s *fakeServer) state() (started bool, stops int) {
+	s.Lock()
+	defer s.Unlock()
+	return s.started, s.stops
+}
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	files := map[string]string{
+		"00000000000000000010.snap":        strings.Repeat("snapshot", 100),
+		"512/0/00000000000000000010.vylog": "vylog",
+		"512/0/00000000000000000008.index": "",
+		"00000000000000000010.xlog":        "xlog",
+	}
+	s, conn := newFakeServer(t, files)
+	dir := t.TempDir()
+
+	backed, err := Run(context.Background(), conn, Dir(dir), &Options{ChunkSize: 64})
+	require.NoError(err)
+	assert.Len(backed, 4)
+
+	started, stops := s.state()
+	assert.False(started)
+	assert.Equal(1, stops)
+	// the snapshot is read in chunks, the empty file isn't read at all
+	assert.Equal(13+1+1, s.reads)
+
+	for name, data := range files {
+		copied, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(err, name)
+		assert.Equal(data, string(copied), name)
+	}
+
+	// no temporary files are left
+	var names []string
+	filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if !d.IsDir() {
+			names = append(names, path)
+		}
+		return nil
+	})
+	assert.Len(names, 4)
+}
+
+func TestRunStops(t *testing.T) {
+	assert := assert.New(t)
+
+	s, conn := newFakeServer(t, map[string]string{"a.snap": "a", "b.xlog": "b"})
+
+	failure := errors.New("transport failed")
+	sent := 0
+	_, err := Run(context.Background(), conn, TransportFunc(func(ctx context.Context, f File, r io.Reader) error {
+		sent++
+		return failure
+	}), nil)
+	assert.Equal(failure, err)
+	assert.Equal(1, sent)
+	started, stops := s.state()
+	assert.False(started)
+	assert.Equal(1, stops)
+
+	// the backup is stopped even if ctx is done
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err = Run(ctx, conn, TransportFunc(func(ctx context.Context, f File, r io.Reader) error {
+		cancel()
+		_, err := io.Copy(io.Discard, r)
+		return err
+	}), nil)
+	assert.Error(err)
+	started, stops = s.state()
+	assert.False(started)
+	assert.Equal(2, stops)
+}
+
+func TestStart(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, conn := newFakeServer(t, map[string]string{"a.snap": "abc"})
+
+	b, err := Start(context.Background(), conn, nil)
+	require.NoError(err)
+	require.Len(b.Files, 1)
+	assert.Equal(File{Path: filepath.Join(s.dir, "a.snap"), Name: "a.snap", Size: 3}, b.Files[0])
+
+	// only one backup may run at a time
+	_, err = Start(context.Background(), conn, nil)
+	assert.Error(err)
+
+	data, err := io.ReadAll(b.Open(context.Background(), b.Files[0]))
+	require.NoError(err)
+	assert.Equal("abc", string(data))
+
+	// the file is shorter than it was
+	_, err = io.ReadAll(b.Open(context.Background(), File{Path: b.Files[0].Path, Size: 10}))
+	assert.Equal(io.ErrUnexpectedEOF, err)
+
+	require.NoError(b.Stop(context.Background()))
+	require.NoError(b.Stop(context.Background()))
+	_, stops := s.state()
+	assert.Equal(1, stops)
+}
+
+func TestStartNoReply(t *testing.T) {
+	assert := assert.New(t)
+
+	s, conn := newFakeServer(t, map[string]string{"a.snap": "abc"})
+	s.delay = 100 * time.Millisecond
+
+	// the backup started by the request is stopped
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	_, err := Start(ctx, conn, nil)
+	cancel()
+	assert.Error(err)
+	started, stops := s.state()
+	assert.False(started)
+	assert.Equal(1, stops)
+
+	// the backup of somebody else is left running
+	s.Lock()
+	s.started = true
+	s.Unlock()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	_, err = Start(ctx, conn, nil)
+	cancel()
+	assert.Error(err)
+	started, stops = s.state()
+	assert.True(started)
+	assert.Equal(1, stops)
+
+	// neither the start nor the stop is answered
+	s.Lock()
+	s.started = false
+	s.Unlock()
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	_, err = Start(ctx, conn, &Options{StopTimeout: 10 * time.Millisecond})
+	cancel()
+	assert.True(errors.Is(err, ErrUnknownState))
+}
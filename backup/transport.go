@@ -0,0 +1,51 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Transport receives the files of the backup.
+type Transport interface {
+	// Send copies the file read from r, which is read through the connection.
+	Send(ctx context.Context, f File, r io.Reader) error
+}
+
+// TransportFunc is the function implementing Transport.
+type TransportFunc func(ctx context.Context, f File, r io.Reader) error
+
+// Send calls the function.
+func (fn TransportFunc) Send(ctx context.Context, f File, r io.Reader) error {
+	return fn(ctx, f, r)
+}
+
+// Dir is the local directory the files are saved to by their names, so the
+// layout of the working directory of the server is kept, e.g. of the vinyl files.
+// A file is written under a temporary name and renamed when it is complete.
+type Dir string
+
+// Send saves the file.
+func (d Dir) Send(ctx context.Context, f File, r io.Reader) error {
+	path := filepath.Join(string(d), filepath.FromSlash(strings.TrimPrefix(f.Name, "/")))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
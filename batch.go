@@ -0,0 +1,136 @@
+package tarantool
+
+import (
+	"context"
+)
+
+// procBatchApply is the name of the Lua function installed by the client to apply batches
+const procBatchApply = "__go_tarantool_batch_apply"
+
+// luaBatchApply defines the function which executes all the batch operations in one transaction.
+// Update operations are expected to have 1-based field numbers.
+const luaBatchApply = `
+rawset(_G, '` + procBatchApply + `', function(ops)
+    local function index(space, id)
+        local idx = space.index[id]
+        if idx == nil then
+            error(string.format("no index %s in space '%s'", tostring(id), space.name))
+        end
+        return idx
+    end
+
+    box.begin()
+    local ok, err = pcall(function()
+        for _, op in ipairs(ops) do
+            local space = box.space[op[2]]
+            if space == nil then
+                error(string.format("space '%s' does not exist", tostring(op[2])))
+            end
+            if op[1] == 'insert' then
+                space:insert(op[3])
+            elseif op[1] == 'replace' then
+                space:replace(op[3])
+            elseif op[1] == 'delete' then
+                index(space, op[3]):delete(op[4])
+            elseif op[1] == 'update' then
+                index(space, op[3]):update(op[4], op[5])
+            elseif op[1] == 'upsert' then
+                space:upsert(op[3], op[4])
+            else
+                error('unknown batch operation ' .. tostring(op[1]))
+            end
+        end
+    end)
+    if not ok then
+        box.rollback()
+        error(err)
+    end
+    box.commit()
+    return #ops
+end)
+`
+
+// Batch collects data modification requests to be applied server-side
+// in a single transaction with one round trip, see Connection.ApplyBatch.
+// Spaces and indexes can be given either by name or by number.
+type Batch struct {
+	ops []interface{}
+}
+
+// Len returns the number of operations in the batch.
+func (b *Batch) Len() int {
+	return len(b.ops)
+}
+
+// Reset removes all the operations from the batch.
+func (b *Batch) Reset() {
+	b.ops = b.ops[:0]
+}
+
+func (b *Batch) Insert(space interface{}, tuple []interface{}) *Batch {
+	b.ops = append(b.ops, []interface{}{"insert", space, tuple})
+	return b
+}
+
+func (b *Batch) Replace(space interface{}, tuple []interface{}) *Batch {
+	b.ops = append(b.ops, []interface{}{"replace", space, tuple})
+	return b
+}
+
+func (b *Batch) Delete(space, index interface{}, key []interface{}) *Batch {
+	b.ops = append(b.ops, []interface{}{"delete", space, batchIndex(index), key})
+	return b
+}
+
+func (b *Batch) Update(space, index interface{}, key []interface{}, set []Operator) *Batch {
+	b.ops = append(b.ops, []interface{}{"update", space, batchIndex(index), key, luaOperators(set)})
+	return b
+}
+
+func (b *Batch) Upsert(space interface{}, tuple []interface{}, set []Operator) *Batch {
+	b.ops = append(b.ops, []interface{}{"upsert", space, tuple, luaOperators(set)})
+	return b
+}
+
+func batchIndex(index interface{}) interface{} {
+	if index == nil {
+		return 0
+	}
+	return index
+}
+
+// luaOperators converts update operations to the Lua form,
+// where non-negative field numbers start from 1.
+func luaOperators(set []Operator) []interface{} {
+	ops := make([]interface{}, len(set))
+	for i, op := range set {
+		t := op.AsTuple()
+		if field, ok := t[1].(int64); ok && field >= 0 {
+			t[1] = field + 1
+		}
+		ops[i] = t
+	}
+	return ops
+}
+
+// ApplyBatch executes all the batch operations in one transaction on the server.
+// Either all of them are applied or none. The helper Lua function is installed
+// with Eval the first time the server reports it is missing, so the user
+// needs the execute privilege on the universe.
+// On success the result contains the number of applied operations.
+func (conn *Connection) ApplyBatch(ctx context.Context, b *Batch) *Result {
+	call := &Call17{Name: procBatchApply, Tuple: []interface{}{b.ops}}
+	if b.ops == nil {
+		call.Tuple = []interface{}{[]interface{}{}}
+	}
+
+	res := conn.Exec(ctx, call)
+	if qe, ok := res.Error.(*QueryError); !ok || qe.Code != ErrNoSuchProc {
+		return res
+	}
+
+	if res = conn.Exec(ctx, &Eval{Expression: luaBatchApply}); res.Error != nil {
+		return res
+	}
+	return conn.Exec(ctx, call)
+}
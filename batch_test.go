@@ -0,0 +1,63 @@
+package tarantool
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyBatch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var mu sync.Mutex
+	var installed bool
+	var calls []*Call17
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		mu.Lock()
+		defer mu.Unlock()
+		switch q := q.(type) {
+		case *Eval:
+			installed = true
+		case *Call17:
+			calls = append(calls, q)
+			if !installed {
+				return &Result{ErrorCode: ErrNoSuchProc, Error: NewQueryError(ErrNoSuchProc, "Procedure is not defined")}
+			}
+			return &Result{Data: [][]interface{}{{int64(len(q.Tuple[0].([]interface{})))}}}
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	b := &Batch{}
+	b.Insert("tester", []interface{}{int64(1), "one"}).
+		Update("tester", nil, []interface{}{int64(2)}, []Operator{&OpAssign{Field: 1, Argument: "two"}}).
+		Delete(uint(512), "primary", []interface{}{int64(3)})
+	require.Equal(3, b.Len())
+
+	res := conn.ApplyBatch(context.Background(), b)
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(3)}}, res.Data)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(installed)
+	require.Len(calls, 2)
+
+	ops := calls[1].Tuple[0].([]interface{})
+	require.Len(ops, 3)
+	assert.Equal("insert", ops[0].([]interface{})[0])
+	update := ops[1].([]interface{})
+	assert.Equal("update", update[0])
+	assert.EqualValues(0, update[2])
+	// field numbers are converted to the Lua convention
+	assert.EqualValues(2, update[4].([]interface{})[0].([]interface{})[1])
+	assert.Equal("primary", ops[2].([]interface{})[2])
+}
@@ -9,9 +9,19 @@ import (
 	"github.com/tinylib/msgp/msgp"
 )
 
+// bodyReadChunk is the initial buffer size for reading the bodies
+// which don't fit into the packet buffer
+const bodyReadChunk = 64 * 1024
+
+// smallBodySize is the size of the body packed into the packet itself,
+// it fits the point reads and writes of short tuples
+const smallBodySize = 64
+
 type BinaryPacket struct {
 	body   []byte
-	header [32]byte
+	header [48]byte
+	// small keeps the small bodies, so a new packet doesn't allocate them
+	small  [smallBodySize]byte
 	pool   *BinaryPacketPool
 	packet Packet
 }
@@ -20,7 +30,7 @@ type UnmarshalBinaryBodyFunc func(*Packet, []byte) error
 
 // WriteTo implements the io.WriterTo interface
 func (pp *BinaryPacket) WriteTo(w io.Writer) (n int64, err error) {
-	h32 := pp.header[:32]
+	h32 := pp.header[:]
 	h32[0], h32[1], h32[2], h32[3], h32[4] = 0xce, 0, 0, 0, 0
 
 	h := h32[5:5]
@@ -30,6 +40,9 @@ func (pp *BinaryPacket) WriteTo(w io.Writer) (n int64, err error) {
 	if pp.packet.SchemaID != 0 {
 		ne++
 	}
+	if pp.packet.StreamID != 0 {
+		ne++
+	}
 	h = msgp.AppendMapHeader(h, ne)
 	h = msgp.AppendUint(h, KeyCode)
 	h = msgp.AppendUint(h, pp.packet.Cmd)
@@ -39,6 +52,10 @@ func (pp *BinaryPacket) WriteTo(w io.Writer) (n int64, err error) {
 		h = msgp.AppendUint(h, KeySchemaID)
 		h = msgp.AppendUint32(h, pp.packet.SchemaID)
 	}
+	if pp.packet.StreamID != 0 {
+		h = msgp.AppendUint(h, KeyStreamID)
+		h = msgp.AppendUint64(h, pp.packet.StreamID)
+	}
 
 	l := len(h) + len(body)
 	h = h32[:5+len(h)]
@@ -60,6 +77,7 @@ func (pp *BinaryPacket) WriteTo(w io.Writer) (n int64, err error) {
 func (pp *BinaryPacket) Reset() {
 	pp.packet.Cmd = OKCommand
 	pp.packet.SchemaID = 0
+	pp.packet.StreamID = 0
 	pp.packet.requestID = 0
 	pp.packet.Result = nil
 	pp.body = pp.body[:0]
@@ -71,11 +89,11 @@ func (pp *BinaryPacket) Release() {
 	}
 }
 
-// ReadFrom implements the io.ReaderFrom interface
+// ReadFrom implements the io.ReaderFrom interface. The length prefix is read
+// into the header scratch buffer of the packet and decoded in place.
 func (pp *BinaryPacket) ReadFrom(r io.Reader) (n int64, err error) {
-	var h = pp.header[:8]
+	var h = pp.header[:5]
 	var bodyLength uint
-	var headerLength uint
 	var rr, crr int
 
 	if rr, err = io.ReadFull(r, h[:1]); err != nil {
@@ -85,33 +103,32 @@ func (pp *BinaryPacket) ReadFrom(r io.Reader) (n int64, err error) {
 	c := h[0]
 	switch {
 	case c <= 0x7f:
-		headerLength = 1
-	case c == 0xcc:
-		headerLength = 2
-	case c == 0xcd:
-		headerLength = 3
-	case c == 0xce:
-		headerLength = 5
-	default:
-		return int64(rr), fmt.Errorf("wrong packet header: %#v", c)
-	}
-
-	if headerLength > 1 {
-		crr, err = io.ReadFull(r, h[1:headerLength])
+		bodyLength = uint(c)
+	case c == 0xcc, c == 0xcd, c == 0xce:
+		h = h[:1+(1<<(c-0xcc))]
+		crr, err = io.ReadFull(r, h[1:])
 		if rr = rr + crr; err != nil {
 			return int64(rr), err
 		}
+		switch c {
+		case 0xcc:
+			bodyLength = uint(h[1])
+		case 0xcd:
+			bodyLength = uint(binary.BigEndian.Uint16(h[1:]))
+		default:
+			bodyLength = uint(binary.BigEndian.Uint32(h[1:]))
+		}
+	default:
+		return int64(rr), fmt.Errorf("wrong packet header: %#v", c)
 	}
 
-	if bodyLength, _, err = msgp.ReadUintBytes(h[:headerLength]); err != nil {
-		return int64(rr), err
-	}
 	if bodyLength == 0 {
 		return int64(rr), errors.New("Packet should not be 0 length")
 	}
 
 	if uint(cap(pp.body)) < bodyLength {
-		pp.body = make([]byte, bodyLength+bodyLength/2)
+		crr, err = pp.readBody(r, bodyLength)
+		return int64(rr) + int64(crr), err
 	}
 
 	pp.body = pp.body[:bodyLength]
@@ -119,9 +136,43 @@ func (pp *BinaryPacket) ReadFrom(r io.Reader) (n int64, err error) {
 	return int64(rr) + int64(crr), err
 }
 
+// readBody reads the body which does not fit into the buffer. The buffer grows
+// as the data arrives, so a corrupted length can't make it allocate more than
+// the stream actually has.
+func (pp *BinaryPacket) readBody(r io.Reader, n uint) (int, error) {
+	body := pp.body[:0]
+	for uint(len(body)) < n {
+		if len(body) == cap(body) {
+			size := 2 * uint(cap(body))
+			if size < bodyReadChunk {
+				size = bodyReadChunk
+			}
+			if size > n+n/2 {
+				size = n + n/2
+			}
+			grown := make([]byte, len(body), size)
+			copy(grown, body)
+			body = grown
+		}
+
+		end := uint(cap(body))
+		if end > n {
+			end = n
+		}
+		rr, err := io.ReadFull(r, body[len(body):end])
+		body = body[:len(body)+rr]
+		if err != nil {
+			pp.body = body
+			return len(body), err
+		}
+	}
+	pp.body = body
+	return len(body), nil
+}
+
 func (pp *BinaryPacket) Unmarshal() error {
 	if err := pp.packet.UnmarshalBinary(pp.body); err != nil {
-		return fmt.Errorf("Error decoding packet type %d: %s", pp.packet.Cmd, err)
+		return &DecodeError{Cmd: pp.packet.Cmd, Err: err}
 	}
 	return nil
 }
@@ -130,16 +181,39 @@ func (pp *BinaryPacket) UnmarshalCustomBody(um UnmarshalBinaryBodyFunc) (err err
 	buf := pp.body
 
 	if buf, err = pp.packet.UnmarshalBinaryHeader(buf); err != nil {
-		return fmt.Errorf("Error decoding packet type %d: %s", pp.packet.Cmd, err)
+		return &DecodeError{Cmd: pp.packet.Cmd, Err: err}
 	}
 
 	if err = um(&pp.packet, buf); err != nil {
-		return fmt.Errorf("Error decoding packet type %d: %s", pp.packet.Cmd, err)
+		return &DecodeError{Cmd: pp.packet.Cmd, Err: err}
 	}
 
 	return nil
 }
 
+// UnmarshalTuples calls fn for every tuple of the reply data with its raw msgpack
+// representation. Error replies are decoded as usual and returned as error.
+// The tuple slice is only valid until fn returns.
+func (pp *BinaryPacket) UnmarshalTuples(fn func(tuple []byte) error) (err error) {
+	var buf []byte
+
+	if buf, err = pp.packet.UnmarshalBinaryHeader(pp.body); err != nil {
+		return &DecodeError{Cmd: pp.packet.Cmd, Err: err}
+	}
+
+	if pp.packet.Cmd != OKCommand {
+		if _, err = pp.packet.UnmarshalBinaryBody(buf); err != nil {
+			return &DecodeError{Cmd: pp.packet.Cmd, Err: err}
+		}
+		if res := pp.packet.Result; res != nil && res.Error != nil {
+			return res.Error
+		}
+		return ErrBadResult
+	}
+
+	return forEachTuple(buf, fn)
+}
+
 func (pp *BinaryPacket) Bytes() []byte {
 	return pp.body
 }
@@ -155,9 +229,10 @@ func (pp *BinaryPacket) readPacket(r io.Reader) (err error) {
 	return pp.packet.UnmarshalBinary(pp.body)
 }
 
-// ReadRawPacket reads the whole packet body and only unpacks request ID for routing purposes
-func (pp *BinaryPacket) readRawPacket(r io.Reader) (requestID uint64, err error) {
+// ReadRawPacket reads the whole packet body and only unpacks request ID and code for routing purposes
+func (pp *BinaryPacket) readRawPacket(r io.Reader) (requestID uint64, code uint, err error) {
 	var l uint32
+	var hasCode, hasSync bool
 
 	requestID = 0
 	if _, err = pp.ReadFrom(r); err != nil {
@@ -174,11 +249,23 @@ func (pp *BinaryPacket) readRawPacket(r io.Reader) (requestID uint64, err error)
 		if cd, buf, err = msgp.ReadUintBytes(buf); err != nil {
 			return
 		}
-		if cd == KeySync {
-			requestID, _, err = msgp.ReadUint64Bytes(buf)
-			return
+		switch cd {
+		case KeyCode:
+			if code, buf, err = msgp.ReadUintBytes(buf); err != nil {
+				return
+			}
+			hasCode = true
+		case KeySync:
+			if requestID, buf, err = msgp.ReadUint64Bytes(buf); err != nil {
+				return
+			}
+			hasSync = true
+		default:
+			if buf, err = msgp.Skip(buf); err != nil {
+				return
+			}
 		}
-		if buf, err = msgp.Skip(buf); err != nil {
+		if hasCode && hasSync {
 			return
 		}
 	}
@@ -187,6 +274,9 @@ func (pp *BinaryPacket) readRawPacket(r io.Reader) (requestID uint64, err error)
 }
 
 func (pp *BinaryPacket) packMsg(q Query, packdata *packData) (err error) {
+	if cap(pp.body) == 0 {
+		pp.body = pp.small[:0]
+	}
 	if iq, ok := q.(internalQuery); ok {
 		if pp.body, err = iq.packMsg(packdata, pp.body[:0]); err != nil {
 			pp.packet.Cmd = ErrorFlag
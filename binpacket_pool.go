@@ -1,12 +1,23 @@
 package tarantool
 
+import "runtime"
+
+// poolCapacity returns the number of idle objects kept by a pool,
+// so that every CPU can have a batch of them in flight.
+func poolCapacity() int {
+	if n := 256 * runtime.GOMAXPROCS(0); n > 1024 {
+		return n
+	}
+	return 1024
+}
+
 type BinaryPacketPool struct {
 	queue chan *BinaryPacket
 }
 
 func newBinaryPacketPool() *BinaryPacketPool {
 	return &BinaryPacketPool{
-		queue: make(chan *BinaryPacket, 1024),
+		queue: make(chan *BinaryPacket, poolCapacity()),
 	}
 }
 
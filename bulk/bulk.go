@@ -0,0 +1,68 @@
+// Package bulk exports spaces to JSON Lines or CSV and imports them back,
+// it's the library behind the tntdump and tntload commands.
+//
+// A JSON line is the object of the fields named by the space format, the
+// unnamed fields are numbered from 1, or the array of the fields if the space
+// has no format. A CSV file starts with the header of the field names if
+// the space has a format, the arrays and the maps are written as JSON and
+// the cells are converted back to the types of the fields on import.
+//
+//	b := bulk.New(conn)
+//	n, err := b.Export(ctx, "users", w, bulk.CSV, nil)
+//	...
+//	stats, err := b.Import(ctx, "users", r, bulk.CSV, &bulk.BatchOptions{Mode: bulk.Replace})
+package bulk
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// Conn is implemented by tarantool.Connection.
+type Conn interface {
+	Exec(ctx context.Context, q tarantool.Query, options ...tarantool.ExecOption) *tarantool.Result
+	ApplyBatch(ctx context.Context, b *tarantool.Batch) *tarantool.Result
+	GetPrimaryKeyFields(space interface{}) ([]int, bool)
+	GetSpaceFields(space interface{}) ([]string, bool)
+}
+
+// Format is the format of the exported rows.
+type Format int
+
+const (
+	JSONLines Format = iota
+	CSV
+)
+
+// ParseFormat returns the format by its name, jsonl or csv.
+func ParseFormat(s string) (Format, error) {
+	switch s {
+	case "jsonl":
+		return JSONLines, nil
+	case "csv":
+		return CSV, nil
+	}
+	return 0, fmt.Errorf("unknown format %q", s)
+}
+
+// String returns the name of the format, which is also the file extension.
+func (f Format) String() string {
+	if f == CSV {
+		return "csv"
+	}
+	return "jsonl"
+}
+
+// Bulk exports and imports the spaces of the connection.
+// It is safe for concurrent use.
+type Bulk struct {
+	conn Conn
+}
+
+// New returns Bulk for the connection established with the schema,
+// the primary keys and the field names are taken from the schema cache.
+func New(conn Conn) *Bulk {
+	return &Bulk{conn: conn}
+}
@@ -0,0 +1,228 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// fakeServer has the users space with the format of the unsigned id,
+// the string name and the nullable unsigned age and the events space
+// without a format, both with the integer primary key in the first field
+type fakeServer struct {
+	sync.Mutex
+	tuples map[string][][]interface{}
+	// selects is the number of the page requests
+	selects int
+	batches int
+	// failAt makes the batches time out after the number of them
+	failAt int
+}
+
+var spaceIDs = map[string]uint{"users": 512, "events": 513}
+
+func newFakeServer(t *testing.T) (*fakeServer, *tarantool.Connection) {
+	s := &fakeServer{tuples: map[string][][]interface{}{}}
+
+	addr := tnttest.NewServer(t, s.handle)
+
+	conn := tnttest.Connect(t, addr, &tarantool.Options{QueryTimeout: 200 * time.Millisecond})
+	return s, conn
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	switch q := q.(type) {
+	case *tarantool.Select:
+		return s.selectPage(q)
+	case *tarantool.Eval:
+		if q.Tuple[0] != "users" {
+			return &tarantool.Result{Data: [][]interface{}{{}}}
+		}
+		return &tarantool.Result{Data: [][]interface{}{{
+			map[string]interface{}{"name": "id", "type": "unsigned"},
+			map[string]interface{}{"name": "name", "type": "string"},
+			map[string]interface{}{"name": "age", "type": "unsigned", "is_nullable": true},
+		}}}
+	case *tarantool.Call17:
+		return s.apply(q.Tuple[0].([]interface{}))
+	}
+	return &tarantool.Result{}
+}
+
+func (s *fakeServer) selectPage(q *tarantool.Select) *tarantool.Result {
+	pk := []interface{}{[]interface{}{uint64(0), "unsigned"}}
+	switch q.Space {
+	case tarantool.ViewSpace:
+		return &tarantool.Result{Data: [][]interface{}{
+			{uint64(512), uint64(1), "users", "memtx", uint64(0), map[string]interface{}{}, []interface{}{
+				map[string]interface{}{"name": "id", "type": "unsigned"},
+				map[string]interface{}{"name": "name", "type": "string"},
+				map[string]interface{}{"name": "age", "type": "unsigned", "is_nullable": true},
+			}},
+			{uint64(513), uint64(1), "events", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+		}}
+	case tarantool.ViewIndex:
+		return &tarantool.Result{Data: [][]interface{}{
+			{uint64(512), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, pk},
+			{uint64(513), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, pk},
+		}}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.selects++
+
+	var key int64
+	if q.Key != nil {
+		key, _ = typeconv.IntfToInt64(q.Key)
+	} else if len(q.KeyTuple) > 0 {
+		key, _ = typeconv.IntfToInt64(q.KeyTuple[0])
+	}
+
+	space, _ := typeconv.IntfToUint(q.Space)
+	var data [][]interface{}
+	for name, id := range spaceIDs {
+		if id != space {
+			continue
+		}
+		for _, t := range s.tuples[name] {
+			id, _ := typeconv.IntfToInt64(t[0])
+			if q.Iterator == tarantool.IterGe && id < key || q.Iterator == tarantool.IterGt && id <= key {
+				continue
+			}
+			if len(data) == int(q.Limit) {
+				break
+			}
+			data = append(data, t)
+		}
+	}
+	return &tarantool.Result{Data: data}
+}
+
+func queryError(format string, args ...interface{}) *tarantool.Result {
+	return &tarantool.Result{
+		ErrorCode: tarantool.ErrProcLua,
+		Error:     tarantool.NewQueryError(tarantool.ErrProcLua, fmt.Sprintf(format, args...)),
+	}
+}
+
+// apply applies the batch to a copy of the tuples, which replaces them on success
+func (s *fakeServer) apply(ops []interface{}) *tarantool.Result {
+	s.Lock()
+	defer s.Unlock()
+
+	s.batches++
+	if s.failAt > 0 && s.batches > s.failAt {
+		s.Unlock()
+		time.Sleep(time.Second)
+		s.Lock()
+		return &tarantool.Result{}
+	}
+
+	spaces := make(map[string]map[int64][]interface{})
+	for _, op := range ops {
+		op := op.([]interface{})
+		name := op[1].(string)
+		tuples, ok := spaces[name]
+		if !ok {
+			tuples = make(map[int64][]interface{})
+			for _, t := range s.tuples[name] {
+				id, _ := typeconv.IntfToInt64(t[0])
+				tuples[id] = t
+			}
+			spaces[name] = tuples
+		}
+
+		tuple := op[2].([]interface{})
+		id, ok := typeconv.IntfToInt64(tuple[0])
+		if !ok {
+			return queryError("bad id %v", tuple[0])
+		}
+		old, exists := tuples[id]
+		if exists && op[0] == "insert" {
+			return queryError("duplicate key %d", id)
+		}
+		if exists && op[0] == "upsert" {
+			updated := append([]interface{}(nil), old...)
+			for _, o := range op[3].([]interface{}) {
+				o := o.([]interface{})
+				field, _ := typeconv.IntfToInt(o[1])
+				for len(updated) < field {
+					updated = append(updated, nil)
+				}
+				updated[field-1] = o[2]
+			}
+			tuple = updated
+		}
+		tuples[id] = tuple
+	}
+
+	for name, tuples := range spaces {
+		sorted := make([][]interface{}, 0, len(tuples))
+		for _, t := range tuples {
+			sorted = append(sorted, t)
+		}
+		sort.Slice(sorted, func(i, j int) bool {
+			a, _ := typeconv.IntfToInt64(sorted[i][0])
+			b, _ := typeconv.IntfToInt64(sorted[j][0])
+			return a < b
+		})
+		s.tuples[name] = sorted
+	}
+	return &tarantool.Result{Data: [][]interface{}{{int64(len(ops))}}}
+}
+
+func (s *fakeServer) space(name string) [][]interface{} {
+	s.Lock()
+	defer s.Unlock()
+	return s.tuples[name]
+}
+
+func TestRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, conn := newFakeServer(t)
+	users := [][]interface{}{
+		{int64(1), "a", int64(30)},
+		{int64(2), "b, \"c\"", nil},
+		{int64(3), "", int64(1), []interface{}{"x", int64(1)}, map[string]interface{}{"k": true}},
+	}
+	s.tuples["users"] = users
+	b := New(conn)
+
+	for _, format := range []Format{JSONLines, CSV} {
+		var buf bytes.Buffer
+		n, err := b.Export(context.Background(), "users", &buf, format, &ExportOptions{PageSize: 2})
+		require.NoError(err)
+		assert.Equal(3, n)
+
+		s.tuples["users"] = nil
+		stats, err := b.Import(context.Background(), "users", &buf, format, nil)
+		require.NoError(err)
+		assert.Equal(&ImportStats{Loaded: 3}, stats)
+		assert.Equal(users, s.space("users"), format.String())
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, f := range []Format{JSONLines, CSV} {
+		parsed, err := ParseFormat(f.String())
+		assert.NoError(err)
+		assert.Equal(f, parsed)
+	}
+	_, err := ParseFormat("xml")
+	assert.Error(err)
+}
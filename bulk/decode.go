@@ -0,0 +1,249 @@
+package bulk
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// field is the field of the space format
+type field struct {
+	name     string
+	typ      string
+	nullable bool
+}
+
+// row is the input row, err is set if it can't be turned into a tuple
+type row struct {
+	n     int // the line of JSON or the record of CSV
+	tuple []interface{}
+	raw   interface{} // the line or the CSV record
+	err   error
+}
+
+type decoder interface {
+	next() (*row, error)
+}
+
+func newDecoder(format Format, r io.Reader, fields []field) (decoder, error) {
+	if format == CSV {
+		return newCSVDecoder(r, fields)
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	return &jsonDecoder{s: s, fields: fields}, nil
+}
+
+// jsonDecoder reads the JSON lines, an object is matched with the space format
+// by the field names, the numbers name the fields from 1
+type jsonDecoder struct {
+	s      *bufio.Scanner
+	fields []field
+	n      int
+}
+
+func (d *jsonDecoder) next() (*row, error) {
+	for d.s.Scan() {
+		line := d.s.Text()
+		d.n++
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		r := &row{n: d.n, raw: line}
+		r.tuple, r.err = d.decode(line)
+		return r, nil
+	}
+	if err := d.s.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (d *jsonDecoder) decode(line string) ([]interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(line))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	switch v := fromJSON(v).(type) {
+	case []interface{}:
+		return v, nil
+	case map[string]interface{}:
+		var tuple []interface{}
+		for k, fv := range v {
+			i, err := fieldIndex(d.fields, k)
+			if err != nil {
+				return nil, err
+			}
+			for len(tuple) <= i {
+				tuple = append(tuple, nil)
+			}
+			tuple[i] = fv
+		}
+		return tuple, nil
+	}
+	return nil, errors.New("the line is neither an array nor an object")
+}
+
+// fieldIndex returns the 0-based number of the named or numbered field
+func fieldIndex(fields []field, name string) (int, error) {
+	for i, f := range fields {
+		if f.name == name {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(name); err == nil && n > 0 {
+		return n - 1, nil
+	}
+	return 0, fmt.Errorf("unknown field %q", name)
+}
+
+// fromJSON turns the JSON numbers into integers or floats
+func fromJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(string(v), 10, 64); err == nil {
+			return u
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i := range v {
+			v[i] = fromJSON(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = fromJSON(v[k])
+		}
+	}
+	return v
+}
+
+// csvDecoder reads the CSV records, which start with the header of the field
+// names if the space has a format, and converts the cells to the field types
+type csvDecoder struct {
+	r      *csv.Reader
+	fields []field
+	// columns are the field numbers of the columns
+	columns []int
+	header  []string
+	n       int
+}
+
+func newCSVDecoder(r io.Reader, fields []field) (*csvDecoder, error) {
+	d := &csvDecoder{r: csv.NewReader(r), fields: fields}
+	d.r.FieldsPerRecord = -1
+
+	if len(fields) == 0 {
+		return d, nil
+	}
+	header, err := d.r.Read()
+	if err == io.EOF {
+		return d, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.header = header
+	d.n++
+	d.columns = make([]int, len(header))
+	for i, name := range header {
+		if d.columns[i], err = fieldIndex(fields, name); err != nil {
+			return nil, fmt.Errorf("header: %w", err)
+		}
+	}
+	return d, nil
+}
+
+func (d *csvDecoder) next() (*row, error) {
+	record, err := d.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	d.n++
+	r := &row{n: d.n, raw: record}
+	r.tuple, r.err = d.decode(record)
+	return r, nil
+}
+
+func (d *csvDecoder) decode(record []string) ([]interface{}, error) {
+	var tuple []interface{}
+	for i, cell := range record {
+		n := i
+		if i < len(d.columns) {
+			n = d.columns[i]
+		}
+		for len(tuple) <= n {
+			tuple = append(tuple, nil)
+		}
+
+		var f field
+		if n < len(d.fields) {
+			f = d.fields[n]
+		}
+		v, err := parseCell(cell, f)
+		if err != nil {
+			return nil, fmt.Errorf("column %d: %w", i+1, err)
+		}
+		tuple[n] = v
+	}
+	return tuple, nil
+}
+
+// parseCell converts the cell to the field type, the type of the cells beyond
+// the format and of the fields of any type is guessed
+func parseCell(cell string, f field) (interface{}, error) {
+	if cell == "" && (f.nullable || f.typ != "string") {
+		return nil, nil
+	}
+
+	switch f.typ {
+	case "string", "varbinary", "uuid", "decimal", "datetime":
+		return cell, nil
+	case "unsigned":
+		return strconv.ParseUint(cell, 10, 64)
+	case "integer":
+		if i, err := strconv.ParseInt(cell, 10, 64); err == nil {
+			return i, nil
+		}
+		return strconv.ParseUint(cell, 10, 64)
+	case "number", "double":
+		if i, err := strconv.ParseInt(cell, 10, 64); err == nil && f.typ == "number" {
+			return i, nil
+		}
+		return strconv.ParseFloat(cell, 64)
+	case "boolean":
+		return strconv.ParseBool(cell)
+	case "array", "map":
+		return parseJSON(cell)
+	}
+
+	if v, err := parseJSON(cell); err == nil {
+		return v, nil
+	}
+	return cell, nil
+}
+
+func parseJSON(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("trailing data after JSON")
+	}
+	return fromJSON(v), nil
+}
@@ -0,0 +1,57 @@
+package bulk
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCell(t *testing.T) {
+	assert := assert.New(t)
+
+	cases := []struct {
+		cell  string
+		field field
+		value interface{}
+	}{
+		{"12", field{typ: "unsigned"}, uint64(12)},
+		{"-12", field{typ: "integer"}, int64(-12)},
+		{"12", field{typ: "string"}, "12"},
+		{"", field{typ: "string"}, ""},
+		{"", field{typ: "string", nullable: true}, nil},
+		{"", field{typ: "unsigned"}, nil},
+		{"2", field{typ: "number"}, int64(2)},
+		{"2", field{typ: "double"}, 2.0},
+		{"2.5", field{typ: "number"}, 2.5},
+		{"true", field{typ: "boolean"}, true},
+		{"[1, {\"a\": null}]", field{typ: "array"}, []interface{}{int64(1), map[string]interface{}{"a": nil}}},
+		{"abc", field{typ: "any"}, "abc"},
+		{"7", field{}, int64(7)},
+		{"1 2", field{}, "1 2"},
+	}
+	for _, c := range cases {
+		v, err := parseCell(c.cell, c.field)
+		assert.NoError(err, c.cell)
+		assert.Equal(c.value, v, c.cell)
+	}
+
+	_, err := parseCell("-1", field{typ: "unsigned"})
+	assert.Error(err)
+	_, err = parseCell("{", field{typ: "map"})
+	assert.Error(err)
+}
+
+func TestDecodeJSON(t *testing.T) {
+	assert := assert.New(t)
+
+	d := &jsonDecoder{fields: []field{{name: "id"}, {name: "name"}}}
+
+	tuple, err := d.decode(`{"name": "a", "id": 18446744073709551615, "4": 1e3}`)
+	assert.NoError(err)
+	assert.Equal([]interface{}{uint64(18446744073709551615), "a", nil, 1000.0}, tuple)
+
+	_, err = d.decode(`"a"`)
+	assert.Error(err)
+	_, err = d.decode(`{"0": 1}`)
+	assert.Error(err)
+}
@@ -0,0 +1,298 @@
+package bulk
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// DefaultPageSize is the number of the tuples selected at once by Export.
+const DefaultPageSize = 1000
+
+// ExportOptions limit the exported tuples.
+type ExportOptions struct {
+	// PageSize is the number of the tuples selected at once,
+	// DefaultPageSize is used if it is 0.
+	PageSize uint32
+	// From and To are the inclusive bounds of the primary key, a bound may be
+	// shorter than the key to compare its first fields only. The fields of
+	// To are compared client-side, so they must be numbers or strings.
+	From, To []interface{}
+}
+
+// Export writes the tuples of the space in the order of the primary key,
+// which is paged through with GT selects, and returns their number.
+// opts may be nil.
+func (b *Bulk) Export(ctx context.Context, space string, w io.Writer, format Format, opts *ExportOptions) (int, error) {
+	if opts == nil {
+		opts = &ExportOptions{}
+	}
+	pageSize := opts.PageSize
+	if pageSize == 0 {
+		pageSize = DefaultPageSize
+	}
+
+	keyFields, ok := b.conn.GetPrimaryKeyFields(space)
+	if !ok {
+		return 0, fmt.Errorf("%w: space '%s'", ErrNoPrimaryKey, space)
+	}
+	fields, _ := b.conn.GetSpaceFields(space)
+
+	enc := newEncoder(format, w, fields)
+
+	q := &tarantool.Select{Space: space, Index: 0, Iterator: tarantool.IterAll, Limit: pageSize}
+	if opts.From != nil {
+		q.KeyTuple, q.Iterator = opts.From, tarantool.IterGe
+	}
+
+	n := 0
+	for {
+		res := b.conn.Exec(ctx, q)
+		if res.Error != nil {
+			return n, res.Error
+		}
+
+		for _, t := range res.Data {
+			key := tupleKey(t, keyFields)
+			if opts.To != nil {
+				c, err := compareKeys(key, opts.To)
+				if err != nil {
+					return n, err
+				}
+				if c > 0 {
+					return n, enc.flush()
+				}
+			}
+			if err := enc.encode(t); err != nil {
+				return n, err
+			}
+			n++
+			q.KeyTuple = key
+		}
+
+		if len(res.Data) < int(pageSize) {
+			return n, enc.flush()
+		}
+		q.Iterator = tarantool.IterGt
+	}
+}
+
+func tupleKey(tuple []interface{}, keyFields []int) []interface{} {
+	key := make([]interface{}, len(keyFields))
+	for i, f := range keyFields {
+		if f < len(tuple) {
+			key[i] = tuple[f]
+		}
+	}
+	return key
+}
+
+var (
+	// ErrNoPrimaryKey is returned if the space has no primary index in the schema cache.
+	ErrNoPrimaryKey = errors.New("primary key is not known")
+	// ErrIncomparable is returned by Export if the key can't be compared with To.
+	ErrIncomparable = errors.New("key is not comparable with the bound")
+)
+
+// compareKeys compares the first fields of the key with the bound
+// the way a tree index does for the numbers and the strings
+func compareKeys(key, bound []interface{}) (int, error) {
+	for i := 0; i < len(bound) && i < len(key); i++ {
+		c, err := compareValues(key[i], bound[i])
+		if err != nil || c != 0 {
+			return c, err
+		}
+	}
+	return 0, nil
+}
+
+func compareValues(a, b interface{}) (int, error) {
+	if sa, ok := a.(string); ok {
+		sb, ok := b.(string)
+		if !ok {
+			return 0, ErrIncomparable
+		}
+		switch {
+		case sa < sb:
+			return -1, nil
+		case sa > sb:
+			return 1, nil
+		}
+		return 0, nil
+	}
+
+	fa, ok := toFloat(a)
+	if !ok {
+		return 0, ErrIncomparable
+	}
+	fb, ok := toFloat(b)
+	if !ok {
+		return 0, ErrIncomparable
+	}
+	switch {
+	case fa < fb:
+		return -1, nil
+	case fa > fb:
+		return 1, nil
+	}
+
+	// the floats are equal but the integers may still differ
+	if ia, ok := typeconv.IntfToInt64(a); ok {
+		if ib, ok := typeconv.IntfToInt64(b); ok {
+			switch {
+			case ia < ib:
+				return -1, nil
+			case ia > ib:
+				return 1, nil
+			}
+		}
+	}
+	return 0, nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	}
+	if i, ok := typeconv.IntfToInt64(v); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+// ParseKey parses the JSON value or array of the key, e.g. a command line
+// argument, anything else is a string.
+func ParseKey(s string) []interface{} {
+	d := json.NewDecoder(strings.NewReader(s))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil || d.More() {
+		return []interface{}{s}
+	}
+	if a, ok := v.([]interface{}); ok {
+		for i := range a {
+			a[i] = fromJSON(a[i])
+		}
+		return a
+	}
+	return []interface{}{fromJSON(v)}
+}
+
+type encoder interface {
+	encode(tuple []interface{}) error
+	flush() error
+}
+
+func newEncoder(format Format, w io.Writer, fields []string) encoder {
+	if format == CSV {
+		return &csvEncoder{w: csv.NewWriter(w), fields: fields}
+	}
+	bw := bufio.NewWriter(w)
+	return &jsonEncoder{w: bw, enc: json.NewEncoder(bw), fields: fields}
+}
+
+// jsonEncoder writes the tuple per line, as the object of the named fields
+// if the space has a format, the unnamed fields are numbered from 1
+type jsonEncoder struct {
+	w      *bufio.Writer
+	enc    *json.Encoder
+	fields []string
+}
+
+func (e *jsonEncoder) encode(tuple []interface{}) error {
+	if len(e.fields) == 0 {
+		return e.enc.Encode(tuple)
+	}
+	row := make(map[string]interface{}, len(tuple))
+	for i, v := range tuple {
+		if i < len(e.fields) && e.fields[i] != "" {
+			row[e.fields[i]] = v
+		} else {
+			row[strconv.Itoa(i+1)] = v
+		}
+	}
+	return e.enc.Encode(row)
+}
+
+func (e *jsonEncoder) flush() error {
+	return e.w.Flush()
+}
+
+// csvEncoder writes the header of the field names if the space has a format
+// and a record per tuple, the arrays and the maps are written as JSON
+type csvEncoder struct {
+	w      *csv.Writer
+	fields []string
+	header bool
+}
+
+func (e *csvEncoder) writeHeader() error {
+	if e.header || len(e.fields) == 0 {
+		return nil
+	}
+	e.header = true
+	return e.w.Write(e.fields)
+}
+
+func (e *csvEncoder) encode(tuple []interface{}) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+
+	record := make([]string, len(tuple))
+	for i, v := range tuple {
+		s, err := csvValue(v)
+		if err != nil {
+			return err
+		}
+		record[i] = s
+	}
+	return e.w.Write(record)
+}
+
+func csvValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	}
+	if i, ok := typeconv.IntfToInt64(v); ok {
+		return strconv.FormatInt(i, 10), nil
+	}
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func (e *csvEncoder) flush() error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	e.w.Flush()
+	return e.w.Error()
+}
@@ -0,0 +1,94 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExport(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, conn := newFakeServer(t)
+	for i := 1; i <= 5; i++ {
+		s.tuples["users"] = append(s.tuples["users"], []interface{}{int64(i), "user" + string(rune('a'+i-1)), nil, []interface{}{"x"}})
+	}
+	for i := 1; i <= 3; i++ {
+		s.tuples["events"] = append(s.tuples["events"], []interface{}{int64(i * 10), nil})
+	}
+	b := New(conn)
+
+	var buf bytes.Buffer
+	n, err := b.Export(context.Background(), "users", &buf, JSONLines, &ExportOptions{PageSize: 2})
+	require.NoError(err)
+	assert.Equal(5, n)
+	assert.Equal(3, s.selects)
+	assert.Equal(`{"4":["x"],"age":null,"id":1,"name":"usera"}
+{"4":["x"],"age":null,"id":2,"name":"userb"}
+{"4":["x"],"age":null,"id":3,"name":"userc"}
+{"4":["x"],"age":null,"id":4,"name":"userd"}
+{"4":["x"],"age":null,"id":5,"name":"usere"}
+`, buf.String())
+
+	buf.Reset()
+	n, err = b.Export(context.Background(), "users", &buf, CSV, &ExportOptions{
+		PageSize: 2,
+		From:     []interface{}{int64(2)},
+		To:       []interface{}{int64(3)},
+	})
+	require.NoError(err)
+	assert.Equal(2, n)
+	assert.Equal("id,name,age\n2,userb,,\"[\"\"x\"\"]\"\n3,userc,,\"[\"\"x\"\"]\"\n", buf.String())
+
+	buf.Reset()
+	n, err = b.Export(context.Background(), "events", &buf, JSONLines, &ExportOptions{From: []interface{}{int64(15)}})
+	require.NoError(err)
+	assert.Equal(2, n)
+	assert.Equal("[20,null]\n[30,null]\n", buf.String())
+
+	// an empty space with a format still gets the header
+	buf.Reset()
+	s.tuples["users"] = nil
+	n, err = b.Export(context.Background(), "users", &buf, CSV, nil)
+	require.NoError(err)
+	assert.Equal(0, n)
+	assert.Equal("id,name,age\n", buf.String())
+
+	_, err = b.Export(context.Background(), "missing", &buf, CSV, nil)
+	assert.True(errors.Is(err, ErrNoPrimaryKey))
+}
+
+func TestCompareKeys(t *testing.T) {
+	assert := assert.New(t)
+
+	c, err := compareKeys([]interface{}{int64(1), "b"}, []interface{}{uint64(1), "a"})
+	assert.NoError(err)
+	assert.Equal(1, c)
+
+	c, err = compareKeys([]interface{}{int64(1), "b"}, []interface{}{1.5})
+	assert.NoError(err)
+	assert.Equal(-1, c)
+
+	c, err = compareKeys([]interface{}{"a", int64(2)}, []interface{}{"a"})
+	assert.NoError(err)
+	assert.Equal(0, c)
+
+	_, err = compareKeys([]interface{}{"a"}, []interface{}{int64(1)})
+	assert.Equal(ErrIncomparable, err)
+}
+
+func TestParseKey(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]interface{}{int64(10)}, ParseKey("10"))
+	assert.Equal([]interface{}{uint64(1 << 63)}, ParseKey("9223372036854775808"))
+	assert.Equal([]interface{}{int64(1), "a", 1.5}, ParseKey(`[1, "a", 1.5]`))
+	assert.Equal([]interface{}{"abc"}, ParseKey("abc"))
+	assert.Equal([]interface{}{"a b"}, ParseKey(`"a b"`))
+	assert.Equal([]interface{}{"1 2"}, ParseKey("1 2"))
+}
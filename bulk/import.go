@@ -0,0 +1,408 @@
+package bulk
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// luaFormat returns the format of the space
+const luaFormat = `
+local space = box.space[...]
+if space == nil then
+    error(string.format("space '%s' does not exist", ...), 0)
+end
+return space:format()
+`
+
+const (
+	// DefaultBatchSize is the number of the rows written in a transaction by Import.
+	DefaultBatchSize = 1000
+	// DefaultParallel is the number of the batches written at once by Import.
+	DefaultParallel = 4
+)
+
+// Mode is the way the rows are written.
+type Mode int
+
+const (
+	Insert Mode = iota
+	Replace
+	// Upsert assigns the fields of the row except the primary key
+	// to the existing tuple.
+	Upsert
+)
+
+// ParseMode returns the mode by its name, insert, replace or upsert.
+func ParseMode(s string) (Mode, error) {
+	switch s {
+	case "insert":
+		return Insert, nil
+	case "replace":
+		return Replace, nil
+	case "upsert":
+		return Upsert, nil
+	}
+	return 0, fmt.Errorf("unknown mode %q", s)
+}
+
+// BatchOptions control how the rows are written by Import.
+type BatchOptions struct {
+	Mode Mode
+	// Size is the number of the rows written in a transaction,
+	// DefaultBatchSize is used if it is 0.
+	Size int
+	// Parallel is the number of the batches written at once,
+	// DefaultParallel is used if it is 0.
+	Parallel int
+	// Skip is the number of the rows handled by the previous import,
+	// e.g. the last one passed to OnProgress, which are skipped.
+	Skip int
+	// OnProgress is called with the number of the rows handled without a gap,
+	// including the skipped ones, the import stops if it fails.
+	OnProgress func(rows int) error
+	// Rejects receives the rejected rows in the input format, so they can
+	// be imported once they are fixed. A CSV header is written before
+	// the first one unless Skip is set.
+	Rejects io.Writer
+	// OnReject is called with the line, or the CSV record, number of
+	// the rejected row and the reason.
+	OnReject func(line int, err error)
+}
+
+// ImportStats are the numbers of the rows handled by Import.
+type ImportStats struct {
+	Loaded   int
+	Rejected int
+}
+
+// Import writes the rows to the space in batches, each in a transaction with
+// Connection.ApplyBatch. If a batch fails, its rows are written one by one
+// and the failing ones are rejected, as well as the rows which can't be
+// decoded. Import stops on the other errors, e.g. of the connection.
+// opts may be nil.
+func (b *Bulk) Import(ctx context.Context, space string, r io.Reader, format Format, opts *BatchOptions) (*ImportStats, error) {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+	size, parallel := opts.Size, opts.Parallel
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	if parallel <= 0 {
+		parallel = DefaultParallel
+	}
+
+	keyFields, ok := b.conn.GetPrimaryKeyFields(space)
+	if !ok {
+		return &ImportStats{}, fmt.Errorf("%w: space '%s'", ErrNoPrimaryKey, space)
+	}
+	fields, err := b.spaceFormat(ctx, space)
+	if err != nil {
+		return &ImportStats{}, err
+	}
+	dec, err := newDecoder(format, r, fields)
+	if err != nil {
+		return &ImportStats{}, err
+	}
+
+	l := &loader{
+		conn:      b.conn,
+		space:     space,
+		opts:      opts,
+		keyFields: keyFields,
+		progress:  &progress{rows: opts.Skip, pending: make(map[int]int), onProgress: opts.OnProgress},
+		rejects:   &rejectWriter{w: opts.Rejects, onReject: opts.OnReject},
+	}
+	if d, ok := dec.(*csvDecoder); ok && opts.Skip == 0 {
+		l.rejects.header = d.header
+	}
+
+	err = l.run(ctx, dec, size, parallel)
+	return &l.stats, err
+}
+
+// spaceFormat returns the fields of the space format
+func (b *Bulk) spaceFormat(ctx context.Context, space string) ([]field, error) {
+	res := b.conn.Exec(ctx, &tarantool.Eval{Expression: luaFormat, Tuple: []interface{}{space}})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if len(res.Data) == 0 {
+		return nil, nil
+	}
+
+	fields := make([]field, len(res.Data[0]))
+	for i, f := range res.Data[0] {
+		m, _ := f.(map[string]interface{})
+		fields[i].name, _ = m["name"].(string)
+		fields[i].typ, _ = m["type"].(string)
+		fields[i].nullable, _ = m["is_nullable"].(bool)
+	}
+	return fields, nil
+}
+
+type batch struct {
+	seq  int
+	rows []*row
+}
+
+// loader writes the batches of the rows with a number of workers,
+// the rows of a failed batch are retried one by one to reject the bad ones
+type loader struct {
+	conn      Conn
+	space     string
+	opts      *BatchOptions
+	keyFields []int
+	progress  *progress
+	rejects   *rejectWriter
+
+	mu    sync.Mutex
+	stats ImportStats
+	err   error
+}
+
+func (l *loader) run(ctx context.Context, dec decoder, batchSize, parallel int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := make(chan *batch)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range batches {
+				if err := l.write(ctx, b); err != nil {
+					l.fail(err)
+					cancel()
+				}
+			}
+		}()
+	}
+
+	err := l.read(ctx, dec, batchSize, batches)
+	close(batches)
+	wg.Wait()
+
+	if err == nil || err == context.Canceled {
+		l.mu.Lock()
+		if l.err != nil {
+			err = l.err
+		}
+		l.mu.Unlock()
+	}
+	return err
+}
+
+func (l *loader) fail(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = err
+	}
+}
+
+// read groups the rows into the batches skipping the handled ones
+func (l *loader) read(ctx context.Context, dec decoder, batchSize int, batches chan<- *batch) error {
+	skip := l.opts.Skip
+	b := &batch{seq: skip}
+	for {
+		r, err := dec.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+
+		b.rows = append(b.rows, r)
+		if len(b.rows) == batchSize {
+			select {
+			case batches <- b:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			b = &batch{seq: b.seq + len(b.rows)}
+		}
+	}
+
+	if len(b.rows) > 0 {
+		select {
+		case batches <- b:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// write applies the batch in a transaction, a server error of the batch
+// is retried row by row, the other errors stop the import
+func (l *loader) write(ctx context.Context, b *batch) error {
+	batch := &tarantool.Batch{}
+	var rows []*row
+	for _, r := range b.rows {
+		if r.err == nil {
+			l.add(batch, r)
+			rows = append(rows, r)
+		}
+	}
+
+	loaded := 0
+	if batch.Len() > 0 {
+		res := l.conn.ApplyBatch(ctx, batch)
+		if _, ok := res.Error.(*tarantool.QueryError); ok {
+			for _, r := range rows {
+				batch.Reset()
+				l.add(batch, r)
+				res := l.conn.ApplyBatch(ctx, batch)
+				if _, ok := res.Error.(*tarantool.QueryError); ok {
+					r.err = res.Error
+					continue
+				}
+				if res.Error != nil {
+					return res.Error
+				}
+				loaded++
+			}
+		} else if res.Error != nil {
+			return res.Error
+		} else {
+			loaded = len(rows)
+		}
+	}
+
+	var rejected []*row
+	for _, r := range b.rows {
+		if r.err != nil {
+			rejected = append(rejected, r)
+		}
+	}
+	if err := l.rejects.write(rejected); err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	l.stats.Loaded += loaded
+	l.stats.Rejected += len(rejected)
+	l.mu.Unlock()
+
+	return l.progress.done(b.seq, len(b.rows))
+}
+
+func (l *loader) add(b *tarantool.Batch, r *row) {
+	switch l.opts.Mode {
+	case Replace:
+		b.Replace(l.space, r.tuple)
+	case Upsert:
+		var set []tarantool.Operator
+		for i, v := range r.tuple {
+			if !l.isKey(i) {
+				set = append(set, &tarantool.OpAssign{Field: int64(i), Argument: v})
+			}
+		}
+		b.Upsert(l.space, r.tuple, set)
+	default:
+		b.Insert(l.space, r.tuple)
+	}
+}
+
+func (l *loader) isKey(field int) bool {
+	for _, f := range l.keyFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// rejectWriter reports the rejected rows and writes them in the input format
+type rejectWriter struct {
+	mu       sync.Mutex
+	w        io.Writer
+	onReject func(line int, err error)
+	csv      *csv.Writer
+	header   []string
+}
+
+func (w *rejectWriter) write(rows []*row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, r := range rows {
+		if w.onReject != nil {
+			w.onReject(r.n, r.err)
+		}
+		if w.w == nil {
+			continue
+		}
+
+		switch raw := r.raw.(type) {
+		case string:
+			if _, err := io.WriteString(w.w, raw+"\n"); err != nil {
+				return err
+			}
+		case []string:
+			if w.csv == nil {
+				w.csv = csv.NewWriter(w.w)
+				if w.header != nil {
+					if err := w.csv.Write(w.header); err != nil {
+						return err
+					}
+				}
+			}
+			if err := w.csv.Write(raw); err != nil {
+				return err
+			}
+		}
+	}
+
+	if w.csv != nil {
+		w.csv.Flush()
+		return w.csv.Error()
+	}
+	return nil
+}
+
+// progress counts the input rows handled without a gap,
+// the batches may complete out of order
+type progress struct {
+	mu         sync.Mutex
+	rows       int
+	pending    map[int]int // the rows of the batches after the gap by their first row
+	onProgress func(rows int) error
+}
+
+func (p *progress) done(seq, rows int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending[seq] = rows
+	moved := false
+	for {
+		n, ok := p.pending[p.rows]
+		if !ok {
+			break
+		}
+		delete(p.pending, p.rows)
+		p.rows += n
+		moved = true
+	}
+	if !moved || p.onProgress == nil {
+		return nil
+	}
+	return p.onProgress(p.rows)
+}
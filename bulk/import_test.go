@@ -0,0 +1,162 @@
+package bulk
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+)
+
+type reject struct {
+	line int
+	err  string
+}
+
+func TestImportRejects(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, conn := newFakeServer(t)
+	s.tuples["users"] = [][]interface{}{{uint64(3), "old", nil}}
+
+	input := `{"id": 1, "name": "a", "age": 30}
+[2, "b"]
+
+{"id": 3, "name": "c"}
+{"id": 4, "nick": "d"}
+{"id": "x", "name": "e"}
+{"id": 6, "name": "f", "4": [1.5]}
+`
+	var (
+		rejected bytes.Buffer
+		rejects  []reject
+	)
+	stats, err := New(conn).Import(context.Background(), "users", strings.NewReader(input), JSONLines, &BatchOptions{
+		Size:     2,
+		Parallel: 2,
+		Rejects:  &rejected,
+		OnReject: func(line int, err error) {
+			rejects = append(rejects, reject{line, err.Error()})
+		},
+	})
+	require.NoError(err)
+	assert.Equal(&ImportStats{Loaded: 3, Rejected: 3}, stats)
+
+	assert.Len(rejects, 3)
+	assert.Contains(rejects, reject{5, `unknown field "nick"`})
+
+	assert.Equal([][]interface{}{
+		{int64(1), "a", int64(30)},
+		{int64(2), "b"},
+		{uint64(3), "old", nil},
+		{int64(6), "f", nil, []interface{}{1.5}},
+	}, s.space("users"))
+
+	assert.ElementsMatch([]string{
+		`{"id": 3, "name": "c"}`,
+		`{"id": 4, "nick": "d"}`,
+		`{"id": "x", "name": "e"}`,
+	}, strings.Split(strings.TrimSpace(rejected.String()), "\n"))
+
+	// the fixed row is upserted
+	stats, err = New(conn).Import(context.Background(), "users", strings.NewReader(`{"id": 3, "name": "c"}`), JSONLines, &BatchOptions{Mode: Upsert})
+	require.NoError(err)
+	assert.Equal(1, stats.Loaded)
+	assert.Equal([]interface{}{uint64(3), "c", nil}, s.space("users")[2])
+}
+
+func TestImportCSV(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, conn := newFakeServer(t)
+
+	var rejected bytes.Buffer
+	input := "name,id,age\na,1,30\nb,2,\nc,x,1\n\"d, e\",4,,\"{\"\"k\"\": 1}\"\n"
+	stats, err := New(conn).Import(context.Background(), "users", strings.NewReader(input), CSV, &BatchOptions{
+		Rejects: &rejected,
+	})
+	require.NoError(err)
+	assert.Equal(&ImportStats{Loaded: 3, Rejected: 1}, stats)
+
+	assert.Equal([][]interface{}{
+		{int64(1), "a", int64(30)},
+		{int64(2), "b", nil},
+		{int64(4), "d, e", nil, map[string]interface{}{"k": int64(1)}},
+	}, s.space("users"))
+	assert.Equal("name,id,age\nc,x,1\n", rejected.String())
+
+	_, err = New(conn).Import(context.Background(), "users", strings.NewReader("id,nick\n"), CSV, nil)
+	assert.Error(err)
+}
+
+func TestImportProgress(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, conn := newFakeServer(t)
+
+	var input strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&input, "[%d, \"u%d\"]\n", i, i)
+	}
+
+	var progress []int
+	opts := &BatchOptions{
+		Size:       3,
+		Parallel:   1,
+		OnProgress: func(rows int) error { progress = append(progress, rows); return nil },
+	}
+
+	s.failAt = 2
+	stats, err := New(conn).Import(context.Background(), "users", strings.NewReader(input.String()), JSONLines, opts)
+	assert.True(errors.Is(err, tarantool.ErrRequestTimeout), err)
+	assert.Equal(6, stats.Loaded)
+	assert.Equal([]int{3, 6}, progress)
+
+	s.Lock()
+	s.failAt = 0
+	s.tuples["users"][0] = []interface{}{int64(1), "changed"}
+	s.Unlock()
+
+	// the handled rows would be rejected as duplicates if they weren't skipped
+	opts.Skip = progress[len(progress)-1]
+	stats, err = New(conn).Import(context.Background(), "users", strings.NewReader(input.String()), JSONLines, opts)
+	require.NoError(err)
+	assert.Equal(&ImportStats{Loaded: 4}, stats)
+	assert.Equal([]int{3, 6, 9, 10}, progress)
+
+	users := s.space("users")
+	assert.Len(users, 10)
+	assert.Equal([]interface{}{int64(1), "changed"}, users[0])
+
+	// the failure of OnProgress stops the import
+	failure := errors.New("disk is full")
+	opts = &BatchOptions{Mode: Replace, OnProgress: func(rows int) error { return failure }}
+	_, err = New(conn).Import(context.Background(), "users", strings.NewReader(input.String()), JSONLines, opts)
+	assert.Equal(failure, err)
+}
+
+func TestProgress(t *testing.T) {
+	assert := assert.New(t)
+
+	var saved []int
+	p := &progress{pending: map[int]int{}, onProgress: func(rows int) error {
+		saved = append(saved, rows)
+		return nil
+	}}
+
+	// the batch after the gap isn't counted until the gap is filled
+	assert.NoError(p.done(3, 3))
+	assert.Nil(saved)
+	assert.NoError(p.done(0, 3))
+	assert.NoError(p.done(9, 2))
+	assert.NoError(p.done(6, 3))
+	assert.Equal([]int{6, 11}, saved)
+}
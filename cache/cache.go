@@ -0,0 +1,145 @@
+// Package cache is a key-value cache with TTL kept in a Tarantool space,
+// with an optional in-process cache in front of it.
+//
+// The expired entries are not returned by Get, but they stay in the space
+// until they are overwritten, so the space must be cleaned up by
+// the expirationd module, see StartExpirationd.
+//
+// Group fills the cache from the source of truth on a miss, like groupcache,
+// and Bytes adapts it to the byte slice Getter and Setter of the caching middleware.
+//
+// The scripts are executed with Eval, so the user needs the execute privilege
+// on the universe. The expiration is checked with the server clock.
+package cache
+
+import (
+	"context"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// DefaultSpace is the name of the space to keep the entries in.
+const DefaultSpace = "_cache"
+
+// luaSpace creates the cache space if it doesn't exist
+const luaSpace = `
+local function cache_space(space)
+    if box.space[space] == nil then
+        box.schema.space.create(space, {
+            if_not_exists = true,
+            format = {
+                {name = 'key', type = 'string'},
+                {name = 'value', type = 'any'},
+                {name = 'expires', type = 'number'},
+            },
+        })
+        box.space[space]:create_index('primary', {parts = {1, 'string'}, if_not_exists = true})
+    end
+    return box.space[space]
+end
+`
+
+// luaGet returns the tuple of the key and the seconds it expires in, or nothing
+// if the key is missing or expired. The value is returned in the tuple, as a
+// value returned on its own would be taken for the tuple if it is an array.
+const luaGet = `
+local space, key = ...
+local s = box.space[space]
+if s == nil then
+    return
+end
+local t = s:get(key)
+local now = require('clock').time()
+if t == nil or t[3] <= now then
+    return
+end
+return t, t[3] - now
+`
+
+// luaSet stores or deletes the value and notifies the local caches
+const luaSet = luaSpace + `
+local space, key, value, ttl, delete = ...
+local s = cache_space(space)
+if delete then
+    s:delete(key)
+else
+    s:replace({key, value, require('clock').time() + ttl})
+end
+if box.broadcast ~= nil then
+    box.broadcast('cache:' .. space, require('uuid').str())
+end
+return true
+`
+
+// Cache is the cache in a space, it is safe for concurrent use.
+type Cache struct {
+	conn  tarantool.Executor
+	space string
+}
+
+// New returns the cache in the space, DefaultSpace is used if it is empty.
+// The space is created on the first Set.
+func New(conn tarantool.Executor, space string) *Cache {
+	if space == "" {
+		space = DefaultSpace
+	}
+	return &Cache{conn: conn, space: space}
+}
+
+// Space returns the name of the cache space.
+func (c *Cache) Space() string {
+	return c.space
+}
+
+// InvalidationKey returns the key broadcast on every change of the cache,
+// so the local caches can be dropped, Tarantool >= 2.10.0.
+func (c *Cache) InvalidationKey() string {
+	return "cache:" + c.space
+}
+
+// Get returns the value of the key, false if it is missing or expired.
+func (c *Cache) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	value, _, ok, err := c.get(ctx, key)
+	return value, ok, err
+}
+
+// get also returns the time the value expires in
+func (c *Cache) get(ctx context.Context, key string) (interface{}, time.Duration, bool, error) {
+	res := c.conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaGet,
+		Tuple:      []interface{}{c.space, key},
+	})
+	if res.Error != nil {
+		return nil, 0, false, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0]) < 2 {
+		return nil, 0, false, nil
+	}
+	var ttl time.Duration
+	if len(res.Data) > 1 && len(res.Data[1]) > 0 {
+		if sec, ok := res.Data[1][0].(float64); ok {
+			ttl = time.Duration(sec * float64(time.Second))
+		} else if sec, ok := typeconv.IntfToInt64(res.Data[1][0]); ok {
+			ttl = time.Duration(sec) * time.Second
+		}
+	}
+	return res.Data[0][1], ttl, true, nil
+}
+
+// Set stores the value of the key for ttl.
+func (c *Cache) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return c.conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaSet,
+		Tuple:      []interface{}{c.space, key, value, ttl.Seconds(), false},
+	}).Error
+}
+
+// Delete removes the key.
+func (c *Cache) Delete(ctx context.Context, key string) error {
+	return c.conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaSet,
+		Tuple:      []interface{}{c.space, key, nil, 0, true},
+	}).Error
+}
@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+type entry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// fakeServer executes the cache scripts against the in-memory spaces and
+// emulates the watchers of the invalidation keys
+type fakeServer struct {
+	sync.Mutex
+	spaces      map[string]map[string]entry
+	expirationd bool
+	tasks       map[string]map[string]interface{}
+	gets        int
+	version     int
+	conns       map[*tarantool.IprotoServer]map[string]watchState
+}
+
+type watchState int
+
+const (
+	watchAcked watchState = iota
+	watchPending
+	watchDirty // pending and changed since the event
+)
+
+func newFakeServer(t *testing.T) (*fakeServer, string) {
+	s := &fakeServer{
+		spaces:      map[string]map[string]entry{},
+		expirationd: true,
+		tasks:       map[string]map[string]interface{}{},
+		conns:       map[*tarantool.IprotoServer]map[string]watchState{},
+	}
+
+	addr := tnttest.NewServerFunc(t, func() *tarantool.IprotoServer {
+		var srv *tarantool.IprotoServer
+		srv = tarantool.NewIprotoServer("", func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+			return s.handle(srv, q)
+		}, nil)
+		s.Lock()
+		s.conns[srv] = map[string]watchState{}
+		s.Unlock()
+		return srv
+	})
+	return s, addr
+}
+
+func (s *fakeServer) handle(srv *tarantool.IprotoServer, q tarantool.Query) *tarantool.Result {
+	s.Lock()
+	defer s.Unlock()
+
+	switch q := q.(type) {
+	case *tarantool.Watch:
+		if state, ok := s.conns[srv][q.Key]; !ok || state == watchDirty {
+			srv.SendEvent(q.Key, s.versionValue())
+			s.conns[srv][q.Key] = watchPending
+		} else {
+			s.conns[srv][q.Key] = watchAcked
+		}
+		return nil
+	case *tarantool.Unwatch:
+		delete(s.conns[srv], q.Key)
+		return nil
+	case *tarantool.Eval:
+		return s.eval(q)
+	}
+	return &tarantool.Result{}
+}
+
+func (s *fakeServer) versionValue() interface{} {
+	if s.version == 0 {
+		return nil
+	}
+	return strconv.Itoa(s.version)
+}
+
+// evalResult encodes the values returned by an Eval the way Tarantool does:
+// an array is sent as is, so it is decoded as a tuple, and the scalars are
+// decoded as the tuples of a single field
+func evalResult(values ...interface{}) *tarantool.Result {
+	res := &tarantool.Result{}
+	for _, v := range values {
+		if t, ok := v.([]interface{}); ok {
+			res.Data = append(res.Data, t)
+		} else {
+			res.Data = append(res.Data, []interface{}{v})
+		}
+	}
+	return res
+}
+
+func (s *fakeServer) eval(q *tarantool.Eval) *tarantool.Result {
+	switch q.Expression {
+	case luaGet:
+		s.gets++
+		e, ok := s.spaces[q.Tuple[0].(string)][q.Tuple[1].(string)]
+		now := time.Now()
+		if !ok || !now.Before(e.expires) {
+			return &tarantool.Result{}
+		}
+		tuple := []interface{}{q.Tuple[1], e.value, float64(e.expires.UnixNano()) / 1e9}
+		return evalResult(tuple, e.expires.Sub(now).Seconds())
+	case luaSet:
+		space, key := q.Tuple[0].(string), q.Tuple[1].(string)
+		if s.spaces[space] == nil {
+			s.spaces[space] = map[string]entry{}
+		}
+		if q.Tuple[4].(bool) {
+			delete(s.spaces[space], key)
+		} else {
+			ttl := time.Duration(q.Tuple[3].(float64) * float64(time.Second))
+			s.spaces[space][key] = entry{value: q.Tuple[2], expires: time.Now().Add(ttl)}
+		}
+		s.broadcast("cache:" + space)
+		return evalResult(true)
+	case luaStartExpirationd:
+		if !s.expirationd {
+			return evalResult(false)
+		}
+		s.tasks[q.Tuple[1].(string)] = q.Tuple[2].(map[string]interface{})
+		return evalResult(true)
+	case luaCheckExpirationd:
+		_, ok := s.tasks[q.Tuple[0].(string)]
+		return evalResult(s.expirationd, ok)
+	}
+	return &tarantool.Result{}
+}
+
+func (s *fakeServer) broadcast(key string) {
+	s.version++
+	for srv, watched := range s.conns {
+		state, ok := watched[key]
+		switch {
+		case !ok:
+		case state == watchAcked:
+			srv.SendEvent(key, s.versionValue())
+			watched[key] = watchPending
+		default:
+			watched[key] = watchDirty
+		}
+	}
+}
+
+// drop closes the client connections
+func (s *fakeServer) drop() {
+	s.Lock()
+	defer s.Unlock()
+	for srv := range s.conns {
+		srv.Shutdown()
+		delete(s.conns, srv)
+	}
+}
+
+func TestCache(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newFakeServer(t)
+	conn, err := tarantool.Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	c := New(conn, "")
+	assert.Equal(DefaultSpace, c.Space())
+	assert.Equal("cache:_cache", c.InvalidationKey())
+
+	_, ok, err := c.Get(ctx, "a")
+	require.NoError(err)
+	assert.False(ok)
+
+	require.NoError(c.Set(ctx, "a", "value", time.Minute))
+	v, ok, err := c.Get(ctx, "a")
+	require.NoError(err)
+	assert.True(ok)
+	assert.Equal("value", v)
+
+	_, ttl, ok, err := c.get(ctx, "a")
+	require.NoError(err)
+	assert.True(ok)
+	assert.InDelta(time.Minute, ttl, float64(time.Second))
+
+	// the arrays are not taken for the reply tuples
+	for _, value := range []interface{}{[]interface{}{int64(1), "x"}, []interface{}{}, nil} {
+		require.NoError(c.Set(ctx, "list", value, time.Minute))
+		v, ok, err = c.Get(ctx, "list")
+		require.NoError(err)
+		assert.True(ok)
+		assert.Equal(value, v)
+	}
+
+	require.NoError(c.Set(ctx, "b", int64(1), time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+	_, ok, err = c.Get(ctx, "b")
+	require.NoError(err)
+	assert.False(ok)
+
+	require.NoError(c.Delete(ctx, "a"))
+	_, ok, err = c.Get(ctx, "a")
+	require.NoError(err)
+	assert.False(ok)
+
+	s.Lock()
+	assert.Equal(6, s.version)
+	s.Unlock()
+}
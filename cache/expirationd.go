@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+)
+
+var (
+	// ErrNoExpirationd is returned if the expirationd module isn't installed on the server.
+	ErrNoExpirationd = errors.New("cache: expirationd module is not installed")
+	// ErrNoExpirationdTask is returned if the expirationd task of the cache isn't running.
+	ErrNoExpirationdTask = errors.New("cache: expirationd task is not running")
+)
+
+// luaStartExpirationd (re)starts the expirationd task deleting the expired entries
+const luaStartExpirationd = luaSpace + `
+local space, name, opts = ...
+local ok, expirationd = pcall(require, 'expirationd')
+if not ok then
+    return false
+end
+local clock = require('clock')
+expirationd.start(name, cache_space(space).id, function(_, t)
+    return t[3] <= clock.time()
+end, opts)
+return true
+`
+
+// luaCheckExpirationd returns whether the module is installed and the task is running
+const luaCheckExpirationd = `
+local name = ...
+local ok, expirationd = pcall(require, 'expirationd')
+if not ok then
+    return false, false
+end
+local ok, task = pcall(expirationd.task, name)
+return true, ok and task ~= nil
+`
+
+// ExpirationdOptions are the options of the expirationd task.
+type ExpirationdOptions struct {
+	// TuplesPerIteration is the number of tuples checked in one iteration.
+	TuplesPerIteration int
+	// FullScanTime is the time to check the whole space in.
+	FullScanTime time.Duration
+}
+
+// ExpirationdTask returns the name of the expirationd task of the cache.
+func (c *Cache) ExpirationdTask() string {
+	return "cache:" + c.space
+}
+
+// StartExpirationd starts the expirationd task deleting the expired entries,
+// restarting it if it is already running. The space is created if needed.
+// The tasks are not persisted, so it must be called on the master after
+// every restart of it, CheckExpirationd helps to find that out.
+func (c *Cache) StartExpirationd(ctx context.Context, opts *ExpirationdOptions) error {
+	o := make(map[string]interface{})
+	if opts != nil {
+		if opts.TuplesPerIteration > 0 {
+			o["tuples_per_iteration"] = opts.TuplesPerIteration
+		}
+		if opts.FullScanTime > 0 {
+			o["full_scan_time"] = opts.FullScanTime.Seconds()
+		}
+	}
+
+	res := c.conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaStartExpirationd,
+		Tuple:      []interface{}{c.space, c.ExpirationdTask(), o},
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if !resultBool(res, 0) {
+		return ErrNoExpirationd
+	}
+	return nil
+}
+
+// CheckExpirationd returns ErrNoExpirationd or ErrNoExpirationdTask if the
+// expired entries are not deleted on the server.
+func (c *Cache) CheckExpirationd(ctx context.Context) error {
+	res := c.conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaCheckExpirationd,
+		Tuple:      []interface{}{c.ExpirationdTask()},
+	})
+	if res.Error != nil {
+		return res.Error
+	}
+	if !resultBool(res, 0) {
+		return ErrNoExpirationd
+	}
+	if !resultBool(res, 1) {
+		return ErrNoExpirationdTask
+	}
+	return nil
+}
+
+func resultBool(res *tarantool.Result, i int) bool {
+	if len(res.Data) <= i || len(res.Data[i]) == 0 {
+		return false
+	}
+	b, _ := res.Data[i][0].(bool)
+	return b
+}
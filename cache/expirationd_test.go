@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+)
+
+func TestExpirationd(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newFakeServer(t)
+	conn, err := tarantool.Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	c := New(conn, "sessions")
+	assert.Equal("cache:sessions", c.ExpirationdTask())
+
+	assert.Equal(ErrNoExpirationdTask, c.CheckExpirationd(ctx))
+
+	require.NoError(c.StartExpirationd(ctx, &ExpirationdOptions{
+		TuplesPerIteration: 100,
+		FullScanTime:       time.Minute,
+	}))
+	assert.NoError(c.CheckExpirationd(ctx))
+
+	s.Lock()
+	opts := s.tasks["cache:sessions"]
+	s.Unlock()
+	assert.EqualValues(100, opts["tuples_per_iteration"])
+	assert.Equal(60.0, opts["full_scan_time"])
+
+	s.Lock()
+	s.expirationd = false
+	s.Unlock()
+	assert.Equal(ErrNoExpirationd, c.CheckExpirationd(ctx))
+	assert.Equal(ErrNoExpirationd, c.StartExpirationd(ctx, nil))
+}
@@ -0,0 +1,193 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrMiss is returned by Bytes.Get if the key is missing or expired.
+var ErrMiss = errors.New("cache: miss")
+
+// Store is the cache the values are kept in, it is implemented by Cache and Local.
+type Store interface {
+	Get(ctx context.Context, key string) (interface{}, bool, error)
+	Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	_ Store = (*Cache)(nil)
+	_ Store = (*Local)(nil)
+)
+
+// Getter loads the value of the key from the source of truth on a miss,
+// like the Getter of groupcache.
+type Getter interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+}
+
+// GetterFunc is the function implementing Getter.
+type GetterFunc func(ctx context.Context, key string) ([]byte, error)
+
+// Get calls the function.
+func (fn GetterFunc) Get(ctx context.Context, key string) ([]byte, error) {
+	return fn(ctx, key)
+}
+
+// Setter stores the values with TTL.
+type Setter interface {
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// Bytes is the store of the byte slice values, the common Getter and Setter
+// of the caching middleware with ErrMiss for the missing keys.
+type Bytes struct {
+	store Store
+}
+
+var (
+	_ Getter = (*Bytes)(nil)
+	_ Setter = (*Bytes)(nil)
+)
+
+// NewBytes returns the byte slice adapter of the store.
+func NewBytes(s Store) *Bytes {
+	return &Bytes{store: s}
+}
+
+// Get returns the value of the key or ErrMiss.
+func (b *Bytes) Get(ctx context.Context, key string) ([]byte, error) {
+	value, ok, err := b.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrMiss
+	}
+	switch v := value.(type) {
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	}
+	return nil, ErrMiss
+}
+
+// Set stores the value of the key for ttl.
+func (b *Bytes) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return b.store.Set(ctx, key, value, ttl)
+}
+
+// Delete removes the key.
+func (b *Bytes) Delete(ctx context.Context, key string) error {
+	return b.store.Delete(ctx, key)
+}
+
+// GroupOptions are the options of a Group.
+type GroupOptions struct {
+	// TTL is the time the loaded values are kept for, DefaultGroupTTL
+	// is used if it is 0.
+	TTL time.Duration
+	// OnError is called with the errors of the store, which are not returned
+	// by Get as the value is loaded by the getter then.
+	OnError func(key string, err error)
+}
+
+// DefaultGroupTTL is the time the values loaded by a Group are kept for.
+const DefaultGroupTTL = time.Minute
+
+// Group is the read-through cache filled by the getter, it is safe for
+// concurrent use. The concurrent loads of a key are done once:
+//
+//	users := cache.NewGroup(cache.New(conn, "users"), cache.GetterFunc(loadUser), nil)
+//	data, err := users.Get(ctx, id)
+type Group struct {
+	bytes  *Bytes
+	getter Getter
+	opts   GroupOptions
+
+	mu    sync.Mutex
+	loads map[string]*load
+}
+
+// load is the loading of a key the concurrent Get calls wait for
+type load struct {
+	done  chan struct{}
+	value []byte
+	err   error
+}
+
+// NewGroup returns the group keeping the values loaded by the getter in
+// the store. opts may be nil.
+func NewGroup(s Store, getter Getter, opts *GroupOptions) *Group {
+	g := &Group{bytes: NewBytes(s), getter: getter, loads: make(map[string]*load)}
+	if opts != nil {
+		g.opts = *opts
+	}
+	if g.opts.TTL <= 0 {
+		g.opts.TTL = DefaultGroupTTL
+	}
+	return g
+}
+
+// Get returns the value of the key from the store, loading it with
+// the getter on a miss. The errors of the getter are not cached.
+func (g *Group) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := g.bytes.Get(ctx, key)
+	if err == nil {
+		return value, nil
+	}
+	if err != ErrMiss {
+		g.onError(key, err)
+	}
+
+	g.mu.Lock()
+	if l, ok := g.loads[key]; ok {
+		g.mu.Unlock()
+		return l.wait(ctx)
+	}
+	l := &load{done: make(chan struct{})}
+	g.loads[key] = l
+	g.mu.Unlock()
+
+	g.load(ctx, key, l)
+	return l.value, l.err
+}
+
+func (l *load) wait(ctx context.Context) ([]byte, error) {
+	select {
+	case <-l.done:
+		return l.value, l.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// load loads the value with the context of the first caller,
+// the other callers get its result
+func (g *Group) load(ctx context.Context, key string, l *load) {
+	l.value, l.err = g.getter.Get(ctx, key)
+	if l.err == nil {
+		if err := g.bytes.Set(ctx, key, l.value, g.opts.TTL); err != nil {
+			g.onError(key, err)
+		}
+	}
+
+	g.mu.Lock()
+	delete(g.loads, key)
+	g.mu.Unlock()
+	close(l.done)
+}
+
+// Forget removes the key from the store, so it is loaded again.
+func (g *Group) Forget(ctx context.Context, key string) error {
+	return g.bytes.Delete(ctx, key)
+}
+
+func (g *Group) onError(key string, err error) {
+	if g.opts.OnError != nil {
+		g.opts.OnError(key, err)
+	}
+}
@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+)
+
+func TestBytes(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, addr := newFakeServer(t)
+	conn, err := tarantool.Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	c := New(conn, "")
+	b := NewBytes(c)
+
+	_, err = b.Get(ctx, "a")
+	assert.Equal(ErrMiss, err)
+
+	require.NoError(b.Set(ctx, "a", []byte("value"), time.Minute))
+	v, err := b.Get(ctx, "a")
+	require.NoError(err)
+	assert.Equal([]byte("value"), v)
+
+	// the strings stored by the other clients are read too
+	require.NoError(c.Set(ctx, "b", "text", time.Minute))
+	v, err = b.Get(ctx, "b")
+	require.NoError(err)
+	assert.Equal([]byte("text"), v)
+
+	require.NoError(b.Delete(ctx, "a"))
+	_, err = b.Get(ctx, "a")
+	assert.Equal(ErrMiss, err)
+}
+
+func TestGroup(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, addr := newFakeServer(t)
+	conn, err := tarantool.Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	var loads int32
+	release := make(chan struct{})
+	g := NewGroup(New(conn, ""), GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		if key == "bad" {
+			return nil, errors.New("no such user")
+		}
+		return []byte("user " + key), nil
+	}), nil)
+
+	ctx := context.Background()
+
+	// the concurrent gets wait for a single load
+	var wg sync.WaitGroup
+	values := make([][]byte, 4)
+	for i := range values {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := g.Get(ctx, "1")
+			assert.NoError(err)
+			values[i] = v
+		}(i)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for _, v := range values {
+		assert.Equal([]byte("user 1"), v)
+	}
+
+	v, err := g.Get(ctx, "1")
+	require.NoError(err)
+	assert.Equal([]byte("user 1"), v)
+	assert.Equal(int32(1), atomic.LoadInt32(&loads))
+
+	// the errors are not cached
+	_, err = g.Get(ctx, "bad")
+	assert.EqualError(err, "no such user")
+	_, err = g.Get(ctx, "bad")
+	assert.Error(err)
+	assert.Equal(int32(3), atomic.LoadInt32(&loads))
+
+	require.NoError(g.Forget(ctx, "1"))
+	_, err = g.Get(ctx, "1")
+	require.NoError(err)
+	assert.Equal(int32(4), atomic.LoadInt32(&loads))
+}
+
+type failingStore struct{}
+
+func (failingStore) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	return nil, false, errors.New("store is down")
+}
+
+func (failingStore) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	return errors.New("store is down")
+}
+
+func (failingStore) Delete(ctx context.Context, key string) error {
+	return errors.New("store is down")
+}
+
+func TestGroupStoreError(t *testing.T) {
+	assert := assert.New(t)
+
+	var errs []string
+	g := NewGroup(failingStore{}, GetterFunc(func(ctx context.Context, key string) ([]byte, error) {
+		return []byte("value"), nil
+	}), &GroupOptions{OnError: func(key string, err error) {
+		errs = append(errs, key+": "+err.Error())
+	}})
+
+	// the value is loaded if the store fails
+	v, err := g.Get(context.Background(), "a")
+	assert.NoError(err)
+	assert.Equal([]byte("value"), v)
+	assert.Equal([]string{"a: store is down", "a: store is down"}, errs)
+}
@@ -0,0 +1,144 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/viciious/go-tarantool/pubsub"
+)
+
+// Local is an in-process read-through cache in front of a Cache, it is safe
+// for concurrent use. It is dropped on every change of the Cache while
+// Watch runs, and the entries are kept for the TTL of Local at most anyway,
+// which limits the staleness if the changes aren't watched.
+type Local struct {
+	cache      *Cache
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]localEntry
+	// gen is incremented on invalidation, so the values fetched before it aren't stored
+	gen uint64
+}
+
+type localEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// NewLocal returns the local cache keeping up to maxEntries values of
+// the cache for ttl, the number of values is not limited if maxEntries is 0.
+func NewLocal(c *Cache, ttl time.Duration, maxEntries int) *Local {
+	return &Local{
+		cache:      c,
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]localEntry),
+	}
+}
+
+// Get returns the value of the key from the local cache,
+// reading it from the Cache if it isn't there. The misses are not cached.
+func (l *Local) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	now := time.Now()
+
+	l.mu.Lock()
+	e, ok := l.entries[key]
+	if ok && now.Before(e.expires) {
+		l.mu.Unlock()
+		return e.value, true, nil
+	}
+	if ok {
+		delete(l.entries, key)
+	}
+	gen := l.gen
+	l.mu.Unlock()
+
+	value, ttl, ok, err := l.cache.get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	if ttl <= 0 || ttl > l.ttl {
+		ttl = l.ttl
+	}
+
+	l.mu.Lock()
+	if l.gen == gen {
+		l.store(key, localEntry{value: value, expires: now.Add(ttl)}, now)
+	}
+	l.mu.Unlock()
+	return value, true, nil
+}
+
+// store adds the entry, evicting the expired ones or any if the cache is full
+func (l *Local) store(key string, e localEntry, now time.Time) {
+	if l.maxEntries > 0 && len(l.entries) >= l.maxEntries {
+		for k, e := range l.entries {
+			if !now.Before(e.expires) {
+				delete(l.entries, k)
+			}
+		}
+		for k := range l.entries {
+			if len(l.entries) < l.maxEntries {
+				break
+			}
+			delete(l.entries, k)
+		}
+	}
+	l.entries[key] = e
+}
+
+// Set stores the value in the Cache and drops it from the local one.
+func (l *Local) Set(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	err := l.cache.Set(ctx, key, value, ttl)
+	l.Forget(key)
+	return err
+}
+
+// Delete removes the key from the Cache and the local one.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	err := l.cache.Delete(ctx, key)
+	l.Forget(key)
+	return err
+}
+
+// Forget drops the key from the local cache.
+func (l *Local) Forget(key string) {
+	l.mu.Lock()
+	delete(l.entries, key)
+	l.gen++
+	l.mu.Unlock()
+}
+
+// Invalidate drops the local cache.
+func (l *Local) Invalidate() {
+	l.mu.Lock()
+	l.entries = make(map[string]localEntry)
+	l.gen++
+	l.mu.Unlock()
+}
+
+// Len returns the number of the values in the local cache.
+func (l *Local) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.entries)
+}
+
+// Watch drops the local cache on every change of the Cache until ctx is done,
+// Tarantool >= 2.10.0. It is also dropped on every subscription, so the
+// changes missed while the connection is lost don't matter.
+// onError is called with the errors of the subscription, it may be nil.
+func (l *Local) Watch(ctx context.Context, dialer pubsub.Dialer, onError func(err error)) {
+	s := &pubsub.Subscriber{Dialer: dialer}
+	if onError != nil {
+		s.OnError = func(_ string, err error) {
+			onError(err)
+		}
+	}
+	for range s.Subscribe(ctx, l.cache.InvalidationKey()) {
+		l.Invalidate()
+	}
+}
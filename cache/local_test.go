@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+)
+
+func TestLocal(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newFakeServer(t)
+	conn, err := tarantool.Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	c := New(conn, "")
+	l := NewLocal(c, time.Minute, 2)
+
+	gets := func() int {
+		s.Lock()
+		defer s.Unlock()
+		return s.gets
+	}
+
+	require.NoError(l.Set(ctx, "a", "1", time.Minute))
+	for i := 0; i < 3; i++ {
+		v, ok, err := l.Get(ctx, "a")
+		require.NoError(err)
+		assert.True(ok)
+		assert.Equal("1", v)
+	}
+	assert.Equal(1, gets())
+
+	// the misses are not cached
+	for i := 0; i < 2; i++ {
+		_, ok, err := l.Get(ctx, "missing")
+		require.NoError(err)
+		assert.False(ok)
+	}
+	assert.Equal(3, gets())
+
+	// the entry is kept no longer than in the Cache
+	require.NoError(l.Set(ctx, "b", "2", 10*time.Millisecond))
+	_, ok, err := l.Get(ctx, "b")
+	require.NoError(err)
+	assert.True(ok)
+	time.Sleep(20 * time.Millisecond)
+	_, ok, err = l.Get(ctx, "b")
+	require.NoError(err)
+	assert.False(ok)
+
+	require.NoError(c.Set(ctx, "c", "3", time.Minute))
+	_, _, err = l.Get(ctx, "c")
+	require.NoError(err)
+	_, _, err = l.Get(ctx, "a")
+	require.NoError(err)
+	assert.Equal(2, l.Len())
+
+	require.NoError(l.Delete(ctx, "a"))
+	_, ok, err = l.Get(ctx, "a")
+	require.NoError(err)
+	assert.False(ok)
+}
+
+func TestLocalWatch(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newFakeServer(t)
+	connector := tarantool.New(addr, nil)
+	defer connector.Close()
+	conn, err := connector.Connect()
+	require.NoError(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := New(connector, "")
+	l := NewLocal(c, time.Minute, 0)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		l.Watch(ctx, connector, nil)
+	}()
+
+	require.NoError(c.Set(ctx, "a", "1", time.Minute))
+	require.Eventually(func() bool {
+		v, _, err := l.Get(ctx, "a")
+		return err == nil && v == "1"
+	}, time.Second, 5*time.Millisecond)
+
+	// a change by another client drops the local cache
+	other, err := tarantool.Connect(addr, nil)
+	require.NoError(err)
+	defer other.Close()
+	require.NoError(New(other, "").Set(ctx, "a", "2", time.Minute))
+	require.Eventually(func() bool {
+		v, _, err := l.Get(ctx, "a")
+		return err == nil && v == "2"
+	}, time.Second, 5*time.Millisecond)
+
+	// and so does the reconnect
+	s.drop()
+	<-conn.Done()
+	require.NoError(c.Set(ctx, "a", "3", time.Minute))
+	require.Eventually(func() bool {
+		v, _, err := l.Get(ctx, "a")
+		return err == nil && v == "3"
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(1, l.Len())
+
+	cancel()
+	<-done
+}
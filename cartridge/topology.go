@@ -0,0 +1,273 @@
+// Package cartridge reads the topology of a Tarantool Cartridge cluster with
+// its Lua admin API: the replicasets, their roles and the masters chosen by
+// the failover.
+//
+// The package doesn't keep a pool of connections, Observe reports the changes
+// of the topology to keep the connections of the caller in sync:
+//
+//	for t := range cartridge.Observe(ctx, conn, nil) {
+//		for _, s := range t.Masters("storage") {
+//			// connect to s.URI for the writes
+//		}
+//	}
+//
+// Any instance of the cluster may be asked, the scripts are executed with Eval,
+// so the user needs the execute privilege on the universe.
+package cartridge
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// DefaultPollInterval is the interval between the reads of the topology by Observe.
+const DefaultPollInterval = 5 * time.Second
+
+// luaTopology returns the plain copies of the replicasets of admin_get_replicasets,
+// the objects of which refer to each other, and the failover mode
+const luaTopology = `
+local cartridge = require('cartridge')
+local function array(t)
+    return setmetatable(t or {}, {__serialize = 'array'})
+end
+local replicasets = array()
+for _, rs in ipairs(cartridge.admin_get_replicasets()) do
+    local servers = array()
+    for _, s in ipairs(rs.servers or {}) do
+        table.insert(servers, {
+            uuid = s.uuid,
+            uri = s.uri,
+            alias = s.alias,
+            status = s.status,
+            disabled = s.disabled == true,
+            priority = s.priority,
+        })
+    end
+    local roles = array()
+    for _, role in ipairs(rs.roles or {}) do
+        table.insert(roles, role)
+    end
+    table.insert(replicasets, {
+        uuid = rs.uuid,
+        alias = rs.alias,
+        roles = roles,
+        status = rs.status,
+        all_rw = rs.all_rw == true,
+        master = rs.master and rs.master.uuid,
+        active_master = rs.active_master and rs.active_master.uuid,
+        servers = servers,
+    })
+end
+local mode = 'disabled'
+if cartridge.failover_get_params ~= nil then
+    mode = cartridge.failover_get_params().mode
+end
+return replicasets, mode
+`
+
+// Topology is the state of the cluster.
+type Topology struct {
+	Replicasets []Replicaset
+	// FailoverMode is disabled, eventual, stateful or raft.
+	FailoverMode string
+}
+
+// Replicaset is a replicaset of the cluster.
+type Replicaset struct {
+	UUID   string
+	Alias  string
+	Roles  []string
+	Status string
+	// AllRW is set if all the servers of the replicaset are writable.
+	AllRW bool
+	// Master is the UUID of the master set by the configuration,
+	// ActiveMaster is the one chosen by the failover.
+	Master       string
+	ActiveMaster string
+	Servers      []Server
+}
+
+// Server is an instance of the replicaset.
+type Server struct {
+	UUID  string
+	URI   string
+	Alias string
+	// Status is healthy, unreachable or the error of the instance.
+	Status   string
+	Disabled bool
+	// Priority is the failover priority of the server in the replicaset, from 1.
+	Priority int
+}
+
+// Healthy reports whether the server is enabled and reachable.
+func (s *Server) Healthy() bool {
+	return !s.Disabled && s.Status == "healthy"
+}
+
+// HasRole reports whether the role is enabled on the replicaset.
+func (rs *Replicaset) HasRole(role string) bool {
+	for _, r := range rs.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Leader returns the server which takes the writes, the active master,
+// or the configured one if the failover hasn't chosen it.
+func (rs *Replicaset) Leader() (Server, bool) {
+	uuid := rs.ActiveMaster
+	if uuid == "" {
+		uuid = rs.Master
+	}
+	for _, s := range rs.Servers {
+		if s.UUID == uuid {
+			return s, true
+		}
+	}
+	return Server{}, false
+}
+
+// Masters returns the leaders of the replicasets with the role,
+// of all the replicasets if the role is empty.
+func (t *Topology) Masters(role string) []Server {
+	var servers []Server
+	for i := range t.Replicasets {
+		rs := &t.Replicasets[i]
+		if role != "" && !rs.HasRole(role) {
+			continue
+		}
+		if s, ok := rs.Leader(); ok {
+			servers = append(servers, s)
+		}
+	}
+	return servers
+}
+
+// Replicas returns the healthy servers of the replicasets with the role except
+// the leaders, of all the replicasets if the role is empty.
+func (t *Topology) Replicas(role string) []Server {
+	var servers []Server
+	for i := range t.Replicasets {
+		rs := &t.Replicasets[i]
+		if role != "" && !rs.HasRole(role) {
+			continue
+		}
+		leader, _ := rs.Leader()
+		for _, s := range rs.Servers {
+			if s.UUID != leader.UUID && s.Healthy() {
+				servers = append(servers, s)
+			}
+		}
+	}
+	return servers
+}
+
+// Get reads the topology of the cluster.
+func Get(ctx context.Context, conn tarantool.Executor) (*Topology, error) {
+	res := conn.Exec(ctx, &tarantool.Eval{Expression: luaTopology})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	t := &Topology{}
+	if len(res.Data) > 0 {
+		for _, r := range res.Data[0] {
+			m, _ := r.(map[string]interface{})
+			t.Replicasets = append(t.Replicasets, parseReplicaset(m))
+		}
+	}
+	if len(res.Data) > 1 && len(res.Data[1]) > 0 {
+		t.FailoverMode, _ = res.Data[1][0].(string)
+	}
+	return t, nil
+}
+
+func parseReplicaset(m map[string]interface{}) Replicaset {
+	rs := Replicaset{}
+	rs.UUID, _ = m["uuid"].(string)
+	rs.Alias, _ = m["alias"].(string)
+	rs.Status, _ = m["status"].(string)
+	rs.AllRW, _ = m["all_rw"].(bool)
+	rs.Master, _ = m["master"].(string)
+	rs.ActiveMaster, _ = m["active_master"].(string)
+
+	roles, _ := m["roles"].([]interface{})
+	for _, r := range roles {
+		if role, ok := r.(string); ok {
+			rs.Roles = append(rs.Roles, role)
+		}
+	}
+
+	servers, _ := m["servers"].([]interface{})
+	for _, s := range servers {
+		sm, _ := s.(map[string]interface{})
+		srv := Server{}
+		srv.UUID, _ = sm["uuid"].(string)
+		srv.URI, _ = sm["uri"].(string)
+		srv.Alias, _ = sm["alias"].(string)
+		srv.Status, _ = sm["status"].(string)
+		srv.Disabled, _ = sm["disabled"].(bool)
+		srv.Priority, _ = typeconv.IntfToInt(sm["priority"])
+		rs.Servers = append(rs.Servers, srv)
+	}
+	return rs
+}
+
+// ObserveOptions are the options of Observe.
+type ObserveOptions struct {
+	// PollInterval is the interval between the reads of the topology,
+	// DefaultPollInterval is used if it is 0.
+	PollInterval time.Duration
+	// OnError is called with the errors of the reads.
+	OnError func(err error)
+}
+
+// Observe returns the channel receiving the current topology and then each
+// change of it, e.g. a new master chosen by the failover. The channel is
+// closed when ctx is done. opts may be nil.
+func Observe(ctx context.Context, conn tarantool.Executor, opts *ObserveOptions) <-chan *Topology {
+	if opts == nil {
+		opts = &ObserveOptions{}
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ch := make(chan *Topology)
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last *Topology
+		for {
+			t, err := Get(ctx, conn)
+			if err != nil {
+				if opts.OnError != nil && ctx.Err() == nil {
+					opts.OnError(err)
+				}
+			} else if last == nil || !reflect.DeepEqual(last, t) {
+				select {
+				case ch <- t:
+					last = t
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
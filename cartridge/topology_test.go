@@ -0,0 +1,168 @@
+package cartridge
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+// fakeServer returns the topology of two replicasets, the failover switches
+// the storage master to the replica after failover is set
+type fakeServer struct {
+	mu       sync.Mutex
+	failover bool
+	broken   bool
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	eval, ok := q.(*tarantool.Eval)
+	if !ok || eval.Expression != luaTopology {
+		return &tarantool.Result{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.broken {
+		return &tarantool.Result{
+			ErrorCode: tarantool.ErrProcLua,
+			Error:     tarantool.NewQueryError(tarantool.ErrProcLua, "module 'cartridge' not found"),
+		}
+	}
+
+	active := "s1"
+	if s.failover {
+		active = "s2"
+	}
+	return &tarantool.Result{Data: [][]interface{}{
+		{
+			map[string]interface{}{
+				"uuid": "rs-router", "alias": "router", "status": "healthy", "all_rw": true,
+				"roles": []interface{}{"vshard-router"}, "master": "r1", "active_master": "r1",
+				"servers": []interface{}{
+					map[string]interface{}{"uuid": "r1", "uri": "localhost:3301", "alias": "router-1", "status": "healthy", "disabled": false, "priority": uint64(1)},
+				},
+			},
+			map[string]interface{}{
+				"uuid": "rs-storage", "alias": "storage", "status": "healthy", "all_rw": false,
+				"roles": []interface{}{"vshard-storage", "metrics"}, "master": "s1", "active_master": active,
+				"servers": []interface{}{
+					map[string]interface{}{"uuid": "s1", "uri": "localhost:3302", "alias": "storage-1", "status": "healthy", "disabled": false, "priority": uint64(1)},
+					map[string]interface{}{"uuid": "s2", "uri": "localhost:3303", "alias": "storage-2", "status": "healthy", "disabled": false, "priority": uint64(2)},
+					map[string]interface{}{"uuid": "s3", "uri": "localhost:3304", "alias": "storage-3", "status": "unreachable", "disabled": false, "priority": uint64(3)},
+				},
+			},
+		},
+		{"stateful"},
+	}}
+}
+
+func (s *fakeServer) set(failover, broken bool) {
+	s.mu.Lock()
+	s.failover, s.broken = failover, broken
+	s.mu.Unlock()
+}
+
+func newConn(t *testing.T) (*tarantool.Connection, *fakeServer) {
+	s := &fakeServer{}
+	addr := tnttest.NewServer(t, s.handle)
+
+	conn := tnttest.Connect(t, addr, nil)
+	return conn, s
+}
+
+func aliases(servers []Server) []string {
+	var a []string
+	for _, s := range servers {
+		a = append(a, s.Alias)
+	}
+	return a
+}
+
+func TestGet(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn, _ := newConn(t)
+
+	topo, err := Get(context.Background(), conn)
+	require.NoError(err)
+	assert.Equal("stateful", topo.FailoverMode)
+	require.Len(topo.Replicasets, 2)
+
+	rs := topo.Replicasets[1]
+	assert.Equal("rs-storage", rs.UUID)
+	assert.Equal([]string{"vshard-storage", "metrics"}, rs.Roles)
+	assert.True(rs.HasRole("metrics"))
+	assert.False(rs.HasRole("vshard-router"))
+	assert.Equal(Server{UUID: "s2", URI: "localhost:3303", Alias: "storage-2", Status: "healthy", Priority: 2}, rs.Servers[1])
+	assert.False(rs.Servers[2].Healthy())
+
+	assert.Equal([]string{"router-1", "storage-1"}, aliases(topo.Masters("")))
+	assert.Equal([]string{"storage-1"}, aliases(topo.Masters("vshard-storage")))
+	assert.Equal([]string{"storage-2"}, aliases(topo.Replicas("vshard-storage")))
+	assert.Empty(topo.Replicas("vshard-router"))
+	assert.Empty(topo.Masters("missing"))
+}
+
+func TestLeader(t *testing.T) {
+	rs := Replicaset{Master: "a", Servers: []Server{{UUID: "a"}, {UUID: "b"}}}
+	s, ok := rs.Leader()
+	assert.True(t, ok)
+	assert.Equal(t, "a", s.UUID)
+
+	rs.ActiveMaster = "b"
+	s, _ = rs.Leader()
+	assert.Equal(t, "b", s.UUID)
+
+	rs.ActiveMaster = "c"
+	_, ok = rs.Leader()
+	assert.False(t, ok)
+}
+
+func TestObserve(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn, s := newConn(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 10)
+	ch := Observe(ctx, conn, &ObserveOptions{
+		PollInterval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+
+	topo := <-ch
+	assert.Equal([]string{"storage-1"}, aliases(topo.Masters("vshard-storage")))
+
+	// the reads failing and the unchanged topology are not reported
+	s.set(false, true)
+	require.Error(<-errs)
+	s.set(false, false)
+	select {
+	case <-ch:
+		t.Fatal("the unchanged topology is reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	s.set(true, false)
+	topo = <-ch
+	assert.Equal([]string{"storage-2"}, aliases(topo.Masters("vshard-storage")))
+	assert.Equal([]string{"storage-1"}, aliases(topo.Replicas("vshard-storage")))
+
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,397 @@
+// Package cdc is the change data capture over the replication protocol:
+// it subscribes to the master as an anonymous replica, filters the rows by
+// space, turns them into Events and keeps track of the vclock position,
+// so the changes are resumed from it after a restart.
+//
+// The replication stream carries the requests rather than the tuples they
+// change, e.g. the key and the operations of an update. So Event.Before and
+// the After of the updates and deletes are only known if CDC.Images is on:
+// then the tuples of the captured spaces are kept in memory. They are
+// complete if the capture is started from the snapshot, otherwise only the
+// tuples changed since the start are known.
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+	"github.com/viciious/go-tarantool"
+)
+
+const (
+	// DefaultSaveInterval is the interval between the saves of the position.
+	DefaultSaveInterval = time.Second
+	// DefaultRetryInterval is the pause before the replication is resumed
+	// after the connection fails.
+	DefaultRetryInterval = time.Second
+
+	// minUserSpace is the id of the first user space, the lower ones are system
+	minUserSpace = 512
+)
+
+// Source is the replication stream of the master.
+type Source interface {
+	// Snapshot returns the rows of the latest checkpoint and its vclock.
+	Snapshot() (tarantool.PacketIterator, tarantool.VectorClock, error)
+	// Subscribe returns the rows written after the vclock.
+	Subscribe(vclock tarantool.VectorClock) (tarantool.PacketIterator, error)
+	Close() error
+}
+
+// Handler handles the event, the event is handled again after a restart if it fails.
+type Handler func(ctx context.Context, e *Event) error
+
+// CDC captures the changes of the spaces.
+type CDC struct {
+	// Dial connects to the master, see AnonDialer.
+	Dial func() (Source, error)
+	// Spaces are the ids of the captured spaces, all the user spaces by default.
+	Spaces []uint
+	// KeyFields are the 0-based primary key field numbers of the spaces,
+	// the first field is the key by default.
+	KeyFields map[uint][]int
+	// Images keeps the tuples of the captured spaces in memory to fill
+	// Event.Before and Event.After.
+	Images bool
+	// Snapshot starts the capture from the snapshot of the master,
+	// which is delivered as Insert events, unless the position is saved.
+	Snapshot bool
+	// Position persists the position, it is kept in memory only if it is nil.
+	Position PositionStore
+	// SaveInterval is the interval between the saves of the position,
+	// DefaultSaveInterval is used if it is 0.
+	SaveInterval time.Duration
+	// RetryInterval is the pause before reconnecting,
+	// DefaultRetryInterval is used if it is 0.
+	RetryInterval time.Duration
+	// OnError is called with the errors of the connection, which is reestablished.
+	OnError func(err error)
+
+	vclock   tarantool.VectorClock
+	saved    time.Time
+	images   map[uint]map[string][]interface{}
+	complete bool // the images are seeded by the snapshot
+}
+
+// AnonDialer returns the Dial function connecting as an anonymous replica,
+// Tarantool >= 2.3.1.
+func AnonDialer(uri string, opts tarantool.Options) func() (Source, error) {
+	return func() (Source, error) {
+		s, err := tarantool.NewAnonSlave(uri, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &anonSource{s}, nil
+	}
+}
+
+type anonSource struct {
+	*tarantool.AnonSlave
+}
+
+func (s *anonSource) Snapshot() (tarantool.PacketIterator, tarantool.VectorClock, error) {
+	it, err := s.JoinWithSnap()
+	if err != nil {
+		return nil, nil, err
+	}
+	return it, s.VClock, nil
+}
+
+func (s *anonSource) Subscribe(vclock tarantool.VectorClock) (tarantool.PacketIterator, error) {
+	if len(vclock) <= 1 {
+		return s.AnonSlave.Subscribe(0)
+	}
+	return s.AnonSlave.Subscribe(vclock[1:]...)
+}
+
+// Run delivers the events to the handler until ctx is done or the handler
+// fails. The position is saved periodically and on return, so the events
+// handled since the last save are delivered again after a restart.
+func (c *CDC) Run(ctx context.Context, handler Handler) (err error) {
+	if c.Dial == nil || handler == nil {
+		return errors.New("cdc needs Dial and the handler")
+	}
+
+	if c.vclock, err = c.load(); err != nil {
+		return fmt.Errorf("cdc: load position: %w", err)
+	}
+	c.saved = time.Now()
+	c.images = make(map[uint]map[string][]interface{})
+	c.complete = false
+	defer func() {
+		if serr := c.save(); serr != nil && err == nil {
+			err = fmt.Errorf("cdc: save position: %w", serr)
+		}
+	}()
+
+	for {
+		err = c.replicate(ctx, handler)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var ferr *fatalError
+		if errors.As(err, &ferr) {
+			return ferr.err
+		}
+		if c.OnError != nil {
+			c.OnError(err)
+		}
+
+		t := time.NewTimer(c.retryInterval())
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// fatalError stops Run instead of reconnecting
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string {
+	return e.err.Error()
+}
+
+func (c *CDC) retryInterval() time.Duration {
+	if c.RetryInterval <= 0 {
+		return DefaultRetryInterval
+	}
+	return c.RetryInterval
+}
+
+func (c *CDC) load() (tarantool.VectorClock, error) {
+	if c.Position == nil {
+		return nil, nil
+	}
+	return c.Position.Load()
+}
+
+func (c *CDC) save() error {
+	c.saved = time.Now()
+	if c.Position == nil || c.vclock == nil {
+		return nil
+	}
+	return c.Position.Save(c.vclock)
+}
+
+// replicate delivers the events of one connection
+func (c *CDC) replicate(ctx context.Context, handler Handler) error {
+	src, err := c.Dial()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		// unblocks the iterator
+		src.Close()
+	}()
+
+	if c.vclock == nil && c.Snapshot {
+		it, vclock, err := src.Snapshot()
+		if err != nil {
+			return err
+		}
+		if err = c.iterate(ctx, it, handler, false); err != io.EOF {
+			return err
+		}
+		c.vclock = copyVClock(vclock)
+		c.complete = c.Images
+		if err = c.save(); err != nil {
+			return &fatalError{fmt.Errorf("cdc: save position: %w", err)}
+		}
+	}
+
+	it, err := src.Subscribe(c.vclock)
+	if err != nil {
+		return err
+	}
+	if c.vclock == nil {
+		c.vclock = tarantool.NewVectorClock()
+	}
+	return c.iterate(ctx, it, handler, true)
+}
+
+// iterate delivers the events of the rows until the iterator fails
+func (c *CDC) iterate(ctx context.Context, it tarantool.PacketIterator, handler Handler, follow bool) error {
+	interval := c.SaveInterval
+	if interval <= 0 {
+		interval = DefaultSaveInterval
+	}
+
+	for {
+		p, err := it.Next()
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			continue
+		}
+
+		if e := c.event(p); e != nil {
+			if err = handler(ctx, e); err != nil {
+				return &fatalError{err}
+			}
+		}
+
+		if !follow {
+			continue
+		}
+		if p.InstanceID != 0 || p.LSN != 0 {
+			c.vclock.Follow(p.InstanceID, p.LSN)
+		}
+		if time.Since(c.saved) >= interval {
+			if err = c.save(); err != nil {
+				return &fatalError{fmt.Errorf("cdc: save position: %w", err)}
+			}
+		}
+	}
+}
+
+func (c *CDC) captured(space uint) bool {
+	if len(c.Spaces) == 0 {
+		return space >= minUserSpace
+	}
+	for _, s := range c.Spaces {
+		if s == space {
+			return true
+		}
+	}
+	return false
+}
+
+// event turns the row into the event, nil if it isn't captured
+func (c *CDC) event(p *tarantool.Packet) *Event {
+	e := &Event{
+		InstanceID: p.InstanceID,
+		LSN:        p.LSN,
+		Timestamp:  p.Timestamp,
+	}
+
+	var space interface{}
+	switch q := p.Request.(type) {
+	case *tarantool.Insert:
+		space = q.Space
+		e.Op, e.After = Insert, q.Tuple
+	case *tarantool.Replace:
+		space = q.Space
+		e.Op, e.After = Upsert, q.Tuple
+	case *tarantool.Upsert:
+		space = q.Space
+		e.Op, e.After, e.Ops = Upsert, q.Tuple, q.Set
+	case *tarantool.Update:
+		space = q.Space
+		e.Op, e.Key, e.Ops = Update, requestKey(q.Key, q.KeyTuple), q.Set
+	case *tarantool.Delete:
+		space = q.Space
+		e.Op, e.Key = Delete, requestKey(q.Key, q.KeyTuple)
+	default:
+		return nil
+	}
+
+	id, ok := space.(uint)
+	if !ok || !c.captured(id) {
+		return nil
+	}
+	e.Space = id
+
+	if e.Key == nil {
+		e.Key = c.tupleKey(id, e.After)
+	}
+	if c.Images {
+		c.track(e)
+	}
+	return e
+}
+
+func requestKey(key interface{}, keyTuple []interface{}) []interface{} {
+	if keyTuple != nil {
+		return keyTuple
+	}
+	return []interface{}{key}
+}
+
+func (c *CDC) tupleKey(space uint, tuple []interface{}) []interface{} {
+	fields, ok := c.KeyFields[space]
+	if !ok {
+		fields = []int{0}
+	}
+	key := make([]interface{}, len(fields))
+	for i, f := range fields {
+		if f < len(tuple) {
+			key[i] = tuple[f]
+		}
+	}
+	return key
+}
+
+// track fills the images of the event and updates the tuple image
+func (c *CDC) track(e *Event) {
+	images := c.images[e.Space]
+	if images == nil {
+		images = make(map[string][]interface{})
+		c.images[e.Space] = images
+	}
+
+	k, err := msgp.AppendIntf(nil, e.Key)
+	if err != nil {
+		return
+	}
+	key := string(k)
+	before, known := images[key]
+	known = known || c.complete
+
+	switch e.Op {
+	case Insert:
+	case Upsert:
+		if !known {
+			break
+		}
+		e.Before = before
+		if before == nil {
+			e.Op = Insert
+			break
+		}
+		e.Op = Update
+		if e.Ops != nil {
+			// upsert updates the existing tuple
+			if e.After, err = applyOps(before, e.Ops); err != nil {
+				e.After = nil
+			}
+		}
+	case Update:
+		e.Before = before
+		e.After = nil
+		if before != nil {
+			if e.After, err = applyOps(before, e.Ops); err != nil {
+				e.After = nil
+			}
+		}
+	case Delete:
+		e.Before = before
+	}
+
+	switch {
+	case e.Op == Delete:
+		delete(images, key)
+	case e.After != nil:
+		images[key] = e.After
+	case before != nil:
+		// the change can't be applied, e.g. a splice, so the tuple isn't known anymore
+		delete(images, key)
+		c.complete = false
+	}
+}
@@ -0,0 +1,268 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+)
+
+// fakeMaster keeps the rows of the snapshot and the log of the master
+type fakeMaster struct {
+	sync.Mutex
+	snapshot   []*tarantool.Packet
+	snapVClock tarantool.VectorClock
+	log        []*tarantool.Packet
+	subscribed []tarantool.VectorClock
+	// fail makes the iterator fail after the number of rows
+	fail int
+}
+
+func (m *fakeMaster) dial() (Source, error) {
+	return &fakeSource{master: m, closed: make(chan struct{})}, nil
+}
+
+type fakeSource struct {
+	master    *fakeMaster
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func (s *fakeSource) Snapshot() (tarantool.PacketIterator, tarantool.VectorClock, error) {
+	m := s.master
+	m.Lock()
+	defer m.Unlock()
+	rows := append([]*tarantool.Packet(nil), m.snapshot...)
+	return &fakeIterator{src: s, rows: rows, eof: true}, m.snapVClock, nil
+}
+
+func (s *fakeSource) Subscribe(vclock tarantool.VectorClock) (tarantool.PacketIterator, error) {
+	m := s.master
+	m.Lock()
+	defer m.Unlock()
+	m.subscribed = append(m.subscribed, copyVClock(vclock))
+
+	var rows []*tarantool.Packet
+	for _, p := range m.log {
+		if int(p.InstanceID) >= len(vclock) || vclock[p.InstanceID] < p.LSN {
+			rows = append(rows, p)
+		}
+	}
+	fail := m.fail
+	m.fail = 0
+	return &fakeIterator{src: s, rows: rows, fail: fail}, nil
+}
+
+func (s *fakeSource) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	return nil
+}
+
+type fakeIterator struct {
+	src  *fakeSource
+	rows []*tarantool.Packet
+	eof  bool
+	fail int
+	n    int
+}
+
+func (it *fakeIterator) Next() (*tarantool.Packet, error) {
+	if it.fail > 0 && it.n == it.fail {
+		return nil, errors.New("connection reset")
+	}
+	if it.n < len(it.rows) {
+		it.n++
+		return it.rows[it.n-1], nil
+	}
+	if it.eof {
+		return nil, io.EOF
+	}
+	<-it.src.closed
+	return nil, errors.New("connection closed")
+}
+
+func row(lsn uint64, q tarantool.Query) *tarantool.Packet {
+	return &tarantool.Packet{Cmd: q.GetCommandID(), InstanceID: 1, LSN: lsn, Request: q}
+}
+
+// collect runs the capture until the number of events is handled
+func collect(t *testing.T, c *CDC, n int) ([]*Event, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var events []*Event
+	err := c.Run(ctx, func(ctx context.Context, e *Event) error {
+		events = append(events, e)
+		if len(events) == n {
+			cancel()
+		}
+		return nil
+	})
+	require.Len(t, events, n)
+	return events, err
+}
+
+func TestCDCSnapshotImages(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	m := &fakeMaster{
+		snapshot: []*tarantool.Packet{
+			{Cmd: tarantool.InsertCommand, Request: &tarantool.Insert{Space: uint(272), Tuple: []interface{}{"cluster", "uuid"}}},
+			{Cmd: tarantool.InsertCommand, Request: &tarantool.Insert{Space: uint(512), Tuple: []interface{}{int64(1), "a", int64(10)}}},
+			{Cmd: tarantool.InsertCommand, Request: &tarantool.Insert{Space: uint(513), Tuple: []interface{}{int64(1)}}},
+		},
+		snapVClock: tarantool.NewVectorClock(10),
+		log: []*tarantool.Packet{
+			row(10, &tarantool.Insert{Space: uint(512), Tuple: []interface{}{int64(0), "old"}}),
+			row(11, &tarantool.Update{Space: uint(512), Index: uint(0), Key: int64(1), Set: []tarantool.Operator{
+				&tarantool.OpAdd{Field: 2, Argument: 5},
+			}}),
+			row(12, &tarantool.Replace{Space: uint(512), Tuple: []interface{}{int64(2), "b", int64(0)}}),
+			row(13, &tarantool.Insert{Space: uint(513), Tuple: []interface{}{int64(2)}}),
+			row(14, &tarantool.Upsert{Space: uint(512), Tuple: []interface{}{int64(2), "c", int64(0)}, Set: []tarantool.Operator{
+				&tarantool.OpAssign{Field: 1, Argument: "d"},
+			}}),
+			row(15, &tarantool.Delete{Space: uint(512), Index: uint(0), Key: int64(1)}),
+		},
+	}
+
+	store := &MemoryStore{}
+	c := &CDC{
+		Dial:         m.dial,
+		Spaces:       []uint{512},
+		Images:       true,
+		Snapshot:     true,
+		Position:     store,
+		SaveInterval: time.Hour,
+	}
+	events, err := collect(t, c, 5)
+	assert.Equal(context.Canceled, err)
+
+	assert.Equal(&Event{
+		Op:    Insert,
+		Space: 512,
+		Key:   []interface{}{int64(1)},
+		After: []interface{}{int64(1), "a", int64(10)},
+	}, events[0])
+
+	e := events[1]
+	assert.Equal(Update, e.Op)
+	assert.Equal(uint64(11), e.LSN)
+	assert.Equal([]interface{}{int64(1)}, e.Key)
+	assert.Equal([]interface{}{int64(1), "a", int64(10)}, e.Before)
+	assert.Equal([]interface{}{int64(1), "a", int64(15)}, e.After)
+
+	e = events[2]
+	assert.Equal(Insert, e.Op)
+	assert.Equal([]interface{}{int64(2)}, e.Key)
+	assert.Nil(e.Before)
+	assert.Equal([]interface{}{int64(2), "b", int64(0)}, e.After)
+
+	e = events[3]
+	assert.Equal(Update, e.Op)
+	assert.Equal([]interface{}{int64(2), "b", int64(0)}, e.Before)
+	assert.Equal([]interface{}{int64(2), "d", int64(0)}, e.After)
+
+	e = events[4]
+	assert.Equal(Delete, e.Op)
+	assert.Equal([]interface{}{int64(1), "a", int64(15)}, e.Before)
+	assert.Nil(e.After)
+
+	// the log is followed from the snapshot
+	assert.Equal([]tarantool.VectorClock{tarantool.NewVectorClock(10)}, m.subscribed)
+
+	vc, err := store.Load()
+	require.NoError(err)
+	assert.Equal(tarantool.NewVectorClock(15), vc)
+}
+
+func TestCDCResume(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	m := &fakeMaster{
+		log: []*tarantool.Packet{
+			row(1, &tarantool.Insert{Space: uint(512), Tuple: []interface{}{int64(1), "a"}}),
+			row(2, &tarantool.Replace{Space: uint(512), Tuple: []interface{}{int64(2), "b"}}),
+			row(3, &tarantool.Update{Space: uint(512), Index: uint(0), KeyTuple: []interface{}{int64(2), "b"}, Set: []tarantool.Operator{
+				&tarantool.OpAssign{Field: 2, Argument: true},
+			}}),
+			row(4, &tarantool.Delete{Space: uint(512), Index: uint(0), Key: int64(1)}),
+		},
+	}
+
+	store := &MemoryStore{}
+	require.NoError(store.Save(tarantool.NewVectorClock(1)))
+
+	var errs []error
+	c := &CDC{
+		Dial:          m.dial,
+		Position:      store,
+		RetryInterval: time.Millisecond,
+		OnError:       func(err error) { errs = append(errs, err) },
+	}
+	m.fail = 1
+
+	events, err := collect(t, c, 3)
+	assert.Equal(context.Canceled, err)
+	assert.Len(errs, 1)
+
+	// the position is kept over the reconnect
+	assert.Equal([]tarantool.VectorClock{
+		tarantool.NewVectorClock(1),
+		tarantool.NewVectorClock(2),
+	}, m.subscribed)
+
+	assert.Equal(Upsert, events[0].Op)
+	assert.Equal(uint64(2), events[0].LSN)
+	assert.Equal([]interface{}{int64(2), "b"}, events[0].After)
+
+	// without the images the tuples are unknown
+	assert.Equal(Update, events[1].Op)
+	assert.Equal([]interface{}{int64(2), "b"}, events[1].Key)
+	assert.Nil(events[1].Before)
+	assert.Nil(events[1].After)
+	assert.Len(events[1].Ops, 1)
+
+	assert.Equal(Delete, events[2].Op)
+	assert.Equal([]interface{}{int64(1)}, events[2].Key)
+
+	vc, err := store.Load()
+	require.NoError(err)
+	assert.Equal(tarantool.NewVectorClock(4), vc)
+}
+
+func TestCDCHandlerError(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	m := &fakeMaster{
+		log: []*tarantool.Packet{
+			row(1, &tarantool.Insert{Space: uint(512), Tuple: []interface{}{int64(1)}}),
+			row(2, &tarantool.Insert{Space: uint(512), Tuple: []interface{}{int64(2)}}),
+		},
+	}
+	store := &MemoryStore{}
+	c := &CDC{Dial: m.dial, Position: store}
+
+	failure := errors.New("handler failed")
+	err := c.Run(context.Background(), func(ctx context.Context, e *Event) error {
+		if e.LSN == 2 {
+			return failure
+		}
+		return nil
+	})
+	assert.Equal(failure, err)
+
+	// the failed event is delivered again
+	vc, err := store.Load()
+	require.NoError(err)
+	assert.Equal(tarantool.NewVectorClock(1), vc)
+}
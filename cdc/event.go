@@ -0,0 +1,180 @@
+package cdc
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// Op is the kind of the change.
+type Op int
+
+const (
+	// Insert is a new tuple.
+	Insert Op = iota + 1
+	// Update is a change of an existing tuple.
+	Update
+	// Delete is a removed tuple.
+	Delete
+	// Upsert is a replace or upsert of a tuple whose previous state is not
+	// known, so it is either inserted or updated.
+	Upsert
+)
+
+func (op Op) String() string {
+	switch op {
+	case Insert:
+		return "insert"
+	case Update:
+		return "update"
+	case Delete:
+		return "delete"
+	case Upsert:
+		return "upsert"
+	}
+	return fmt.Sprintf("Op(%d)", int(op))
+}
+
+// Event is a change of a tuple.
+type Event struct {
+	Op         Op
+	Space      uint
+	InstanceID uint32
+	LSN        uint64
+	Timestamp  time.Time
+	// Key is the primary key of the tuple.
+	Key []interface{}
+	// Before is the tuple before the change, nil for Insert or if it is not known.
+	Before []interface{}
+	// After is the tuple after the change, nil for Delete or if it is not known.
+	After []interface{}
+	// Ops are the operations of the update or upsert request.
+	Ops []tarantool.Operator
+}
+
+var errCantApply = errors.New("can't apply the operation")
+
+// applyOps returns the copy of the tuple with the update operations applied
+func applyOps(tuple []interface{}, ops []tarantool.Operator) ([]interface{}, error) {
+	t := append([]interface{}(nil), tuple...)
+
+	for _, op := range ops {
+		switch op := op.(type) {
+		case *tarantool.OpAssign:
+			i, ok := fieldIndex(t, op.Field, true)
+			if !ok {
+				return nil, errCantApply
+			}
+			if i == len(t) {
+				t = append(t, op.Argument)
+			} else {
+				t[i] = op.Argument
+			}
+		case *tarantool.OpInsert:
+			before := op.Before
+			if before < 0 {
+				// -1 is the position after the last field
+				before++
+				if before == 0 {
+					before = int64(len(t))
+				}
+			}
+			i, ok := fieldIndex(t, before, true)
+			if !ok {
+				return nil, errCantApply
+			}
+			t = append(t, nil)
+			copy(t[i+1:], t[i:])
+			t[i] = op.Argument
+		case *tarantool.OpDelete:
+			i, ok := fieldIndex(t, op.From, false)
+			if !ok {
+				return nil, errCantApply
+			}
+			n := int(op.Count)
+			if n > len(t)-i {
+				n = len(t) - i
+			}
+			t = append(t[:i], t[i+n:]...)
+		case *tarantool.OpAdd:
+			if err := applyArith(t, op.Field, op.Argument); err != nil {
+				return nil, err
+			}
+		case *tarantool.OpSub:
+			if err := applyArith(t, op.Field, -op.Argument); err != nil {
+				return nil, err
+			}
+		case *tarantool.OpBitAND:
+			if err := applyBits(t, op.Field, func(v uint64) uint64 { return v & op.Argument }); err != nil {
+				return nil, err
+			}
+		case *tarantool.OpBitOR:
+			if err := applyBits(t, op.Field, func(v uint64) uint64 { return v | op.Argument }); err != nil {
+				return nil, err
+			}
+		case *tarantool.OpBitXOR:
+			if err := applyBits(t, op.Field, func(v uint64) uint64 { return v ^ op.Argument }); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, errCantApply
+		}
+	}
+	return t, nil
+}
+
+// fieldIndex resolves the 0-based field number of the operation, the negative
+// ones count from the end. The position after the last field is allowed if
+// the tuple can be extended with it.
+func fieldIndex(t []interface{}, field int64, extend bool) (int, bool) {
+	i := int(field)
+	if i < 0 {
+		i += len(t)
+	}
+	if i < 0 || i > len(t) || i == len(t) && !extend {
+		return 0, false
+	}
+	return i, true
+}
+
+func applyArith(t []interface{}, field int64, arg int64) error {
+	i, ok := fieldIndex(t, field, false)
+	if !ok {
+		return errCantApply
+	}
+	switch v := t[i].(type) {
+	case float64:
+		t[i] = v + float64(arg)
+	case float32:
+		t[i] = v + float32(arg)
+	default:
+		n, ok := typeconv.IntfToInt64(v)
+		if !ok {
+			return errCantApply
+		}
+		t[i] = n + arg
+	}
+	return nil
+}
+
+func applyBits(t []interface{}, field int64, fn func(uint64) uint64) error {
+	i, ok := fieldIndex(t, field, false)
+	if !ok {
+		return errCantApply
+	}
+	n, ok := typeconv.IntfToUint64(t[i])
+	if !ok {
+		return errCantApply
+	}
+	if n = fn(n); n > math.MaxInt64 {
+		t[i] = n
+	} else {
+		// the way msgp decodes it
+		t[i] = int64(n)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cdc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viciious/go-tarantool"
+)
+
+func TestApplyOps(t *testing.T) {
+	assert := assert.New(t)
+
+	tuple := []interface{}{int64(1), "name", int64(10), 1.5}
+	for _, tc := range []struct {
+		ops    []tarantool.Operator
+		result []interface{}
+	}{
+		{
+			[]tarantool.Operator{&tarantool.OpAssign{Field: 1, Argument: "new"}},
+			[]interface{}{int64(1), "new", int64(10), 1.5},
+		},
+		{
+			[]tarantool.Operator{&tarantool.OpAssign{Field: 4, Argument: true}},
+			[]interface{}{int64(1), "name", int64(10), 1.5, true},
+		},
+		{
+			[]tarantool.Operator{&tarantool.OpAssign{Field: -1, Argument: 2.5}},
+			[]interface{}{int64(1), "name", int64(10), 2.5},
+		},
+		{
+			[]tarantool.Operator{&tarantool.OpAdd{Field: 2, Argument: 5}, &tarantool.OpSub{Field: 3, Argument: 1}},
+			[]interface{}{int64(1), "name", int64(15), 0.5},
+		},
+		{
+			[]tarantool.Operator{&tarantool.OpBitOR{Field: 2, Argument: 5}},
+			[]interface{}{int64(1), "name", int64(15), 1.5},
+		},
+		{
+			[]tarantool.Operator{&tarantool.OpBitAND{Field: 2, Argument: 3}, &tarantool.OpBitXOR{Field: 0, Argument: 3}},
+			[]interface{}{int64(2), "name", int64(2), 1.5},
+		},
+		{
+			[]tarantool.Operator{&tarantool.OpDelete{From: 1, Count: 2}},
+			[]interface{}{int64(1), 1.5},
+		},
+		{
+			[]tarantool.Operator{&tarantool.OpInsert{Before: 1, Argument: "x"}, &tarantool.OpInsert{Before: -1, Argument: "y"}},
+			[]interface{}{int64(1), "x", "name", int64(10), 1.5, "y"},
+		},
+	} {
+		result, err := applyOps(tuple, tc.ops)
+		if assert.NoError(err) {
+			assert.Equal(tc.result, result)
+		}
+	}
+	assert.Equal([]interface{}{int64(1), "name", int64(10), 1.5}, tuple)
+
+	for _, ops := range [][]tarantool.Operator{
+		{&tarantool.OpAdd{Field: 1, Argument: 1}},
+		{&tarantool.OpAssign{Field: 5, Argument: 1}},
+		{&tarantool.OpDelete{From: 4, Count: 1}},
+		{&tarantool.OpSplice{Field: 1, Position: 1, Offset: 1, Argument: "x"}},
+	} {
+		_, err := applyOps(tuple, ops)
+		assert.Error(err)
+	}
+}
+
+func TestOpString(t *testing.T) {
+	assert.Equal(t, "insert", Insert.String())
+	assert.Equal(t, "upsert", Upsert.String())
+	assert.Equal(t, "Op(0)", Op(0).String())
+}
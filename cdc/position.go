@@ -0,0 +1,106 @@
+package cdc
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// PositionStore persists the vclock of the last handled change,
+// so the changes are resumed from it after a restart.
+type PositionStore interface {
+	// Load returns the saved vclock, nil if there is none.
+	Load() (tarantool.VectorClock, error)
+	Save(vclock tarantool.VectorClock) error
+}
+
+// FileStore keeps the position in a file as a JSON array of the vclock,
+// the file is replaced atomically on every Save.
+type FileStore struct {
+	Path string
+}
+
+var _ PositionStore = (*FileStore)(nil)
+
+// Load implements PositionStore
+func (fs *FileStore) Load() (tarantool.VectorClock, error) {
+	data, err := os.ReadFile(fs.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lsns []uint64
+	if err = json.Unmarshal(data, &lsns); err != nil {
+		return nil, err
+	}
+	vc := tarantool.NewVectorClock()
+	for id, lsn := range lsns {
+		vc.Follow(uint32(id), lsn)
+	}
+	return vc, nil
+}
+
+// Save implements PositionStore
+func (fs *FileStore) Save(vclock tarantool.VectorClock) error {
+	data, err := json.Marshal([]uint64(vclock))
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(fs.Path), filepath.Base(fs.Path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), fs.Path)
+}
+
+// MemoryStore keeps the position in memory, e.g. for tests.
+type MemoryStore struct {
+	mu     sync.Mutex
+	vclock tarantool.VectorClock
+}
+
+var _ PositionStore = (*MemoryStore)(nil)
+
+// Load implements PositionStore
+func (ms *MemoryStore) Load() (tarantool.VectorClock, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	return copyVClock(ms.vclock), nil
+}
+
+// Save implements PositionStore
+func (ms *MemoryStore) Save(vclock tarantool.VectorClock) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.vclock = copyVClock(vclock)
+	return nil
+}
+
+func copyVClock(vc tarantool.VectorClock) tarantool.VectorClock {
+	if vc == nil {
+		return nil
+	}
+	c := tarantool.NewVectorClock()
+	for id, lsn := range vc {
+		c.Follow(uint32(id), lsn)
+	}
+	return c
+}
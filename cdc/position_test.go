@@ -0,0 +1,47 @@
+package cdc
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+)
+
+func TestFileStore(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fs := &FileStore{Path: filepath.Join(t.TempDir(), "position.json")}
+
+	vc, err := fs.Load()
+	require.NoError(err)
+	assert.Nil(vc)
+
+	require.NoError(fs.Save(tarantool.NewVectorClock(10, 20)))
+	require.NoError(fs.Save(tarantool.NewVectorClock(11, 20)))
+	vc, err = fs.Load()
+	require.NoError(err)
+	assert.Equal(tarantool.NewVectorClock(11, 20), vc)
+
+	matches, err := filepath.Glob(fs.Path + ".tmp*")
+	require.NoError(err)
+	assert.Empty(matches)
+}
+
+func TestMemoryStore(t *testing.T) {
+	assert := assert.New(t)
+
+	var ms MemoryStore
+	vc, err := ms.Load()
+	assert.NoError(err)
+	assert.Nil(vc)
+
+	saved := tarantool.NewVectorClock(5)
+	assert.NoError(ms.Save(saved))
+	saved[1] = 6
+	vc, err = ms.Load()
+	assert.NoError(err)
+	assert.Equal(tarantool.NewVectorClock(5), vc)
+}
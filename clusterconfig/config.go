@@ -0,0 +1,282 @@
+// Package clusterconfig discovers the instances and the users of a Tarantool 3
+// cluster from its centralized configuration kept in etcd or in
+// a Tarantool config storage.
+//
+// The configuration is the YAML of the keys under PREFIX/config/, merged in
+// the order of the keys as Tarantool does. The options of an instance are
+// inherited from its replicaset, group and the global scope:
+//
+//	c, err := clusterconfig.Get(ctx, &clusterconfig.Etcd{
+//		Endpoint: "http://127.0.0.1:2379",
+//		Prefix:   "/myapp",
+//	})
+//	for _, i := range c.Writable() {
+//		// connect to i.URI
+//	}
+//
+// The package doesn't keep a pool of connections, Observe reports the changes
+// of the configuration to keep the connections of the caller in sync.
+package clusterconfig
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultPollInterval is the interval between the reads of the configuration by Observe.
+const DefaultPollInterval = 5 * time.Second
+
+// The modes of the instances.
+const (
+	ModeRW = "rw"
+	ModeRO = "ro"
+)
+
+// Source is the storage of the configuration.
+type Source interface {
+	// Fetch returns the documents of the configuration in the order of their keys.
+	Fetch(ctx context.Context) ([][]byte, error)
+}
+
+// SourceFunc is the function implementing Source.
+type SourceFunc func(ctx context.Context) ([][]byte, error)
+
+// Fetch calls the function.
+func (fn SourceFunc) Fetch(ctx context.Context) ([][]byte, error) {
+	return fn(ctx)
+}
+
+// Cluster is the configuration of the cluster.
+type Cluster struct {
+	Instances []Instance
+	// Users are the users of credentials.users by their names.
+	Users map[string]User
+}
+
+// Instance is an instance of the cluster.
+type Instance struct {
+	Name       string
+	Group      string
+	Replicaset string
+	// URI is iproto.advertise.client, or the first of iproto.listen.
+	URI string
+	// Mode is ModeRW or ModeRO, it is empty if the leader is elected,
+	// i.e. replication.failover is election or supervised.
+	Mode string
+}
+
+// User is a user of the configuration.
+type User struct {
+	Password string
+	Roles    []string
+}
+
+// Writable returns the instances in the rw mode.
+func (c *Cluster) Writable() []Instance {
+	return c.filter(ModeRW)
+}
+
+// ReadOnly returns the instances in the ro mode.
+func (c *Cluster) ReadOnly() []Instance {
+	return c.filter(ModeRO)
+}
+
+func (c *Cluster) filter(mode string) []Instance {
+	var instances []Instance
+	for _, i := range c.Instances {
+		if i.Mode == mode {
+			instances = append(instances, i)
+		}
+	}
+	return instances
+}
+
+// Get reads and parses the configuration.
+func Get(ctx context.Context, src Source) (*Cluster, error) {
+	docs, err := src.Fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(docs...)
+}
+
+// Parse merges the documents of the configuration and returns the cluster.
+func Parse(docs ...[]byte) (*Cluster, error) {
+	cfg := map[string]interface{}{}
+	for i, data := range docs {
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("document %d: %w", i+1, err)
+		}
+		merge(cfg, doc)
+	}
+
+	c := &Cluster{Users: map[string]User{}}
+	users := mapAt(cfg, "credentials", "users")
+	for name, u := range users {
+		um, _ := u.(map[string]interface{})
+		user := User{}
+		user.Password, _ = um["password"].(string)
+		roles, _ := um["roles"].([]interface{})
+		for _, r := range roles {
+			if role, ok := r.(string); ok {
+				user.Roles = append(user.Roles, role)
+			}
+		}
+		c.Users[name] = user
+	}
+
+	groups := mapAt(cfg, "groups")
+	for _, gname := range sortedKeys(groups) {
+		group, _ := groups[gname].(map[string]interface{})
+		replicasets := mapAt(group, "replicasets")
+		for _, rsname := range sortedKeys(replicasets) {
+			rs, _ := replicasets[rsname].(map[string]interface{})
+			instances := mapAt(rs, "instances")
+			scopes := []map[string]interface{}{rs, group, cfg}
+
+			failover, _ := inherited(scopes, "replication", "failover").(string)
+			leader, _ := rs["leader"].(string)
+
+			for _, iname := range sortedKeys(instances) {
+				inst, _ := instances[iname].(map[string]interface{})
+				i := Instance{Name: iname, Group: gname, Replicaset: rsname}
+				i.URI = instanceURI(append([]map[string]interface{}{inst}, scopes...))
+				i.Mode = instanceMode(inst, failover, leader, iname, len(instances))
+				c.Instances = append(c.Instances, i)
+			}
+		}
+	}
+	return c, nil
+}
+
+func instanceURI(scopes []map[string]interface{}) string {
+	if uri, ok := inherited(scopes, "iproto", "advertise", "client").(string); ok && uri != "" {
+		return uri
+	}
+	listen, _ := inherited(scopes, "iproto", "listen").([]interface{})
+	for _, l := range listen {
+		if lm, ok := l.(map[string]interface{}); ok {
+			if uri, ok := lm["uri"].(string); ok && uri != "" {
+				return uri
+			}
+		}
+	}
+	return ""
+}
+
+func instanceMode(inst map[string]interface{}, failover, leader, name string, instances int) string {
+	switch failover {
+	case "", "off":
+		if mode, ok := mapAt(inst, "database")["mode"].(string); ok && mode != "" {
+			return mode
+		}
+		if instances == 1 {
+			return ModeRW
+		}
+		return ModeRO
+	case "manual":
+		if name == leader {
+			return ModeRW
+		}
+		return ModeRO
+	}
+	return ""
+}
+
+// inherited returns the option from the first scope it is set in
+func inherited(scopes []map[string]interface{}, path ...string) interface{} {
+	for _, s := range scopes {
+		m := mapAt(s, path[:len(path)-1]...)
+		if v, ok := m[path[len(path)-1]]; ok && v != nil {
+			return v
+		}
+	}
+	return nil
+}
+
+func mapAt(m map[string]interface{}, path ...string) map[string]interface{} {
+	for _, p := range path {
+		m, _ = m[p].(map[string]interface{})
+	}
+	return m
+}
+
+// merge merges the maps of src into dst recursively, the other values of src replace the ones of dst
+func merge(dst, src map[string]interface{}) {
+	for k, v := range src {
+		sm, ok := v.(map[string]interface{})
+		dm, dok := dst[k].(map[string]interface{})
+		if ok && dok {
+			merge(dm, sm)
+			continue
+		}
+		dst[k] = v
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ObserveOptions are the options of Observe.
+type ObserveOptions struct {
+	// PollInterval is the interval between the reads of the configuration,
+	// DefaultPollInterval is used if it is 0.
+	PollInterval time.Duration
+	// OnError is called with the errors of the reads.
+	OnError func(err error)
+}
+
+// Observe returns the channel receiving the current cluster and then each
+// change of it. The channel is closed when ctx is done. opts may be nil.
+func Observe(ctx context.Context, src Source, opts *ObserveOptions) <-chan *Cluster {
+	if opts == nil {
+		opts = &ObserveOptions{}
+	}
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = DefaultPollInterval
+	}
+
+	ch := make(chan *Cluster)
+	go func() {
+		defer close(ch)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var last *Cluster
+		for {
+			c, err := Get(ctx, src)
+			if err != nil {
+				if opts.OnError != nil && ctx.Err() == nil {
+					opts.OnError(err)
+				}
+			} else if last == nil || !reflect.DeepEqual(last, c) {
+				select {
+				case ch <- c:
+					last = c
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
@@ -0,0 +1,156 @@
+package clusterconfig
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfig = `
+credentials:
+  users:
+    app:
+      password: secret
+      roles: [super]
+iproto:
+  listen:
+    - uri: 'localhost:3300'
+groups:
+  storages:
+    replication:
+      failover: manual
+    replicasets:
+      s1:
+        leader: s1-a
+        instances:
+          s1-a:
+            iproto:
+              listen:
+                - uri: '10.0.0.1:3301'
+              advertise:
+                client: 'storage-a:3301'
+          s1-b:
+            iproto:
+              listen:
+                - uri: '10.0.0.2:3301'
+  routers:
+    replicasets:
+      r1:
+        instances:
+          router:
+            iproto:
+              listen:
+                - uri: '10.0.0.3:3301'
+      r2:
+        instances:
+          r2-a:
+            database:
+              mode: rw
+          r2-b: {}
+`
+
+// the keys after the first one override it
+const testOverride = `
+groups:
+  storages:
+    replicasets:
+      s1:
+        leader: s1-b
+  raft:
+    replication:
+      failover: election
+    replicasets:
+      e1:
+        instances:
+          e1-a: {}
+`
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	c, err := Parse([]byte(testConfig))
+	require.NoError(err)
+
+	assert.Equal(map[string]User{"app": {Password: "secret", Roles: []string{"super"}}}, c.Users)
+	assert.Equal([]Instance{
+		{Name: "router", Group: "routers", Replicaset: "r1", URI: "10.0.0.3:3301", Mode: ModeRW},
+		{Name: "r2-a", Group: "routers", Replicaset: "r2", URI: "localhost:3300", Mode: ModeRW},
+		{Name: "r2-b", Group: "routers", Replicaset: "r2", URI: "localhost:3300", Mode: ModeRO},
+		{Name: "s1-a", Group: "storages", Replicaset: "s1", URI: "storage-a:3301", Mode: ModeRW},
+		{Name: "s1-b", Group: "storages", Replicaset: "s1", URI: "10.0.0.2:3301", Mode: ModeRO},
+	}, c.Instances)
+	assert.Len(c.Writable(), 3)
+	assert.Len(c.ReadOnly(), 2)
+
+	c, err = Parse([]byte(testConfig), []byte(testOverride))
+	require.NoError(err)
+	writable := c.Writable()
+	assert.Equal("s1-b", writable[len(writable)-1].Name)
+	assert.Equal(Instance{Name: "e1-a", Group: "raft", Replicaset: "e1", URI: "localhost:3300"}, c.Instances[0])
+
+	_, err = Parse([]byte("groups: ["))
+	assert.Error(err)
+}
+
+func TestObserve(t *testing.T) {
+	assert := assert.New(t)
+
+	var (
+		mu   sync.Mutex
+		docs = [][]byte{[]byte(testConfig)}
+		fail bool
+	)
+	set := func(d [][]byte, f bool) {
+		mu.Lock()
+		docs, fail = d, f
+		mu.Unlock()
+	}
+	src := SourceFunc(func(ctx context.Context) ([][]byte, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail {
+			return nil, errors.New("etcd is down")
+		}
+		return docs, nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errs := make(chan error, 1)
+	ch := Observe(ctx, src, &ObserveOptions{
+		PollInterval: 10 * time.Millisecond,
+		OnError: func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+
+	c := <-ch
+	assert.Len(c.Instances, 5)
+
+	set(docs, true)
+	assert.EqualError(<-errs, "etcd is down")
+	set(docs, false)
+	select {
+	case <-ch:
+		t.Fatal("the unchanged configuration is reported")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	set([][]byte{[]byte(testConfig), []byte(testOverride)}, false)
+	c = <-ch
+	assert.Len(c.Instances, 6)
+
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,167 @@
+package clusterconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// configKey returns the key the configuration is kept under
+func configKey(prefix string) string {
+	return strings.TrimSuffix(prefix, "/") + "/config/"
+}
+
+// ConfigStorage is the Tarantool config storage, a replicaset with the
+// config.storage role.
+type ConfigStorage struct {
+	Conn tarantool.Executor
+	// Prefix is the config.storage.prefix of the instances.
+	Prefix string
+}
+
+// Fetch implements Source
+func (s *ConfigStorage) Fetch(ctx context.Context) ([][]byte, error) {
+	res := s.Conn.Exec(ctx, &tarantool.Call17{Name: "config.storage.get", Tuple: []interface{}{configKey(s.Prefix)}})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 {
+		return nil, nil
+	}
+
+	m, _ := res.Data[0][0].(map[string]interface{})
+	data, _ := m["data"].([]interface{})
+
+	type kv struct{ path, value string }
+	kvs := make([]kv, 0, len(data))
+	for _, d := range data {
+		dm, _ := d.(map[string]interface{})
+		path, _ := dm["path"].(string)
+		value, _ := dm["value"].(string)
+		kvs = append(kvs, kv{path, value})
+	}
+	sort.Slice(kvs, func(i, j int) bool { return kvs[i].path < kvs[j].path })
+
+	docs := make([][]byte, len(kvs))
+	for i, kv := range kvs {
+		docs[i] = []byte(kv.value)
+	}
+	return docs, nil
+}
+
+// Etcd is the etcd cluster, it is read with the JSON gateway of the v3 API.
+type Etcd struct {
+	// Endpoint is the URL of etcd, e.g. http://127.0.0.1:2379.
+	Endpoint string
+	// Prefix is the config.etcd.prefix of the instances.
+	Prefix string
+	// Username and Password authenticate the requests if Username is set.
+	Username string
+	Password string
+	// Client is used for the requests, http.DefaultClient if it is nil.
+	Client *http.Client
+}
+
+type etcdRange struct {
+	Kvs []struct {
+		Key   string `json:"key"`
+		Value string `json:"value"`
+	} `json:"kvs"`
+}
+
+// Fetch implements Source
+func (e *Etcd) Fetch(ctx context.Context) ([][]byte, error) {
+	var token string
+	if e.Username != "" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+		req := map[string]string{"name": e.Username, "password": e.Password}
+		if err := e.post(ctx, "/v3/auth/authenticate", "", req, &auth); err != nil {
+			return nil, err
+		}
+		token = auth.Token
+	}
+
+	key := configKey(e.Prefix)
+	req := map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(key)),
+		"range_end": base64.StdEncoding.EncodeToString(prefixEnd(key)),
+	}
+	var resp etcdRange
+	if err := e.post(ctx, "/v3/kv/range", token, req, &resp); err != nil {
+		return nil, err
+	}
+
+	// the keys are sorted by etcd
+	docs := make([][]byte, len(resp.Kvs))
+	for i, kv := range resp.Kvs {
+		value, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			return nil, fmt.Errorf("etcd: value of key %s: %w", kv.Key, err)
+		}
+		docs[i] = value
+	}
+	return docs, nil
+}
+
+func (e *Etcd) post(ctx context.Context, path, token string, in, out interface{}) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(e.Endpoint, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var msg struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(data, &msg) == nil && msg.Message != "" {
+			return fmt.Errorf("etcd: %s: %s", path, msg.Message)
+		}
+		return fmt.Errorf("etcd: %s: %s", path, resp.Status)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// prefixEnd returns the end of the range of the keys with the prefix
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	// all the keys after the prefix
+	return []byte{0}
+}
@@ -0,0 +1,101 @@
+package clusterconfig
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+func TestConfigStorage(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	handler := func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+		call, ok := q.(*tarantool.Call17)
+		if !ok || call.Name != "config.storage.get" {
+			return &tarantool.Result{}
+		}
+		if call.Tuple[0] != "/myapp/config/" {
+			return &tarantool.Result{Data: [][]interface{}{{map[string]interface{}{"data": []interface{}{}}}}}
+		}
+		return &tarantool.Result{Data: [][]interface{}{{map[string]interface{}{
+			"data": []interface{}{
+				map[string]interface{}{"path": "/myapp/config/b", "value": testOverride, "mod_revision": uint64(7)},
+				map[string]interface{}{"path": "/myapp/config/all", "value": testConfig, "mod_revision": uint64(5)},
+			},
+			"revision": uint64(7),
+		}}}}
+	}
+	addr := tnttest.NewServer(t, handler)
+	conn, err := tarantool.Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	docs, err := (&ConfigStorage{Conn: conn, Prefix: "/myapp"}).Fetch(context.Background())
+	require.NoError(err)
+	assert.Equal([][]byte{[]byte(testConfig), []byte(testOverride)}, docs)
+
+	docs, err = (&ConfigStorage{Conn: conn, Prefix: "/other/"}).Fetch(context.Background())
+	require.NoError(err)
+	assert.Empty(docs)
+}
+
+func TestEtcd(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	b64 := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		assert.NoError(json.NewDecoder(r.Body).Decode(&req))
+
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			if req["name"] != "root" || req["password"] != "pass" {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"message": "authentication failed, invalid user ID or password"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "tkn"})
+		case "/v3/kv/range":
+			if r.Header.Get("Authorization") != "tkn" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			assert.Equal(b64("/myapp/config/"), req["key"])
+			assert.Equal(b64("/myapp/config0"), req["range_end"])
+			json.NewEncoder(w).Encode(map[string]interface{}{"kvs": []map[string]string{
+				{"key": b64("/myapp/config/all"), "value": b64(testConfig)},
+			}})
+		}
+	}))
+	defer srv.Close()
+
+	src := &Etcd{Endpoint: srv.URL + "/", Prefix: "/myapp", Username: "root", Password: "pass"}
+	c, err := Get(context.Background(), src)
+	require.NoError(err)
+	assert.Len(c.Instances, 5)
+
+	src.Password = "wrong"
+	_, err = src.Fetch(context.Background())
+	assert.EqualError(err, "etcd: /v3/auth/authenticate: authentication failed, invalid user ID or password")
+
+	src.Username = ""
+	_, err = src.Fetch(context.Background())
+	assert.EqualError(err, "etcd: /v3/kv/range: 401 Unauthorized")
+}
+
+func TestPrefixEnd(t *testing.T) {
+	assert.Equal(t, []byte("/a0"), prefixEnd("/a/"))
+	assert.Equal(t, []byte{'a', 0x01}, prefixEnd(string([]byte{'a', 0x00, 0xff})))
+	assert.Equal(t, []byte{0}, prefixEnd(string([]byte{0xff})))
+}
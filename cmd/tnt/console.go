@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// luaEval evaluates the code as an expression, or as a chunk if it isn't one,
+// and returns the array of the values it returns
+const luaEval = `
+local code = ...
+local f, err = loadstring('return ' .. code)
+if f == nil then
+    f, err = loadstring(code)
+end
+if f == nil then
+    error(err, 0)
+end
+local function pack(...)
+    local r = {...}
+    for i = 1, select('#', ...) do
+        if r[i] == nil then
+            r[i] = box.NULL
+        end
+    end
+    return setmetatable(r, {__serialize = 'array'})
+end
+return pack(f())
+`
+
+// luaSQL executes the SQL statement
+const luaSQL = `
+local res, err = box.execute(...)
+if err ~= nil then
+    error(err, 0)
+end
+return res
+`
+
+const help = `\lua              evaluate Lua (default)
+\sql              execute SQL
+\select SPACE [KEY...]  print the tuples of the space
+\spaces           list the spaces
+\help             this help
+\q                quit
+A line ending with \ is continued on the next one.`
+
+var errQuit = errors.New("quit")
+
+type console struct {
+	conn *tarantool.Connection
+	out  io.Writer
+	sql  bool
+	json bool
+}
+
+// interactive reads the statements from the terminal until EOF or \q
+func (c *console) interactive(r io.Reader, errOut io.Writer, addr string) {
+	statements := newStatementScanner(r)
+	for {
+		mode := "lua"
+		if c.sql {
+			mode = "sql"
+		}
+		fmt.Fprintf(c.out, "%s %s> ", addr, mode)
+
+		stmt, ok := statements.next()
+		if !ok {
+			fmt.Fprintln(c.out)
+			return
+		}
+		if err := c.exec(stmt); err == errQuit {
+			return
+		} else if err != nil {
+			fmt.Fprintln(errOut, "error:", err)
+		}
+	}
+}
+
+// script executes the statements in order and stops at the first error
+func (c *console) script(r io.Reader) error {
+	statements := newStatementScanner(r)
+	for {
+		stmt, ok := statements.next()
+		if !ok {
+			return statements.err()
+		}
+		if err := c.exec(stmt); err == errQuit {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("%s: %w", stmt, err)
+		}
+	}
+}
+
+// exec executes the statement or the console command
+func (c *console) exec(stmt string) error {
+	stmt = strings.TrimSpace(stmt)
+	if stmt == "" {
+		return nil
+	}
+	if strings.HasPrefix(stmt, `\`) {
+		return c.command(strings.Fields(stmt[1:]))
+	}
+
+	ctx := context.Background()
+	if c.sql {
+		res := c.conn.Exec(ctx, &tarantool.Eval{Expression: luaSQL, Tuple: []interface{}{stmt}})
+		if res.Error != nil {
+			return res.Error
+		}
+		if len(res.Data) == 0 || len(res.Data[0]) == 0 {
+			return nil
+		}
+		return c.printSQL(res.Data[0][0])
+	}
+
+	res := c.conn.Exec(ctx, &tarantool.Eval{Expression: luaEval, Tuple: []interface{}{stmt}})
+	if res.Error != nil {
+		return res.Error
+	}
+	if len(res.Data) == 0 {
+		return nil
+	}
+	return c.printValues(res.Data[0])
+}
+
+func (c *console) command(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("empty command, see \\help")
+	}
+
+	switch args[0] {
+	case "q", "quit":
+		return errQuit
+	case "help":
+		fmt.Fprintln(c.out, help)
+	case "lua":
+		c.sql = false
+	case "sql":
+		c.sql = true
+	case "spaces":
+		for _, name := range c.conn.GetSpaceNames() {
+			fmt.Fprintln(c.out, name)
+		}
+	case "select":
+		if len(args) < 2 {
+			return errors.New(`usage: \select SPACE [KEY...]`)
+		}
+		return c.selectTuples(args[1], args[2:])
+	default:
+		return fmt.Errorf("unknown command \\%s, see \\help", args[0])
+	}
+	return nil
+}
+
+// selectTuples prints the tuples of the space with the key
+func (c *console) selectTuples(space string, args []string) error {
+	q := &tarantool.Select{Space: space, Index: 0, Iterator: tarantool.IterAll, Limit: 1000}
+	if len(args) > 0 {
+		key := make([]interface{}, len(args))
+		for i, a := range args {
+			key[i] = parseKey(a)
+		}
+		q.KeyTuple = key
+		q.Iterator = tarantool.IterEq
+	}
+
+	res := c.conn.Exec(context.Background(), q)
+	if res.Error != nil {
+		return res.Error
+	}
+	fields, _ := c.conn.GetSpaceFields(space)
+	return c.printTuples(fields, res.Data)
+}
+
+// parseKey turns the number-looking arguments into numbers
+func parseKey(s string) interface{} {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if n, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// statementScanner joins the lines ending with a backslash
+type statementScanner struct {
+	s *bufio.Scanner
+}
+
+func newStatementScanner(r io.Reader) *statementScanner {
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	return &statementScanner{s: s}
+}
+
+func (ss *statementScanner) next() (string, bool) {
+	var b strings.Builder
+	for ss.s.Scan() {
+		line := ss.s.Text()
+		if strings.HasSuffix(line, `\`) {
+			b.WriteString(strings.TrimSuffix(line, `\`))
+			b.WriteByte('\n')
+			continue
+		}
+		b.WriteString(line)
+		return b.String(), true
+	}
+	if b.Len() > 0 {
+		return b.String(), true
+	}
+	return "", false
+}
+
+func (ss *statementScanner) err() error {
+	return ss.s.Err()
+}
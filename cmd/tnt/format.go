@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+var identRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// format prints the value in the flow style of the Tarantool console
+func format(v interface{}) string {
+	var b strings.Builder
+	writeValue(&b, v)
+	return b.String()
+}
+
+func writeValue(b *strings.Builder, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteString("null")
+	case string:
+		b.WriteString(strconv.Quote(v))
+	case []byte:
+		b.WriteString(strconv.Quote(string(v)))
+	case float64:
+		b.WriteString(strconv.FormatFloat(v, 'g', -1, 64))
+	case float32:
+		b.WriteString(strconv.FormatFloat(float64(v), 'g', -1, 32))
+	case []interface{}:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeValue(b, e)
+		}
+		b.WriteByte(']')
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			writeKey(b, k)
+			writeValue(b, v[k])
+		}
+		b.WriteByte('}')
+	default:
+		fmt.Fprint(b, v)
+	}
+}
+
+func writeKey(b *strings.Builder, k string) {
+	if identRe.MatchString(k) {
+		b.WriteString(k)
+	} else {
+		b.WriteString(strconv.Quote(k))
+	}
+	b.WriteString(": ")
+}
+
+// formatTuple prints the tuple with the field names,
+// the fields beyond the format are numbered from 1
+func formatTuple(fields []string, tuple []interface{}) string {
+	if len(fields) == 0 {
+		return format(tuple)
+	}
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, v := range tuple {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if i < len(fields) && fields[i] != "" {
+			writeKey(&b, fields[i])
+		} else {
+			b.WriteString(strconv.Itoa(i + 1))
+			b.WriteString(": ")
+		}
+		writeValue(&b, v)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (c *console) printJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, string(data))
+	return nil
+}
+
+// printValues prints the values returned by Lua
+func (c *console) printValues(values []interface{}) error {
+	if c.json {
+		return c.printJSON(values)
+	}
+	for _, v := range values {
+		fmt.Fprintln(c.out, "-", format(v))
+	}
+	return nil
+}
+
+// printTuples prints the tuples of a space
+func (c *console) printTuples(fields []string, tuples [][]interface{}) error {
+	if c.json {
+		rows := make([]interface{}, len(tuples))
+		for i, t := range tuples {
+			if len(fields) == 0 {
+				rows[i] = t
+				continue
+			}
+			row := make(map[string]interface{}, len(t))
+			for j, v := range t {
+				if j < len(fields) && fields[j] != "" {
+					row[fields[j]] = v
+				} else {
+					row[strconv.Itoa(j+1)] = v
+				}
+			}
+			rows[i] = row
+		}
+		return c.printJSON(rows)
+	}
+	for _, t := range tuples {
+		fmt.Fprintln(c.out, "-", formatTuple(fields, t))
+	}
+	return nil
+}
+
+// printSQL prints the result of box.execute: the table of the rows
+// or the number of the changed rows
+func (c *console) printSQL(res interface{}) error {
+	if c.json {
+		return c.printJSON(res)
+	}
+
+	m, ok := res.(map[string]interface{})
+	if !ok {
+		fmt.Fprintln(c.out, "-", format(res))
+		return nil
+	}
+	if _, ok := m["metadata"]; !ok {
+		fmt.Fprintln(c.out, "-", format(res))
+		return nil
+	}
+
+	meta, _ := m["metadata"].([]interface{})
+	rows, _ := m["rows"].([]interface{})
+
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	header := make([]string, len(meta))
+	for i, col := range meta {
+		if descr, ok := col.(map[string]interface{}); ok {
+			header[i], _ = descr["name"].(string)
+		}
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t"))
+	for _, r := range rows {
+		row, _ := r.([]interface{})
+		cells := make([]string, len(row))
+		for i, v := range row {
+			cells[i] = format(v)
+		}
+		fmt.Fprintln(w, strings.Join(cells, "\t"))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "(%d rows)\n", len(rows))
+	return nil
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("null", format(nil))
+	assert.Equal(`"a\n"`, format("a\n"))
+	assert.Equal("1.5", format(1.5))
+	assert.Equal("42", format(int64(42)))
+	assert.Equal("true", format(true))
+	assert.Equal(`[1, "x", [null]]`, format([]interface{}{int64(1), "x", []interface{}{nil}}))
+	assert.Equal(`{a: 1, "b c": {}}`, format(map[string]interface{}{"b c": map[string]interface{}{}, "a": int64(1)}))
+}
+
+func TestFormatTuple(t *testing.T) {
+	assert := assert.New(t)
+
+	tuple := []interface{}{int64(1), "a@b", true}
+	assert.Equal(`{id: 1, email: "a@b", 3: true}`, formatTuple([]string{"id", "email"}, tuple))
+	assert.Equal(`[1, "a@b", true]`, formatTuple(nil, tuple))
+}
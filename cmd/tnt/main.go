@@ -0,0 +1,177 @@
+// Command tnt is an interactive console for Tarantool built on the client.
+//
+//	tnt [flags] [user[:password]@]host:port
+//
+// It evaluates Lua expressions and statements or SQL (\sql to switch),
+// prints the tuples of a space with the field names of its format
+// (\select space [key...]) and lists the spaces (\spaces).
+//
+// Statements are read line by line, a line ending with a backslash is
+// continued on the next one. Without a terminal, or with -e or -f, it runs in
+// script mode: the statements are executed in order and it exits with status 1
+// on the first error, e.g. to check a deployment in CI.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+)
+
+type statements []string
+
+func (s *statements) String() string {
+	return strings.Join(*s, "; ")
+}
+
+func (s *statements) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+type config struct {
+	user, password string
+	timeout        time.Duration
+	tls            bool
+	tlsCA          string
+	tlsCert        string
+	tlsKey         string
+	tlsSkipVerify  bool
+	sql            bool
+	json           bool
+	exprs          statements
+	file           string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var cfg config
+
+	fs := flag.NewFlagSet("tnt", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tnt [flags] [user[:password]@]host:port")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.user, "u", "", "user name")
+	fs.StringVar(&cfg.password, "p", os.Getenv("TNT_PASSWORD"), "password, $TNT_PASSWORD by default")
+	fs.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "connect and query timeout")
+	fs.BoolVar(&cfg.tls, "tls", false, "connect with TLS")
+	fs.StringVar(&cfg.tlsCA, "tls-ca", "", "PEM file of the CA certificates to verify the server with, implies -tls")
+	fs.StringVar(&cfg.tlsCert, "tls-cert", "", "PEM file of the client certificate, implies -tls")
+	fs.StringVar(&cfg.tlsKey, "tls-key", "", "PEM file of the client key")
+	fs.BoolVar(&cfg.tlsSkipVerify, "tls-skip-verify", false, "don't verify the server certificate, implies -tls")
+	fs.BoolVar(&cfg.sql, "sql", false, "start in SQL mode")
+	fs.BoolVar(&cfg.json, "json", false, "print the results as JSON")
+	fs.Var(&cfg.exprs, "e", "statement to execute, may be repeated (script mode)")
+	fs.StringVar(&cfg.file, "f", "", "file of the statements to execute, - for stdin (script mode)")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	opts, err := cfg.options()
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	conn, err := tarantool.ConnectContext(ctx, fs.Arg(0), opts)
+	cancel()
+	if err != nil {
+		fmt.Fprintln(stderr, "connect:", err)
+		return 1
+	}
+	defer conn.Close()
+
+	c := &console{conn: conn, out: stdout, sql: cfg.sql, json: cfg.json}
+
+	switch {
+	case len(cfg.exprs) > 0:
+		for _, e := range cfg.exprs {
+			if err = c.exec(e); err != nil {
+				break
+			}
+		}
+	case cfg.file == "-":
+		err = c.script(stdin)
+	case cfg.file != "":
+		var f *os.File
+		if f, err = os.Open(cfg.file); err == nil {
+			err = c.script(f)
+			f.Close()
+		}
+	case isTerminal(stdin):
+		c.interactive(stdin, stderr, fs.Arg(0))
+	default:
+		err = c.script(stdin)
+	}
+
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	return 0
+}
+
+func (cfg *config) options() (*tarantool.Options, error) {
+	opts := &tarantool.Options{
+		User:           cfg.user,
+		Password:       cfg.password,
+		ConnectTimeout: cfg.timeout,
+		QueryTimeout:   cfg.timeout,
+	}
+	if !cfg.tls && cfg.tlsCA == "" && cfg.tlsCert == "" && !cfg.tlsSkipVerify {
+		return opts, nil
+	}
+
+	tc := &tls.Config{InsecureSkipVerify: cfg.tlsSkipVerify}
+	if cfg.tlsCA != "" {
+		pem, err := os.ReadFile(cfg.tlsCA)
+		if err != nil {
+			return nil, err
+		}
+		tc.RootCAs = x509.NewCertPool()
+		if !tc.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in %s", cfg.tlsCA)
+		}
+	}
+	if cfg.tlsCert != "" {
+		if cfg.tlsKey == "" {
+			return nil, errors.New("-tls-cert needs -tls-key")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.tlsCert, cfg.tlsKey)
+		if err != nil {
+			return nil, err
+		}
+		tc.Certificates = []tls.Certificate{cert}
+	}
+	opts.TLSConfig = tc
+	return opts, nil
+}
+
+func isTerminal(r io.Reader) bool {
+	f, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
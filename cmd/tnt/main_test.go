@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+// newFakeServer answers the schema requests, the selects of the users space
+// and the statements of the console
+func newFakeServer(t *testing.T) string {
+	handler := func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+		switch q := q.(type) {
+		case *tarantool.Select:
+			switch q.Space {
+			case tarantool.ViewSpace:
+				return &tarantool.Result{Data: [][]interface{}{
+					{uint64(512), uint64(1), "users", "memtx", uint64(0), map[string]interface{}{}, []interface{}{
+						map[string]interface{}{"name": "id", "type": "unsigned"},
+						map[string]interface{}{"name": "email", "type": "string"},
+					}},
+				}}
+			case tarantool.ViewIndex:
+				return &tarantool.Result{Data: [][]interface{}{
+					{uint64(512), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, []interface{}{[]interface{}{uint64(0), "unsigned"}}},
+				}}
+			}
+			users := [][]interface{}{{int64(1), "a@example.com"}, {int64(2), "b@example.com"}}
+			if q.Key != nil {
+				id, _ := q.Key.(int64)
+				return &tarantool.Result{Data: users[id-1 : id]}
+			}
+			return &tarantool.Result{Data: users}
+		case *tarantool.Eval:
+			stmt := q.Tuple[0].(string)
+			switch {
+			case q.Expression == luaSQL && strings.HasPrefix(stmt, "SELECT"):
+				return &tarantool.Result{Data: [][]interface{}{{map[string]interface{}{
+					"metadata": []interface{}{
+						map[string]interface{}{"name": "ID", "type": "unsigned"},
+						map[string]interface{}{"name": "EMAIL", "type": "string"},
+					},
+					"rows": []interface{}{[]interface{}{int64(1), "a@example.com"}},
+				}}}}
+			case q.Expression == luaSQL:
+				return &tarantool.Result{Data: [][]interface{}{{map[string]interface{}{"row_count": int64(1)}}}}
+			case stmt == "1 + 1":
+				return &tarantool.Result{Data: [][]interface{}{{int64(2)}}}
+			case stmt == "box.info.status, \nnil":
+				return &tarantool.Result{Data: [][]interface{}{{"running", nil}}}
+			case stmt == "x = 1":
+				return &tarantool.Result{Data: [][]interface{}{{}}}
+			}
+			return &tarantool.Result{ErrorCode: tarantool.ErrProcLua, Error: tarantool.NewQueryError(tarantool.ErrProcLua, "unexpected symbol")}
+		}
+		return &tarantool.Result{}
+	}
+
+	return tnttest.NewServer(t, handler)
+}
+
+func runTnt(args []string, stdin string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestScript(t *testing.T) {
+	assert := assert.New(t)
+
+	addr := newFakeServer(t)
+
+	code, out, errOut := runTnt([]string{addr}, "1 + 1\nbox.info.status, \\\nnil\nx = 1\n\\select users\n\\select users 2\n\\spaces\n")
+	assert.Equal(0, code, errOut)
+	assert.Equal(`- 2
+- "running"
+- null
+- {id: 1, email: "a@example.com"}
+- {id: 2, email: "b@example.com"}
+- {id: 2, email: "b@example.com"}
+users
+`, out)
+
+	code, out, errOut = runTnt([]string{"-sql", "-e", "SELECT * FROM users", "-e", "DELETE FROM users WHERE id = 2"}, "")
+	assert.Equal(2, code)
+
+	code, out, errOut = runTnt([]string{"-sql", "-e", "SELECT * FROM users", "-e", "DELETE FROM users WHERE id = 2", addr}, "")
+	assert.Equal(0, code, errOut)
+	assert.Equal("ID  EMAIL\n1   \"a@example.com\"\n(1 rows)\n- {row_count: 1}\n", out)
+
+	code, out, _ = runTnt([]string{"-json", "-e", "\\select users 1", addr}, "")
+	assert.Equal(0, code)
+	assert.Equal(`[{"email":"a@example.com","id":1}]`+"\n", out)
+}
+
+func TestScriptError(t *testing.T) {
+	assert := assert.New(t)
+
+	addr := newFakeServer(t)
+
+	code, out, errOut := runTnt([]string{addr}, "1 + 1\nbad(\n1 + 1\n")
+	assert.Equal(1, code)
+	assert.Equal("- 2\n", out)
+	assert.Contains(errOut, "unexpected symbol")
+
+	code, _, errOut = runTnt([]string{"-e", "\\unknown", addr}, "")
+	assert.Equal(1, code)
+	assert.Contains(errOut, "unknown command")
+
+	code, _, errOut = runTnt([]string{"-tls-cert", "cert.pem", addr}, "")
+	assert.Equal(2, code)
+	assert.Contains(errOut, "-tls-key")
+}
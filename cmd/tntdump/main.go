@@ -0,0 +1,209 @@
+// Command tntdump exports spaces to JSON Lines or CSV files.
+//
+//	tntdump [flags] [user[:password]@]host:port [space...]
+//
+// The spaces, all the non-system ones by default, are read page by page in the
+// order of their primary keys, several spaces at once, and written to
+// SPACE.jsonl or SPACE.csv in the output directory. -from and -to limit
+// the primary key range, a key is a JSON value or array, e.g. -from '[1, "a"]'.
+//
+// The JSON lines are the objects of the named fields if the space has a format,
+// the unnamed fields are numbered from 1, or the arrays of the fields otherwise.
+// The CSV files start with the header of the field names if the space has
+// a format, the arrays and the maps are written as JSON.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/bulk"
+)
+
+type config struct {
+	user, password string
+	timeout        time.Duration
+	format         string
+	bulkFormat     bulk.Format
+	out            string
+	batch          uint
+	parallel       int
+	from, to       string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var cfg config
+
+	fs := flag.NewFlagSet("tntdump", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tntdump [flags] [user[:password]@]host:port [space...]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.user, "u", "", "user name")
+	fs.StringVar(&cfg.password, "p", os.Getenv("TNT_PASSWORD"), "password, $TNT_PASSWORD by default")
+	fs.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "connect and page timeout")
+	fs.StringVar(&cfg.format, "format", "jsonl", "output format, jsonl or csv")
+	fs.StringVar(&cfg.out, "o", ".", "output directory, - for stdout if a single space is dumped")
+	fs.UintVar(&cfg.batch, "batch", 1000, "tuples per page")
+	fs.IntVar(&cfg.parallel, "parallel", 4, "spaces dumped at once")
+	fs.StringVar(&cfg.from, "from", "", "first primary key to dump")
+	fs.StringVar(&cfg.to, "to", "", "last primary key to dump")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return 2
+	}
+	if err := cfg.validate(fs.NArg() - 1); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	conn, err := tarantool.ConnectContext(ctx, fs.Arg(0), &tarantool.Options{
+		User:           cfg.user,
+		Password:       cfg.password,
+		ConnectTimeout: cfg.timeout,
+		QueryTimeout:   cfg.timeout,
+	})
+	cancel()
+	if err != nil {
+		fmt.Fprintln(stderr, "connect:", err)
+		return 1
+	}
+	defer conn.Close()
+
+	spaces := fs.Args()[1:]
+	if len(spaces) == 0 {
+		spaces = userSpaces(conn)
+	}
+
+	d := &dumper{
+		bulk:   bulk.New(conn),
+		format: cfg.bulkFormat,
+		opts:   &bulk.ExportOptions{PageSize: uint32(cfg.batch)},
+	}
+	if cfg.from != "" {
+		d.opts.From = bulk.ParseKey(cfg.from)
+	}
+	if cfg.to != "" {
+		d.opts.To = bulk.ParseKey(cfg.to)
+	}
+
+	if cfg.out == "-" {
+		if _, err = d.dump(spaces[0], stdout); err != nil {
+			fmt.Fprintf(stderr, "%s: %s\n", spaces[0], err)
+			return 1
+		}
+		return 0
+	}
+
+	if !dumpFiles(d, spaces, &cfg, stderr) {
+		return 1
+	}
+	return 0
+}
+
+func (cfg *config) validate(spaces int) (err error) {
+	if cfg.bulkFormat, err = bulk.ParseFormat(cfg.format); err != nil {
+		return err
+	}
+	if cfg.batch == 0 || cfg.batch > 1<<31 {
+		return errors.New("-batch is out of range")
+	}
+	if cfg.parallel < 1 {
+		return errors.New("-parallel must be positive")
+	}
+	if cfg.out == "-" && spaces != 1 {
+		return errors.New("-o - needs a single space")
+	}
+	return nil
+}
+
+// userSpaces returns the spaces except the system ones starting with _
+func userSpaces(conn *tarantool.Connection) []string {
+	var spaces []string
+	for _, name := range conn.GetSpaceNames() {
+		if !strings.HasPrefix(name, "_") {
+			spaces = append(spaces, name)
+		}
+	}
+	return spaces
+}
+
+type dumper struct {
+	bulk   *bulk.Bulk
+	format bulk.Format
+	opts   *bulk.ExportOptions
+}
+
+func (d *dumper) dump(space string, w io.Writer) (int, error) {
+	return d.bulk.Export(context.Background(), space, w, d.format, d.opts)
+}
+
+// dumpFiles dumps the spaces to the files at most cfg.parallel at once,
+// it reports the number of the tuples or the error of every space
+func dumpFiles(d *dumper, spaces []string, cfg *config, stderr io.Writer) bool {
+	var (
+		wg  sync.WaitGroup
+		mu  sync.Mutex
+		ok  = true
+		sem = make(chan struct{}, cfg.parallel)
+	)
+
+	for _, space := range spaces {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(space string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			n, err := dumpFile(d, space, filepath.Join(cfg.out, space+"."+d.format.String()))
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				ok = false
+				fmt.Fprintf(stderr, "%s: %s\n", space, err)
+				return
+			}
+			fmt.Fprintf(stderr, "%s: %d tuples\n", space, n)
+		}(space)
+	}
+	wg.Wait()
+	return ok
+}
+
+// dumpFile writes the file under a temporary name and renames it when it's complete
+func dumpFile(d *dumper, space, path string) (int, error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+
+	n, err := d.dump(space, f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, os.Rename(f.Name(), path)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// fakeServer has the users space with a format and the events space without one,
+// both with the integer primary key in the first field
+type fakeServer struct {
+	tuples map[uint][][]interface{}
+}
+
+func newFakeServer(t *testing.T, users, events int) (*fakeServer, string) {
+	s := &fakeServer{tuples: map[uint][][]interface{}{}}
+	for i := 1; i <= users; i++ {
+		s.tuples[512] = append(s.tuples[512], []interface{}{int64(i), "user" + string(rune('a'+i-1)), []interface{}{"x"}})
+	}
+	for i := 1; i <= events; i++ {
+		s.tuples[513] = append(s.tuples[513], []interface{}{int64(i * 10), nil})
+	}
+
+	addr := tnttest.NewServer(t, s.handle)
+	return s, addr
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	sel, ok := q.(*tarantool.Select)
+	if !ok {
+		return &tarantool.Result{}
+	}
+
+	switch sel.Space {
+	case tarantool.ViewSpace:
+		return &tarantool.Result{Data: [][]interface{}{
+			{uint64(280), uint64(1), "_space", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+			{uint64(512), uint64(1), "users", "memtx", uint64(0), map[string]interface{}{}, []interface{}{
+				map[string]interface{}{"name": "id", "type": "unsigned"},
+				map[string]interface{}{"name": "name", "type": "string"},
+			}},
+			{uint64(513), uint64(1), "events", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+		}}
+	case tarantool.ViewIndex:
+		return &tarantool.Result{Data: [][]interface{}{
+			{uint64(280), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, []interface{}{[]interface{}{uint64(0), "unsigned"}}},
+			{uint64(512), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, []interface{}{[]interface{}{uint64(0), "unsigned"}}},
+			{uint64(513), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, []interface{}{[]interface{}{uint64(0), "unsigned"}}},
+		}}
+	}
+
+	space, _ := typeconv.IntfToUint(sel.Space)
+	var key int64
+	if sel.Key != nil {
+		key, _ = typeconv.IntfToInt64(sel.Key)
+	} else if len(sel.KeyTuple) > 0 {
+		key, _ = typeconv.IntfToInt64(sel.KeyTuple[0])
+	}
+
+	var data [][]interface{}
+	for _, t := range s.tuples[space] {
+		id := t[0].(int64)
+		if sel.Iterator == tarantool.IterGe && id < key || sel.Iterator == tarantool.IterGt && id <= key {
+			continue
+		}
+		if len(data) == int(sel.Limit) {
+			break
+		}
+		data = append(data, t)
+	}
+	return &tarantool.Result{Data: data}
+}
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	_, addr := newFakeServer(t, 2, 1)
+	dir := t.TempDir()
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-o", dir, "-format", "csv", addr}, &stdout, &stderr)
+	assert.Equal(0, code, stderr.String())
+	assert.Contains(stderr.String(), "users: 2 tuples\n")
+	assert.Contains(stderr.String(), "events: 1 tuples\n")
+
+	users, err := os.ReadFile(filepath.Join(dir, "users.csv"))
+	require.NoError(err)
+	assert.Equal("id,name\n1,usera,\"[\"\"x\"\"]\"\n2,userb,\"[\"\"x\"\"]\"\n", string(users))
+
+	events, err := os.ReadFile(filepath.Join(dir, "events.csv"))
+	require.NoError(err)
+	assert.Equal("10,\n", string(events))
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"))
+	require.NoError(err)
+	assert.Len(files, 2)
+
+	stdout.Reset()
+	code = run([]string{"-o", "-", "-to", "1", addr, "users"}, &stdout, &stderr)
+	assert.Equal(0, code)
+	assert.Equal(`{"3":["x"],"id":1,"name":"usera"}`+"\n", stdout.String())
+
+	stderr.Reset()
+	code = run([]string{"-o", dir, addr, "users", "missing"}, &stdout, &stderr)
+	assert.Equal(1, code)
+	assert.Contains(stderr.String(), "missing: ")
+
+	assert.Equal(2, run([]string{"-o", "-", addr}, &stdout, &stderr))
+	assert.Equal(2, run([]string{"-format", "xml", addr}, &stdout, &stderr))
+}
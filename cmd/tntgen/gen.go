@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	"github.com/viciious/go-tarantool/admin"
+)
+
+// goTypes are the Go types of the field types, the others are interface{}
+var goTypes = map[string]string{
+	"unsigned":  "uint64",
+	"integer":   "int64",
+	"number":    "float64",
+	"double":    "float64",
+	"string":    "string",
+	"boolean":   "bool",
+	"varbinary": "[]byte",
+	"array":     "[]interface{}",
+	"map":       "map[string]interface{}",
+}
+
+// initialisms are written in upper case in the Go names
+var initialisms = map[string]bool{
+	"api": true, "id": true, "ip": true, "json": true, "ttl": true,
+	"uid": true, "uri": true, "url": true, "uuid": true,
+}
+
+// reserved are the identifiers of the generated functions the parameters can't take
+var reserved = map[string]bool{
+	"conn": true, "ctx": true, "iterator": true, "limit": true, "offset": true,
+	"ops": true, "t": true, "tarantool": true, "context": true,
+}
+
+type field struct {
+	Name   string
+	GoName string
+	Type   string
+}
+
+type param struct {
+	Name string
+	Type string
+}
+
+type index struct {
+	Name   string
+	GoName string
+	// Func is the name in the functions, GoName without By, e.g. SelectByUser for by_user
+	Func   string
+	Unique bool
+	// Keyed is false for BITSET and RTREE, which are not selected by key
+	Keyed  bool
+	Params []param
+}
+
+// Args returns the declaration of the key parameters.
+func (i *index) Args() string {
+	args := make([]string, len(i.Params))
+	for n, p := range i.Params {
+		args[n] = p.Name + " " + p.Type
+	}
+	return strings.Join(args, ", ")
+}
+
+// Key returns the key tuple of the parameters.
+func (i *index) Key() string {
+	names := make([]string, len(i.Params))
+	for n, p := range i.Params {
+		names[n] = p.Name
+	}
+	return "[]interface{}{" + strings.Join(names, ", ") + "}"
+}
+
+type space struct {
+	Name    string
+	Package string
+	Fields  []field
+	Indexes []*index
+	// Primary is the primary index, nil if the space has no indexes
+	Primary *index
+}
+
+// packageName returns the package name of the space: its name in lower case
+// without the other characters than letters and digits
+func packageName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+		}
+	}
+	pkg := b.String()
+	if pkg == "" || unicode.IsDigit(rune(pkg[0])) || token.IsKeyword(pkg) {
+		pkg = "space" + pkg
+	}
+	return pkg
+}
+
+// words splits the name into the words of letters and digits
+func words(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return r >= unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r))
+	})
+}
+
+// exported returns the exported Go name, e.g. UserID for user_id
+func exported(name string) string {
+	var b strings.Builder
+	for _, w := range words(name) {
+		if initialisms[strings.ToLower(w)] {
+			b.WriteString(strings.ToUpper(w))
+		} else {
+			b.WriteString(strings.ToUpper(w[:1]) + w[1:])
+		}
+	}
+	s := b.String()
+	if s == "" || unicode.IsDigit(rune(s[0])) {
+		s = "F" + s
+	}
+	return s
+}
+
+// unexported returns the parameter name, e.g. userID for user_id
+func unexported(name string) string {
+	s := exported(name)
+	n := 1
+	for n < len(s) && unicode.IsUpper(rune(s[n])) && (n+1 == len(s) || unicode.IsUpper(rune(s[n+1]))) {
+		n++
+	}
+	s = strings.ToLower(s[:n]) + s[n:]
+	if token.IsKeyword(s) || reserved[s] {
+		s += "Key"
+	}
+	return s
+}
+
+// unique makes the names distinct by appending the numbers to the repeated ones
+func unique(names []string) []string {
+	seen := make(map[string]int, len(names))
+	for _, name := range names {
+		seen[name]++
+	}
+	next := make(map[string]int, len(names))
+	for i, name := range names {
+		if seen[name] > 1 {
+			next[name]++
+			names[i] = name + strconv.Itoa(next[name])
+		}
+	}
+	return names
+}
+
+func newSpace(def *admin.SpaceDef) (*space, error) {
+	if len(def.Format) == 0 {
+		return nil, fmt.Errorf("space %s has no format", def.Name)
+	}
+
+	s := &space{Name: def.Name, Package: packageName(def.Name)}
+	types := make(map[string]string, len(def.Format))
+	names := make([]string, len(def.Format))
+	for i, f := range def.Format {
+		names[i] = exported(f.Name)
+	}
+	unique(names)
+	for i, f := range def.Format {
+		typ, ok := goTypes[f.Type]
+		if !ok {
+			typ = "interface{}"
+		}
+		types[f.Name] = typ
+		s.Fields = append(s.Fields, field{Name: f.Name, GoName: names[i], Type: typ})
+	}
+
+	indexNames := make([]string, len(def.Indexes))
+	for i, d := range def.Indexes {
+		indexNames[i] = exported(d.Name)
+	}
+	unique(indexNames)
+	for i, d := range def.Indexes {
+		idx := &index{Name: d.Name, GoName: indexNames[i], Func: indexNames[i], Unique: !d.NonUnique}
+		if f := strings.TrimPrefix(idx.GoName, "By"); f != "" && unicode.IsUpper(rune(f[0])) {
+			idx.Func = f
+		}
+		switch strings.ToUpper(d.Type) {
+		case "", "TREE", "HASH":
+			idx.Keyed = len(d.Parts) > 0
+		}
+
+		params := make([]string, len(d.Parts))
+		for n, p := range d.Parts {
+			params[n] = unexported(p.Field)
+		}
+		unique(params)
+		for n, p := range d.Parts {
+			typ, ok := types[p.Field]
+			if !ok {
+				typ = "interface{}"
+			}
+			idx.Params = append(idx.Params, param{Name: params[n], Type: typ})
+		}
+		s.Indexes = append(s.Indexes, idx)
+	}
+	// by_user and user would both be User in the function names
+	funcs := make([]string, len(s.Indexes))
+	for i, idx := range s.Indexes {
+		funcs[i] = idx.Func
+	}
+	unique(funcs)
+	for i, idx := range s.Indexes {
+		idx.Func = funcs[i]
+	}
+
+	if len(s.Indexes) > 0 {
+		s.Primary = s.Indexes[0]
+		s.Primary.Unique = true
+	}
+	return s, nil
+}
+
+// generate returns the formatted source of the package of the space
+func generate(def *admin.SpaceDef) (string, []byte, error) {
+	s, err := newSpace(def)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var b bytes.Buffer
+	if err := spaceTemplate.Execute(&b, s); err != nil {
+		return "", nil, err
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return "", nil, fmt.Errorf("space %s: %w", def.Name, err)
+	}
+	return s.Package, src, nil
+}
+
+var spaceTemplate = template.Must(template.New("space").Parse(`// Code generated by tntgen from the schema of space "{{.Name}}". DO NOT EDIT.
+
+// Package {{.Package}} accesses the tuples of space "{{.Name}}".
+package {{.Package}}
+
+import (
+	"context"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// Space is the name of the space.
+const Space = {{printf "%q" .Name}}
+{{if .Indexes}}
+// The names of the indexes.
+const (
+{{- range .Indexes}}
+	Index{{.GoName}} = {{printf "%q" .Name}}
+{{- end}}
+)
+{{end}}
+// The numbers of the fields for the update operations.
+const (
+{{- range $i, $f := .Fields}}
+	Field{{$f.GoName}} = {{$i}}
+{{- end}}
+)
+
+// Tuple is a tuple of the space. The nulls are decoded as the zero values.
+type Tuple struct {
+{{- range .Fields}}
+	{{.GoName}} {{.Type}} ` + "`" + `tarantool:"{{.Name}}"` + "`" + `
+{{- end}}
+}
+
+var decoder tarantool.TupleDecoder
+
+// AsTuple returns the fields of t in the order of the format.
+func (t *Tuple) AsTuple() []interface{} {
+	return []interface{}{
+{{- range .Fields}}
+		t.{{.GoName}},
+{{- end}}
+	}
+}
+
+// Insert inserts t and returns the inserted tuple.
+func Insert(ctx context.Context, conn tarantool.Executor, t *Tuple) (*Tuple, error) {
+	return first(exec(ctx, conn, &tarantool.Insert{Space: Space, Tuple: t.AsTuple()}))
+}
+
+// Replace inserts or replaces t and returns the stored tuple.
+func Replace(ctx context.Context, conn tarantool.Executor, t *Tuple) (*Tuple, error) {
+	return first(exec(ctx, conn, &tarantool.Replace{Space: Space, Tuple: t.AsTuple()}))
+}
+{{- with .Primary}}
+
+// SelectAll returns up to limit tuples in the order of the primary index, skipping offset ones.
+func SelectAll(ctx context.Context, conn tarantool.Executor, offset, limit uint32) ([]*Tuple, error) {
+	return exec(ctx, conn, &tarantool.Select{Space: Space, Index: Index{{.GoName}}, Iterator: tarantool.IterAll, Offset: offset, Limit: limit})
+}
+{{- end}}
+{{- range .Indexes}}
+{{- if .Keyed}}
+{{- if .Unique}}
+
+// GetBy{{.Func}} returns the tuple with the key of index {{.Name}}, nil if there is none.
+func GetBy{{.Func}}(ctx context.Context, conn tarantool.Executor, {{.Args}}) (*Tuple, error) {
+	return first(exec(ctx, conn, &tarantool.Select{Space: Space, Index: Index{{.GoName}}, Iterator: tarantool.IterEq, Limit: 1, KeyTuple: {{.Key}}}))
+}
+{{- end}}
+
+// SelectBy{{.Func}} returns up to limit tuples of index {{.Name}} matching the key
+// by the iterator, skipping offset ones.
+func SelectBy{{.Func}}(ctx context.Context, conn tarantool.Executor, iterator uint8, offset, limit uint32, {{.Args}}) ([]*Tuple, error) {
+	return exec(ctx, conn, &tarantool.Select{Space: Space, Index: Index{{.GoName}}, Iterator: iterator, Offset: offset, Limit: limit, KeyTuple: {{.Key}}})
+}
+{{- if .Unique}}
+
+// UpdateBy{{.Func}} updates the tuple with the key of index {{.Name}} and returns
+// the updated tuple, nil if there is none.
+func UpdateBy{{.Func}}(ctx context.Context, conn tarantool.Executor, {{.Args}}, ops ...tarantool.Operator) (*Tuple, error) {
+	return first(exec(ctx, conn, &tarantool.Update{Space: Space, Index: Index{{.GoName}}, KeyTuple: {{.Key}}, Set: ops}))
+}
+
+// DeleteBy{{.Func}} deletes the tuple with the key of index {{.Name}} and returns it,
+// nil if there is none.
+func DeleteBy{{.Func}}(ctx context.Context, conn tarantool.Executor, {{.Args}}) (*Tuple, error) {
+	return first(exec(ctx, conn, &tarantool.Delete{Space: Space, Index: Index{{.GoName}}, KeyTuple: {{.Key}}}))
+}
+{{- end}}
+{{- end}}
+{{- end}}
+
+func exec(ctx context.Context, conn tarantool.Executor, q tarantool.Query) ([]*Tuple, error) {
+	res := conn.Exec(ctx, q)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	tuples := make([]*Tuple, len(res.Data))
+	for i, data := range res.Data {
+		tuples[i] = &Tuple{}
+		if err := decoder.DecodeStruct(data, tuples[i]); err != nil {
+			return nil, err
+		}
+	}
+	return tuples, nil
+}
+
+func first(tuples []*Tuple, err error) (*Tuple, error) {
+	if err != nil || len(tuples) == 0 {
+		return nil, err
+	}
+	return tuples[0], nil
+}
+`))
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool/admin"
+)
+
+func TestNames(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("UserID", exported("user_id"))
+	assert.Equal("CreatedAt", exported("createdAt"))
+	assert.Equal("F1st", exported("1st"))
+	assert.Equal("userID", unexported("user_id"))
+	assert.Equal("id", unexported("ID"))
+	assert.Equal("urlPath", unexported("url_path"))
+	assert.Equal("typeKey", unexported("type"))
+	assert.Equal("limitKey", unexported("limit"))
+	assert.Equal("orderitems", packageName("Order-Items"))
+	assert.Equal("spacefunc", packageName("func"))
+	assert.Equal("space2fa", packageName("2fa"))
+	assert.Equal([]string{"ID1", "Name", "ID2"}, unique([]string{"ID", "Name", "ID"}))
+}
+
+var users = &admin.SpaceDef{
+	Name: "users",
+	Format: []admin.Field{
+		{Name: "id", Type: "unsigned"},
+		{Name: "email", Type: "string"},
+		{Name: "user_id", Type: "integer", IsNullable: true},
+		{Name: "type", Type: "any"},
+		{Name: "tags", Type: "array"},
+		{Name: "avatar", Type: "varbinary"},
+	},
+	Indexes: []admin.IndexDef{
+		{Name: "primary", Parts: []admin.Part{{Field: "id"}}},
+		{Name: "email", Type: "HASH", Parts: []admin.Part{{Field: "email"}, {Field: "type"}}},
+		{Name: "by_user", NonUnique: true, Parts: []admin.Part{{Field: "user_id"}}},
+		{Name: "bits", Type: "BITSET", NonUnique: true, Parts: []admin.Part{{Field: "id"}}},
+	},
+}
+
+func TestGenerate(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	pkg, src, err := generate(users)
+	require.NoError(err)
+	assert.Equal("users", pkg)
+
+	// the generated package compiles against the library
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "users.go", src, 0)
+	require.NoError(err, string(src))
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	checked, err := conf.Check("users", fset, []*ast.File{file}, nil)
+	require.NoError(err, string(src))
+
+	scope := checked.Scope()
+	for _, name := range []string{
+		"Space", "IndexPrimary", "IndexByUser", "FieldUserID", "Tuple",
+		"Insert", "Replace", "SelectAll",
+		"GetByPrimary", "SelectByPrimary", "UpdateByPrimary", "DeleteByPrimary",
+		"GetByEmail", "SelectByEmail", "UpdateByEmail", "DeleteByEmail",
+		"SelectByUser", "IndexBits",
+	} {
+		assert.NotNil(scope.Lookup(name), name)
+	}
+	// the non-unique indexes are not updated by key and the bitset ones are not selected
+	for _, name := range []string{"GetByUser", "UpdateByUser", "DeleteByUser", "SelectByBits"} {
+		assert.Nil(scope.Lookup(name), name)
+	}
+
+	assert.Equal("func(ctx context.Context, conn github.com/viciious/go-tarantool.Executor, email string, typeKey interface{}, ops ...github.com/viciious/go-tarantool.Operator) (*users.Tuple, error)",
+		scope.Lookup("UpdateByEmail").Type().String())
+	tuple := scope.Lookup("Tuple").Type().Underlying().(*types.Struct)
+	require.Equal(6, tuple.NumFields())
+	assert.Equal("UserID", tuple.Field(2).Name())
+	assert.Equal("int64", tuple.Field(2).Type().String())
+	assert.Equal(`tarantool:"user_id"`, tuple.Tag(2))
+
+	_, _, err = generate(&admin.SpaceDef{Name: "raw"})
+	assert.Error(err)
+}
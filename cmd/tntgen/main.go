@@ -0,0 +1,199 @@
+// Command tntgen generates typed Go packages for the spaces from the live
+// schema or from its dump, so the Go code follows the schema changes:
+//
+//	tntgen [flags] [user[:password]@]host:port [space...]
+//	tntgen [flags] -schema schema.json [space...]
+//
+// The spaces, all the non-system ones by default, get a package each in the
+// output directory, named after the space, e.g. ./users for space users. The
+// package has the Tuple struct matching the space format, the constants of
+// the field numbers and the index names, and the typed functions inserting,
+// selecting, updating and deleting the tuples by the index keys. The spaces
+// without a format are skipped.
+//
+// -dump writes the schema of the spaces to a JSON file instead, so the code can
+// be generated without the server, e.g. by go:generate:
+//
+//	//go:generate go run github.com/viciious/go-tarantool/cmd/tntgen -schema schema.json -o spaces
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/admin"
+)
+
+type config struct {
+	user, password string
+	timeout        time.Duration
+	schema         string
+	dump           string
+	out            string
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	var cfg config
+
+	fs := flag.NewFlagSet("tntgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tntgen [flags] [user[:password]@]host:port [space...]")
+		fmt.Fprintln(stderr, "       tntgen [flags] -schema schema.json [space...]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.user, "u", "", "user name")
+	fs.StringVar(&cfg.password, "p", os.Getenv("TNT_PASSWORD"), "password, $TNT_PASSWORD by default")
+	fs.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "connect and query timeout")
+	fs.StringVar(&cfg.schema, "schema", "", "schema dump to read instead of connecting to the server")
+	fs.StringVar(&cfg.dump, "dump", "", "file to dump the schema to instead of generating the code, - for stdout")
+	fs.StringVar(&cfg.out, "o", ".", "output directory")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	spaces := fs.Args()
+	if cfg.schema == "" {
+		if len(spaces) < 1 {
+			fs.Usage()
+			return 2
+		}
+		spaces = spaces[1:]
+	}
+
+	var defs []*admin.SpaceDef
+	var err error
+	if cfg.schema != "" {
+		defs, err = readSchema(cfg.schema, spaces)
+	} else {
+		defs, err = describe(fs.Arg(0), spaces, &cfg)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	if cfg.dump != "" {
+		if err = dumpSchema(cfg.dump, defs, stdout); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	ok := true
+	for _, def := range defs {
+		pkg, err := writePackage(cfg.out, def)
+		if err != nil {
+			ok = false
+			fmt.Fprintf(stderr, "%s: %s\n", def.Name, err)
+			continue
+		}
+		fmt.Fprintf(stderr, "%s: %s\n", def.Name, pkg)
+	}
+	if !ok {
+		return 1
+	}
+	return 0
+}
+
+// describe reads the definitions of the spaces from the server
+func describe(addr string, spaces []string, cfg *config) ([]*admin.SpaceDef, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	defer cancel()
+
+	conn, err := tarantool.ConnectContext(ctx, addr, &tarantool.Options{
+		User:           cfg.user,
+		Password:       cfg.password,
+		ConnectTimeout: cfg.timeout,
+		QueryTimeout:   cfg.timeout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("connect: %w", err)
+	}
+	defer conn.Close()
+
+	defs, err := admin.Describe(ctx, conn, spaces...)
+	if err != nil {
+		return nil, err
+	}
+	return defs, missing(defs, spaces)
+}
+
+// readSchema reads the definitions of the spaces from the dump
+func readSchema(path string, spaces []string) ([]*admin.SpaceDef, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var all []*admin.SpaceDef
+	if err = json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if len(spaces) == 0 {
+		return all, nil
+	}
+
+	byName := make(map[string]*admin.SpaceDef, len(all))
+	for _, def := range all {
+		byName[def.Name] = def
+	}
+	var defs []*admin.SpaceDef
+	for _, name := range spaces {
+		if def := byName[name]; def != nil {
+			defs = append(defs, def)
+		}
+	}
+	return defs, missing(defs, spaces)
+}
+
+// missing fails if some of the spaces have not been found
+func missing(defs []*admin.SpaceDef, spaces []string) error {
+	found := make(map[string]bool, len(defs))
+	for _, def := range defs {
+		found[def.Name] = true
+	}
+	for _, name := range spaces {
+		if !found[name] {
+			return fmt.Errorf("space %s does not exist", name)
+		}
+	}
+	return nil
+}
+
+func dumpSchema(path string, defs []*admin.SpaceDef, stdout io.Writer) error {
+	data, err := json.MarshalIndent(defs, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// writePackage generates the package of the space and returns its directory
+func writePackage(out string, def *admin.SpaceDef) (string, error) {
+	pkg, src, err := generate(def)
+	if err != nil {
+		return "", err
+	}
+	dir := filepath.Join(out, pkg)
+	if err = os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, os.WriteFile(filepath.Join(dir, pkg+".go"), src, 0o644)
+}
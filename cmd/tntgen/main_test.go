@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+// newFakeServer returns the address of a server describing the users space
+// with a format and the events space without one
+func newFakeServer(t *testing.T) string {
+	handler := func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+		eval, ok := q.(*tarantool.Eval)
+		if !ok {
+			return &tarantool.Result{}
+		}
+		spaces := []interface{}{
+			map[string]interface{}{
+				"name":   "events",
+				"engine": "memtx",
+				"format": map[string]interface{}{},
+				"indexes": []interface{}{
+					map[string]interface{}{"id": int64(0), "name": "primary", "type": "TREE", "unique": true, "parts": []interface{}{
+						map[string]interface{}{"field": "1", "type": "unsigned", "is_nullable": false},
+					}},
+				},
+			},
+			map[string]interface{}{
+				"name":   "users",
+				"engine": "memtx",
+				"format": []interface{}{
+					map[string]interface{}{"name": "id", "type": "unsigned", "is_nullable": false},
+					map[string]interface{}{"name": "name", "type": "string", "is_nullable": true},
+				},
+				"indexes": []interface{}{
+					map[string]interface{}{"id": int64(0), "name": "primary", "type": "TREE", "unique": true, "parts": []interface{}{
+						map[string]interface{}{"field": "id", "type": "unsigned", "is_nullable": false},
+					}},
+				},
+			},
+		}
+		names := eval.Tuple[0].([]interface{})
+		if len(names) == 0 {
+			return &tarantool.Result{Data: [][]interface{}{spaces}}
+		}
+		var found []interface{}
+		for _, space := range spaces {
+			for _, name := range names {
+				if space.(map[string]interface{})["name"] == name {
+					found = append(found, space)
+				}
+			}
+		}
+		return &tarantool.Result{Data: [][]interface{}{found}}
+	}
+
+	return tnttest.NewServer(t, handler)
+}
+
+func TestRun(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	addr := newFakeServer(t)
+	dir := t.TempDir()
+	schema := filepath.Join(dir, "schema.json")
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"-dump", schema, addr}, &stdout, &stderr)
+	require.Equal(0, code, stderr.String())
+	dump, err := os.ReadFile(schema)
+	require.NoError(err)
+	assert.Contains(string(dump), `"name": "users"`)
+	assert.Contains(string(dump), `"is_nullable": true`)
+
+	// the code is generated from the dump without the server
+	out := filepath.Join(dir, "spaces")
+	code = run([]string{"-schema", schema, "-o", out}, &stdout, &stderr)
+	assert.Equal(1, code, "events have no format")
+	assert.Contains(stderr.String(), "events: space events has no format\n")
+	src, err := os.ReadFile(filepath.Join(out, "users", "users.go"))
+	require.NoError(err)
+	assert.Contains(string(src), "func GetByPrimary(ctx context.Context, conn tarantool.Executor, id uint64) (*Tuple, error) {")
+
+	// and the same from the server
+	live := filepath.Join(dir, "live")
+	stderr.Reset()
+	code = run([]string{"-o", live, addr, "users"}, &stdout, &stderr)
+	require.Equal(0, code, stderr.String())
+	generated, err := os.ReadFile(filepath.Join(live, "users", "users.go"))
+	require.NoError(err)
+	assert.Equal(string(src), string(generated))
+
+	stdout.Reset()
+	code = run([]string{"-schema", schema, "-dump", "-", "users"}, &stdout, &stderr)
+	require.Equal(0, code)
+	assert.Contains(stdout.String(), `"name": "users"`)
+	assert.NotContains(stdout.String(), `"name": "events"`)
+
+	stderr.Reset()
+	assert.Equal(1, run([]string{"-schema", schema, "-o", out, "missing"}, &stdout, &stderr))
+	assert.Contains(stderr.String(), "space missing does not exist")
+	assert.Equal(2, run(nil, &stdout, &stderr))
+}
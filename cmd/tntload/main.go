@@ -0,0 +1,219 @@
+// Command tntload loads JSON Lines or CSV files into a space, the inverse of tntdump.
+//
+//	tntload [flags] [user[:password]@]host:port space [file]
+//
+// The rows are read from the file, or stdin, and written in batches,
+// each in a transaction, by several workers at once. A JSON line is the array
+// of the fields or the object of them named by the space format or numbered
+// from 1. A CSV file starts with the header of the field names if the space
+// has a format, the cells are converted to the types of the fields.
+//
+// If a batch fails, its rows are written one by one and the rejected ones are
+// reported and saved to the -errors file in the input format to be fixed and
+// loaded again. With -resume the number of the handled rows is saved to the file,
+// so an interrupted load continues after them when it's run again.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/bulk"
+)
+
+type config struct {
+	user, password string
+	timeout        time.Duration
+	format         string
+	bulkFormat     bulk.Format
+	mode           string
+	errors         string
+	resume         string
+	opts           bulk.BatchOptions
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stderr))
+}
+
+func run(args []string, stdin io.Reader, stderr io.Writer) int {
+	var cfg config
+
+	fs := flag.NewFlagSet("tntload", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tntload [flags] [user[:password]@]host:port space [file]")
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&cfg.user, "u", "", "user name")
+	fs.StringVar(&cfg.password, "p", os.Getenv("TNT_PASSWORD"), "password, $TNT_PASSWORD by default")
+	fs.DurationVar(&cfg.timeout, "timeout", 30*time.Second, "connect and batch timeout")
+	fs.StringVar(&cfg.format, "format", "", "input format, jsonl or csv, by the file extension by default")
+	fs.StringVar(&cfg.mode, "mode", "insert", "insert, replace or upsert the rows")
+	fs.IntVar(&cfg.opts.Size, "batch", bulk.DefaultBatchSize, "rows per batch")
+	fs.IntVar(&cfg.opts.Parallel, "parallel", bulk.DefaultParallel, "batches written at once")
+	fs.StringVar(&cfg.errors, "errors", "", "file to save the rejected rows to")
+	fs.StringVar(&cfg.resume, "resume", "", "file to keep the number of the handled rows in")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() < 2 || fs.NArg() > 3 {
+		fs.Usage()
+		return 2
+	}
+	file := fs.Arg(2)
+	if err := cfg.validate(file); err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	in := stdin
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		defer f.Close()
+		in = f
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	conn, err := tarantool.ConnectContext(ctx, fs.Arg(0), &tarantool.Options{
+		User:           cfg.user,
+		Password:       cfg.password,
+		ConnectTimeout: cfg.timeout,
+		QueryTimeout:   cfg.timeout,
+	})
+	cancel()
+	if err != nil {
+		fmt.Fprintln(stderr, "connect:", err)
+		return 1
+	}
+	defer conn.Close()
+
+	stats, err := cfg.load(conn, fs.Arg(1), in, stderr)
+	if stats != nil {
+		fmt.Fprintf(stderr, "%d loaded, %d rejected\n", stats.Loaded, stats.Rejected)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+	if stats.Rejected > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (cfg *config) validate(file string) (err error) {
+	if cfg.format == "" {
+		switch filepath.Ext(file) {
+		case ".csv":
+			cfg.format = "csv"
+		default:
+			cfg.format = "jsonl"
+		}
+	}
+	if cfg.bulkFormat, err = bulk.ParseFormat(cfg.format); err != nil {
+		return err
+	}
+	if cfg.opts.Mode, err = bulk.ParseMode(cfg.mode); err != nil {
+		return err
+	}
+	if cfg.opts.Size < 1 {
+		return errors.New("-batch must be positive")
+	}
+	if cfg.opts.Parallel < 1 {
+		return errors.New("-parallel must be positive")
+	}
+	return nil
+}
+
+func (cfg *config) load(conn *tarantool.Connection, space string, in io.Reader, stderr io.Writer) (*bulk.ImportStats, error) {
+	opts := cfg.opts
+	opts.OnReject = func(line int, err error) {
+		if cfg.bulkFormat == bulk.CSV {
+			fmt.Fprintf(stderr, "record %d: %s\n", line, err)
+		} else {
+			fmt.Fprintf(stderr, "line %d: %s\n", line, err)
+		}
+	}
+
+	if cfg.resume != "" {
+		rows, err := loadResume(cfg.resume)
+		if err != nil {
+			return nil, err
+		}
+		opts.Skip = rows
+		opts.OnProgress = func(rows int) error {
+			return saveResume(cfg.resume, rows)
+		}
+	}
+
+	if cfg.errors != "" {
+		// the rejects of the previous runs are kept when the load is resumed
+		flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+		if opts.Skip == 0 {
+			flags |= os.O_TRUNC
+		}
+		f, err := os.OpenFile(cfg.errors, flags, 0644)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		opts.Rejects = f
+	}
+
+	return bulk.New(conn).Import(context.Background(), space, in, cfg.bulkFormat, &opts)
+}
+
+// loadResume returns the number of the rows handled by the previous runs
+func loadResume(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	var st struct {
+		Rows int `json:"rows"`
+	}
+	if err = json.Unmarshal(data, &st); err != nil {
+		return 0, fmt.Errorf("%s: %w", path, err)
+	}
+	return st.Rows, nil
+}
+
+// saveResume writes the file under a temporary name and renames it
+func saveResume(path string, rows int) error {
+	data, err := json.Marshal(map[string]int{"rows": rows})
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.Write(data)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// fakeServer has the users space with the unsigned id, the string name
+// and the nullable age, it applies the batches in a transaction
+type fakeServer struct {
+	sync.Mutex
+	users   map[uint64][]interface{}
+	batches int
+	// failAt makes the batches time out after the number of them
+	failAt int
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, string) {
+	s := &fakeServer{users: map[uint64][]interface{}{}}
+
+	addr := tnttest.NewServer(t, s.handle)
+	return s, addr
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	switch q := q.(type) {
+	case *tarantool.Select:
+		switch q.Space {
+		case tarantool.ViewSpace:
+			return &tarantool.Result{Data: [][]interface{}{
+				{uint64(512), uint64(1), "users", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+			}}
+		case tarantool.ViewIndex:
+			return &tarantool.Result{Data: [][]interface{}{
+				{uint64(512), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, []interface{}{[]interface{}{uint64(0), "unsigned"}}},
+			}}
+		}
+	case *tarantool.Eval:
+		return &tarantool.Result{Data: [][]interface{}{{
+			map[string]interface{}{"name": "id", "type": "unsigned"},
+			map[string]interface{}{"name": "name", "type": "string"},
+			map[string]interface{}{"name": "age", "type": "unsigned", "is_nullable": true},
+		}}}
+	case *tarantool.Call17:
+		return s.apply(q.Tuple[0].([]interface{}))
+	}
+	return &tarantool.Result{}
+}
+
+func queryError(format string, args ...interface{}) *tarantool.Result {
+	return &tarantool.Result{
+		ErrorCode: tarantool.ErrProcLua,
+		Error:     tarantool.NewQueryError(tarantool.ErrProcLua, fmt.Sprintf(format, args...)),
+	}
+}
+
+func (s *fakeServer) apply(ops []interface{}) *tarantool.Result {
+	s.Lock()
+	defer s.Unlock()
+
+	s.batches++
+	if s.failAt > 0 && s.batches > s.failAt {
+		s.Unlock()
+		time.Sleep(time.Second)
+		s.Lock()
+		return &tarantool.Result{}
+	}
+
+	users := make(map[uint64][]interface{}, len(s.users))
+	for k, v := range s.users {
+		users[k] = v
+	}
+	for _, op := range ops {
+		op := op.([]interface{})
+		tuple := op[2].([]interface{})
+		id, ok := typeconv.IntfToUint64(tuple[0])
+		if !ok {
+			return queryError("bad id %v", tuple[0])
+		}
+		if _, ok := users[id]; ok && op[0] == "insert" {
+			return queryError("duplicate key %d", id)
+		}
+		if old, ok := users[id]; ok && op[0] == "upsert" {
+			updated := append([]interface{}(nil), old...)
+			for _, o := range op[3].([]interface{}) {
+				o := o.([]interface{})
+				field, _ := typeconv.IntfToInt(o[1])
+				for len(updated) < field {
+					updated = append(updated, nil)
+				}
+				updated[field-1] = o[2]
+			}
+			tuple = updated
+		}
+		users[id] = tuple
+	}
+	s.users = users
+	return &tarantool.Result{Data: [][]interface{}{{int64(len(ops))}}}
+}
+
+func (s *fakeServer) user(id uint64) []interface{} {
+	s.Lock()
+	defer s.Unlock()
+	return s.users[id]
+}
+
+func runLoad(args []string, stdin string) (int, string) {
+	var stderr bytes.Buffer
+	code := run(args, strings.NewReader(stdin), &stderr)
+	return code, stderr.String()
+}
+
+func TestLoadJSON(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newFakeServer(t)
+	s.users[3] = []interface{}{uint64(3), "old", nil}
+	errFile := filepath.Join(t.TempDir(), "errors.jsonl")
+
+	input := `{"id": 1, "name": "a", "age": 30}
+[2, "b"]
+
+{"id": 3, "name": "c"}
+{"id": 4, "nick": "d"}
+{"id": "x", "name": "e"}
+{"id": 6, "name": "f", "4": [1.5]}
+`
+	code, out := runLoad([]string{"-batch", "2", "-parallel", "2", "-errors", errFile, addr, "users"}, input)
+	assert.Equal(1, code)
+	assert.Contains(out, `line 4: `)
+	assert.Contains(out, `line 5: unknown field "nick"`)
+	assert.Contains(out, `line 6: `)
+	assert.Contains(out, "3 loaded, 3 rejected\n")
+
+	assert.Equal([]interface{}{int64(1), "a", int64(30)}, s.user(1))
+	assert.Equal([]interface{}{int64(2), "b"}, s.user(2))
+	assert.Equal([]interface{}{uint64(3), "old", nil}, s.user(3))
+	assert.Equal([]interface{}{int64(6), "f", nil, []interface{}{1.5}}, s.user(6))
+
+	rejected, err := os.ReadFile(errFile)
+	require.NoError(err)
+	lines := strings.Split(strings.TrimSpace(string(rejected)), "\n")
+	assert.ElementsMatch([]string{
+		`{"id": 3, "name": "c"}`,
+		`{"id": 4, "nick": "d"}`,
+		`{"id": "x", "name": "e"}`,
+	}, lines)
+
+	// the fixed rows are upserted
+	code, out = runLoad([]string{"-mode", "upsert", addr, "users", "-"}, `{"id": 3, "name": "c"}`)
+	assert.Equal(0, code, out)
+	assert.Equal([]interface{}{uint64(3), "c", nil}, s.user(3))
+}
+
+func TestLoadCSV(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newFakeServer(t)
+	dir := t.TempDir()
+	file := filepath.Join(dir, "users.csv")
+	errFile := filepath.Join(dir, "errors.csv")
+	require.NoError(os.WriteFile(file, []byte("name,id,age\na,1,30\nb,2,\nc,x,1\n\"d, e\",4,,\"{\"\"k\"\": 1}\"\n"), 0644))
+
+	code, out := runLoad([]string{"-batch", "10", "-errors", errFile, addr, "users", file}, "")
+	assert.Equal(1, code)
+	assert.Contains(out, "record 4: column 2: ")
+	assert.Contains(out, "3 loaded, 1 rejected\n")
+
+	assert.Equal([]interface{}{int64(1), "a", int64(30)}, s.user(1))
+	assert.Equal([]interface{}{int64(2), "b", nil}, s.user(2))
+	assert.Equal([]interface{}{int64(4), "d, e", nil, map[string]interface{}{"k": int64(1)}}, s.user(4))
+
+	rejected, err := os.ReadFile(errFile)
+	require.NoError(err)
+	assert.Equal("name,id,age\nc,x,1\n", string(rejected))
+}
+
+func TestLoadResume(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newFakeServer(t)
+	resume := filepath.Join(t.TempDir(), "state.json")
+
+	var input strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&input, "[%d, \"u%d\"]\n", i, i)
+	}
+
+	s.failAt = 2
+	code, out := runLoad([]string{"-batch", "3", "-parallel", "1", "-timeout", "200ms", "-resume", resume, addr, "users"}, input.String())
+	assert.Equal(1, code)
+	assert.Contains(out, "6 loaded, 0 rejected\n")
+
+	state, err := os.ReadFile(resume)
+	require.NoError(err)
+	assert.JSONEq(`{"rows": 6}`, string(state))
+
+	s.Lock()
+	s.failAt = 0
+	s.users[1] = []interface{}{"changed"}
+	s.Unlock()
+
+	// the loaded rows would be rejected as duplicates if they weren't skipped
+	code, out = runLoad([]string{"-batch", "3", "-resume", resume, addr, "users"}, input.String())
+	assert.Equal(0, code, out)
+	assert.Contains(out, "4 loaded, 0 rejected\n")
+	assert.Equal([]interface{}{int64(10), "u10"}, s.user(10))
+	assert.Equal([]interface{}{"changed"}, s.user(1))
+
+	state, err = os.ReadFile(resume)
+	require.NoError(err)
+	assert.JSONEq(`{"rows": 10}`, string(state))
+}
+
+func TestRunUsage(t *testing.T) {
+	assert := assert.New(t)
+
+	code, _ := runLoad([]string{"127.0.0.1:1"}, "")
+	assert.Equal(2, code)
+	code, out := runLoad([]string{"-mode", "merge", "127.0.0.1:1", "users"}, "")
+	assert.Equal(2, code)
+	assert.Contains(out, "unknown mode")
+}
@@ -1,7 +1,26 @@
 package tarantool
 
 import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -118,3 +137,522 @@ func TestConnectOptionsDSN(t *testing.T) {
 	}
 
 }
+
+func TestConnectOptionsValidation(t *testing.T) {
+	assert := assert.New(t)
+
+	tt := []struct {
+		uri  string
+		opts Options
+	}{
+		{"", Options{}},
+		{"tcp://", Options{}},
+		{"127.0.0.1", Options{ConnectTimeout: -time.Second}},
+		{"127.0.0.1", Options{QueryTimeout: -time.Second}},
+		{"127.0.0.1", Options{SendTimeout: -time.Second}},
+		{"127.0.0.1", Options{WriteTimeout: -time.Second}},
+		{"127.0.0.1", Options{IdlePingInterval: -time.Second}},
+		{"127.0.0.1", Options{WriteQueueSize: -1}},
+		{"127.0.0.1", Options{PoolMaxPacketSize: -1}},
+		{"127.0.0.1", Options{QueueFullPolicy: QueueFailFast + 1}},
+		{"127.0.0.1", Options{RequiredSpaces: []string{""}}},
+	}
+	for tc, item := range tt {
+		_, _, err := parseOptions(item.uri, item.opts)
+		assert.True(errors.Is(err, ErrInvalidOptions), "case %v: %v", tc+1, err)
+	}
+
+	_, err := Connect("127.0.0.1", &Options{QueryTimeout: -1})
+	assert.True(errors.Is(err, ErrInvalidOptions))
+
+	// the password without the user is ignored as before
+	_, opts, err := parseOptions("127.0.0.1", Options{Password: "secret"})
+	assert.NoError(err)
+	assert.Empty(opts.User)
+
+	// the password comes along with the user from the dsn
+	_, opts, err = parseOptions("user@127.0.0.1", Options{Password: "secret"})
+	assert.NoError(err)
+	assert.Equal("user", opts.User)
+	assert.Equal("secret", opts.Password)
+}
+
+func TestConnectOptionsNotMutated(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if sel, ok := q.(*Select); ok && sel.Space == ViewSpace {
+			return &Result{Data: [][]interface{}{
+				{uint64(512), uint64(1), "tester", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+			}}
+		}
+		return &Result{}
+	})
+
+	options := &Options{RequiredSpaces: []string{"tester"}}
+	original := *options
+	original.RequiredSpaces = []string{"tester"}
+
+	conn, err := Connect("guest@"+addr+"/tester", options)
+	require.NoError(err)
+	conn.Close()
+	assert.Equal(original, *options)
+
+	c := New("guest@"+addr+"/tester", options)
+	defer c.Close()
+	conn, err = c.Connect()
+	require.NoError(err)
+	conn.Close()
+	assert.Equal(original, *options)
+
+	// the connector keeps its own copy
+	options.RequiredSpaces[0] = "missing"
+	_, err = c.Connect()
+	require.NoError(err)
+}
+
+func TestConnectHandshake(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var mu sync.Mutex
+	var queries []Query
+	var authed bool
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		mu.Lock()
+		queries = append(queries, q)
+		mu.Unlock()
+		if _, ok := q.(*Auth); ok {
+			// the auth yields, e.g. in the on_auth triggers
+			time.Sleep(50 * time.Millisecond)
+			mu.Lock()
+			authed = true
+			mu.Unlock()
+		}
+		mu.Lock()
+		guest := !authed
+		mu.Unlock()
+		// the system views show nothing to the guest
+		if sel, ok := q.(*Select); ok && !guest {
+			switch sel.Space {
+			case ViewSpace:
+				return &Result{Data: [][]interface{}{
+					{uint64(512), uint64(1), "tester", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+				}}
+			case ViewIndex:
+				return &Result{Data: [][]interface{}{
+					{uint64(512), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, []interface{}{[]interface{}{uint64(0), "unsigned"}}},
+				}}
+			}
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, &Options{User: "tester", Password: "secret"})
+	require.NoError(err)
+	defer conn.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(queries, 3)
+	assert.IsType(&Auth{}, queries[0])
+	for _, q := range queries {
+		if sel, ok := q.(*Select); ok {
+			assert.EqualValues(math.MaxUint32, sel.Limit)
+		} else {
+			assert.IsType(&Auth{}, q)
+		}
+	}
+
+	pk, ok := conn.GetPrimaryKeyFields("tester")
+	require.True(ok)
+	assert.Equal([]int{0}, pk)
+}
+
+func TestConnectAuthError(t *testing.T) {
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if _, ok := q.(*Auth); ok {
+			return &Result{ErrorCode: ErrPasswordMismatch, Error: NewQueryError(ErrPasswordMismatch, "Incorrect password")}
+		}
+		return &Result{}
+	})
+
+	_, err := Connect(addr, &Options{User: "tester", Password: "wrong"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Incorrect password")
+}
+
+func TestConnectWriteQueueSize(t *testing.T) {
+	require := require.New(t)
+
+	addr := newTestServer(t, nil)
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+	require.Equal(DefaultWriteQueueSize, cap(conn.writeChan))
+
+	conn2, err := Connect(addr, &Options{WriteQueueSize: 8})
+	require.NoError(err)
+	defer conn2.Close()
+	require.Equal(8, cap(conn2.writeChan))
+	require.Equal(0, conn2.WriteQueueLen())
+}
+
+func TestConnectQueueDelay(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var (
+		mu     sync.Mutex
+		delays []time.Duration
+	)
+	d := &stallDialer{writing: make(chan struct{}, 1)}
+	conn, err := Connect(newTestServer(t, nil), &Options{
+		Dialer:         d,
+		WriteQueueSize: 1,
+		Perf: PerfCount{QueueDelay: func(delay time.Duration) {
+			mu.Lock()
+			delays = append(delays, delay)
+			mu.Unlock()
+		}},
+	})
+	require.NoError(err)
+	defer conn.Close()
+
+	mu.Lock()
+	connected := len(delays)
+	mu.Unlock()
+	// forget the writes of the handshake
+	select {
+	case <-d.writing:
+	default:
+	}
+
+	var wg sync.WaitGroup
+	ping := func() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.NoError(conn.Exec(context.Background(), &Ping{}).Error)
+		}()
+	}
+
+	// the second ping waits in the queue while the writer is stuck with the first one
+	d.stalled.Lock()
+	ping()
+	<-d.writing
+	ping()
+	require.Eventually(func() bool {
+		return conn.WriteQueueLen() == 1
+	}, time.Second, time.Millisecond)
+	queued := time.Now()
+	// only the first ping has left the queue
+	mu.Lock()
+	assert.Len(delays, connected+1)
+	mu.Unlock()
+	stall := time.Since(queued)
+	d.stalled.Unlock()
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Len(delays, connected+2)
+	var max time.Duration
+	for _, delay := range delays[connected:] {
+		if delay > max {
+			max = delay
+		}
+	}
+	// the delay covers at least the time the ping has been seen in the queue
+	assert.True(max >= stall, "queue delay %s, stalled for %s", max, stall)
+}
+
+func TestReloadSchema(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var mu sync.Mutex
+	spaceName := "tester"
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		sel, ok := q.(*Select)
+		if !ok || sel.Space != ViewSpace {
+			return &Result{}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return &Result{Data: [][]interface{}{
+			{uint64(512), uint64(1), spaceName, "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+		}}
+	})
+
+	conn, err := Connect(addr, &Options{})
+	require.NoError(err)
+	defer conn.Close()
+
+	spaceNo, err := conn.packData.spaceNo("tester")
+	require.NoError(err)
+	assert.EqualValues(512, spaceNo)
+
+	mu.Lock()
+	spaceName = "renamed"
+	mu.Unlock()
+
+	// resolve names while the schema is being swapped
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				conn.packData.spaceNo("tester")
+			}
+		}
+	}()
+
+	require.NoError(conn.ReloadSchema(context.Background()))
+	close(stop)
+	wg.Wait()
+
+	_, err = conn.packData.spaceNo("tester")
+	assert.Error(err)
+	spaceNo, err = conn.packData.spaceNo("renamed")
+	require.NoError(err)
+	assert.EqualValues(512, spaceNo)
+}
+
+func TestParseGreeting(t *testing.T) {
+	line := func(s string) string {
+		return fmt.Sprintf("%-63s\n", s)
+	}
+	salt := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	greeting, err := parseGreeting(strings.NewReader(line("Tarantool 2.10.4 (Binary) 8a7b1a8e-d0ba-4d4f-9bf2-5fa4f5bb5d4b") + line(salt)))
+	require.NoError(t, err)
+	assert.Equal(t, VersionID(2, 10, 4), greeting.Version)
+	assert.Equal(t, []byte(salt), greeting.Auth)
+	assert.Equal(t, "8a7b1a8e-d0ba-4d4f-9bf2-5fa4f5bb5d4b", greeting.UUID)
+
+	tests := []struct {
+		name     string
+		greeting string
+		reason   string
+	}{
+		{"http", "HTTP/1.1 400 Bad Request\r\nContent-Length: 0\r\n\r\n", "short greeting"},
+		{"empty", "", "short greeting"},
+		{"malformed", strings.Repeat("x", GreetingSize), "malformed greeting"},
+		{"identity", line("Redis 7.0.0") + line(salt), "unexpected server identity"},
+		{"salt", line("Tarantool 2.10.4 (Binary)") + line("not a salt"), "invalid salt"},
+	}
+
+	for _, tc := range tests {
+		_, err := parseGreeting(strings.NewReader(tc.greeting))
+		assert.True(t, errors.Is(err, ErrInvalidGreeting), tc.name)
+		assert.Contains(t, err.Error(), "not a Tarantool server", tc.name)
+		assert.Contains(t, err.Error(), tc.reason, tc.name)
+	}
+}
+
+func TestConnectRequiredSpaces(t *testing.T) {
+	assert := assert.New(t)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if sel, ok := q.(*Select); ok && sel.Space == ViewSpace {
+			return &Result{Data: [][]interface{}{
+				{uint64(512), uint64(1), "tester", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+			}}
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, &Options{DefaultSpace: "tester", RequiredSpaces: []string{"tester"}})
+	if assert.NoError(err) {
+		conn.Close()
+	}
+
+	_, err = Connect(addr, &Options{DefaultSpace: "testr"})
+	var qe *QueryError
+	if assert.True(errors.As(err, &qe)) {
+		assert.Equal(ErrNoSuchSpace, qe.Code)
+		assert.Contains(qe.Error(), "'testr'")
+	}
+
+	_, err = Connect(addr, &Options{RequiredSpaces: []string{"tester", "missing"}})
+	if assert.True(errors.As(err, &qe)) {
+		assert.Equal(ErrNoSuchSpace, qe.Code)
+		assert.Contains(qe.Error(), "'missing'")
+	}
+}
+
+// blackholeWriter drops the data once the connection is blackholed
+type blackholeWriter struct {
+	w    io.Writer
+	drop *int32
+}
+
+func (b *blackholeWriter) Write(p []byte) (int, error) {
+	if atomic.LoadInt32(b.drop) != 0 {
+		return len(p), nil
+	}
+	return b.w.Write(p)
+}
+
+// newBlackholeProxy returns the address of a proxy to addr,
+// which silently drops all the traffic once drop is set
+func newBlackholeProxy(t *testing.T, addr string, drop *int32) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			backend, err := net.Dial("tcp", addr)
+			if err != nil {
+				c.Close()
+				continue
+			}
+			t.Cleanup(func() {
+				c.Close()
+				backend.Close()
+			})
+			go io.Copy(&blackholeWriter{w: backend, drop: drop}, c)
+			go io.Copy(&blackholeWriter{w: c, drop: drop}, backend)
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestConnectIdlePing(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var drop int32
+	addr := newBlackholeProxy(t, newTestServer(t, nil), &drop)
+
+	perf := PerfCount{NetPacketsOut: new(expvar.Int)}
+	conn, err := Connect(addr, &Options{IdlePingInterval: 10 * time.Millisecond, QueryTimeout: 50 * time.Millisecond, Perf: perf})
+	require.NoError(err)
+	defer conn.Close()
+
+	// the idle connection is pinged
+	require.Eventually(func() bool {
+		return perf.NetPacketsOut.Value() >= 3
+	}, time.Second, time.Millisecond)
+	assert.False(conn.IsClosed())
+
+	// the network is gone silently
+	atomic.StoreInt32(&drop, 1)
+	require.Eventually(conn.IsClosed, time.Second, time.Millisecond)
+
+	res := conn.Exec(context.Background(), &Ping{})
+	require.Error(res.Error)
+	assert.Contains(res.Error.Error(), "idle ping failed")
+}
+
+func TestGetSpaceFields(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if sel, ok := q.(*Select); ok && sel.Space == ViewSpace {
+			return &Result{Data: [][]interface{}{
+				{uint64(512), uint64(1), "users", "memtx", uint64(0), map[string]interface{}{}, []interface{}{
+					map[string]interface{}{"name": "id", "type": "unsigned"},
+					map[string]interface{}{"name": "email", "type": "string"},
+				}},
+				{uint64(513), uint64(1), "blobs", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+			}}
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	fields, ok := conn.GetSpaceFields("users")
+	require.True(ok)
+	assert.Equal([]string{"id", "email"}, fields)
+
+	fields, ok = conn.GetSpaceFields(uint64(512))
+	require.True(ok)
+	assert.Equal([]string{"id", "email"}, fields)
+
+	// the schema cache is not modified through the result
+	fields[0] = "changed"
+	fields, _ = conn.GetSpaceFields("users")
+	assert.Equal([]string{"id", "email"}, fields)
+
+	_, ok = conn.GetSpaceFields("blobs")
+	assert.False(ok)
+	_, ok = conn.GetSpaceFields("missing")
+	assert.False(ok)
+
+	types, ok := conn.GetSpaceFieldTypes("users")
+	require.True(ok)
+	assert.Equal([]string{"unsigned", "string"}, types)
+	types[0] = "changed"
+	types, _ = conn.GetSpaceFieldTypes("users")
+	assert.Equal([]string{"unsigned", "string"}, types)
+	_, ok = conn.GetSpaceFieldTypes("blobs")
+	assert.False(ok)
+
+	assert.Equal([]string{"blobs", "users"}, conn.GetSpaceNames())
+}
+
+// newTLSCert returns the self-signed certificate of 127.0.0.1
+func newTLSCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "tarantool"},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, pool
+}
+
+func TestConnectTLS(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	cert, pool := newTLSCert(t)
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(err)
+	serveTest(t, ln, func() *IprotoServer {
+		return NewIprotoServer(testServerUUID, func(ctx context.Context, q Query) *Result {
+			return &Result{}
+		}, nil)
+	})
+
+	conn, err := Connect(ln.Addr().String(), &Options{TLSConfig: &tls.Config{RootCAs: pool}})
+	require.NoError(err)
+	defer conn.Close()
+	assert.NoError(conn.Exec(context.Background(), &Ping{}).Error)
+
+	// the certificate isn't trusted
+	_, err = Connect(ln.Addr().String(), &Options{TLSConfig: &tls.Config{}})
+	assert.Error(err)
+}
@@ -4,12 +4,16 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/tls"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"net"
 	"net/url"
 	"runtime"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -20,6 +24,8 @@ var (
 	ErrInvalidGreeting   = errors.New("invalid greeting")
 	ErrEmptyDefaultSpace = errors.New("zero-length default space or unnecessary slash in dsn.path")
 	ErrSyncFailed        = errors.New("SYNC failed")
+	ErrBadSchema         = errors.New("unexpected schema format")
+	ErrInvalidOptions    = errors.New("invalid options")
 
 	versionPrefix = []byte("Tarantool ")
 )
@@ -27,38 +33,131 @@ var (
 type Options struct {
 	ConnectTimeout time.Duration
 	QueryTimeout   time.Duration
-	DefaultSpace   string
-	User           string
+
+	// SendTimeout limits the time a request may wait to be handed over to
+	// the writer. If it is set, QueryTimeout only limits the time to wait for
+	// the reply after that, so a brief write stall does not consume the whole
+	// query budget. Otherwise QueryTimeout covers both.
+	SendTimeout time.Duration
+
+	DefaultSpace string
+	User         string
+	// Password is used to authenticate the User, it is ignored if no User is set.
 	Password       string
 	UUID           string
 	ReplicaSetUUID string
 	Perf           PerfCount
 
+	// RequiredSpaces are the names of the spaces which must exist for Connect to succeed.
+	// The DefaultSpace is required as well, so misconfiguration is reported right away
+	// instead of failing the first request which uses the space.
+	RequiredSpaces []string
+
 	// PoolMaxPacketSize describes maximum size of packet buffer
 	// that can be added to packet pool.
 	// If the packet size is 0, option is ignored.
 	PoolMaxPacketSize int
+
+	// WriteQueueSize is the number of requests which can be queued for writing
+	// before callers start to block. DefaultWriteQueueSize is used if it is 0.
+	WriteQueueSize int
+
+	// QueueFullPolicy defines what happens to a request when the write queue is full.
+	QueueFullPolicy QueueFullPolicy
+	// QueueWaitTimeout limits the time to wait for the write queue with QueueBlockWithTimeout.
+	// DefaultQueueWaitTimeout is used if it is 0.
+	QueueWaitTimeout time.Duration
+
+	// FailFastWhenDisconnected makes Connector.Exec fail with ErrDisconnected at once
+	// while the connection is down, reconnecting in the background instead of
+	// making callers wait for the reconnect. The first connection of a Connector
+	// is waited for, the calls fail fast once it has been attempted.
+	FailFastWhenDisconnected bool
+
+	// IdlePingInterval makes the connection send a ping after having read
+	// nothing from the server for the interval. The connection is closed if
+	// the ping fails, so half-open TCP connections, e.g. after a pulled cable
+	// or a dropped NAT entry, are detected in seconds rather than by the next
+	// request. The ping is limited by QueryTimeout. It is disabled if 0.
+	IdlePingInterval time.Duration
+
+	// WriteTimeout limits the time to write buffered requests to the socket.
+	// The connection is closed if the write does not complete in time.
+	// There is no limit if it is 0.
+	WriteTimeout time.Duration
+
+	// TLSConfig enables TLS, e.g. for the SSL transport of Tarantool Enterprise.
+	// The ServerName is taken from the address if it is empty.
+	TLSConfig *tls.Config
+
+	// Dialer establishes the connections, e.g. through a proxy or with injected
+	// faults in tests. The dial is limited by ConnectTimeout.
+	// A net.Dialer is used if it is nil.
+	Dialer Dialer
 }
 
+// Dialer establishes network connections, it is implemented by net.Dialer.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// QueueFullPolicy is the behavior of a request when the write queue is full.
+type QueueFullPolicy int
+
+const (
+	// QueueBlock makes the caller wait for the queue until the request deadline.
+	QueueBlock QueueFullPolicy = iota
+	// QueueBlockWithTimeout makes the caller wait for the queue at most
+	// Options.QueueWaitTimeout and fail with ErrQueueFull after that.
+	QueueBlockWithTimeout
+	// QueueFailFast fails the request with ErrQueueFull immediately,
+	// so latency-sensitive callers can shed load.
+	QueueFailFast
+)
+
 type Greeting struct {
 	Version uint32
 	Auth    []byte
+	// UUID of the server instance, empty if the server does not send it
+	UUID string
 }
 
+// Connection is a single connection to Tarantool, safe for concurrent use.
+//
+// Requests are written to the socket in the order they are queued, so the
+// requests submitted one after another by a goroutine are sent in submission
+// order, while there is no order between the requests of different goroutines.
+// The server may still execute and answer them out of order: a request which
+// yields, e.g. a Lua call or a disk read, can be overtaken by the next one.
+// Callers which need a request applied before the next one must wait for its
+// reply. Requests are not re-sent after a reconnect, except for the idempotent
+// ones replayed by Connector.Exec, which go after the requests already queued.
 type Connection struct {
+	// requestID is allocated with atomic operations by concurrent submitters,
+	// it must stay the first field to keep 64-bit alignment on 32-bit platforms
 	requestID uint64
-	requests  *requestMap
-	writeChan chan *request // packed messages with header
-	closeOnce sync.Once
-	exit      chan bool
-	closed    chan bool
-	tcpConn   net.Conn
+	// lastRead is the time of the last packet read in unix nanoseconds,
+	// it is accessed atomically as well
+	lastRead int64
+	// lastStreamID is the last stream id allocated by NewStreamID
+	lastStreamID uint64
+	requests     *requestMap
+	writeChan    chan *request // packed messages with header
+	closeOnce    sync.Once
+	exit         chan bool
+	closed       chan bool
+	tcpConn      net.Conn
 
 	ccr io.Reader
 	ccw io.Writer
 
 	// options
 	queryTimeout      time.Duration
+	sendTimeout       time.Duration
+	idlePingInterval  time.Duration
+	writeTimeout      time.Duration
+	queuePolicy       QueueFullPolicy
+	queueWaitTimeout  time.Duration
 	greeting          *Greeting
 	packData          *packData
 	remoteAddr        string
@@ -66,6 +165,20 @@ type Connection struct {
 	firstErrorLock    *sync.Mutex
 	perf              PerfCount
 	poolMaxPacketSize int
+
+	// preallocated errors for failing requests
+	errors      *requestErrors
+	closedError atomic.Value // *ConnectionError, set once the connection is shut down
+
+	// watches of the keys by NewWatcher
+	watchLock sync.Mutex
+	watches   map[string]*watch
+	// watchSends are the Watch and Unwatch packets queued under watchLock
+	// and handed over to the writer by sendWatches in order, so watchLock,
+	// which the reader takes, is never held across a blocking send
+	watchSendLock sync.Mutex
+	watchSends    []*watchSend
+	watchSendWake chan struct{}
 }
 
 // Connect to tarantool instance with options using the provided context.
@@ -88,31 +201,22 @@ func Connect(dsnString string, options *Options) (conn *Connection, err error) {
 }
 
 func connect(ctx context.Context, scheme, addr string, opts Options) (conn *Connection, err error) {
-	conn, err = newConn(ctx, scheme, addr, opts)
+	conn, err = newConn(ctx, scheme, addr, opts, true)
 	if err != nil {
 		return
 	}
 
-	// set schema pulling deadline
-	deadline := time.Now().Add(opts.ConnectTimeout)
-	conn.tcpConn.SetDeadline(deadline)
-
-	err = conn.pullSchema()
-	if err != nil {
-		conn.tcpConn.Close()
-		conn = nil
-		return
-	}
-
-	// remove deadline
-	conn.tcpConn.SetDeadline(time.Time{})
-
 	go conn.worker()
+	if conn.idlePingInterval > 0 {
+		go conn.pinger()
+	}
 
 	return
 }
 
-func newConn(ctx context.Context, scheme, addr string, opts Options) (conn *Connection, err error) {
+// newConn dials the server and performs the handshake: authentication and,
+// if withSchema is set, fetching of the space and index schema.
+func newConn(ctx context.Context, scheme, addr string, opts Options, withSchema bool) (conn *Connection, err error) {
 	defer func() { // close opened connection if error
 		if err != nil && conn != nil {
 			if conn.tcpConn != nil {
@@ -125,25 +229,47 @@ func newConn(ctx context.Context, scheme, addr string, opts Options) (conn *Conn
 	conn = &Connection{
 		remoteAddr:        addr,
 		requests:          newRequestMap(),
-		writeChan:         make(chan *request, 256),
+		writeChan:         make(chan *request, opts.WriteQueueSize),
 		exit:              make(chan bool),
 		closed:            make(chan bool),
 		firstErrorLock:    &sync.Mutex{},
 		packData:          newPackData(opts.DefaultSpace),
 		queryTimeout:      opts.QueryTimeout,
+		sendTimeout:       opts.SendTimeout,
+		idlePingInterval:  opts.IdlePingInterval,
+		writeTimeout:      opts.WriteTimeout,
+		queuePolicy:       opts.QueueFullPolicy,
+		queueWaitTimeout:  opts.QueueWaitTimeout,
 		perf:              opts.Perf,
 		poolMaxPacketSize: opts.PoolMaxPacketSize,
+		errors:            newRequestErrors(addr),
 	}
 
-	d := &net.Dialer{
-		Timeout: opts.ConnectTimeout,
+	d := opts.Dialer
+	if d == nil {
+		d = &net.Dialer{}
 	}
 
-	conn.tcpConn, err = d.DialContext(ctx, scheme, conn.remoteAddr)
+	dialCtx, cancel := context.WithTimeout(ctx, opts.ConnectTimeout)
+	conn.tcpConn, err = d.DialContext(dialCtx, scheme, conn.remoteAddr)
+	cancel()
 	if err != nil {
 		return nil, err
 	}
 
+	connectDeadline := time.Now().Add(opts.ConnectTimeout)
+	conn.tcpConn.SetDeadline(connectDeadline)
+	// removing deadline deferred
+	defer conn.tcpConn.SetDeadline(time.Time{})
+
+	if opts.TLSConfig != nil {
+		tlsConn := tls.Client(conn.tcpConn, tlsConfig(opts.TLSConfig, addr))
+		conn.tcpConn = tlsConn
+		if err = tlsConn.Handshake(); err != nil {
+			return
+		}
+	}
+
 	if conn.perf.NetRead != nil {
 		conn.ccr = NewCountedReader(conn.tcpConn, conn.perf.NetRead)
 	} else {
@@ -156,57 +282,121 @@ func newConn(ctx context.Context, scheme, addr string, opts Options) (conn *Conn
 		conn.ccw = conn.tcpConn
 	}
 
-	connectDeadline := time.Now().Add(opts.ConnectTimeout)
-	conn.tcpConn.SetDeadline(connectDeadline)
-	// removing deadline deferred
-	defer conn.tcpConn.SetDeadline(time.Time{})
-
 	if conn.greeting, err = parseGreeting(conn.ccr); err != nil {
 		return
 	}
 
-	// try to authenticate if user have been provided
-	if len(opts.User) > 0 {
-		requestID := conn.nextID()
+	err = conn.handshake(opts, withSchema)
+	return
+}
 
-		pp := packetPool.GetWithID(requestID)
+// tlsConfig sets the ServerName from the address if it is not set
+func tlsConfig(config *tls.Config, addr string) *tls.Config {
+	if config.ServerName != "" || config.InsecureSkipVerify {
+		return config
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	config = config.Clone()
+	config.ServerName = host
+	return config
+}
 
-		err = pp.packMsg(&Auth{
+// handshake authenticates the connection if the user is provided and then
+// selects the schema. The schema requests wait for the auth reply, as the
+// server may run them in parallel with the auth and the system views would
+// be filtered with the guest privileges then.
+func (conn *Connection) handshake(opts Options, withSchema bool) error {
+	if len(opts.User) > 0 {
+		results, err := conn.roundTrip(&Auth{
 			User:         opts.User,
 			Password:     opts.Password,
 			GreetingAuth: conn.greeting.Auth,
-		}, conn.packData)
+		})
 		if err != nil {
-			conn.releasePacket(pp)
-			return
+			return err
+		}
+		if results[0].Error != nil {
+			return results[0].Error
 		}
+	}
+
+	if !withSchema {
+		return nil
+	}
 
-		_, err = pp.WriteTo(conn.ccw)
+	results, err := conn.roundTrip(schemaQueries()...)
+	if err != nil {
+		return err
+	}
+	for _, res := range results {
+		if res.Error != nil {
+			return res.Error
+		}
+	}
+
+	sc, err := parseSchema(results[0].Data, results[1].Data)
+	if err != nil {
+		return err
+	}
+	if err = sc.requireSpaces(opts); err != nil {
+		return err
+	}
+	conn.packData.setSchema(sc)
+	return nil
+}
+
+// roundTrip writes all the queries at once and reads the replies to them.
+// It may only be used before the worker has been started.
+func (conn *Connection) roundTrip(queries ...Query) ([]*Result, error) {
+	w := bufio.NewWriterSize(conn.ccw, DefaultWriterBufSize)
+	index := make(map[uint64]int, len(queries))
+
+	for i, q := range queries {
+		requestID := conn.nextID()
+
+		pp := packetPool.GetWithID(requestID)
+		err := pp.packMsg(q, conn.packData)
+		if err == nil {
+			_, err = pp.WriteTo(w)
+		}
 		conn.releasePacket(pp)
 		if err != nil {
-			return
+			return nil, err
 		}
 
-		pp = packetPool.Get()
-		defer conn.releasePacket(pp)
+		index[requestID] = i
+	}
 
-		if err = pp.readPacket(conn.ccr); err != nil {
-			return
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+
+	results := make([]*Result, len(queries))
+
+	pp := packetPool.Get()
+	defer conn.releasePacket(pp)
+
+	for range queries {
+		if err := pp.readPacket(conn.ccr); err != nil {
+			return nil, err
 		}
 
-		authResponse := &pp.packet
-		if authResponse.requestID != requestID {
-			err = ErrSyncFailed
-			return
+		response := &pp.packet
+		i, ok := index[response.requestID]
+		if !ok || results[i] != nil {
+			return nil, ErrSyncFailed
 		}
 
-		if authResponse.Result != nil && authResponse.Result.Error != nil {
-			err = authResponse.Result.Error
-			return
+		if response.Result == nil {
+			return nil, errors.New("nil response result")
 		}
+		results[i] = response.Result
 	}
 
-	return
+	return results, nil
 }
 
 func parseOptions(dsnString string, opts Options) (*url.URL, Options, error) {
@@ -227,11 +417,18 @@ func parseOptions(dsnString string, opts Options) (*url.URL, Options, error) {
 	if err != nil {
 		return dsn, opts, err
 	}
+	if dsn.Host == "" {
+		return nil, opts, fmt.Errorf("%w: no address in %q", ErrInvalidOptions, dsnString)
+	}
+
+	opts = opts.clone()
 
 	if len(opts.User) == 0 {
 		if user := dsn.User; user != nil {
 			opts.User = user.Username()
-			opts.Password, _ = user.Password()
+			if password, ok := user.Password(); ok {
+				opts.Password = password
+			}
 		}
 	}
 
@@ -254,29 +451,116 @@ func parseOptions(dsnString string, opts Options) (*url.URL, Options, error) {
 	if opts.QueryTimeout.Nanoseconds() == 0 {
 		opts.QueryTimeout = DefaultQueryTimeout
 	}
+	if opts.WriteQueueSize == 0 {
+		opts.WriteQueueSize = DefaultWriteQueueSize
+	}
+	if opts.QueueWaitTimeout == 0 {
+		opts.QueueWaitTimeout = DefaultQueueWaitTimeout
+	}
 
+	if err = opts.validate(); err != nil {
+		return nil, opts, err
+	}
 	return dsn, opts, nil
 }
 
+// clone returns a deep copy of the options, so the connection never shares
+// memory with the caller. The Perf counters and the Dialer are shared on purpose.
+func (opts Options) clone() Options {
+	if opts.RequiredSpaces != nil {
+		opts.RequiredSpaces = append([]string(nil), opts.RequiredSpaces...)
+	}
+	if opts.TLSConfig != nil {
+		opts.TLSConfig = opts.TLSConfig.Clone()
+	}
+	return opts
+}
+
+// validate rejects the options which make no sense.
+func (opts *Options) validate() error {
+	durations := []struct {
+		name  string
+		value time.Duration
+	}{
+		{"ConnectTimeout", opts.ConnectTimeout},
+		{"QueryTimeout", opts.QueryTimeout},
+		{"SendTimeout", opts.SendTimeout},
+		{"QueueWaitTimeout", opts.QueueWaitTimeout},
+		{"IdlePingInterval", opts.IdlePingInterval},
+		{"WriteTimeout", opts.WriteTimeout},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			return fmt.Errorf("%w: %s is negative: %s", ErrInvalidOptions, d.name, d.value)
+		}
+	}
+
+	switch {
+	case opts.WriteQueueSize < 0:
+		return fmt.Errorf("%w: WriteQueueSize is negative: %d", ErrInvalidOptions, opts.WriteQueueSize)
+	case opts.PoolMaxPacketSize < 0:
+		return fmt.Errorf("%w: PoolMaxPacketSize is negative: %d", ErrInvalidOptions, opts.PoolMaxPacketSize)
+	case opts.QueueFullPolicy < QueueBlock || opts.QueueFullPolicy > QueueFailFast:
+		return fmt.Errorf("%w: unknown QueueFullPolicy %d", ErrInvalidOptions, opts.QueueFullPolicy)
+	}
+	for _, space := range opts.RequiredSpaces {
+		if space == "" {
+			return fmt.Errorf("%w: empty space name in RequiredSpaces", ErrInvalidOptions)
+		}
+	}
+	return nil
+}
+
+// parseGreeting reads and validates the greeting, so that connecting to
+// something which is not a Tarantool server fails early with a clear error.
 func parseGreeting(r io.Reader) (*Greeting, error) {
-	greeting := make([]byte, 128)
+	greeting := make([]byte, GreetingSize)
 
-	_, err := io.ReadFull(r, greeting)
+	n, err := io.ReadFull(r, greeting)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return nil, invalidGreeting("short greeting", greeting[:n])
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	version, err := parseVersion(greeting[:64])
+	half := GreetingSize / 2
+	if greeting[half-1] != '\n' || greeting[GreetingSize-1] != '\n' {
+		return nil, invalidGreeting("malformed greeting", greeting)
+	}
+
+	version, err := parseVersion(greeting[:half])
 	if err != nil {
-		return nil, err
+		return nil, invalidGreeting("unexpected server identity", greeting[:half])
+	}
+
+	auth := greeting[half : half+44]
+	if salt, err := base64.StdEncoding.DecodeString(string(auth)); err != nil || len(salt) < scrambleSize {
+		return nil, invalidGreeting("invalid salt", greeting[half:])
 	}
 
 	return &Greeting{
 		Version: version,
-		Auth:    greeting[64:108],
+		Auth:    auth,
+		UUID:    parseGreetingUUID(greeting[:half]),
 	}, nil
 }
 
+// parseGreetingUUID returns the instance UUID which follows the protocol name
+// since Tarantool 1.6.7, e.g. "Tarantool 2.10.4 (Binary) 7c4e...".
+func parseGreetingUUID(line []byte) string {
+	fields := strings.Fields(string(line))
+	if n := len(fields); n > 0 && len(fields[n-1]) == UUIDStrLength {
+		return fields[n-1]
+	}
+	return ""
+}
+
+func invalidGreeting(reason string, data []byte) error {
+	return fmt.Errorf("%w: not a Tarantool server or wrong port, %s: %q",
+		ErrInvalidGreeting, reason, bytes.TrimRight(data, " \n\x00"))
+}
+
 func parseVersion(version []byte) (uint32, error) {
 	if !bytes.HasPrefix(version, versionPrefix) {
 		return 0, ErrInvalidGreeting
@@ -295,113 +579,149 @@ func VersionID(major, minor, patch uint32) uint32 {
 	return (((major << 8) | minor) << 8) | patch
 }
 
-func (conn *Connection) pullSchema() (err error) {
-	// select space and index schema
-	request := func(q Query) (*Result, error) {
-		var err error
-
-		requestID := conn.nextID()
+// schemaQueries returns requests to fetch all the _vspace and _vindex tuples.
+func schemaQueries() []Query {
+	return []Query{
+		&Select{
+			Space:    ViewSpace,
+			Key:      0,
+			Iterator: IterAll,
+			Limit:    math.MaxUint32,
+		},
+		&Select{
+			Space:    ViewIndex,
+			Key:      0,
+			Iterator: IterAll,
+			Limit:    math.MaxUint32,
+		},
+	}
+}
 
-		pp := packetPool.GetWithID(requestID)
-		if err = pp.packMsg(q, conn.packData); err != nil {
-			conn.releasePacket(pp)
-			return nil, err
-		}
+// parseSchema builds the space and index maps from _vspace and _vindex tuples.
+func parseSchema(spaces, indexes [][]interface{}) (*schema, error) {
+	sc := newSchema()
 
-		_, err = pp.WriteTo(conn.ccw)
-		conn.releasePacket(pp)
-		if err != nil {
-			return nil, err
+	for _, space := range spaces {
+		if len(space) < 3 {
+			return nil, fmt.Errorf("%w: _vspace tuple has %d fields", ErrBadSchema, len(space))
 		}
-
-		pp = packetPool.Get()
-		defer conn.releasePacket(pp)
-
-		if err = pp.readPacket(conn.ccr); err != nil {
-			return nil, err
+		spaceID, _ := numberToUint64(space[0])
+		spaceName, ok := space[2].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: space %d name is %T", ErrBadSchema, spaceID, space[2])
 		}
-
-		response := &pp.packet
-		if response.requestID != requestID {
-			return nil, errors.New("bad response requestID")
+		sc.spaceMap[spaceName] = spaceID
+
+		if len(space) > 6 {
+			format, _ := space[6].([]interface{}) // e.g: [{"name": "id", "type": "unsigned"}]
+			if len(format) > 0 {
+				fields := make([]string, len(format))
+				types := make([]string, len(format))
+				for i, f := range format {
+					if descr, ok := f.(map[string]interface{}); ok {
+						fields[i], _ = descr["name"].(string)
+						types[i], _ = descr["type"].(string)
+					}
+				}
+				sc.fieldMap[spaceID] = fields
+				sc.fieldTypeMap[spaceID] = types
+			}
 		}
+	}
 
-		if response.Result == nil {
-			return nil, errors.New("nil response result")
+	for _, index := range indexes {
+		if len(index) < 6 {
+			return nil, fmt.Errorf("%w: _vindex tuple has %d fields", ErrBadSchema, len(index))
 		}
-
-		if response.Result.Error != nil {
-			return nil, response.Result.Error
+		spaceID, _ := numberToUint64(index[0])
+		indexID, _ := numberToUint64(index[1])
+		indexName, ok := index[2].(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: space %d index %d name is %T", ErrBadSchema, spaceID, indexID, index[2])
 		}
+		indexAttr, _ := index[4].(map[string]interface{}) // e.g: {"unique": true}
+		indexFields, _ := index[5].([]interface{})        // e.g: [[0 num] [1 str]]
 
-		return response.Result, nil
-	}
-
-	res, err := request(&Select{
-		Space:    ViewSpace,
-		Key:      0,
-		Iterator: IterAll,
-	})
-	if err != nil {
-		return
-	}
-
-	for _, space := range res.Data {
-		spaceID, _ := conn.packData.spaceNo(space[0])
-		conn.packData.spaceMap[space[2].(string)] = spaceID
-	}
-
-	res, err = request(&Select{
-		Space:    ViewIndex,
-		Key:      0,
-		Iterator: IterAll,
-	})
-	if err != nil {
-		return
-	}
-
-	for _, index := range res.Data {
-		spaceID, _ := conn.packData.fieldNo(index[0])
-		indexID, _ := conn.packData.fieldNo(index[1])
-		indexName := index[2].(string)
-		indexAttr := index[4].(map[string]interface{}) // e.g: {"unique": true}
-		indexFields := index[5].([]interface{})        // e.g: [[0 num] [1 str]]
-
-		indexSpaceMap, exists := conn.packData.indexMap[spaceID]
+		indexSpaceMap, exists := sc.indexMap[spaceID]
 		if !exists {
 			indexSpaceMap = make(map[string]uint64)
-			conn.packData.indexMap[spaceID] = indexSpaceMap
+			sc.indexMap[spaceID] = indexSpaceMap
 		}
 		indexSpaceMap[indexName] = indexID
 
 		// build list of primary key field numbers for this space, if the PK is detected
 		if indexAttr != nil && indexID == 0 {
-			if unique, ok := indexAttr["unique"]; ok && unique.(bool) {
+			if unique, _ := indexAttr["unique"].(bool); unique {
 				pk := make([]int, len(indexFields))
 				for i := range indexFields {
+					var field interface{}
 					switch descr := indexFields[i].(type) {
 					case []interface{}:
-						f, _ := conn.packData.fieldNo(descr[0])
-						pk[i] = int(f)
+						if len(descr) == 0 {
+							return nil, fmt.Errorf("%w: empty primary key part of space %d", ErrBadSchema, spaceID)
+						}
+						field = descr[0]
 					case map[string]interface{}:
-						f, _ := conn.packData.fieldNo(descr["field"])
-						pk[i] = int(f)
+						field = descr["field"]
 					default:
-						panic("invalid index field format")
+						return nil, fmt.Errorf("%w: invalid primary key part %v of space %d", ErrBadSchema, descr, spaceID)
 					}
+					f, _ := numberToUint64(field)
+					pk[i] = int(f)
 				}
-				conn.packData.primaryKeyMap[spaceID] = pk
+				sc.primaryKeyMap[spaceID] = pk
 			}
 		}
 	}
 
-	return
+	return sc, nil
+}
+
+// requireSpaces checks that the spaces the connection is configured to use exist.
+func (sc *schema) requireSpaces(opts Options) error {
+	if opts.DefaultSpace != "" {
+		if _, ok := sc.spaceMap[opts.DefaultSpace]; !ok {
+			return NewQueryError(ErrNoSuchSpace, fmt.Sprintf("Space '%s' does not exist, it is set as Options.DefaultSpace", opts.DefaultSpace))
+		}
+	}
+	for _, space := range opts.RequiredSpaces {
+		if _, ok := sc.spaceMap[space]; !ok {
+			return NewQueryError(ErrNoSuchSpace, fmt.Sprintf("Space '%s' does not exist, it is listed in Options.RequiredSpaces", space))
+		}
+	}
+	return nil
+}
+
+// ReloadSchema fetches space and index definitions and replaces the cached
+// schema at once. Requests packed concurrently keep using the previous snapshot.
+func (conn *Connection) ReloadSchema(ctx context.Context) error {
+	var data [2][][]interface{}
+
+	for i, q := range schemaQueries() {
+		res := conn.Exec(ctx, q)
+		if res.Error != nil {
+			return res.Error
+		}
+		data[i] = res.Data
+	}
+
+	sc, err := parseSchema(data[0], data[1])
+	if err != nil {
+		return err
+	}
+	conn.packData.setSchema(sc)
+	return nil
 }
 
 func (conn *Connection) nextID() uint64 {
 	return atomic.AddUint64(&conn.requestID, 1)
 }
 
+// NewStreamID returns the id of a new stream of the connection, see StreamExecOption.
+func (conn *Connection) NewStreamID() uint64 {
+	return atomic.AddUint64(&conn.lastStreamID, 1)
+}
+
 func (conn *Connection) stop() {
 	conn.closeOnce.Do(func() {
 		// debug.PrintStack()
@@ -411,6 +731,18 @@ func (conn *Connection) stop() {
 	})
 }
 
+// WriteQueueLen returns the number of requests waiting to be written.
+// A queue which stays close to Options.WriteQueueSize means the connection
+// is congested and callers are about to block.
+func (conn *Connection) WriteQueueLen() int {
+	return len(conn.writeChan)
+}
+
+// InstanceUUID returns the UUID of the server instance sent in the greeting.
+func (conn *Connection) InstanceUUID() string {
+	return conn.greeting.UUID
+}
+
 func (conn *Connection) GetPerf() PerfCount {
 	return conn.perf
 }
@@ -426,10 +758,67 @@ func (conn *Connection) GetPrimaryKeyFields(space interface{}) ([]int, bool) {
 		return nil, false
 	}
 
-	f, ok := conn.packData.primaryKeyMap[spaceID]
+	f, ok := conn.packData.schema().primaryKeyMap[spaceID]
 	return f, ok
 }
 
+// GetSpaceFields returns a copy of the field names of the space format from
+// the schema cache, false if the space is unknown or has no format.
+func (conn *Connection) GetSpaceFields(space interface{}) ([]string, bool) {
+	if conn.packData == nil {
+		return nil, false
+	}
+
+	sc := conn.packData.schema()
+	spaceID, err := conn.packData.schemaSpaceNo(sc, space)
+	if err != nil {
+		return nil, false
+	}
+
+	f, ok := sc.fieldMap[spaceID]
+	if !ok {
+		return nil, false
+	}
+	// the schema is shared by all the callers
+	return append([]string(nil), f...), true
+}
+
+// GetSpaceFieldTypes returns a copy of the field types of the space format from
+// the schema cache, e.g. unsigned or string, false if the space is unknown or has no format.
+func (conn *Connection) GetSpaceFieldTypes(space interface{}) ([]string, bool) {
+	if conn.packData == nil {
+		return nil, false
+	}
+
+	sc := conn.packData.schema()
+	spaceID, err := conn.packData.schemaSpaceNo(sc, space)
+	if err != nil {
+		return nil, false
+	}
+
+	t, ok := sc.fieldTypeMap[spaceID]
+	if !ok {
+		return nil, false
+	}
+	// the schema is shared by all the callers
+	return append([]string(nil), t...), true
+}
+
+// GetSpaceNames returns the names of the spaces in the schema cache, sorted.
+func (conn *Connection) GetSpaceNames() []string {
+	if conn.packData == nil {
+		return nil
+	}
+
+	sc := conn.packData.schema()
+	names := make([]string, 0, len(sc.spaceMap))
+	for name := range sc.spaceMap {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
 func (conn *Connection) Close() {
 	conn.stop()
 	<-conn.closed
@@ -448,8 +837,15 @@ func (conn *Connection) IsClosed() bool {
 	}
 }
 
+// Done returns the channel closed when the connection is shut down, see IsClosed.
+func (conn *Connection) Done() <-chan bool {
+	return conn.exit
+}
+
+// releasePacket returns the packet and its body buffer to the pool for reuse,
+// unless the buffer has grown beyond PoolMaxPacketSize.
 func (conn *Connection) releasePacket(pp *BinaryPacket) {
-	if conn.poolMaxPacketSize == 0 || conn.poolMaxPacketSize < cap(pp.body) {
+	if conn.poolMaxPacketSize == 0 || cap(pp.body) <= conn.poolMaxPacketSize {
 		pp.Release()
 	}
 }
@@ -473,7 +869,7 @@ func (conn *Connection) setError(err error) {
 func (conn *Connection) worker() {
 	var wg sync.WaitGroup
 
-	wg.Add(2)
+	wg.Add(1)
 
 	go func() {
 		err := conn.writer()
@@ -482,15 +878,17 @@ func (conn *Connection) worker() {
 		wg.Done()
 	}()
 
-	go func() {
-		err := conn.reader()
-		conn.setError(err)
-		conn.stop()
-		wg.Done()
-	}()
+	// the reader runs in the worker goroutine itself,
+	// so each connection costs two goroutines
+	err := conn.reader()
+	conn.setError(err)
+	conn.stop()
 
 	wg.Wait()
 
+	// the first error is final now, share it among all the failed requests
+	conn.closedError.Store(newConnectionClosedError(conn))
+
 	// release all pending packets
 	writeChan := conn.writeChan
 
@@ -508,8 +906,9 @@ CLEANUP_LOOP:
 		}
 	}
 
-	// send error reply to all pending requests
-	conn.requests.CleanUp(func(req *request) {
+	// send error reply to all pending requests,
+	// new requests are rejected from now on
+	conn.requests.Close(func(req *request) {
 		select {
 		case req.replyChan <- &AsyncResult{
 			Error:     ConnectionClosedError(conn),
@@ -527,18 +926,32 @@ CLEANUP_LOOP:
 func (conn *Connection) writer() (err error) {
 	writeChan := conn.writeChan
 	stopChan := conn.exit
-	w := bufio.NewWriterSize(conn.ccw, DefaultWriterBufSize)
+	w := bufio.NewWriterSize(&frameWriter{
+		w:       conn.ccw,
+		conn:    conn.tcpConn,
+		timeout: conn.writeTimeout,
+	}, DefaultWriterBufSize)
+
+	// requests buffered since the last successful flush,
+	// they are failed with the write error if the flush fails
+	var unflushed []uint64
 
 	wr := func(w io.Writer, req *request) error {
 		packet := req.packet
+		unflushed = append(unflushed, packet.packet.requestID)
 
 		if conn.perf.NetPacketsOut != nil {
 			conn.perf.NetPacketsOut.Add(1)
 		}
+
 		if conn.perf.QueryComplete != nil && req.opaque != nil {
 			req.startedAt = time.Now()
 		}
 
+		if conn.perf.QueueDelay != nil && !req.enqueuedAt.IsZero() {
+			conn.perf.QueueDelay(time.Since(req.enqueuedAt))
+		}
+
 		_, err := packet.WriteTo(w)
 		req.packet = nil
 		conn.releasePacket(packet)
@@ -561,6 +974,7 @@ WRITER_LOOP:
 			if err = w.Flush(); err != nil {
 				break WRITER_LOOP
 			}
+			unflushed = unflushed[:0]
 
 			// same without flush
 			select {
@@ -577,28 +991,116 @@ WRITER_LOOP:
 		}
 	}
 
+	if err != nil {
+		werr := newWriteError(conn, err)
+		for _, requestID := range unflushed {
+			conn.failRequest(requestID, ErrNoConnection, werr)
+		}
+	}
+
 	return
 }
 
+// pinger pings the server when nothing has been read for IdlePingInterval
+// and closes the connection if the ping fails.
+func (conn *Connection) pinger() {
+	atomic.StoreInt64(&conn.lastRead, time.Now().UnixNano())
+
+	timer := time.NewTimer(conn.idlePingInterval)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-conn.exit:
+			return
+		case <-timer.C:
+		}
+
+		idle := time.Since(time.Unix(0, atomic.LoadInt64(&conn.lastRead)))
+		if idle < conn.idlePingInterval {
+			timer.Reset(conn.idlePingInterval - idle)
+			continue
+		}
+
+		if res := conn.Exec(context.Background(), &Ping{}); res.Error != nil {
+			if !conn.IsClosed() {
+				conn.setError(fmt.Errorf("idle ping failed: %w", res.Error))
+				conn.stop()
+			}
+			return
+		}
+		timer.Reset(conn.idlePingInterval)
+	}
+}
+
+// failRequest delivers the error to the pending request, if it is still waiting for the reply.
+func (conn *Connection) failRequest(requestID uint64, errorCode uint, err error) bool {
+	req := conn.requests.Pop(requestID)
+	if req == nil {
+		return false
+	}
+
+	select {
+	case req.replyChan <- &AsyncResult{
+		Error:      err,
+		ErrorCode:  errorCode,
+		Connection: conn,
+		Opaque:     req.opaque,
+	}:
+	default:
+	}
+	return true
+}
+
 func (conn *Connection) reader() (err error) {
 	var pp *BinaryPacket
 	var requestID uint64
+	var code uint
 
 	r := bufio.NewReaderSize(conn.ccr, DefaultReaderBufSize)
 
 READER_LOOP:
 	for {
-		pp := packetPool.Get()
-		if requestID, err = pp.readRawPacket(r); err != nil {
+		// only the frame length and the sync are parsed here, the body is
+		// read into the pooled packet buffer and decoded by the caller
+		pp = packetPool.Get()
+		if requestID, code, err = pp.readRawPacket(r); err != nil {
 			break READER_LOOP
 		}
 
 		if conn.perf.NetPacketsIn != nil {
 			conn.perf.NetPacketsIn.Add(1)
 		}
+		if conn.idlePingInterval > 0 {
+			atomic.StoreInt64(&conn.lastRead, time.Now().UnixNano())
+		}
+
+		if code == ChunkCommand {
+			// pushes precede the reply, so the request stays pending
+			conn.handlePush(requestID, pp)
+			conn.releasePacket(pp)
+			pp = nil
+			continue
+		}
 
 		req := conn.requests.Pop(requestID)
+		if req == nil && requestID == 0 {
+			// events of the watched keys come with sync 0
+			if err = pp.packet.UnmarshalBinary(pp.body); err == nil && pp.packet.Cmd == EventCommand {
+				conn.handleEvent(pp.packet.Request.(*Event))
+				conn.releasePacket(pp)
+				pp = nil
+				continue
+			}
+			err = nil
+		}
 		if req == nil {
+			if conn.perf.OrphanReplies != nil {
+				conn.perf.OrphanReplies.Add(1)
+			}
+			if conn.perf.OrphanReply != nil {
+				conn.perf.OrphanReply(requestID)
+			}
 			conn.releasePacket(pp)
 			pp = nil
 			continue
@@ -610,9 +1112,10 @@ READER_LOOP:
 
 		select {
 		case req.replyChan <- &AsyncResult{0, nil, pp, conn, req.opaque}:
-			pp = nil
 		default:
+			conn.releasePacket(pp)
 		}
+		pp = nil
 
 		requestPool.Put(req)
 	}
@@ -2,21 +2,43 @@ package tarantool
 
 import (
 	"context"
+	"errors"
 	"net/url"
 	"sync"
+	"sync/atomic"
 )
 
+// RestartFn receives the instance UUIDs seen before and after a reconnect.
+type RestartFn func(oldUUID, newUUID string)
+
 type Connector struct {
 	sync.Mutex
 	RemoteAddr string
-	options    Options
-	conn       *Connection
+	// OnRestart is called if a new connection lands on a different server
+	// instance than the previous one, e.g. after a restart from scratch or
+	// a failover. The schema is loaded anew by every connection, so state
+	// the caller derived from the old instance is what needs invalidation.
+	// It is called with the Connector locked and must be set before the first Connect.
+	OnRestart    RestartFn
+	options      Options
+	conn         *Connection
+	instanceUUID string
+
+	// live is the established connection, it is read without locking
+	// in the fail fast mode while a reconnect is in progress
+	failFast     bool
+	live         atomic.Value // *Connection
+	reconnecting int32
 }
 
 // New Connector instance.
 func New(dsnString string, options *Options) *Connector {
 	if options != nil {
-		return &Connector{RemoteAddr: dsnString, options: *options}
+		return &Connector{
+			RemoteAddr: dsnString,
+			options:    options.clone(),
+			failFast:   options.FailFastWhenDisconnected,
+		}
 	}
 	return &Connector{RemoteAddr: dsnString}
 }
@@ -35,17 +57,84 @@ func (c *Connector) ConnectContext(ctx context.Context) (conn *Connection, err e
 		// clear possible user:pass in order to log c.RemoteAddr securely
 		c.RemoteAddr = dsn.Host
 		c.conn, err = connect(ctx, dsn.Scheme, dsn.Host, c.options)
+		if err == nil {
+			c.checkRestart(c.conn.InstanceUUID())
+		}
+		c.live.Store(c.conn)
 	}
 	conn = c.conn
 
 	return conn, err
 }
 
+func (c *Connector) checkRestart(uuid string) {
+	oldUUID := c.instanceUUID
+	c.instanceUUID = uuid
+	if oldUUID != "" && uuid != "" && oldUUID != uuid && c.OnRestart != nil {
+		c.OnRestart(oldUUID, uuid)
+	}
+}
+
 // Connect returns existing connection or will establish another one.
 func (c *Connector) Connect() (conn *Connection, err error) {
 	return c.ConnectContext(context.Background())
 }
 
+// Exec executes the query on the connection, establishing it if needed.
+// A query marked with IdempotentExecOption is re-submitted once on a new
+// connection if the connection is closed before the reply arrives,
+// other queries fail back to the caller with the connection error.
+func (c *Connector) Exec(ctx context.Context, q Query, options ...ExecOption) *Result {
+	replay := isIdempotent(options)
+
+	for {
+		var conn *Connection
+		var err error
+
+		if c.failFast && c.live.Load() == nil {
+			// nothing has been dialed yet, the first connection is waited for
+			conn, err = c.ConnectContext(ctx)
+		} else if c.failFast {
+			if conn = c.liveConn(); conn == nil {
+				return &Result{
+					Error:     ErrDisconnected,
+					ErrorCode: ErrNoConnection,
+				}
+			}
+		} else {
+			conn, err = c.ConnectContext(ctx)
+		}
+		if err != nil {
+			return &Result{
+				Error:     err,
+				ErrorCode: ErrNoConnection,
+			}
+		}
+
+		res := conn.Exec(ctx, q, options...)
+		if !replay || !errors.Is(res.Error, ErrConnectionClosed) {
+			return res
+		}
+		replay = false
+	}
+}
+
+// liveConn returns the established connection without waiting for the lock.
+// If the connection is down, it starts reconnecting in the background and returns nil.
+func (c *Connector) liveConn() *Connection {
+	if conn, _ := c.live.Load().(*Connection); conn != nil && !conn.IsClosed() {
+		return conn
+	}
+
+	if atomic.CompareAndSwapInt32(&c.reconnecting, 0, 1) {
+		go func() {
+			defer atomic.StoreInt32(&c.reconnecting, 0)
+			c.ConnectContext(context.Background())
+		}()
+	}
+	return nil
+}
+
 // Close underlying connection.
 func (c *Connector) Close() {
 	c.Lock()
@@ -54,4 +143,57 @@ func (c *Connector) Close() {
 		c.conn.Close()
 	}
 	c.conn = nil
+	c.live.Store(c.conn)
+}
+
+// ConnectAll establishes the connections of all the connectors, dialing at most
+// workers of them at once, so the startup time of a cluster client doesn't grow
+// with the number of nodes. Every connector is dialed even if some of them fail,
+// the first error is returned. Zero workers means all of them at once.
+func ConnectAll(ctx context.Context, connectors []*Connector, workers int) error {
+	if workers <= 0 || workers > len(connectors) {
+		workers = len(connectors)
+	}
+
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	next := make(chan *Connector)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range next {
+				if _, err := c.ConnectContext(ctx); err != nil {
+					once.Do(func() { firstErr = err })
+				}
+			}
+		}()
+	}
+	for _, c := range connectors {
+		next <- c
+	}
+	close(next)
+	wg.Wait()
+
+	return firstErr
+}
+
+// PickByHash returns the connector for the hash of a shard key, so the requests
+// with the same key always go to the same node. The hash is mapped with the jump
+// consistent hash, adding a connector to the end of the slice moves only
+// the keys which go to the new one. It returns nil if no connectors are given.
+func PickByHash(connectors []*Connector, hash uint64) *Connector {
+	if len(connectors) == 0 {
+		return nil
+	}
+
+	var b, j int64 = -1, 0
+	for j < int64(len(connectors)) {
+		b = j
+		hash = hash*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((hash>>33)+1)))
+	}
+	return connectors[b]
 }
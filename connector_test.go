@@ -0,0 +1,209 @@
+package tarantool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectorExecIdempotentReplay(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var mu sync.Mutex
+	var evals int
+	var drop *Connection
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if _, ok := q.(*Eval); !ok {
+			return &Result{}
+		}
+
+		mu.Lock()
+		evals++
+		conn := drop
+		drop = nil
+		mu.Unlock()
+
+		// the connection drops while the query is in flight
+		if conn != nil {
+			conn.tcpConn.Close()
+			<-conn.closed
+		}
+		return &Result{Data: [][]interface{}{{int64(1)}}}
+	})
+
+	c := New(addr, nil)
+	defer c.Close()
+
+	dropNext := func() *Connection {
+		conn, err := c.Connect()
+		require.NoError(err)
+		mu.Lock()
+		drop = conn
+		mu.Unlock()
+		return conn
+	}
+
+	first := dropNext()
+	res := c.Exec(context.Background(), &Eval{Expression: "return 1"})
+	assert.True(errors.Is(res.Error, ErrConnectionClosed))
+	assert.True(first.IsClosed())
+
+	dropNext()
+	res = c.Exec(context.Background(), &Eval{Expression: "return 1"}, IdempotentExecOption())
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(1)}}, res.Data)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(3, evals)
+}
+
+func TestConnectorOnRestart(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var mu sync.Mutex
+	uuid := testServerUUID
+	addr := newTestServerUUID(t, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return uuid
+	}, nil)
+
+	var restarts [][2]string
+	c := New(addr, nil)
+	c.OnRestart = func(oldUUID, newUUID string) {
+		restarts = append(restarts, [2]string{oldUUID, newUUID})
+	}
+	defer c.Close()
+
+	conn, err := c.Connect()
+	require.NoError(err)
+	assert.Equal(testServerUUID, conn.InstanceUUID())
+
+	// reconnect to the same instance
+	conn.Close()
+	_, err = c.Connect()
+	require.NoError(err)
+	assert.Empty(restarts)
+
+	mu.Lock()
+	uuid = "0d5bd431-7f3e-4695-a5c2-82de0a9cbc95"
+	mu.Unlock()
+
+	c.Close()
+	conn, err = c.Connect()
+	require.NoError(err)
+	assert.Equal([][2]string{{testServerUUID, uuid}}, restarts)
+}
+
+func TestConnectorFailFastFirstConnect(t *testing.T) {
+	assert := assert.New(t)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	c := New(addr, &Options{FailFastWhenDisconnected: true})
+	defer c.Close()
+
+	// the first request gets the dial error, the next ones fail fast
+	res := c.Exec(context.Background(), &Ping{})
+	assert.Error(res.Error)
+	assert.NotEqual(ErrDisconnected, res.Error)
+	assert.Equal(ErrNoConnection, res.ErrorCode)
+
+	res = c.Exec(context.Background(), &Ping{})
+	assert.Equal(ErrDisconnected, res.Error)
+}
+
+func TestConnectorFailFastWhenDisconnected(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	addr := newTestServer(t, nil)
+
+	c := New(addr, &Options{FailFastWhenDisconnected: true})
+	defer c.Close()
+
+	// the first request waits for the connection
+	assert.NoError(c.Exec(context.Background(), &Ping{}).Error)
+
+	conn, err := c.Connect()
+	require.NoError(err)
+	conn.Close()
+
+	// the next ones start reconnecting in the background
+	res := c.Exec(context.Background(), &Ping{})
+	assert.Equal(ErrDisconnected, res.Error)
+	assert.Equal(ErrNoConnection, res.ErrorCode)
+
+	require.Eventually(func() bool {
+		return c.Exec(context.Background(), &Ping{}).Error == nil
+	}, time.Second, time.Millisecond)
+}
+
+func TestConnectAll(t *testing.T) {
+	assert := assert.New(t)
+
+	var connectors []*Connector
+	for i := 0; i < 5; i++ {
+		c := New(newTestServer(t, nil), nil)
+		defer c.Close()
+		connectors = append(connectors, c)
+	}
+
+	assert.NoError(ConnectAll(context.Background(), connectors, 2))
+	for _, c := range connectors {
+		assert.NotNil(c.live.Load().(*Connection))
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	down := New(ln.Addr().String(), nil)
+	ln.Close()
+	defer down.Close()
+
+	// the failed node doesn't stop the others from connecting
+	up := New(newTestServer(t, nil), nil)
+	defer up.Close()
+	assert.Error(ConnectAll(context.Background(), []*Connector{down, up}, 0))
+	assert.NotNil(up.live.Load().(*Connection))
+
+	assert.NoError(ConnectAll(context.Background(), nil, 2))
+}
+
+func TestPickByHash(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Nil(PickByHash(nil, 1))
+
+	connectors := make([]*Connector, 10)
+	for i := range connectors {
+		connectors[i] = New(fmt.Sprintf("127.0.0.1:%d", 3301+i), nil)
+	}
+
+	hits := make(map[*Connector]int)
+	for key := uint64(0); key < 1000; key++ {
+		hash := key * 0x9e3779b97f4a7c15
+		c := PickByHash(connectors, hash)
+		assert.Equal(c, PickByHash(connectors, hash))
+		hits[c]++
+
+		// adding a node only moves the keys to it
+		if prev := PickByHash(connectors[:9], hash); prev != c {
+			assert.Equal(connectors[9], c)
+		}
+	}
+	assert.Len(hits, len(connectors))
+}
@@ -16,13 +16,23 @@ const (
 	EvalCommand          = uint(8)
 	UpsertCommand        = uint(9)
 	Call17Command        = uint(10) // Tarantool >= 1.7.2
+	ExecuteCommand       = uint(11) // Tarantool >= 2.1.0
+	PrepareCommand       = uint(13) // Tarantool >= 2.3.1
+	BeginCommand         = uint(14) // Tarantool >= 2.10.0
+	CommitCommand        = uint(15) // Tarantool >= 2.10.0
+	RollbackCommand      = uint(16) // Tarantool >= 2.10.0
 	PingCommand          = uint(64)
 	JoinCommand          = uint(65)
 	SubscribeCommand     = uint(66)
 	VoteCommand          = uint(68) // Tarantool >= 1.9.0
 	FetchSnapshotCommand = uint(69) // for starting anonymous replication. Tarantool >= 2.3.1
 	RegisterCommand      = uint(70) // for leaving anonymous replication (anon => normal replica). Tarantool >= 2.3.1
+	WatchCommand         = uint(74) // Tarantool >= 2.10.0
+	UnwatchCommand       = uint(75) // Tarantool >= 2.10.0
+	EventCommand         = uint(76) // Tarantool >= 2.10.0
 	ErrorFlag            = uint(0x8000)
+
+	ChunkCommand = uint(0x80) // box.session.push before the reply, Tarantool >= 1.10.0
 )
 
 const (
@@ -32,6 +42,7 @@ const (
 	KeyLSN            = uint(0x03)
 	KeyTimestamp      = uint(0x04)
 	KeySchemaID       = uint(0x05)
+	KeyStreamID       = uint(0x0a) // Tarantool >= 2.10.0
 	KeySpaceNo        = uint(0x10)
 	KeyIndexNo        = uint(0x11)
 	KeyLimit          = uint(0x12)
@@ -47,9 +58,21 @@ const (
 	KeyExpression     = uint(0x27)
 	KeyDefTuple       = uint(0x28)
 	KeyBallot         = uint(0x29) // Tarantool >= 1.9.0
+	KeyOptions        = uint(0x2b) // Tarantool >= 2.1.0
 	KeyData           = uint(0x30)
 	KeyError          = uint(0x31)
+	KeyMetadata       = uint(0x32) // Tarantool >= 2.1.0
+	KeyBindMetadata   = uint(0x33) // Tarantool >= 2.3.1
+	KeyBindCount      = uint(0x34) // Tarantool >= 2.3.1
+	KeySQLText        = uint(0x40) // Tarantool >= 2.1.0
+	KeySQLBind        = uint(0x41) // Tarantool >= 2.1.0
+	KeySQLInfo        = uint(0x42) // Tarantool >= 2.1.0
+	KeyStmtID         = uint(0x43) // Tarantool >= 2.3.1
 	KeyReplicaAnon    = uint(0x50) // Tarantool >= 2.3.1
+	KeyTimeout        = uint(0x56) // Tarantool >= 2.10.0
+	KeyEventKey       = uint(0x57) // Tarantool >= 2.10.0
+	KeyEventData      = uint(0x58) // Tarantool >= 2.10.0
+	KeyTxnIsolation   = uint(0x59) // Tarantool >= 2.10.0
 )
 
 const (
@@ -199,6 +222,9 @@ const (
 	ErrWrongIndexOptions             = uint(0x6c) // Wrong index options (field %u): %s
 	ErrWrongSchemaVaersion           = uint(0x6d) // Wrong schema version, current: %d, in request: %u
 	ErrSlabAllocMax                  = uint(0x6e) // Failed to allocate %u bytes for tuple in the slab allocator: tuple is too large. Check 'slab_alloc_maximal' configuration option.
+	ErrSyncMasterMismatch            = uint(0xd7) // CONFIRM message arrived for an unknown master id %d, expected %d
+	ErrSyncQuorumTimeout             = uint(0xd8) // Quorum collection for a synchronous transaction is timed out
+	ErrSyncRollback                  = uint(0xd9) // A rollback for a synchronous transaction is received
 	ErrXLogGap                       = uint(0xdb) // Missing .xlog file between LSN %lld %s and %lld %s
 )
 
@@ -0,0 +1,81 @@
+package tarantool
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// readArrayHeaderBytes is msgp.ReadArrayHeaderBytes which does not trust the
+// array length: every element takes at least one byte, so a malformed header
+// claiming more elements than the bytes left is rejected before anything
+// is allocated for them.
+func readArrayHeaderBytes(b []byte) (sz uint32, o []byte, err error) {
+	if sz, o, err = msgp.ReadArrayHeaderBytes(b); err != nil {
+		return
+	}
+	if uint64(sz) > uint64(len(o)) {
+		return 0, b, msgp.ErrShortBytes
+	}
+	return
+}
+
+// readMapHeaderBytes is readArrayHeaderBytes for maps, which take at least
+// two bytes per entry.
+func readMapHeaderBytes(b []byte) (sz uint32, o []byte, err error) {
+	if sz, o, err = msgp.ReadMapHeaderBytes(b); err != nil {
+		return
+	}
+	if 2*uint64(sz) > uint64(len(o)) {
+		return 0, b, msgp.ErrShortBytes
+	}
+	return
+}
+
+// readIntfBytes is msgp.ReadIntfBytes with the container lengths checked
+// against the data, so a truncated or malformed reply fails with an error
+// instead of making the decoder allocate gigabytes.
+func readIntfBytes(b []byte) (i interface{}, o []byte, err error) {
+	switch msgp.NextType(b) {
+	case msgp.ArrayType:
+		var sz uint32
+		if sz, o, err = readArrayHeaderBytes(b); err != nil {
+			return
+		}
+		j := make([]interface{}, sz)
+		for d := range j {
+			if j[d], o, err = readIntfBytes(o); err != nil {
+				return
+			}
+		}
+		return j, o, nil
+	case msgp.MapType:
+		var sz uint32
+		if sz, o, err = readMapHeaderBytes(b); err != nil {
+			return
+		}
+		m := make(map[string]interface{}, sz)
+		for ; sz > 0; sz-- {
+			var key []byte
+			if key, o, err = msgp.ReadMapKeyZC(o); err != nil {
+				return
+			}
+			var val interface{}
+			if val, o, err = readIntfBytes(o); err != nil {
+				return
+			}
+			m[string(key)] = val
+		}
+		return m, o, nil
+	default:
+		return msgp.ReadIntfBytes(b)
+	}
+}
+
+// tupleString returns the i-th tuple field if it is a string, or "" otherwise.
+func tupleString(tuple []interface{}, i int) string {
+	if i < len(tuple) {
+		if s, ok := tuple[i].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+//go:build go1.18
+// +build go1.18
+
+package tarantool
+
+import (
+	"bytes"
+	"testing"
+)
+
+func fuzzSeeds(f *testing.F) [][]byte {
+	return [][]byte{
+		packedFrame(f, &Result{Data: [][]interface{}{{int64(1), "a"}, {uint64(2), []interface{}{true, nil}}}}),
+		packedFrame(f, &Result{Data: [][]interface{}{{map[string]interface{}{"a": 1.5}}}}),
+		packedFrame(f, &Result{
+			Metadata: []SQLColumn{{Name: "ID", Type: "integer"}, {Name: "NAME", Type: "string"}},
+			Data:     [][]interface{}{{int64(1), "a"}},
+		}),
+		packedFrame(f, &Result{SQLInfo: &SQLInfo{RowCount: 2, AutoincrementIDs: []int64{1, 2}}}),
+		packedFrame(f, &Result{ErrorCode: ErrTupleFound, Error: NewQueryError(ErrTupleFound, "Duplicate key exists")}),
+		packedFrame(f, &Insert{Space: uint(512), Tuple: []interface{}{int64(1), "a"}}),
+		packedFrame(f, &Select{Space: uint(512), Index: uint(0), KeyTuple: []interface{}{int64(1)}, Limit: 10}),
+		packedFrame(f, &Update{Space: uint(512), Key: int64(1), Set: []Operator{&OpAdd{Field: 1, Argument: 2}, &OpSplice{Field: 2, Argument: "x"}}}),
+		packedFrame(f, &Upsert{Space: uint(512), Tuple: []interface{}{int64(1)}, Set: []Operator{&OpAssign{Field: 1, Argument: "x"}}}),
+		packedFrame(f, &Delete{Space: uint(512), Key: int64(1)}),
+		packedFrame(f, &Call17{Name: "f", Tuple: []interface{}{int64(1)}}),
+		packedFrame(f, &Eval{Expression: "return ...", Tuple: []interface{}{"a"}}),
+		packedFrame(f, &VClock{VClock: VectorClock{0, 10, 20}}),
+	}
+}
+
+// FuzzReadPacket feeds the reader with arbitrary frames, which must be
+// either decoded or rejected with an error, but never panic.
+func FuzzReadPacket(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, frame []byte) {
+		pp := &BinaryPacket{}
+		if _, _, err := pp.readRawPacket(bytes.NewReader(frame)); err != nil {
+			return
+		}
+		body := append([]byte(nil), pp.body...)
+
+		pp.Unmarshal()
+
+		pp.body = body
+		pp.UnmarshalTuples(func([]byte) error { return nil })
+
+		pp.body = body
+		new(VClock).UnmarshalMsg(body)
+		new(SubscribeResponse).UnmarshalMsg(body)
+	})
+}
+
+// FuzzDecodeTuple checks the tuple decoders with arbitrary msgpack values.
+func FuzzDecodeTuple(f *testing.F) {
+	for _, seed := range fuzzSeeds(f) {
+		f.Add(seed[5:])
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var a int64
+		var b string
+		var c []byte
+		var d map[string]interface{}
+		TupleDecoder{}.DecodeRaw(data, &a, &b, &c, &d)
+		TupleDecoder{Strict: true}.DecodeRaw(data, &a, &b, &c, &d)
+
+		tuple := RawTuple(data)
+		if n, err := tuple.Len(); err == nil {
+			for i := 0; i < n && i < 4; i++ {
+				tuple.Field(i)
+				tuple.IntField(i)
+				tuple.StringField(i)
+			}
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package tarantool
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// packedFrame returns the query packed as it is sent over the wire
+func packedFrame(t testing.TB, q Query) []byte {
+	pp := packetPool.GetWithID(1)
+	defer pp.Release()
+
+	require.NoError(t, pp.packMsg(q, newPackData(nil)))
+
+	var frame bytes.Buffer
+	_, err := pp.WriteTo(&frame)
+	require.NoError(t, err)
+	return frame.Bytes()
+}
+
+// packedBody returns the packet body without the length prefix
+func packedBody(t testing.TB, q Query) []byte {
+	pp := &BinaryPacket{}
+	_, err := pp.ReadFrom(bytes.NewReader(packedFrame(t, q)))
+	require.NoError(t, err)
+	return pp.body
+}
+
+func TestReadIntfBytes(t *testing.T) {
+	assert := assert.New(t)
+
+	data, err := msgp.AppendIntf(nil, []interface{}{int64(-1), "a", map[string]interface{}{"b": []interface{}{true, nil}}})
+	require.NoError(t, err)
+
+	v, rest, err := readIntfBytes(data)
+	assert.NoError(err)
+	assert.Empty(rest)
+	assert.Equal([]interface{}{int64(-1), "a", map[string]interface{}{"b": []interface{}{true, nil}}}, v)
+
+	// the lengths claim much more than there is
+	for _, data := range [][]byte{
+		{0xdd, 0xff, 0xff, 0xff, 0xff, 0x01},
+		{0xdf, 0xff, 0xff, 0xff, 0xff, 0x01, 0x01},
+		{0x91, 0xdd, 0xff, 0xff, 0xff, 0xff},
+	} {
+		_, _, err = readIntfBytes(data)
+		assert.Equal(msgp.ErrShortBytes, err, "%x", data)
+	}
+}
+
+func TestDecodeMalformedPacket(t *testing.T) {
+	insert := packedBody(t, &Insert{Space: uint(512), Tuple: []interface{}{int64(1), "a"}})
+	result := packedBody(t, &Result{Data: [][]interface{}{{int64(1), "a"}, {int64(2), "b"}}})
+	execute := packedBody(t, &Result{
+		Metadata: []SQLColumn{{Name: "ID", Type: "integer"}},
+		SQLInfo:  &SQLInfo{RowCount: 1, AutoincrementIDs: []int64{1}},
+	})
+
+	cases := map[string][]byte{
+		"truncated insert": insert[:len(insert)-3],
+		"truncated result": result[:len(result)-3],
+		// the tuple is replaced with nil
+		"nil tuple": bytes.Replace(insert, []byte{0x92, 0x01, 0xa1, 'a'}, []byte{0xc0, 0xc0, 0xc0, 0xc0}, 1),
+		// the data array claims 2^32-1 tuples
+		"huge result": bytes.Replace(result, []byte{0x92, 0x92}, []byte{0xdd, 0xff, 0xff, 0xff, 0xff, 0x92}, 1),
+		// the metadata claims 2^32-1 columns
+		"huge metadata": bytes.Replace(execute, []byte{0x32, 0x91}, []byte{0x32, 0xdd, 0xff, 0xff, 0xff, 0xff}, 1),
+		// the autoincrement ids claim 2^32-1 ids
+		"huge sql info": bytes.Replace(execute, []byte{0x01, 0x91, 0x01}, []byte{0x01, 0xdd, 0xff, 0xff, 0xff, 0xff, 0x01}, 1),
+	}
+
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			pp := &BinaryPacket{body: body}
+			err := pp.Unmarshal()
+			require.Error(t, err)
+
+			var decodeErr *DecodeError
+			assert.True(t, errors.As(err, &decodeErr))
+		})
+	}
+}
+
+func TestReadPacketCorruptedLength(t *testing.T) {
+	assert := assert.New(t)
+
+	// the length claims 4GB while the stream only has a few bytes
+	pp := &BinaryPacket{}
+	_, err := pp.ReadFrom(bytes.NewReader([]byte{0xce, 0xff, 0xff, 0xff, 0xff, 0x80}))
+	assert.Equal(io.ErrUnexpectedEOF, err)
+	assert.True(cap(pp.body) <= bodyReadChunk)
+
+	// the bodies larger than the buffer are read in full
+	body := bytes.Repeat([]byte{0xc0}, 3*bodyReadChunk+1)
+	frame := append([]byte{0xce, 0, 0x03, 0, 0x01}, body...)
+	_, err = pp.ReadFrom(bytes.NewReader(frame))
+	assert.NoError(err)
+	assert.Equal(body, pp.body)
+}
+
+func TestParseSchemaMalformed(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := parseSchema([][]interface{}{{uint64(512), uint64(1)}}, nil)
+	assert.True(errors.Is(err, ErrBadSchema))
+
+	_, err = parseSchema([][]interface{}{{uint64(512), uint64(1), nil}}, nil)
+	assert.True(errors.Is(err, ErrBadSchema))
+
+	_, err = parseSchema(nil, [][]interface{}{
+		{uint64(512), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, []interface{}{"bad"}},
+	})
+	assert.True(errors.Is(err, ErrBadSchema))
+
+	// unknown attributes are tolerated
+	sc, err := parseSchema(nil, [][]interface{}{
+		{uint64(512), uint64(0), "primary", "tree", nil, nil},
+	})
+	assert.NoError(err)
+	assert.Equal(uint64(0), sc.indexMap[512]["primary"])
+}
@@ -8,9 +8,16 @@ const (
 
 	DefaultConnectTimeout = time.Second
 	DefaultQueryTimeout   = time.Second
+
+	DefaultQueueWaitTimeout = 10 * time.Millisecond
+
+	DefaultVClockPollInterval = 10 * time.Millisecond
+	DefaultLagPollInterval    = time.Second
 )
 
 var (
 	DefaultReaderBufSize = 128 * 1024
 	DefaultWriterBufSize = 4 * 1024
+
+	DefaultWriteQueueSize = 256
 )
@@ -39,7 +39,7 @@ func (q *Delete) packMsg(data *packData, o []byte) ([]byte, error) {
 		}
 	} else if q.KeyTuple != nil {
 		o = msgp.AppendUint(o, KeyKey)
-		if o, err = msgp.AppendIntf(o, q.KeyTuple); err != nil {
+		if o, err = appendTuple(o, q.KeyTuple); err != nil {
 			return o, err
 		}
 	}
@@ -83,8 +83,10 @@ func (q *Delete) UnmarshalMsg(data []byte) (buf []byte, err error) {
 				return
 			}
 		case KeyKey:
-			t, buf, err = msgp.ReadIntfBytes(buf)
-			if q.KeyTuple = t.([]interface{}); q.KeyTuple == nil {
+			if t, buf, err = readIntfBytes(buf); err != nil {
+				return
+			}
+			if q.KeyTuple, _ = t.([]interface{}); q.KeyTuple == nil {
 				return buf, errors.New("interface type is not []interface{}")
 			}
 
@@ -4,6 +4,9 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
+	"strings"
+	"time"
 )
 
 var (
@@ -21,9 +24,50 @@ var (
 	ErrOldVersionAnon = errors.New("tarantool version is too old for anonymous replication. Min version is 2.3.1")
 
 	// ErrConnectionClosed returns when connection is no longer alive.
+	// Errors of requests failed due to the closed connection match it with errors.Is.
 	ErrConnectionClosed = errors.New("connection closed")
+	// ErrRequestTimeout is matched with errors.Is by errors of requests which have not
+	// been sent or answered in time. The name differs from the ErrTimeout error code.
+	ErrRequestTimeout = &temporaryError{message: "request timeout", timeout: true}
+	// ErrWriteNotConfirmed is matched with errors.Is by the errors of synchronous transactions
+	// which have not been confirmed by the replication quorum in time. Such a write
+	// may still be applied later when the synchro queue is resolved, so like the errors
+	// reporting MaybeApplied it should be retried only if it is idempotent.
+	ErrWriteNotConfirmed = errors.New("write not confirmed by the replication quorum")
+	// ErrDisconnected is returned by Connector.Exec with Options.FailFastWhenDisconnected
+	// while the connection is down.
+	ErrDisconnected = &temporaryError{message: "disconnected"}
+	// ErrNoReplicas is returned by ReadAfter if no replicas are given.
+	ErrNoReplicas = errors.New("no replicas to read from")
+	// ErrQueueFull is returned when the write queue is full and the request can not
+	// be queued according to Options.QueueFullPolicy.
+	ErrQueueFull = &temporaryError{message: "write queue is full"}
+	// ErrSyncCollision is returned to a request whose sync has been reused by a new request.
+	// Syncs are 64-bit and never reused while a request is pending unless it is stuck
+	// for the whole wraparound period, so the old request is failed in favor of the new one.
+	ErrSyncCollision = &temporaryError{message: "request sync collision, the request is stuck", timeout: true}
 )
 
+// temporaryError is a sentinel error worth retrying.
+type temporaryError struct {
+	message string
+	timeout bool
+}
+
+func (e *temporaryError) Error() string {
+	return e.message
+}
+
+// Temporary implements Error interface.
+func (e *temporaryError) Temporary() bool {
+	return true
+}
+
+// Timeout implements net.Error interface.
+func (e *temporaryError) Timeout() bool {
+	return e.timeout
+}
+
 // Error has Temporary method which returns true if error is temporary.
 // It is useful to quickly decide retry or not retry.
 type Error interface {
@@ -45,7 +89,15 @@ func NewConnectionError(con *Connection, err error) *ConnectionError {
 
 // ConnectionClosedError returns ConnectionError with message about closed connection
 // or error depending on the connection state. It is also has remoteAddr in error text.
+// Once the connection is shut down, the same error value is returned to all callers.
 func ConnectionClosedError(con *Connection) *ConnectionError {
+	if err, ok := con.closedError.Load().(*ConnectionError); ok {
+		return err
+	}
+	return newConnectionClosedError(con)
+}
+
+func newConnectionClosedError(con *Connection) *ConnectionError {
 	var err = ErrConnectionClosed
 	if connErr := con.getError(); connErr != nil {
 		err = fmt.Errorf("%w: %v", err, connErr.Error())
@@ -53,29 +105,97 @@ func ConnectionClosedError(con *Connection) *ConnectionError {
 	return NewConnectionError(con, err)
 }
 
+// Unwrap returns the underlying error, e.g. ErrConnectionClosed.
+func (e *ConnectionError) Unwrap() error {
+	return e.error
+}
+
 // Temporary implements Error interface.
 func (e *ConnectionError) Temporary() bool {
 	return !errors.Is(e.error, ErrConnectionClosed)
 }
 
 // Timeout implements net.Error interface.
+// It is true if the network operation has timed out, e.g. dial.
 func (e *ConnectionError) Timeout() bool {
-	return false
+	var ne net.Error
+	return errors.As(e.error, &ne) && ne.Timeout()
 }
 
 // ContextError is returned when request has been ended with context timeout or cancel.
 type ContextError struct {
-	error
-	CtxErr error
+	CtxErr       error
+	message      string
+	remoteAddr   string
+	maybeApplied bool
 }
 
 // NewContextError returns ContextError with message and remoteAddr in error text.
 // It is also has context error itself in CtxErr.
 func NewContextError(ctx context.Context, con *Connection, message string) *ContextError {
 	return &ContextError{
-		error:  fmt.Errorf("%s: %s, remote: %s", message, ctx.Err(), con.remoteAddr),
-		CtxErr: ctx.Err(),
+		CtxErr:     ctx.Err(),
+		message:    message,
+		remoteAddr: con.remoteAddr,
+	}
+}
+
+// Error implements error interface. The text is formatted on demand,
+// so failing requests can share preallocated errors cheaply.
+func (e *ContextError) Error() string {
+	return fmt.Sprintf("%s: %s, remote: %s", e.message, e.CtxErr, e.remoteAddr)
+}
+
+// MaybeApplied returns true if the request has been queued for writing before
+// the context ended, so the server may have executed it. It is false if the
+// request has never left the queue and it is safe to retry it.
+func (e *ContextError) MaybeApplied() bool {
+	return e.maybeApplied
+}
+
+// Unwrap returns the context error.
+func (e *ContextError) Unwrap() error {
+	return e.CtxErr
+}
+
+// Is reports the expired request as ErrRequestTimeout.
+func (e *ContextError) Is(target error) bool {
+	return target == ErrRequestTimeout && e.CtxErr == context.DeadlineExceeded
+}
+
+// requestErrors are allocated once per connection and returned to
+// every request which fails to be sent or to receive the reply in time,
+// so error storms do not add garbage.
+type requestErrors struct {
+	sendTimeout  ContextError
+	sendCanceled ContextError
+	recvTimeout  ContextError
+	recvCanceled ContextError
+}
+
+func newRequestErrors(remoteAddr string) *requestErrors {
+	return &requestErrors{
+		sendTimeout:  ContextError{CtxErr: context.DeadlineExceeded, message: "Send error", remoteAddr: remoteAddr},
+		sendCanceled: ContextError{CtxErr: context.Canceled, message: "Send error", remoteAddr: remoteAddr},
+		recvTimeout:  ContextError{CtxErr: context.DeadlineExceeded, message: "Recv error", remoteAddr: remoteAddr, maybeApplied: true},
+		recvCanceled: ContextError{CtxErr: context.Canceled, message: "Recv error", remoteAddr: remoteAddr, maybeApplied: true},
+	}
+}
+
+// send returns the error for a request which has not been queued for writing.
+func (e *requestErrors) send(ctxErr error) *ContextError {
+	if ctxErr == context.DeadlineExceeded {
+		return &e.sendTimeout
+	}
+	return &e.sendCanceled
+}
+
+// recv returns the error for a request which has not received the reply.
+func (e *requestErrors) recv(ctxErr error) *ContextError {
+	if ctxErr == context.DeadlineExceeded {
+		return &e.recvTimeout
 	}
+	return &e.recvCanceled
 }
 
 // Temporary implements Error interface.
@@ -88,11 +208,91 @@ func (e *ContextError) Timeout() bool {
 	return e.CtxErr == context.DeadlineExceeded
 }
 
+// WriteError is returned to the requests which have failed to be written
+// to the connection. The connection is shut down after that.
+type WriteError struct {
+	error
+	Err error // the error of the network connection
+}
+
+func newWriteError(con *Connection, err error) *WriteError {
+	return &WriteError{
+		error: fmt.Errorf("write error: %s, remote: %s", err, con.remoteAddr),
+		Err:   err,
+	}
+}
+
+// Unwrap returns the error of the network connection.
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// MaybeApplied returns true since a part of the requests may have reached the server.
+func (e *WriteError) MaybeApplied() bool {
+	return true
+}
+
+// Is reports the write error as ErrConnectionClosed since the connection is shut down.
+func (e *WriteError) Is(target error) bool {
+	return target == ErrConnectionClosed
+}
+
+// Temporary implements Error interface.
+func (e *WriteError) Temporary() bool {
+	return true
+}
+
+// Timeout implements net.Error interface.
+func (e *WriteError) Timeout() bool {
+	var ne net.Error
+	return errors.As(e.Err, &ne) && ne.Timeout()
+}
+
 // QueryError is returned when query error has been happened.
 // It has error Code.
 type QueryError struct {
 	error
 	Code uint
+	// Request describes the failed request returned by Connection.Exec,
+	// it is nil for errors which have not been returned by the server.
+	Request *RequestDetails
+}
+
+// RequestDetails describes the request which has caused the server error.
+type RequestDetails struct {
+	Kind    string // e.g. "insert" or "call"
+	Space   string // space name, or number if it is not in the schema
+	Index   string // index name, or number if it is not in the schema
+	Func    string // called function name
+	Elapsed time.Duration
+}
+
+func (d *RequestDetails) String() string {
+	var b strings.Builder
+	b.WriteString(d.Kind)
+	if d.Func != "" {
+		b.WriteString(" func=")
+		b.WriteString(d.Func)
+	}
+	if d.Space != "" {
+		b.WriteString(" space=")
+		b.WriteString(d.Space)
+	}
+	if d.Index != "" {
+		b.WriteString(" index=")
+		b.WriteString(d.Index)
+	}
+	b.WriteString(" elapsed=")
+	b.WriteString(d.Elapsed.String())
+	return b.String()
+}
+
+// Error implements error interface, adding request details if they are known.
+func (e *QueryError) Error() string {
+	if e.Request == nil {
+		return e.error.Error()
+	}
+	return fmt.Sprintf("%s [%s]", e.error, e.Request)
 }
 
 // NewQueryError returns QueryError with message and Code.
@@ -103,11 +303,26 @@ func NewQueryError(code uint, message string) *QueryError {
 	}
 }
 
+// Is reports the quorum errors of synchronous replication as ErrWriteNotConfirmed.
+func (e *QueryError) Is(target error) bool {
+	return target == ErrWriteNotConfirmed && isSyncQuorumError(e.Code)
+}
+
 // Temporary implements Error interface.
 func (e *QueryError) Temporary() bool {
 	return false
 }
 
+// MaybeApplied returns true for the quorum errors of synchronous replication
+// since the write may still be confirmed later.
+func (e *QueryError) MaybeApplied() bool {
+	return isSyncQuorumError(e.Code)
+}
+
+func isSyncQuorumError(code uint) bool {
+	return code == ErrSyncQuorumTimeout || code == ErrSyncRollback
+}
+
 // Timeout implements net.Error interface.
 func (e *QueryError) Timeout() bool {
 	return false
@@ -136,7 +351,32 @@ func (e *UnexpectedReplicaSetUUIDError) Is(target error) bool {
 	return ok
 }
 
+// DecodeError is returned when a packet can not be decoded,
+// e.g. it is truncated or malformed.
+type DecodeError struct {
+	Cmd uint // packet type, if the header has been decoded
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Error decoding packet type %d: %s", e.Cmd, e.Err)
+}
+
+// Unwrap returns the underlying msgpack error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
 var _ Error = (*ConnectionError)(nil)
 var _ Error = (*QueryError)(nil)
 var _ Error = (*ContextError)(nil)
+var _ Error = (*WriteError)(nil)
 var _ Error = (*UnexpectedReplicaSetUUIDError)(nil)
+var _ Error = (*temporaryError)(nil)
+
+// all the errors can be classified by generic net.Error aware retry helpers
+var _ net.Error = (*ConnectionError)(nil)
+var _ net.Error = (*QueryError)(nil)
+var _ net.Error = (*ContextError)(nil)
+var _ net.Error = (*WriteError)(nil)
+var _ net.Error = (*temporaryError)(nil)
@@ -0,0 +1,88 @@
+package tarantool
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorsNetError(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{remoteAddr: "test", firstErrorLock: new(sync.Mutex)}
+	errs := newRequestErrors("test")
+
+	_, dialErr := net.DialTimeout("tcp", "10.255.255.1:3301", time.Nanosecond)
+
+	tests := []struct {
+		name      string
+		err       error
+		temporary bool
+		timeout   bool
+	}{
+		{"send timeout", errs.send(context.DeadlineExceeded), true, true},
+		{"recv canceled", errs.recv(context.Canceled), true, false},
+		{"queue full", ErrQueueFull, true, false},
+		{"sync collision", ErrSyncCollision, true, true},
+		{"closed", ConnectionClosedError(conn), false, false},
+		{"dial timeout", NewConnectionError(conn, dialErr), true, true},
+		{"write", newWriteError(conn, errors.New("broken pipe")), true, false},
+		{"query", NewQueryError(ErrNoSuchSpace, "no such space"), false, false},
+		{"quorum timeout", NewQueryError(ErrSyncQuorumTimeout, "Quorum collection for a synchronous transaction is timed out"), false, false},
+		{"sync rollback", NewQueryError(ErrSyncRollback, "A rollback for a synchronous transaction is received"), false, false},
+	}
+
+	for _, tc := range tests {
+		var ne net.Error
+		if assert.True(errors.As(tc.err, &ne), tc.name) {
+			assert.Equal(tc.temporary, ne.Temporary(), tc.name)
+			assert.Equal(tc.timeout, ne.Timeout(), tc.name)
+		}
+	}
+}
+
+func TestErrorsSentinels(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{remoteAddr: "test", firstErrorLock: new(sync.Mutex)}
+	errs := newRequestErrors("test")
+
+	assert.True(errors.Is(ConnectionClosedError(conn), ErrConnectionClosed))
+	assert.True(errors.Is(newWriteError(conn, errors.New("broken pipe")), ErrConnectionClosed))
+
+	assert.True(errors.Is(errs.send(context.DeadlineExceeded), ErrRequestTimeout))
+	assert.True(errors.Is(errs.recv(context.DeadlineExceeded), ErrRequestTimeout))
+	assert.True(errors.Is(errs.recv(context.DeadlineExceeded), context.DeadlineExceeded))
+	assert.False(errors.Is(errs.recv(context.Canceled), ErrRequestTimeout))
+	assert.True(errors.Is(errs.recv(context.Canceled), context.Canceled))
+
+	assert.False(errors.Is(NewQueryError(ErrTimeout, "timeout"), ErrRequestTimeout))
+	assert.True(errors.Is(NewQueryError(ErrSyncQuorumTimeout, "timeout"), ErrWriteNotConfirmed))
+	assert.True(errors.Is(NewQueryError(ErrSyncRollback, "rollback"), ErrWriteNotConfirmed))
+	assert.False(errors.Is(NewQueryError(ErrReadonly, "readonly"), ErrWriteNotConfirmed))
+	assert.False(errors.Is(ConnectionClosedError(conn), ErrRequestTimeout))
+}
+
+func TestErrorsMaybeApplied(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{remoteAddr: "test"}
+	errs := newRequestErrors("test")
+
+	assert.False(errs.send(context.DeadlineExceeded).MaybeApplied())
+	assert.False(errs.send(context.Canceled).MaybeApplied())
+	assert.True(errs.recv(context.DeadlineExceeded).MaybeApplied())
+	assert.True(errs.recv(context.Canceled).MaybeApplied())
+	assert.True(newWriteError(conn, errors.New("broken pipe")).MaybeApplied())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(NewContextError(ctx, conn, "Send error").MaybeApplied())
+	assert.True(NewQueryError(ErrSyncQuorumTimeout, "timeout").MaybeApplied())
+	assert.False(NewQueryError(ErrReadonly, "readonly").MaybeApplied())
+}
@@ -31,7 +31,7 @@ func (q *Eval) MarshalMsg(b []byte) (o []byte, err error) {
 		o = msgp.AppendArrayHeader(o, 0)
 	} else {
 		o = msgp.AppendUint(o, KeyTuple)
-		if o, err = msgp.AppendIntf(o, q.Tuple); err != nil {
+		if o, err = appendTuple(o, q.Tuple); err != nil {
 			return o, err
 		}
 	}
@@ -65,8 +65,10 @@ func (q *Eval) UnmarshalMsg(data []byte) (buf []byte, err error) {
 				return
 			}
 		case KeyTuple:
-			t, buf, err = msgp.ReadIntfBytes(buf)
-			if q.Tuple = t.([]interface{}); q.Tuple == nil {
+			if t, buf, err = readIntfBytes(buf); err != nil {
+				return
+			}
+			if q.Tuple, _ = t.([]interface{}); q.Tuple == nil {
 				return buf, errors.New("interface type is not []interface{}")
 			}
 			if len(q.Tuple) == 0 {
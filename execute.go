@@ -2,6 +2,8 @@ package tarantool
 
 import (
 	"context"
+	"fmt"
+	"time"
 )
 
 type ExecOption interface {
@@ -20,8 +22,44 @@ func OpaqueExecOption(opaque interface{}) ExecOption {
 	return &opaqueOption{opaque: opaque}
 }
 
+type idempotentOption struct{}
+
+// apply does nothing, the option is only inspected by Connector.Exec
+func (o idempotentOption) apply(r *request) {}
+
+// IdempotentExecOption marks the query as safe to be executed more than once.
+// Connector.Exec re-submits such a query on a new connection if the connection
+// drops while the query is in flight.
+func IdempotentExecOption() ExecOption {
+	return idempotentOption{}
+}
+
+type streamOption struct {
+	streamID uint64
+}
+
+func (o *streamOption) apply(r *request) {
+	r.streamID = o.streamID
+}
+
+// StreamExecOption executes the query in the stream, the queries of a stream
+// are executed by the server one by one in order, Tarantool >= 2.10.0.
+// Use Connection.NewStreamID to get the id of a new stream.
+func StreamExecOption(streamID uint64) ExecOption {
+	return &streamOption{streamID: streamID}
+}
+
+func isIdempotent(options []ExecOption) bool {
+	for _, o := range options {
+		if _, ok := o.(idempotentOption); ok {
+			return true
+		}
+	}
+	return false
+}
+
 // the Result type is used to return write errors here
-func (conn *Connection) writeRequest(ctx context.Context, request *request, q Query) (*request, *Result, uint64) {
+func (conn *Connection) writeRequest(ctx context.Context, timeout <-chan struct{}, request *request, q Query) (*request, *Result, uint64) {
 	var err error
 
 	requestID := conn.nextID()
@@ -34,45 +72,98 @@ func (conn *Connection) writeRequest(ctx context.Context, request *request, q Qu
 			ErrorCode: ErrInvalidMsgpack,
 		}, 0
 	}
+	pp.packet.StreamID = request.streamID
 
 	request.packet = pp
+	if conn.perf.QueueDelay != nil {
+		request.enqueuedAt = time.Now()
+	}
 
-	if oldRequest := conn.requests.Put(requestID, request); oldRequest != nil {
-		select {
-		case oldRequest.replyChan <- &AsyncResult{
-			Error:     ConnectionClosedError(conn),
-			ErrorCode: ErrNoConnection,
-			Opaque:    oldRequest.opaque,
-		}:
-		default:
-		}
+	// the expiry is set before the request is published to the reader,
+	// it does nothing if the request is answered by then, as the ids are not reused
+	if request.expireAfter != 0 {
+		timeouts.AfterFunc(request.expireAfter, func() {
+			conn.expireRequest(requestID)
+		})
 	}
 
-	writeChan := conn.writeChan
-	if writeChan == nil {
-		r := conn.requests.Pop(requestID)
-		requestPool.Put(r)
+	oldRequest, ok := conn.requests.Put(requestID, request)
+	if !ok {
+		// the connection has been shut down and all the pending requests answered
 		conn.releasePacket(pp)
 		return nil, &Result{
 			Error:     ConnectionClosedError(conn),
 			ErrorCode: ErrNoConnection,
 		}, 0
 	}
+	if oldRequest != nil {
+		conn.failCollided(oldRequest)
+	}
+
+	writeChan := conn.writeChan
+
+	// the queue wait limit is only armed if the queue is full
+	var queueTimeout <-chan struct{}
+	if conn.queuePolicy != QueueBlock {
+		select {
+		case writeChan <- request:
+			return request, nil, requestID
+		default:
+		}
+
+		if conn.queuePolicy == QueueFailFast {
+			if !conn.abandonRequest(requestID, pp) {
+				return request, nil, requestID
+			}
+			return nil, &Result{
+				Error:     ErrQueueFull,
+				ErrorCode: ErrTimeout,
+			}, 0
+		}
+
+		if queueTimeout = timeouts.After(conn.queueWaitTimeout); queueTimeout == nil {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, conn.queueWaitTimeout)
+			defer cancel()
+		}
+	}
 
 	select {
 	case writeChan <- request:
+	case <-queueTimeout:
+		if !conn.abandonRequest(requestID, pp) {
+			return request, nil, requestID
+		}
+		return nil, &Result{
+			Error:     ErrQueueFull,
+			ErrorCode: ErrTimeout,
+		}, 0
 	case <-ctx.Done():
+		if !conn.abandonRequest(requestID, pp) {
+			return request, nil, requestID
+		}
 		if conn.perf.QueryTimeouts != nil && ctx.Err() == context.DeadlineExceeded {
 			conn.perf.QueryTimeouts.Add(1)
 		}
-		r := conn.requests.Pop(requestID)
-		requestPool.Put(r)
-		conn.releasePacket(pp)
 		return nil, &Result{
-			Error:     NewContextError(ctx, conn, "Send error"),
+			Error:     conn.errors.send(ctx.Err()),
+			ErrorCode: ErrTimeout,
+		}, 0
+	case <-timeout:
+		if !conn.abandonRequest(requestID, pp) {
+			return request, nil, requestID
+		}
+		if conn.perf.QueryTimeouts != nil {
+			conn.perf.QueryTimeouts.Add(1)
+		}
+		return nil, &Result{
+			Error:     conn.errors.send(context.DeadlineExceeded),
 			ErrorCode: ErrTimeout,
 		}, 0
 	case <-conn.exit:
+		if !conn.abandonRequest(requestID, pp) {
+			return request, nil, requestID
+		}
 		return nil, &Result{
 			Error:     ConnectionClosedError(conn),
 			ErrorCode: ErrNoConnection,
@@ -82,7 +173,21 @@ func (conn *Connection) writeRequest(ctx context.Context, request *request, q Qu
 	return request, nil, requestID
 }
 
-func (conn *Connection) readResult(ctx context.Context, arc chan *AsyncResult, requestID uint64) *AsyncResult {
+// abandonRequest removes the request which has not been queued for writing.
+// It returns false if the request has already been answered by somebody else,
+// e.g. the connection shutdown, then the caller must treat it as queued and
+// wait for that answer, so every request is answered exactly once.
+func (conn *Connection) abandonRequest(requestID uint64, pp *BinaryPacket) bool {
+	r := conn.requests.Pop(requestID)
+	if r == nil {
+		return false
+	}
+	requestPool.Put(r)
+	conn.releasePacket(pp)
+	return true
+}
+
+func (conn *Connection) readResult(ctx context.Context, timeout <-chan struct{}, arc chan *AsyncResult, requestID uint64) *AsyncResult {
 	select {
 	case ar := <-arc:
 		if ar == nil {
@@ -99,7 +204,17 @@ func (conn *Connection) readResult(ctx context.Context, arc chan *AsyncResult, r
 		r := conn.requests.Pop(requestID)
 		requestPool.Put(r)
 		return &AsyncResult{
-			Error:     NewContextError(ctx, conn, "Recv error"),
+			Error:     conn.errors.recv(ctx.Err()),
+			ErrorCode: ErrTimeout,
+		}
+	case <-timeout:
+		if conn.perf.QueryTimeouts != nil {
+			conn.perf.QueryTimeouts.Add(1)
+		}
+		r := conn.requests.Pop(requestID)
+		requestPool.Put(r)
+		return &AsyncResult{
+			Error:     conn.errors.recv(context.DeadlineExceeded),
 			ErrorCode: ErrTimeout,
 		}
 	case <-conn.exit:
@@ -110,14 +225,144 @@ func (conn *Connection) readResult(ctx context.Context, arc chan *AsyncResult, r
 	}
 }
 
+// Executor executes queries, it is implemented by Connection, Connector and Tx.
+// The packages built on the connector take it, so they can be given any of
+// them or a wrapper, e.g. the tenant or fieldcrypt ones.
+type Executor interface {
+	Exec(ctx context.Context, q Query, options ...ExecOption) *Result
+}
+
+var (
+	_ Executor = (*Connection)(nil)
+	_ Executor = (*Connector)(nil)
+	_ Executor = (*Tx)(nil)
+)
+
+// Exec sends the query and waits for the reply. Errors returned by the server
+// are *QueryError with the Request details describing the query.
 func (conn *Connection) Exec(ctx context.Context, q Query, options ...ExecOption) (result *Result) {
-	var cancel context.CancelFunc = func() {}
+	started := time.Now()
+
+	pp, rerr := conn.exec(ctx, q, options...)
+	if rerr != nil {
+		return rerr
+	}
+
+	if err := pp.Unmarshal(); err != nil {
+		result = &Result{
+			Error:     err,
+			ErrorCode: ErrInvalidMsgpack,
+		}
+	} else {
+		result = pp.Result()
+		if result == nil {
+			result = &Result{}
+		}
+		if qe, ok := result.Error.(*QueryError); ok && qe.Request == nil {
+			enriched := *qe
+			enriched.Request = conn.requestDetails(q, time.Since(started))
+			result.Error = &enriched
+		}
+	}
+	conn.releasePacket(pp)
+
+	return result
+}
+
+var requestKinds = map[uint]string{
+	SelectCommand:  "select",
+	InsertCommand:  "insert",
+	ReplaceCommand: "replace",
+	UpdateCommand:  "update",
+	DeleteCommand:  "delete",
+	CallCommand:    "call",
+	AuthCommand:    "auth",
+	EvalCommand:    "eval",
+	UpsertCommand:  "upsert",
+	Call17Command:  "call",
+	ExecuteCommand: "execute",
+	PrepareCommand: "prepare",
+	PingCommand:    "ping",
+}
+
+// requestDetails describes the query which has failed on the server
+func (conn *Connection) requestDetails(q Query, elapsed time.Duration) *RequestDetails {
+	d := &RequestDetails{
+		Kind:    requestKinds[q.GetCommandID()],
+		Elapsed: elapsed,
+	}
+	if d.Kind == "" {
+		d.Kind = fmt.Sprintf("%T", q)
+	}
+
+	switch q := q.(type) {
+	case *Select:
+		d.Space, d.Index = conn.packData.describe(q.Space, q.Index)
+	case *Insert:
+		d.Space, _ = conn.packData.describe(q.Space, nil)
+	case *Replace:
+		d.Space, _ = conn.packData.describe(q.Space, nil)
+	case *Delete:
+		d.Space, d.Index = conn.packData.describe(q.Space, q.Index)
+	case *Update:
+		d.Space, d.Index = conn.packData.describe(q.Space, q.Index)
+	case *Upsert:
+		d.Space, _ = conn.packData.describe(q.Space, nil)
+	case *Call:
+		d.Func = q.Name
+	case *Call17:
+		d.Func = q.Name
+	}
+	return d
+}
+
+// ExecTuples executes the query and calls fn for every tuple of the reply data
+// with its raw msgpack representation, skipping decoding into interface{} values.
+// It is meant to be used with msgp-generated types (see the //msgp:tuple directive),
+// any other hand-written msgp.Unmarshaler or RawTuple field accessors.
+// The tuple slice is only valid until fn returns.
+func (conn *Connection) ExecTuples(ctx context.Context, q Query, fn func(tuple []byte) error, options ...ExecOption) error {
+	pp, rerr := conn.exec(ctx, q, options...)
+	if rerr != nil {
+		return rerr.Error
+	}
+
+	err := pp.UnmarshalTuples(fn)
+	conn.releasePacket(pp)
+
+	return err
+}
+
+// withTimeout limits the time to wait with d, if it is not 0.
+// Timeouts are tracked by the shared timing wheel, which is much cheaper
+// than a timer per request, unless they are too long for the wheel.
+func withTimeout(ctx context.Context, d time.Duration) (context.Context, <-chan struct{}, context.CancelFunc) {
+	if d == 0 {
+		return ctx, nil, func() {}
+	}
+	if timeout := timeouts.After(d); timeout != nil {
+		return ctx, timeout, func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, d)
+	return ctx, nil, cancel
+}
+
+// sendDeadline limits the time to hand the request over to the writer,
+// which is either SendTimeout or the whole QueryTimeout
+func (conn *Connection) sendDeadline(ctx context.Context) (context.Context, <-chan struct{}, context.CancelFunc) {
+	if conn.sendTimeout != 0 {
+		return withTimeout(ctx, conn.sendTimeout)
+	}
+	return withTimeout(ctx, conn.queryTimeout)
+}
+
+// exec sends the query and waits for the raw reply packet.
+// The Result type is used to return errors here
+func (conn *Connection) exec(ctx context.Context, q Query, options ...ExecOption) (*BinaryPacket, *Result) {
 	var requestID uint64
 	var rerr *Result
 
-	if conn.queryTimeout != 0 {
-		ctx, cancel = context.WithTimeout(ctx, conn.queryTimeout)
-	}
+	sendCtx, sendTimeout, cancel := conn.sendDeadline(ctx)
 
 	replyChan := make(chan *AsyncResult, 1)
 
@@ -127,16 +372,25 @@ func (conn *Connection) Exec(ctx context.Context, q Query, options ...ExecOption
 		options[i].apply(request)
 	}
 
-	if _, rerr, requestID = conn.writeRequest(ctx, request, q); rerr != nil {
+	if _, rerr, requestID = conn.writeRequest(sendCtx, sendTimeout, request, q); rerr != nil {
 		cancel()
-		return rerr
+		return nil, rerr
 	}
 
-	ar := conn.readResult(ctx, replyChan, requestID)
+	// without SendTimeout QueryTimeout has been running since the submission
+	timeout := sendTimeout
+	if conn.sendTimeout != 0 {
+		cancel()
+		ctx, timeout, cancel = withTimeout(ctx, conn.queryTimeout)
+	} else {
+		ctx = sendCtx
+	}
+
+	ar := conn.readResult(ctx, timeout, replyChan, requestID)
 	cancel()
 
 	if rerr := ar.Error; rerr != nil {
-		return &Result{
+		return nil, &Result{
 			Error:     rerr,
 			ErrorCode: ar.ErrorCode,
 		}
@@ -144,28 +398,25 @@ func (conn *Connection) Exec(ctx context.Context, q Query, options ...ExecOption
 
 	pp := ar.BinaryPacket
 	if pp == nil {
-		return &Result{
+		return nil, &Result{
 			Error:     ConnectionClosedError(conn),
 			ErrorCode: ErrNoConnection,
 		}
 	}
 
-	if err := pp.Unmarshal(); err != nil {
-		result = &Result{
-			Error:     err,
-			ErrorCode: ErrInvalidMsgpack,
-		}
-	} else {
-		result = pp.Result()
-		if result == nil {
-			result = &Result{}
-		}
-	}
-	pp.Release()
-
-	return result
+	return pp, nil
 }
 
+// ExecAsync sends the query without waiting for the reply. The reply packet is
+// delivered to replyChan as is, decoding it is up to the receiver.
+// If replyChan is nil, the reply is dropped without being decoded.
+// Every request gets exactly one answer: either ExecAsync returns an error
+// or a reply is sent to replyChan. Replies which do not fit into replyChan
+// are dropped, so it must have enough capacity for the requests in flight.
+// With Options.QueryTimeout set, the timeout error is delivered to replyChan
+// if the server does not reply in time, and the late reply is discarded.
+// The reply timer starts on submission and lasts Options.SendTimeout plus
+// Options.QueryTimeout.
 func (conn *Connection) ExecAsync(ctx context.Context, q Query, opaque interface{}, replyChan chan *AsyncResult) error {
 	var rerr *Result
 
@@ -173,12 +424,44 @@ func (conn *Connection) ExecAsync(ctx context.Context, q Query, opaque interface
 	request.opaque = opaque
 	request.replyChan = replyChan
 
-	if _, rerr, _ = conn.writeRequest(ctx, request, q); rerr != nil {
+	ctx, timeout, cancel := conn.sendDeadline(ctx)
+	defer cancel()
+
+	// the reply timer is armed on submission, so it also covers the send phase
+	request.expireAfter = conn.sendTimeout + conn.queryTimeout
+
+	if _, rerr, _ = conn.writeRequest(ctx, timeout, request, q); rerr != nil {
 		return rerr.Error
 	}
 	return nil
 }
 
+// failCollided fails the stuck request which has been replaced in the
+// request map by a new one with the same sync.
+func (conn *Connection) failCollided(req *request) {
+	if conn.perf.SyncCollisions != nil {
+		conn.perf.SyncCollisions.Add(1)
+	}
+
+	select {
+	case req.replyChan <- &AsyncResult{
+		Error:      ErrSyncCollision,
+		ErrorCode:  ErrTimeout,
+		Connection: conn,
+		Opaque:     req.opaque,
+	}:
+	default:
+	}
+}
+
+// expireRequest fails the pending async request with the timeout error.
+// The reply, if it ever arrives, is dropped by the reader.
+func (conn *Connection) expireRequest(requestID uint64) {
+	if conn.failRequest(requestID, ErrTimeout, conn.errors.recv(context.DeadlineExceeded)) && conn.perf.QueryTimeouts != nil {
+		conn.perf.QueryTimeouts.Add(1)
+	}
+}
+
 func (conn *Connection) Execute(q Query) ([][]interface{}, error) {
 	res := conn.Exec(context.Background(), q)
 	return res.Data, res.Error
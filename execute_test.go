@@ -0,0 +1,574 @@
+package tarantool
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"expvar"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestExecAsyncFireAndForget(t *testing.T) {
+	require := require.New(t)
+
+	evals := make(chan string, 1)
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if eval, ok := q.(*Eval); ok {
+			evals <- eval.Expression
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	require.NoError(conn.ExecAsync(context.Background(), &Eval{Expression: "return 1"}, nil, nil))
+	require.Equal("return 1", <-evals)
+
+	// the reply to the forgotten request has been consumed by the reader
+	_, err = conn.Execute(&Ping{})
+	require.NoError(err)
+
+	pending := 0
+	conn.requests.CleanUp(func(*request) { pending++ })
+	require.Equal(0, pending)
+}
+
+// testTuple mimics a msgp-generated type with the //msgp:tuple directive
+type testTuple struct {
+	ID   int64
+	Name string
+}
+
+func (t *testTuple) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	var n uint32
+
+	buf = data
+	if n, buf, err = msgp.ReadArrayHeaderBytes(buf); err != nil {
+		return
+	}
+	if n != 2 {
+		return buf, msgp.ArrayError{Wanted: 2, Got: n}
+	}
+	if t.ID, buf, err = msgp.ReadInt64Bytes(buf); err != nil {
+		return
+	}
+	t.Name, buf, err = msgp.ReadStringBytes(buf)
+	return
+}
+
+func TestExecTuples(t *testing.T) {
+	require := require.New(t)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if _, ok := q.(*Eval); ok {
+			return &Result{ErrorCode: ErrProcLua, Error: NewQueryError(ErrProcLua, "eval is not allowed")}
+		}
+		if sel, ok := q.(*Select); ok && sel.Space == uint(512) {
+			return &Result{Data: [][]interface{}{{int64(1), "one"}, {int64(2), "two"}}}
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	var tuples []testTuple
+	err = conn.ExecTuples(context.Background(), &Select{Space: uint64(512)}, func(tuple []byte) error {
+		var tt testTuple
+		if _, err := tt.UnmarshalMsg(tuple); err != nil {
+			return err
+		}
+		tuples = append(tuples, tt)
+		return nil
+	})
+	require.NoError(err)
+	require.Equal([]testTuple{{1, "one"}, {2, "two"}}, tuples)
+
+	err = conn.ExecTuples(context.Background(), &Eval{Expression: "return"}, func([]byte) error {
+		return nil
+	})
+	require.Error(err)
+	require.Contains(err.Error(), "eval is not allowed")
+}
+
+func TestExecQueryTimeout(t *testing.T) {
+	require := require.New(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if _, ok := q.(*Eval); ok {
+			<-release
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, &Options{QueryTimeout: 50 * time.Millisecond})
+	require.NoError(err)
+	defer conn.Close()
+
+	started := time.Now()
+	res := conn.Exec(context.Background(), &Eval{Expression: "require('fiber').sleep(1)"})
+	require.Error(res.Error)
+	require.Equal(ErrTimeout, res.ErrorCode)
+	require.True(time.Since(started) >= 50*time.Millisecond)
+
+	var ctxErr *ContextError
+	require.True(errors.As(res.Error, &ctxErr))
+	require.True(ctxErr.Timeout())
+	require.True(ctxErr.MaybeApplied())
+	require.Contains(ctxErr.Error(), "Recv error: context deadline exceeded")
+
+	// timed out requests share the same error value
+	res2 := conn.Exec(context.Background(), &Eval{Expression: "require('fiber').sleep(1)"})
+	require.True(res.Error == res2.Error)
+
+	// the connection is still usable
+	_, err = conn.Execute(&Ping{})
+	require.NoError(err)
+}
+
+func TestExecAsyncQueryTimeout(t *testing.T) {
+	require := require.New(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if _, ok := q.(*Eval); ok {
+			<-release
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, &Options{QueryTimeout: 50 * time.Millisecond})
+	require.NoError(err)
+	defer conn.Close()
+
+	replyChan := make(chan *AsyncResult, 1)
+	require.NoError(conn.ExecAsync(context.Background(), &Eval{Expression: "require('fiber').sleep(1)"}, "opaque", replyChan))
+
+	select {
+	case ar := <-replyChan:
+		require.Equal(ErrTimeout, ar.ErrorCode)
+		require.Equal("opaque", ar.Opaque)
+		var ctxErr *ContextError
+		require.True(errors.As(ar.Error, &ctxErr))
+		require.True(ctxErr.Timeout())
+	case <-time.After(time.Second):
+		require.FailNow("async request has not expired")
+	}
+
+	pending := 0
+	conn.requests.CleanUp(func(*request) { pending++ })
+	require.Equal(0, pending)
+
+	// replies which arrive in time are not affected by the timer
+	require.NoError(conn.ExecAsync(context.Background(), &Ping{}, nil, replyChan))
+	ar := <-replyChan
+	require.NoError(ar.Error)
+	ar.BinaryPacket.Release()
+}
+
+func TestExecClosedError(t *testing.T) {
+	require := require.New(t)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	conn.Close()
+
+	res := conn.Exec(context.Background(), &Ping{})
+	require.Equal(ErrNoConnection, res.ErrorCode)
+	require.IsType(&ConnectionError{}, res.Error)
+
+	res2 := conn.Exec(context.Background(), &Ping{})
+	require.True(res.Error == res2.Error)
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestWriteErrorDelivered(t *testing.T) {
+	require := require.New(t)
+
+	writeErr := errors.New("broken pipe")
+	conn := &Connection{
+		remoteAddr: "test",
+		requests:   newRequestMap(),
+		writeChan:  make(chan *request, 1),
+		exit:       make(chan bool),
+		packData:   newPackData(nil),
+		ccw:        &failingWriter{err: writeErr},
+		errors:     newRequestErrors("test"),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- conn.writer()
+	}()
+
+	replyChan := make(chan *AsyncResult, 1)
+	require.NoError(conn.ExecAsync(context.Background(), &Ping{}, "opaque", replyChan))
+
+	require.Equal(writeErr, <-done)
+
+	ar := <-replyChan
+	require.Equal(ErrNoConnection, ar.ErrorCode)
+	require.Equal("opaque", ar.Opaque)
+
+	var werr *WriteError
+	require.True(errors.As(ar.Error, &werr))
+	require.True(errors.Is(ar.Error, writeErr))
+	require.True(werr.Temporary())
+	require.False(werr.Timeout())
+}
+
+func TestExecSyncCollision(t *testing.T) {
+	require := require.New(t)
+
+	release := make(chan struct{})
+	defer close(release)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if _, ok := q.(*Eval); ok {
+			<-release
+		}
+		return &Result{}
+	})
+
+	collisions := new(expvar.Int)
+	conn, err := Connect(addr, &Options{
+		QueryTimeout: 10 * time.Second,
+		Perf:         PerfCount{SyncCollisions: collisions},
+	})
+	require.NoError(err)
+	defer conn.Close()
+
+	stuck := make(chan *AsyncResult, 1)
+	require.NoError(conn.ExecAsync(context.Background(), &Eval{Expression: "require('fiber').sleep(10)"}, "stuck", stuck))
+
+	// make the next request reuse the sync of the pending one
+	atomic.AddUint64(&conn.requestID, ^uint64(0))
+
+	replyChan := make(chan *AsyncResult, 1)
+	require.NoError(conn.ExecAsync(context.Background(), &Ping{}, nil, replyChan))
+
+	ar := <-stuck
+	require.Equal(ErrSyncCollision, ar.Error)
+	require.Equal("stuck", ar.Opaque)
+	require.EqualValues(1, collisions.Value())
+
+	ar = <-replyChan
+	require.NoError(ar.Error)
+	ar.BinaryPacket.Release()
+}
+
+func TestExecQueueFullPolicy(t *testing.T) {
+	require := require.New(t)
+
+	newConn := func(policy QueueFullPolicy) *Connection {
+		conn := &Connection{
+			remoteAddr:       "test",
+			requests:         newRequestMap(),
+			writeChan:        make(chan *request, 1),
+			exit:             make(chan bool),
+			packData:         newPackData(nil),
+			errors:           newRequestErrors("test"),
+			queuePolicy:      policy,
+			queueWaitTimeout: 50 * time.Millisecond,
+		}
+		// nobody reads the queue, fill it up
+		conn.writeChan <- &request{}
+		return conn
+	}
+
+	started := time.Now()
+	res := newConn(QueueFailFast).Exec(context.Background(), &Ping{})
+	require.Equal(ErrQueueFull, res.Error)
+	require.True(time.Since(started) < 50*time.Millisecond)
+
+	started = time.Now()
+	res = newConn(QueueBlockWithTimeout).Exec(context.Background(), &Ping{})
+	require.Equal(ErrQueueFull, res.Error)
+	require.True(time.Since(started) >= 50*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	started = time.Now()
+	conn := newConn(QueueBlock)
+	res = conn.Exec(ctx, &Ping{})
+	require.True(time.Since(started) >= 100*time.Millisecond)
+	require.Equal(ErrTimeout, res.ErrorCode)
+	require.NotEqual(ErrQueueFull, res.Error)
+
+	// requests which failed to be queued are not left pending
+	pending := 0
+	conn.requests.CleanUp(func(*request) { pending++ })
+	require.Equal(0, pending)
+}
+
+func TestExecConcurrentClose(t *testing.T) {
+	const workers = 8
+	const perWorker = 200
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		return &Result{}
+	})
+
+	for round := 0; round < 5; round++ {
+		conn, err := Connect(addr, nil)
+		require.NoError(t, err)
+
+		replyChan := make(chan *AsyncResult, workers*perWorker)
+		var failed int64
+
+		var wg sync.WaitGroup
+		wg.Add(workers)
+		for w := 0; w < workers; w++ {
+			go func(w int) {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					if w%2 == 0 {
+						conn.Exec(context.Background(), &Ping{})
+						continue
+					}
+					if err := conn.ExecAsync(context.Background(), &Ping{}, i, replyChan); err != nil {
+						atomic.AddInt64(&failed, 1)
+					}
+				}
+			}(w)
+		}
+
+		time.Sleep(time.Millisecond)
+		conn.Close()
+		wg.Wait()
+
+		// every async request is answered exactly once
+		answered := int(atomic.LoadInt64(&failed))
+		for answered < workers/2*perWorker {
+			select {
+			case ar := <-replyChan:
+				if ar.BinaryPacket != nil {
+					ar.BinaryPacket.Release()
+				}
+				answered++
+			case <-time.After(time.Second):
+				require.FailNow(t, "requests are left unanswered", "%d of %d", answered, workers/2*perWorker)
+			}
+		}
+
+		select {
+		case <-replyChan:
+			require.FailNow(t, "request answered twice")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestExecOrphanReply(t *testing.T) {
+	require := require.New(t)
+
+	release := make(chan struct{})
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if _, ok := q.(*Eval); ok {
+			<-release
+		}
+		return &Result{}
+	})
+
+	orphans := make(chan uint64, 1)
+	perf := PerfCount{
+		OrphanReplies: new(expvar.Int),
+		OrphanReply: func(requestID uint64) {
+			orphans <- requestID
+		},
+	}
+	conn, err := Connect(addr, &Options{QueryTimeout: 20 * time.Millisecond, Perf: perf})
+	require.NoError(err)
+	defer conn.Close()
+
+	res := conn.Exec(context.Background(), &Eval{Expression: "require('fiber').sleep(1)"})
+	require.Equal(ErrTimeout, res.ErrorCode)
+	requestID := atomic.LoadUint64(&conn.requestID)
+
+	// the late reply is accounted for
+	close(release)
+	select {
+	case id := <-orphans:
+		require.Equal(requestID, id)
+	case <-time.After(time.Second):
+		require.FailNow("orphan reply is not reported")
+	}
+	require.EqualValues(1, perf.OrphanReplies.Value())
+}
+
+// syncWriter records the syncs of the written packets
+type syncWriter struct {
+	sync.Mutex
+	buf bytes.Buffer
+}
+
+func (w *syncWriter) Write(p []byte) (int, error) {
+	w.Lock()
+	defer w.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *syncWriter) syncs(t *testing.T) []uint64 {
+	w.Lock()
+	defer w.Unlock()
+
+	var ids []uint64
+	r := bytes.NewReader(w.buf.Bytes())
+	for r.Len() > 0 {
+		pp := packetPool.Get()
+		_, err := pp.ReadFrom(r)
+		require.NoError(t, err)
+		require.NoError(t, pp.packet.UnmarshalBinary(pp.body))
+		ids = append(ids, pp.packet.requestID)
+		pp.Release()
+	}
+	return ids
+}
+
+func TestExecSubmissionOrder(t *testing.T) {
+	require := require.New(t)
+
+	w := &syncWriter{}
+	conn := &Connection{
+		remoteAddr: "test",
+		requests:   newRequestMap(),
+		writeChan:  make(chan *request, 4),
+		exit:       make(chan bool),
+		packData:   newPackData(nil),
+		ccw:        w,
+		errors:     newRequestErrors("test"),
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- conn.writer()
+	}()
+
+	const n = 100
+	for i := 0; i < n; i++ {
+		require.NoError(conn.ExecAsync(context.Background(), &Ping{}, nil, nil))
+	}
+
+	require.Eventually(func() bool {
+		return len(w.syncs(t)) == n
+	}, time.Second, time.Millisecond)
+
+	close(conn.exit)
+	<-done
+
+	syncs := w.syncs(t)
+	for i := 1; i < n; i++ {
+		require.Equal(syncs[i-1]+1, syncs[i])
+	}
+}
+
+func TestExecErrorDetails(t *testing.T) {
+	require := require.New(t)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if sel, ok := q.(*Select); ok {
+			switch sel.Space {
+			case ViewSpace:
+				return &Result{Data: [][]interface{}{
+					{uint64(512), uint64(1), "tester", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+				}}
+			case ViewIndex:
+				return &Result{Data: [][]interface{}{
+					{uint64(512), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, []interface{}{[]interface{}{uint64(0), "unsigned"}}},
+				}}
+			}
+			return &Result{}
+		}
+		return &Result{ErrorCode: ErrTupleFound, Error: NewQueryError(ErrTupleFound, "Duplicate key exists")}
+	})
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	res := conn.Exec(context.Background(), &Insert{Space: uint(512), Tuple: []interface{}{int64(1)}})
+	var qe *QueryError
+	require.True(errors.As(res.Error, &qe))
+	require.Equal(ErrTupleFound, qe.Code)
+	require.NotNil(qe.Request)
+	require.Equal("insert", qe.Request.Kind)
+	require.Equal("tester", qe.Request.Space)
+	require.Regexp(`^Duplicate key exists \[insert space=tester elapsed=\S+\]$`, qe.Error())
+
+	res = conn.Exec(context.Background(), &Update{Space: "tester", Index: 0, Key: int64(1)})
+	require.Contains(res.Error.Error(), "[update space=tester index=primary elapsed=")
+
+	res = conn.Exec(context.Background(), &Call17{Name: "box.info"})
+	require.Contains(res.Error.Error(), "[call func=box.info elapsed=")
+
+	// shared errors are never modified
+	require.Nil(ErrUnknownError.Request)
+}
+
+func TestExecSendTimeout(t *testing.T) {
+	require := require.New(t)
+
+	conn := &Connection{
+		remoteAddr:   "test",
+		requests:     newRequestMap(),
+		writeChan:    make(chan *request),
+		exit:         make(chan bool),
+		packData:     newPackData(nil),
+		errors:       newRequestErrors("test"),
+		queryTimeout: 50 * time.Millisecond,
+		sendTimeout:  200 * time.Millisecond,
+	}
+	defer close(conn.exit)
+
+	// the writer stalls longer than QueryTimeout, then the reply comes at once,
+	// the ping request packet is a good enough reply for the ping
+	go func() {
+		time.Sleep(80 * time.Millisecond)
+		r := <-conn.writeChan
+		var buf bytes.Buffer
+		r.packet.WriteTo(&buf)
+		pp := packetPool.Get()
+		pp.ReadFrom(&buf)
+		req := conn.requests.Pop(r.packet.packet.requestID)
+		req.replyChan <- &AsyncResult{BinaryPacket: pp}
+	}()
+
+	res := conn.Exec(context.Background(), &Ping{})
+	require.NoError(res.Error)
+
+	// nobody picks the request up
+	started := time.Now()
+	res = conn.Exec(context.Background(), &Ping{})
+	require.Equal(ErrTimeout, res.ErrorCode)
+	require.True(time.Since(started) >= 200*time.Millisecond)
+
+	var ctxErr *ContextError
+	require.True(errors.As(res.Error, &ctxErr))
+	require.False(ctxErr.MaybeApplied())
+	require.Contains(ctxErr.Error(), "Send error")
+}
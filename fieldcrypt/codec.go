@@ -0,0 +1,130 @@
+package fieldcrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	// ErrUnknownKey is returned by KeyProvider.Key for an unknown key ID.
+	ErrUnknownKey = errors.New("unknown encryption key")
+	// ErrCiphertext is returned if the ciphertext is malformed or doesn't authenticate.
+	ErrCiphertext = errors.New("invalid ciphertext")
+)
+
+// Codec encrypts and decrypts the field values. The associated data binds
+// the ciphertext to the field, so it can't be moved to another one.
+type Codec interface {
+	Encrypt(ctx context.Context, plaintext, associated []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext, associated []byte) ([]byte, error)
+}
+
+// KeyProvider provides the encryption keys, e.g. from a KMS. The keys are
+// identified, so they can be rotated: the new values are encrypted with the
+// current key and the old ones are decrypted with the key they were encrypted with.
+type KeyProvider interface {
+	// CurrentKey returns the key to encrypt with and its ID.
+	CurrentKey(ctx context.Context) (id string, key []byte, err error)
+	// Key returns the key with the ID.
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// StaticKeys is the KeyProvider of the keys known in advance.
+type StaticKeys struct {
+	// Current is the ID of the key to encrypt with.
+	Current string
+	Keys    map[string][]byte
+}
+
+// CurrentKey implements KeyProvider.
+func (s *StaticKeys) CurrentKey(ctx context.Context) (string, []byte, error) {
+	key, err := s.Key(ctx, s.Current)
+	return s.Current, key, err
+}
+
+// Key implements KeyProvider.
+func (s *StaticKeys) Key(ctx context.Context, id string) ([]byte, error) {
+	key, ok := s.Keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// AESGCM is the Codec encrypting with AES-GCM under the keys of 16, 24 or 32 bytes.
+// The ciphertext is the key ID length byte, the key ID, the nonce and the sealed value.
+type AESGCM struct {
+	keys KeyProvider
+}
+
+// NewAESGCM returns the AES-GCM codec using the keys.
+func NewAESGCM(keys KeyProvider) *AESGCM {
+	return &AESGCM{keys: keys}
+}
+
+// Encrypt implements Codec.
+func (c *AESGCM) Encrypt(ctx context.Context, plaintext, associated []byte) ([]byte, error) {
+	id, key, err := c.keys.CurrentKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(id) > 255 {
+		return nil, fmt.Errorf("key ID %q is too long", id)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]byte, 0, 1+len(id)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	out = append(out, byte(len(id)))
+	out = append(out, id...)
+
+	nonce := out[len(out) : len(out)+aead.NonceSize()]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	out = out[:len(out)+aead.NonceSize()]
+	return aead.Seal(out, nonce, plaintext, associated), nil
+}
+
+// Decrypt implements Codec.
+func (c *AESGCM) Decrypt(ctx context.Context, ciphertext, associated []byte) ([]byte, error) {
+	if len(ciphertext) == 0 || len(ciphertext) < 1+int(ciphertext[0]) {
+		return nil, ErrCiphertext
+	}
+	n := 1 + int(ciphertext[0])
+	id := string(ciphertext[1:n])
+	ciphertext = ciphertext[n:]
+
+	key, err := c.keys.Key(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrCiphertext
+	}
+
+	plaintext, err := aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], associated)
+	if err != nil {
+		return nil, ErrCiphertext
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
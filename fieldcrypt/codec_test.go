@@ -0,0 +1,60 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAESGCM(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	keys := &StaticKeys{
+		Current: "old",
+		Keys: map[string][]byte{
+			"old": bytes.Repeat([]byte{1}, 16),
+			"new": bytes.Repeat([]byte{2}, 32),
+		},
+	}
+	c := NewAESGCM(keys)
+
+	old, err := c.Encrypt(ctx, []byte("secret"), []byte("users/1"))
+	require.NoError(err)
+	assert.NotContains(string(old), "secret")
+
+	again, err := c.Encrypt(ctx, []byte("secret"), []byte("users/1"))
+	require.NoError(err)
+	assert.NotEqual(old, again)
+
+	// the values encrypted with the previous key stay readable after the rotation
+	keys.Current = "new"
+	fresh, err := c.Encrypt(ctx, []byte("secret"), []byte("users/1"))
+	require.NoError(err)
+
+	for _, ciphertext := range [][]byte{old, fresh} {
+		plaintext, err := c.Decrypt(ctx, ciphertext, []byte("users/1"))
+		require.NoError(err)
+		assert.Equal("secret", string(plaintext))
+	}
+
+	_, err = c.Decrypt(ctx, fresh, []byte("users/2"))
+	assert.Equal(ErrCiphertext, err)
+
+	tampered := append([]byte(nil), fresh...)
+	tampered[len(tampered)-1] ^= 1
+	_, err = c.Decrypt(ctx, tampered, []byte("users/1"))
+	assert.Equal(ErrCiphertext, err)
+
+	_, err = c.Decrypt(ctx, []byte{10, 'x'}, nil)
+	assert.Equal(ErrCiphertext, err)
+
+	delete(keys.Keys, "old")
+	_, err = c.Decrypt(ctx, old, []byte("users/1"))
+	assert.True(errors.Is(err, ErrUnknownKey))
+}
@@ -0,0 +1,330 @@
+// Package fieldcrypt encrypts the sensitive tuple fields before they leave
+// the process and decrypts them in the returned tuples.
+//
+// The Encryptor wraps the connection and rewrites the tuples of Insert,
+// Replace and Upsert and the assignments of Update and Upsert for the configured
+// fields. The encrypted values are binary, so the fields must be typed as
+// varbinary or any, and as the encryption is randomized, they can't be
+// a part of an index key. Plaintext strings already stored in such fields are
+// returned as is, which allows the encryption to be enabled on the live data.
+//
+// The fields are configured by number, by name from the schema with FieldsByName,
+// or by struct tags with FieldsOf:
+//
+//	type User struct {
+//		ID  uint64 `tarantool:"id"`
+//		SSN string `tarantool:"ssn" encrypt:"true"`
+//	}
+//
+//	fields, err := fieldcrypt.FieldsOf(User{})
+//	keys := &fieldcrypt.StaticKeys{Current: "2024", Keys: map[string][]byte{"2024": key}}
+//	conn := fieldcrypt.New(conn, fieldcrypt.NewAESGCM(keys), fieldcrypt.Fields{
+//		"users": {Label: "users", Fields: fields},
+//	})
+package fieldcrypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/tinylib/msgp/msgp"
+	"github.com/viciious/go-tarantool"
+)
+
+var (
+	// ErrOperator is returned for the update operations other than assignment
+	// on the encrypted fields, or shifting them.
+	ErrOperator = errors.New("operator can't be applied to an encrypted field")
+	// ErrUnknownField is returned by FieldsByName for the fields missing from the space format.
+	ErrUnknownField = errors.New("unknown field")
+)
+
+// Fields are the encrypted fields by space. The spaces are keyed the way
+// the queries reference them: by name or by decimal ID.
+type Fields map[string]Space
+
+// Space is the encrypted fields of a space.
+type Space struct {
+	// Label identifies the space in the associated data of the ciphertexts,
+	// so a value doesn't decrypt if it is moved to another space. It must not
+	// change, and a space keyed both by name and by ID in Fields must have
+	// the same Label under both keys. The key is used if it is empty.
+	Label string
+	// Fields are the numbers of the encrypted fields, starting with 0.
+	Fields []int
+}
+
+// Encryptor executes the queries encrypting and decrypting the fields. It implements
+// tarantool.Executor, so it can be passed to the other packages in place of the connection.
+type Encryptor struct {
+	conn   tarantool.Executor
+	codec  Codec
+	fields Fields
+}
+
+// New returns the Encryptor of the fields executing the queries with conn.
+func New(conn tarantool.Executor, codec Codec, fields Fields) *Encryptor {
+	return &Encryptor{conn: conn, codec: codec, fields: fields}
+}
+
+// Exec executes q with the encrypted fields of its tuples encrypted, and decrypts
+// them in the result. The query itself is not modified.
+func (e *Encryptor) Exec(ctx context.Context, q tarantool.Query, options ...tarantool.ExecOption) *tarantool.Result {
+	var space interface{}
+	switch q := q.(type) {
+	case *tarantool.Select:
+		space = q.Space
+	case *tarantool.Insert:
+		space = q.Space
+	case *tarantool.Replace:
+		space = q.Space
+	case *tarantool.Delete:
+		space = q.Space
+	case *tarantool.Update:
+		space = q.Space
+	case *tarantool.Upsert:
+		space = q.Space
+	}
+	s := e.scope(space)
+	if s == nil {
+		return e.conn.Exec(ctx, q, options...)
+	}
+	var err error
+
+	switch query := q.(type) {
+	case *tarantool.Insert:
+		enc := *query
+		enc.Tuple, err = s.encryptTuple(ctx, query.Tuple)
+		q = &enc
+	case *tarantool.Replace:
+		enc := *query
+		enc.Tuple, err = s.encryptTuple(ctx, query.Tuple)
+		q = &enc
+	case *tarantool.Update:
+		enc := *query
+		enc.Set, err = s.encryptOps(ctx, query.Set)
+		q = &enc
+	case *tarantool.Upsert:
+		enc := *query
+		if enc.Tuple, err = s.encryptTuple(ctx, query.Tuple); err == nil {
+			enc.Set, err = s.encryptOps(ctx, query.Set)
+		}
+		q = &enc
+	}
+	if err != nil {
+		return &tarantool.Result{Error: err, ErrorCode: tarantool.ErrIllegalParams}
+	}
+
+	res := e.conn.Exec(ctx, q, options...)
+	if res.Error != nil {
+		return res
+	}
+	for i, tuple := range res.Data {
+		if res.Data[i], err = s.decryptTuple(ctx, tuple); err != nil {
+			return &tarantool.Result{Error: err, ErrorCode: tarantool.ErrIllegalParams}
+		}
+	}
+	return res
+}
+
+// scope returns the encrypted fields of the space, nil if there are none
+func (e *Encryptor) scope(space interface{}) *scope {
+	if space == nil {
+		return nil
+	}
+	key := fmt.Sprint(space)
+	sp := e.fields[key]
+	if len(sp.Fields) == 0 {
+		return nil
+	}
+	label := sp.Label
+	if label == "" {
+		label = key
+	}
+	return &scope{codec: e.codec, space: label, fields: sp.Fields}
+}
+
+// scope is the encrypted fields of a space
+type scope struct {
+	codec  Codec
+	space  string
+	fields []int
+}
+
+func (s *scope) encrypted(field int64) bool {
+	for _, f := range s.fields {
+		if int64(f) == field {
+			return true
+		}
+	}
+	return false
+}
+
+// associated binds the ciphertext to the label of the space and the field
+func (s *scope) associated(field int) []byte {
+	return []byte(s.space + "/" + strconv.Itoa(field))
+}
+
+func (s *scope) encrypt(ctx context.Context, field int, v interface{}) (interface{}, error) {
+	plaintext, err := msgp.AppendIntf(nil, v)
+	if err != nil {
+		return nil, err
+	}
+	return s.codec.Encrypt(ctx, plaintext, s.associated(field))
+}
+
+func (s *scope) encryptTuple(ctx context.Context, tuple []interface{}) ([]interface{}, error) {
+	enc := make([]interface{}, len(tuple))
+	copy(enc, tuple)
+	for _, f := range s.fields {
+		if f >= len(enc) || enc[f] == nil {
+			continue
+		}
+		v, err := s.encrypt(ctx, f, enc[f])
+		if err != nil {
+			return nil, err
+		}
+		enc[f] = v
+	}
+	return enc, nil
+}
+
+func (s *scope) encryptOps(ctx context.Context, ops []tarantool.Operator) ([]tarantool.Operator, error) {
+	if ops == nil {
+		return nil, nil
+	}
+
+	last := -1
+	for _, f := range s.fields {
+		if f > last {
+			last = f
+		}
+	}
+
+	enc := make([]tarantool.Operator, len(ops))
+	for i, op := range ops {
+		enc[i] = op
+		var field int64
+		switch op := op.(type) {
+		case *tarantool.OpAssign:
+			if s.encrypted(op.Field) {
+				v, err := s.encrypt(ctx, int(op.Field), op.Argument)
+				if err != nil {
+					return nil, err
+				}
+				enc[i] = &tarantool.OpAssign{Field: op.Field, Argument: v}
+			}
+			if op.Field < 0 {
+				return nil, fmt.Errorf("%w: field %d", ErrOperator, op.Field)
+			}
+			continue
+		case *tarantool.OpInsert:
+			field = op.Before
+			if field <= int64(last) {
+				return nil, fmt.Errorf("%w: insert before %d", ErrOperator, field)
+			}
+		case *tarantool.OpDelete:
+			field = op.From
+			if field <= int64(last) {
+				return nil, fmt.Errorf("%w: delete from %d", ErrOperator, field)
+			}
+		case *tarantool.OpAdd:
+			field = op.Field
+		case *tarantool.OpSub:
+			field = op.Field
+		case *tarantool.OpBitAND:
+			field = op.Field
+		case *tarantool.OpBitXOR:
+			field = op.Field
+		case *tarantool.OpBitOR:
+			field = op.Field
+		case *tarantool.OpSplice:
+			field = op.Field
+		default:
+			return nil, fmt.Errorf("%w: %T", ErrOperator, op)
+		}
+		// the negative fields are counted from the end and may be encrypted ones
+		if field < 0 || s.encrypted(field) {
+			return nil, fmt.Errorf("%w: field %d", ErrOperator, field)
+		}
+	}
+	return enc, nil
+}
+
+func (s *scope) decryptTuple(ctx context.Context, tuple []interface{}) ([]interface{}, error) {
+	for _, f := range s.fields {
+		if f >= len(tuple) {
+			continue
+		}
+		ciphertext, ok := tuple[f].([]byte)
+		if !ok {
+			continue
+		}
+		plaintext, err := s.codec.Decrypt(ctx, ciphertext, s.associated(f))
+		if err != nil {
+			return nil, fmt.Errorf("space %s field %d: %w", s.space, f, err)
+		}
+		v, _, err := msgp.ReadIntfBytes(plaintext)
+		if err != nil {
+			return nil, fmt.Errorf("space %s field %d: %w", s.space, f, err)
+		}
+		tuple[f] = v
+	}
+	return tuple, nil
+}
+
+// SchemaConn is implemented by tarantool.Connection.
+type SchemaConn interface {
+	GetSpaceFields(space interface{}) ([]string, bool)
+}
+
+// FieldsByName returns the numbers of the fields with the names in the space format.
+func FieldsByName(conn SchemaConn, space interface{}, names ...string) ([]int, error) {
+	format, _ := conn.GetSpaceFields(space)
+
+	fields := make([]int, 0, len(names))
+	for _, name := range names {
+		n := -1
+		for i, f := range format {
+			if f == name {
+				n = i
+				break
+			}
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("%w: %s of space %v", ErrUnknownField, name, space)
+		}
+		fields = append(fields, n)
+	}
+	return fields, nil
+}
+
+// FieldsOf returns the numbers of the fields tagged with `encrypt:"true"` of the struct
+// type of model, which is a struct or a pointer to struct. The struct fields are
+// numbered the way tarantool.TupleDecoder.DecodeStruct matches them to the tuple.
+func FieldsOf(model interface{}) ([]int, error) {
+	typ := reflect.TypeOf(model)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%T is not a struct", model)
+	}
+
+	var fields []int
+	n := 0
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("tarantool") == "-" {
+			continue
+		}
+		if f.Tag.Get("encrypt") == "true" {
+			fields = append(fields, n)
+		}
+		n++
+	}
+	return fields, nil
+}
@@ -0,0 +1,181 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// testSpace is the id of the first user space, the schema isn't loaded
+// by the test server, so the space names can't be used
+const testSpace = 512
+
+// fakeServer keeps the tuples of testSpace by the first field
+type fakeServer struct {
+	sync.Mutex
+	tuples map[int64][]interface{}
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	s.Lock()
+	defer s.Unlock()
+
+	key := func(k interface{}, kt []interface{}) int64 {
+		if k == nil && len(kt) > 0 {
+			k = kt[0]
+		}
+		n, _ := typeconv.IntfToInt64(k)
+		return n
+	}
+	found := func(t []interface{}) *tarantool.Result {
+		if t == nil {
+			return &tarantool.Result{}
+		}
+		return &tarantool.Result{Data: [][]interface{}{append([]interface{}(nil), t...)}}
+	}
+
+	switch q := q.(type) {
+	case *tarantool.Select:
+		if space, _ := typeconv.IntfToInt(q.Space); space < testSpace {
+			// the schema requests of Connect
+			return &tarantool.Result{}
+		}
+		return found(s.tuples[key(q.Key, q.KeyTuple)])
+	case *tarantool.Insert:
+		s.tuples[key(q.Tuple[0], nil)] = q.Tuple
+		return found(q.Tuple)
+	case *tarantool.Update:
+		t := s.tuples[key(q.Key, q.KeyTuple)]
+		for _, op := range q.Set {
+			assign := op.(*tarantool.OpAssign)
+			t[assign.Field] = assign.Argument
+		}
+		return found(t)
+	case *tarantool.Eval:
+		return &tarantool.Result{Data: [][]interface{}{{int64(1)}}}
+	}
+	return &tarantool.Result{}
+}
+
+func newTestConn(t *testing.T, s *fakeServer) *tarantool.Connection {
+	addr := tnttest.NewServer(t, s.handle)
+
+	conn := tnttest.Connect(t, addr, nil)
+	return conn
+}
+
+func TestEncryptor(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{tuples: map[int64][]interface{}{}}
+	keys := &StaticKeys{Current: "k1", Keys: map[string][]byte{"k1": bytes.Repeat([]byte{7}, 32)}}
+	e := New(newTestConn(t, s), NewAESGCM(keys), Fields{"512": {Fields: []int{1, 3}}})
+	ctx := context.Background()
+
+	tuple := []interface{}{int64(1), "123-45-6789", "alice", map[string]interface{}{"card": "4111"}}
+	res := e.Exec(ctx, &tarantool.Insert{Space: testSpace, Tuple: tuple})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{tuple}, res.Data)
+	assert.Equal("123-45-6789", tuple[1], "the query is not modified")
+
+	// only the ciphertext reaches the server
+	stored := s.tuples[1]
+	require.IsType([]byte{}, stored[1])
+	assert.NotContains(string(stored[1].([]byte)), "123-45-6789")
+	assert.Equal("alice", stored[2])
+	require.IsType([]byte{}, stored[3])
+
+	res = e.Exec(ctx, &tarantool.Select{Space: testSpace, Key: int64(1)})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{tuple}, res.Data)
+
+	res = e.Exec(ctx, &tarantool.Update{Space: testSpace, Key: int64(1), Set: []tarantool.Operator{
+		&tarantool.OpAssign{Field: 1, Argument: int64(42)},
+		&tarantool.OpAssign{Field: 2, Argument: "bob"},
+	}})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(1), int64(42), "bob", map[string]interface{}{"card": "4111"}}}, res.Data)
+	assert.Equal("bob", s.tuples[1][2])
+
+	// the plaintext stored before the encryption is returned as is
+	s.tuples[2] = []interface{}{int64(2), "plain", "carol"}
+	res = e.Exec(ctx, &tarantool.Select{Space: testSpace, Key: int64(2)})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(2), "plain", "carol"}}, res.Data)
+
+	for _, op := range []tarantool.Operator{
+		&tarantool.OpSplice{Field: 1, Argument: "x"},
+		&tarantool.OpDelete{From: 0, Count: 1},
+		&tarantool.OpAssign{Field: -1, Argument: "x"},
+	} {
+		res = e.Exec(ctx, &tarantool.Update{Space: testSpace, Key: int64(1), Set: []tarantool.Operator{op}})
+		assert.True(errors.Is(res.Error, ErrOperator), "%T", op)
+	}
+
+	// the ciphertext moved to another field doesn't decrypt
+	s.tuples[3] = []interface{}{int64(3), s.tuples[1][3], "dave"}
+	res = e.Exec(ctx, &tarantool.Select{Space: testSpace, Key: int64(3)})
+	assert.True(errors.Is(res.Error, ErrCiphertext))
+
+	// the other spaces and queries are passed as is
+	res = e.Exec(ctx, &tarantool.Insert{Space: testSpace + 1, Tuple: []interface{}{int64(5), "open"}})
+	require.NoError(res.Error)
+	assert.Equal("open", s.tuples[5][1])
+	require.NoError(e.Exec(ctx, &tarantool.Eval{Expression: "return 1"}).Error)
+}
+
+func TestEncryptorLabel(t *testing.T) {
+	assert := assert.New(t)
+
+	users := Space{Label: "users", Fields: []int{1}}
+	e := New(nil, nil, Fields{"users": users, "512": users, "513": {Fields: []int{1}}})
+
+	// the space referenced by name and by ID is the same
+	byName, byID := e.scope("users"), e.scope(uint(512))
+	assert.Equal(byName.associated(1), byID.associated(1))
+	assert.Equal([]byte("513/1"), e.scope(513).associated(1))
+	assert.Nil(e.scope(514))
+	assert.Nil(e.scope(nil))
+}
+
+type fakeSchema []string
+
+func (s fakeSchema) GetSpaceFields(space interface{}) ([]string, bool) {
+	return s, true
+}
+
+func TestFields(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	fields, err := FieldsByName(fakeSchema{"id", "ssn", "name", "card"}, "users", "card", "ssn")
+	require.NoError(err)
+	assert.Equal([]int{3, 1}, fields)
+
+	_, err = FieldsByName(fakeSchema{"id"}, "users", "ssn")
+	assert.True(errors.Is(err, ErrUnknownField))
+
+	type user struct {
+		ID       uint64 `tarantool:"id"`
+		Skipped  string `tarantool:"-" encrypt:"true"`
+		SSN      string `tarantool:"ssn" encrypt:"true"`
+		internal string
+		Name     string
+		Card     []byte `encrypt:"true"`
+	}
+	fields, err = FieldsOf(&user{})
+	require.NoError(err)
+	assert.Equal([]int{1, 3}, fields)
+
+	_, err = FieldsOf("user")
+	assert.Error(err)
+}
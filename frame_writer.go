@@ -0,0 +1,43 @@
+package tarantool
+
+import (
+	"io"
+	"net"
+	"time"
+)
+
+// maxStalledWrites is the number of consecutive writes without any progress
+// after which the connection is considered broken.
+const maxStalledWrites = 16
+
+// frameWriter makes sure buffered frames are written completely: short writes
+// are retried and the optional write deadline is applied before every write.
+type frameWriter struct {
+	w       io.Writer
+	conn    net.Conn
+	timeout time.Duration
+}
+
+func (fw *frameWriter) Write(p []byte) (n int, err error) {
+	if fw.timeout > 0 {
+		if err = fw.conn.SetWriteDeadline(time.Now().Add(fw.timeout)); err != nil {
+			return
+		}
+	}
+
+	stalled := 0
+	for n < len(p) {
+		var m int
+		m, err = fw.w.Write(p[n:])
+		n += m
+		if err != nil {
+			return
+		}
+		if m > 0 {
+			stalled = 0
+		} else if stalled++; stalled == maxStalledWrites {
+			return n, io.ErrShortWrite
+		}
+	}
+	return
+}
@@ -0,0 +1,64 @@
+package tarantool
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// shortWriter writes at most max bytes at once
+type shortWriter struct {
+	bytes.Buffer
+	max int
+}
+
+func (w *shortWriter) Write(p []byte) (int, error) {
+	if len(p) > w.max {
+		p = p[:w.max]
+	}
+	return w.Buffer.Write(p)
+}
+
+func TestFrameWriterShortWrites(t *testing.T) {
+	assert := assert.New(t)
+
+	sw := &shortWriter{max: 3}
+	fw := &frameWriter{w: sw}
+
+	n, err := fw.Write([]byte("hello, tarantool"))
+	assert.NoError(err)
+	assert.Equal(16, n)
+	assert.Equal("hello, tarantool", sw.String())
+
+	sw = &shortWriter{max: 0}
+	fw = &frameWriter{w: sw}
+
+	n, err = fw.Write([]byte("hello"))
+	assert.Equal(io.ErrShortWrite, err)
+	assert.Equal(0, n)
+}
+
+func TestFrameWriterDeadline(t *testing.T) {
+	require := require.New(t)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	// nobody reads from the pipe, so the write blocks until the deadline
+	fw := &frameWriter{w: client, conn: client, timeout: 50 * time.Millisecond}
+
+	started := time.Now()
+	_, err := fw.Write([]byte("hello"))
+	require.Error(err)
+	require.True(time.Since(started) >= 50*time.Millisecond)
+
+	ne, ok := err.(net.Error)
+	require.True(ok)
+	require.True(ne.Timeout())
+}
@@ -8,5 +8,5 @@ require (
 	github.com/philhofer/fwd v1.0.0 // indirect
 	github.com/stretchr/testify v1.6.2-0.20201103103935-92707c0b2d50
 	github.com/tinylib/msgp v1.0.3-0.20180215042507-3b5c87ab5fb0
-	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200615113413-eeeca48fe776
 )
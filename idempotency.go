@@ -0,0 +1,27 @@
+package tarantool
+
+import (
+	"github.com/google/uuid"
+)
+
+// ProcIdempotentCall is the name of the function installed by
+// the lua/tarantool_idempotency.lua server-side helper.
+const ProcIdempotentCall = "idempotent_call"
+
+// NewIdempotencyKey returns a random key to identify an operation across retries.
+func NewIdempotencyKey() string {
+	return uuid.New().String()
+}
+
+// IdempotentCall returns the call of the function name with args, which the
+// server executes at most once for the key: retries with the same key get the
+// result of the first execution. The key must be generated once per operation,
+// e.g. with NewIdempotencyKey, and reused by all its retries.
+// The server needs the lua/tarantool_idempotency.lua helper, see its description.
+// The call is safe to be replayed, so it may be executed with IdempotentExecOption.
+func IdempotentCall(key, name string, args ...interface{}) *Call17 {
+	tuple := make([]interface{}, 0, len(args)+2)
+	tuple = append(tuple, key, name)
+	tuple = append(tuple, args...)
+	return &Call17{Name: ProcIdempotentCall, Tuple: tuple}
+}
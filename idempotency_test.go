@@ -0,0 +1,58 @@
+package tarantool
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIdempotentCall(t *testing.T) {
+	assert := assert.New(t)
+
+	key := NewIdempotencyKey()
+	assert.Len(key, 36)
+	assert.NotEqual(key, NewIdempotencyKey())
+
+	call := IdempotentCall(key, "transfer", int64(1), "a")
+	assert.Equal(ProcIdempotentCall, call.Name)
+	assert.Equal([]interface{}{key, "transfer", int64(1), "a"}, call.Tuple)
+}
+
+func TestIdempotentCallReplay(t *testing.T) {
+	require := require.New(t)
+
+	var mu sync.Mutex
+	var keys []interface{}
+
+	var c *Connector
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		call, ok := q.(*Call17)
+		if !ok || call.Name != ProcIdempotentCall {
+			return &Result{}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		keys = append(keys, call.Tuple[0])
+		if len(keys) == 1 {
+			// the connection drops before the reply is sent
+			conn, _ := c.Connect()
+			conn.Close()
+		}
+		return &Result{}
+	})
+
+	c = New(addr, nil)
+	defer c.Close()
+
+	key := NewIdempotencyKey()
+	res := c.Exec(context.Background(), IdempotentCall(key, "transfer", int64(1)), IdempotentExecOption())
+	require.NoError(res.Error)
+
+	// the retry carries the same key, so the server can recognize it
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal([]interface{}{key, key}, keys)
+}
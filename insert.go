@@ -30,7 +30,7 @@ func (q *Insert) packMsg(data *packData, b []byte) (o []byte, err error) {
 	}
 
 	o = msgp.AppendUint(o, KeyTuple)
-	return msgp.AppendIntf(o, q.Tuple)
+	return appendTuple(o, q.Tuple)
 }
 
 // MarshalMsg implements msgp.Marshaler
@@ -63,8 +63,10 @@ func (q *Insert) UnmarshalMsg(data []byte) (buf []byte, err error) {
 				return
 			}
 		case KeyTuple:
-			t, buf, err = msgp.ReadIntfBytes(buf)
-			if q.Tuple = t.([]interface{}); q.Tuple == nil {
+			if t, buf, err = readIntfBytes(buf); err != nil {
+				return
+			}
+			if q.Tuple, _ = t.([]interface{}); q.Tuple == nil {
 				return buf, errors.New("interface type is not []interface{}")
 			}
 		}
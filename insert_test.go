@@ -1,6 +1,7 @@
 package tarantool
 
 import (
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -83,3 +84,19 @@ func BenchmarkInsertPack(b *testing.B) {
 		buf, _ = (&Insert{Tuple: []interface{}{3, "Hello world"}}).MarshalMsg(buf[:0])
 	}
 }
+
+func BenchmarkInsertPacket(b *testing.B) {
+	b.ReportAllocs()
+	data := newPackData(uint64(512))
+
+	for i := 0; i < b.N; i++ {
+		pp := packetPool.GetWithID(uint64(i))
+		if err := pp.packMsg(&Insert{Tuple: []interface{}{int64(i), "name"}}, data); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := pp.WriteTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+		pp.Release()
+	}
+}
@@ -0,0 +1,282 @@
+// Package lifecycle implements the soft deletion and the expiration of tuples
+// by the field conventions, so they are handled the same way by all the services
+// sharing the spaces.
+//
+// A space opts in with a Policy naming its "deleted_at" and "expires_at" fields,
+// which keep the Unix time in seconds or null. The Lifecycle wraps the connection:
+// Delete sets deleted_at instead of deleting the tuple, Insert and Replace clear it
+// and set expires_at to now + TTL, and the soft-deleted and the expired tuples
+// are dropped from the results. Insert replaces the soft-deleted or expired tuple
+// with the same primary key, as if it had been deleted, and Upsert restores it
+// clearing deleted_at, with the operations applied to its fields:
+//
+//	policy, err := lifecycle.PolicyOf(conn, "sessions", time.Hour)
+//	l := lifecycle.New(conn, lifecycle.Policies{"sessions": policy})
+//	l.Exec(ctx, &tarantool.Delete{Space: "sessions", Key: id})
+//	// updates deleted_at of the tuple and returns it
+//
+// The tuples are filtered by the client, so Select may return fewer tuples than
+// its Limit. They stay in the space until Purge removes them.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+const (
+	// DeletedAtField is the name of the soft deletion field looked up by PolicyOf.
+	DeletedAtField = "deleted_at"
+	// ExpiresAtField is the name of the expiration field looked up by PolicyOf.
+	ExpiresAtField = "expires_at"
+)
+
+// ErrNoFields is returned by PolicyOf for the spaces without the convention fields.
+var ErrNoFields = errors.New("no deleted_at or expires_at field")
+
+// luaPurge deletes up to limit tuples deleted before the time or expired,
+// returning their number
+const luaPurge = `
+local space, deleted_at, expires_at, deleted_before, now, limit = ...
+local s = box.space[space]
+if s == nil then
+    error(string.format("space '%s' does not exist", space))
+end
+local key_def = require('key_def').new(s.index[0].parts)
+local keys = {}
+for _, t in s:pairs() do
+    if #keys >= limit then
+        break
+    end
+    local d = deleted_at > 0 and t[deleted_at] or nil
+    local e = expires_at > 0 and t[expires_at] or nil
+    if (d ~= nil and d <= deleted_before) or (e ~= nil and e <= now) then
+        table.insert(keys, key_def:extract_key(t))
+    end
+end
+for _, key in ipairs(keys) do
+    s:delete(key)
+end
+return #keys
+`
+
+// luaInsert inserts the tuple, replacing the soft-deleted or expired one
+// with the same primary key
+const luaInsert = `
+local space, tuple, deleted_at, expires_at, now = ...
+local s = box.space[space]
+if s == nil then
+    error(string.format("space '%s' does not exist", space))
+end
+local key_def = require('key_def').new(s.index[0].parts)
+return box.atomic(function()
+    local old = s:get(key_def:extract_key(box.tuple.new(tuple)))
+    if old ~= nil then
+        local d = deleted_at > 0 and old[deleted_at] or nil
+        local e = expires_at > 0 and old[expires_at] or nil
+        if d ~= nil or (e ~= nil and e <= now) then
+            return s:replace(tuple)
+        end
+    end
+    return s:insert(tuple)
+end)
+`
+
+// Policy is the lifecycle of the tuples of a space. The fields are numbered
+// starting with 0, the field 0 can't be used as it holds the primary key
+// in practice, so 0 disables the field.
+type Policy struct {
+	// DeletedAt is the field set to the time of the deletion.
+	DeletedAt int
+	// ExpiresAt is the field holding the time the tuple expires at.
+	ExpiresAt int
+	// TTL is the time to live of the inserted tuples without expires_at,
+	// they don't expire if it is 0.
+	TTL time.Duration
+}
+
+func (p Policy) last() int {
+	if p.DeletedAt > p.ExpiresAt {
+		return p.DeletedAt
+	}
+	return p.ExpiresAt
+}
+
+// Policies are the policies by space. The spaces are keyed the way
+// the queries reference them: by name or by decimal ID.
+type Policies map[string]Policy
+
+// SchemaConn is implemented by tarantool.Connection.
+type SchemaConn interface {
+	GetSpaceFields(space interface{}) ([]string, bool)
+}
+
+// PolicyOf returns the policy with the DeletedAtField and ExpiresAtField
+// fields of the space format and the TTL.
+func PolicyOf(conn SchemaConn, space interface{}, ttl time.Duration) (Policy, error) {
+	format, _ := conn.GetSpaceFields(space)
+
+	p := Policy{TTL: ttl}
+	for i, name := range format {
+		switch name {
+		case DeletedAtField:
+			p.DeletedAt = i
+		case ExpiresAtField:
+			p.ExpiresAt = i
+		}
+	}
+	if p.DeletedAt == 0 && p.ExpiresAt == 0 {
+		return p, fmt.Errorf("%w in space %v", ErrNoFields, space)
+	}
+	return p, nil
+}
+
+// Lifecycle executes the queries applying the policies. It implements tarantool.Executor,
+// so it can be passed to the other packages in place of the connection.
+type Lifecycle struct {
+	conn     tarantool.Executor
+	policies Policies
+}
+
+// New returns the Lifecycle of the spaces executing the queries with conn.
+func New(conn tarantool.Executor, policies Policies) *Lifecycle {
+	return &Lifecycle{conn: conn, policies: policies}
+}
+
+// Exec executes q applying the policy of its space. The query itself is not modified.
+func (l *Lifecycle) Exec(ctx context.Context, q tarantool.Query, options ...tarantool.ExecOption) *tarantool.Result {
+	var space interface{}
+	switch q := q.(type) {
+	case *tarantool.Select:
+		space = q.Space
+	case *tarantool.Insert:
+		space = q.Space
+	case *tarantool.Replace:
+		space = q.Space
+	case *tarantool.Delete:
+		space = q.Space
+	case *tarantool.Update:
+		space = q.Space
+	case *tarantool.Upsert:
+		space = q.Space
+	}
+	p, ok := l.policies[fmt.Sprint(space)]
+	if space == nil || !ok {
+		return l.conn.Exec(ctx, q, options...)
+	}
+
+	now := time.Now()
+	deleted := false
+	switch query := q.(type) {
+	case *tarantool.Insert:
+		q = &tarantool.Eval{
+			Expression: luaInsert,
+			Tuple:      []interface{}{query.Space, p.tuple(query.Tuple, now), lua(p.DeletedAt), lua(p.ExpiresAt), now.Unix()},
+		}
+	case *tarantool.Replace:
+		q = &tarantool.Replace{Space: query.Space, Tuple: p.tuple(query.Tuple, now)}
+	case *tarantool.Upsert:
+		u := *query
+		u.Tuple = p.tuple(query.Tuple, now)
+		if p.DeletedAt > 0 {
+			// the soft-deleted tuple is restored
+			u.Set = append([]tarantool.Operator{&tarantool.OpAssign{Field: int64(p.DeletedAt), Argument: nil}}, query.Set...)
+		}
+		q = &u
+	case *tarantool.Delete:
+		if p.DeletedAt > 0 {
+			q = &tarantool.Update{
+				Space:    query.Space,
+				Index:    query.Index,
+				Key:      query.Key,
+				KeyTuple: query.KeyTuple,
+				Set:      []tarantool.Operator{&tarantool.OpAssign{Field: int64(p.DeletedAt), Argument: now.Unix()}},
+			}
+			deleted = true
+		}
+	}
+
+	res := l.conn.Exec(ctx, q, options...)
+	if res.Error != nil || deleted {
+		// the deleted tuple is returned like Delete does
+		return res
+	}
+
+	data := res.Data[:0]
+	for _, tuple := range res.Data {
+		if p.live(tuple, now) {
+			data = append(data, tuple)
+		}
+	}
+	res.Data = data
+	return res
+}
+
+// tuple pads the tuple up to the policy fields, clears deleted_at and sets expires_at
+func (p Policy) tuple(tuple []interface{}, now time.Time) []interface{} {
+	n := len(tuple)
+	if last := p.last(); last >= n {
+		n = last + 1
+	}
+	t := make([]interface{}, n)
+	copy(t, tuple)
+
+	if p.DeletedAt > 0 {
+		t[p.DeletedAt] = nil
+	}
+	if p.ExpiresAt > 0 && p.TTL > 0 && t[p.ExpiresAt] == nil {
+		t[p.ExpiresAt] = now.Add(p.TTL).Unix()
+	}
+	return t
+}
+
+// live returns whether the tuple is neither deleted nor expired
+func (p Policy) live(tuple []interface{}, now time.Time) bool {
+	if p.DeletedAt > 0 && p.DeletedAt < len(tuple) && tuple[p.DeletedAt] != nil {
+		return false
+	}
+	if p.ExpiresAt > 0 && p.ExpiresAt < len(tuple) {
+		if expires, ok := typeconv.IntfToInt64(tuple[p.ExpiresAt]); ok && expires <= now.Unix() {
+			return false
+		}
+	}
+	return true
+}
+
+// lua returns the number of the field in Lua, where they start with 1,
+// 0 disables the field
+func lua(field int) int {
+	if field > 0 {
+		return field + 1
+	}
+	return 0
+}
+
+// Purge deletes up to limit tuples of the space deleted before the time or
+// expired and returns their number. It scans the whole space, so it should
+// be called in a loop with a moderate limit until it returns less than it.
+// The expiration is checked with the client clock, like in Exec.
+func (l *Lifecycle) Purge(ctx context.Context, space interface{}, deletedBefore time.Time, limit int) (int, error) {
+	p, ok := l.policies[fmt.Sprint(space)]
+	if !ok {
+		return 0, fmt.Errorf("no policy for space %v", space)
+	}
+
+	res := l.conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaPurge,
+		Tuple:      []interface{}{space, lua(p.DeletedAt), lua(p.ExpiresAt), deletedBefore.Unix(), time.Now().Unix(), limit},
+	})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 {
+		return 0, nil
+	}
+	n, _ := typeconv.IntfToInt(res.Data[0][0])
+	return n, nil
+}
@@ -0,0 +1,207 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// testSpace is the id of the first user space, the schema isn't loaded
+// by the test server, so the space names can't be used
+const testSpace = 512
+
+// newTestConn returns the connection to a server recording the queries
+// and answering them with the tuples returned by reply
+func newTestConn(t *testing.T, reply func(q tarantool.Query) [][]interface{}) (*tarantool.Connection, func() []tarantool.Query) {
+	var mu sync.Mutex
+	var queries []tarantool.Query
+
+	handler := func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+		if s, ok := q.(*tarantool.Select); ok {
+			if space, _ := typeconv.IntfToInt(s.Space); space < testSpace {
+				// the schema requests of Connect
+				return &tarantool.Result{}
+			}
+		}
+		mu.Lock()
+		queries = append(queries, q)
+		mu.Unlock()
+		return &tarantool.Result{Data: reply(q)}
+	}
+
+	addr := tnttest.NewServer(t, handler)
+
+	conn := tnttest.Connect(t, addr, nil)
+
+	return conn, func() []tarantool.Query {
+		mu.Lock()
+		defer mu.Unlock()
+		q := queries
+		queries = nil
+		return q
+	}
+}
+
+// policies keep deleted_at in the field 2 and expires_at in the field 3 of testSpace
+var policies = Policies{"512": {DeletedAt: 2, ExpiresAt: 3, TTL: time.Hour}}
+
+func TestLifecycleWrite(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn, received := newTestConn(t, func(q tarantool.Query) [][]interface{} {
+		switch q := q.(type) {
+		case *tarantool.Eval:
+			return [][]interface{}{q.Tuple[1].([]interface{})}
+		case *tarantool.Update:
+			return [][]interface{}{{int64(1), "a", time.Now().Unix(), nil}}
+		}
+		return nil
+	})
+	l := New(conn, policies)
+	ctx := context.Background()
+	now := time.Now().Unix()
+
+	tuple := []interface{}{int64(1), "a"}
+	res := l.Exec(ctx, &tarantool.Insert{Space: testSpace, Tuple: tuple})
+	require.NoError(res.Error)
+	require.Len(res.Data, 1)
+	assert.Len(tuple, 2, "the query is not modified")
+
+	expires := time.Now().Add(time.Hour).Unix()
+	require.NoError(l.Exec(ctx, &tarantool.Replace{Space: testSpace, Tuple: []interface{}{int64(1), "a", now, expires + 60}}).Error)
+
+	res = l.Exec(ctx, &tarantool.Delete{Space: testSpace, Index: 1, Key: "a"})
+	require.NoError(res.Error)
+	assert.Len(res.Data, 1, "the deleted tuple is returned")
+
+	require.NoError(l.Exec(ctx, &tarantool.Upsert{
+		Space: testSpace,
+		Tuple: []interface{}{int64(1), "a"},
+		Set:   []tarantool.Operator{&tarantool.OpAssign{Field: 1, Argument: "b"}},
+	}).Error)
+
+	queries := received()
+	require.Len(queries, 4)
+
+	// the insert replaces the soft-deleted or expired tuple
+	eval := queries[0].(*tarantool.Eval)
+	assert.Equal(luaInsert, eval.Expression)
+	require.Len(eval.Tuple, 5)
+	assert.Equal([]interface{}{int64(testSpace)}, eval.Tuple[:1])
+	insert := eval.Tuple[1].([]interface{})
+	require.Len(insert, 4)
+	assert.Equal([]interface{}{int64(1), "a", nil}, insert[:3])
+	assert.InDelta(expires, insert[3], 2)
+	assert.Equal([]interface{}{int64(3), int64(4)}, eval.Tuple[2:4])
+
+	// deleted_at is cleared, expires_at is kept
+	assert.Equal([]interface{}{int64(1), "a", nil, expires + 60}, queries[1].(*tarantool.Replace).Tuple)
+
+	update := queries[2].(*tarantool.Update)
+	assert.Equal(uint(1), update.Index)
+	assert.Equal("a", update.Key)
+	require.Len(update.Set, 1)
+	assign := update.Set[0].(*tarantool.OpAssign)
+	assert.Equal(int64(2), assign.Field)
+	assert.InDelta(now, assign.Argument, 2)
+
+	// the upsert restores the soft-deleted tuple
+	upsert := queries[3].(*tarantool.Upsert)
+	assert.Len(upsert.Tuple, 4)
+	assert.Equal([]tarantool.Operator{
+		&tarantool.OpAssign{Field: 2, Argument: nil},
+		&tarantool.OpAssign{Field: 1, Argument: "b"},
+	}, upsert.Set)
+}
+
+func TestLifecycleSelect(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	now := time.Now().Unix()
+	conn, _ := newTestConn(t, func(q tarantool.Query) [][]interface{} {
+		if _, ok := q.(*tarantool.Eval); ok {
+			return [][]interface{}{{int64(42)}}
+		}
+		return [][]interface{}{
+			{int64(1), "live", nil, now + 60},
+			{int64(2), "deleted", now - 60, now + 60},
+			{int64(3), "expired", nil, now - 1},
+			{int64(4), "short"},
+			{int64(5), "forever", nil, nil},
+		}
+	})
+	l := New(conn, policies)
+	ctx := context.Background()
+
+	res := l.Exec(ctx, &tarantool.Select{Space: testSpace, Iterator: tarantool.IterAll, Limit: 10})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{
+		{int64(1), "live", nil, now + 60},
+		{int64(4), "short"},
+		{int64(5), "forever", nil, nil},
+	}, res.Data)
+
+	// the other spaces and queries are passed as is
+	res = l.Exec(ctx, &tarantool.Select{Space: testSpace + 1, Iterator: tarantool.IterAll, Limit: 10})
+	require.NoError(res.Error)
+	assert.Len(res.Data, 5)
+	res = l.Exec(ctx, &tarantool.Eval{Expression: "return 42"})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(42)}}, res.Data)
+}
+
+func TestPurge(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn, received := newTestConn(t, func(q tarantool.Query) [][]interface{} {
+		return [][]interface{}{{int64(3)}}
+	})
+	l := New(conn, Policies{"512": {ExpiresAt: 3}})
+	ctx := context.Background()
+
+	before := time.Now().Add(-time.Hour)
+	n, err := l.Purge(ctx, testSpace, before, 100)
+	require.NoError(err)
+	assert.Equal(3, n)
+
+	queries := received()
+	require.Len(queries, 1)
+	eval := queries[0].(*tarantool.Eval)
+	assert.Equal(luaPurge, eval.Expression)
+	require.Len(eval.Tuple, 6)
+	// the Lua fields start with 1, deleted_at is disabled
+	assert.Equal([]interface{}{int64(testSpace), int64(0), int64(4), before.Unix()}, eval.Tuple[:4])
+	assert.Equal(int64(100), eval.Tuple[5])
+
+	_, err = l.Purge(ctx, "unknown", before, 100)
+	assert.Error(err)
+}
+
+type fakeSchema []string
+
+func (s fakeSchema) GetSpaceFields(space interface{}) ([]string, bool) {
+	return s, true
+}
+
+func TestPolicyOf(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	p, err := PolicyOf(fakeSchema{"id", "token", "expires_at", "deleted_at"}, "sessions", time.Minute)
+	require.NoError(err)
+	assert.Equal(Policy{DeletedAt: 3, ExpiresAt: 2, TTL: time.Minute}, p)
+
+	_, err = PolicyOf(fakeSchema{"id", "token"}, "sessions", 0)
+	assert.True(errors.Is(err, ErrNoFields))
+}
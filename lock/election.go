@@ -0,0 +1,157 @@
+package lock
+
+import (
+	"context"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/pubsub"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// luaLeader returns the owner and the token of the lock if it is held
+const luaLeader = `
+local space, name = ...
+local s = box.space[space]
+if s == nil then
+    return
+end
+local t = s:get(name)
+if t == nil or t[4] <= require('clock').time() then
+    return
+end
+return t[2], t[3]
+`
+
+// Leadership describes the current leader of an election.
+type Leadership struct {
+	// ID is the identity of the leader, empty if there is no leader.
+	ID string
+	// Token is the fencing token of the leader's term.
+	Token uint64
+}
+
+// Elector takes part in the election of a single leader among the candidates
+// sharing the election name. The leader holds the lock of the same name, so
+// it keeps the leadership until it resigns or fails to renew the lease.
+type Elector struct {
+	*Mutex
+}
+
+// NewElector returns the candidate with the identity id, e.g. the host name,
+// which must be unique among the candidates. The Owner option is ignored.
+func NewElector(conn tarantool.Executor, name, id string, opts *Options) *Elector {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+	o.Owner = id
+	return &Elector{Mutex: NewMutex(conn, name, &o)}
+}
+
+// Campaign waits until the candidate becomes the leader or ctx is done.
+// The leadership ends with Resign, or when the Lost channel is closed.
+func (e *Elector) Campaign(ctx context.Context) error {
+	return e.Lock(ctx)
+}
+
+// Resign gives up the leadership so another candidate can take it at once.
+func (e *Elector) Resign(ctx context.Context) error {
+	return e.Unlock(ctx)
+}
+
+// IsLeader reports whether the candidate holds the leadership, as far as it
+// knows: it may have already expired on the server if the renewals fail.
+func (e *Elector) IsLeader() bool {
+	select {
+	case <-e.Lost():
+		return false
+	default:
+		return e.Token() != 0
+	}
+}
+
+// Leader returns the current leader as seen by the server.
+func (e *Elector) Leader(ctx context.Context) (Leadership, error) {
+	res := e.conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaLeader,
+		Tuple:      []interface{}{e.opts.Space, e.name},
+	})
+	if res.Error != nil {
+		return Leadership{}, res.Error
+	}
+	if len(res.Data) < 2 || len(res.Data[0]) == 0 || len(res.Data[1]) == 0 {
+		return Leadership{}, nil
+	}
+	id, _ := res.Data[0][0].(string)
+	token, _ := typeconv.IntfToUint64(res.Data[1][0])
+	return Leadership{ID: id, Token: token}, nil
+}
+
+// Observe returns the channel receiving the current leadership and then each
+// change of it. The channel is closed when ctx is done.
+//
+// If the Elector is given a tarantool.Connection or Connector, the leadership
+// is read again whenever the lock broadcasts a new owner, Tarantool >= 2.10.0,
+// and every TTL to notice the leases expired without a successor.
+// Otherwise the server is polled every RetryInterval. The errors of reading
+// the leadership are ignored.
+func (e *Elector) Observe(ctx context.Context) <-chan Leadership {
+	ch := make(chan Leadership)
+	go func() {
+		defer close(ch)
+
+		interval := e.opts.RetryInterval
+		var events <-chan pubsub.Message
+		if d := e.dialer(); d != nil {
+			s := &pubsub.Subscriber{Dialer: d, RetryInterval: e.opts.RetryInterval}
+			events = s.Subscribe(ctx, e.eventKey())
+			interval = e.opts.TTL
+		}
+
+		t := time.NewTicker(interval)
+		defer t.Stop()
+
+		var last *Leadership
+		for {
+			if l, err := e.Leader(ctx); err == nil && (last == nil || *last != l) {
+				select {
+				case ch <- l:
+					last = &l
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case <-t.C:
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+// dialer returns the connection to watch the lock on, nil if it can't be watched
+func (e *Elector) dialer() pubsub.Dialer {
+	switch conn := e.conn.(type) {
+	case pubsub.Dialer:
+		return conn
+	case *tarantool.Connection:
+		return connDialer{conn}
+	}
+	return nil
+}
+
+// connDialer returns the same connection, it is not reestablished once closed
+type connDialer struct {
+	conn *tarantool.Connection
+}
+
+func (d connDialer) Connect() (*tarantool.Connection, error) {
+	return d.conn, nil
+}
@@ -0,0 +1,79 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+func TestElector(t *testing.T) {
+	s := &fakeServer{locks: map[string]*fakeLock{}}
+	conn := tnttest.Connect(t, newFakeServer(t, s), nil)
+
+	t.Run("Watch", func(t *testing.T) {
+		// the changes are observed long before the leadership is polled
+		testElector(t, conn, "watched", &Options{TTL: time.Minute, RetryInterval: 10 * time.Millisecond})
+	})
+	t.Run("Poll", func(t *testing.T) {
+		// the wrapper of the connection can't be watched
+		exec := struct{ tarantool.Executor }{conn}
+		testElector(t, exec, "polled", &Options{TTL: time.Second, RetryInterval: 10 * time.Millisecond})
+	})
+}
+
+func testElector(t *testing.T, conn tarantool.Executor, name string, opts *Options) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := NewElector(conn, name, "a", opts)
+	b := NewElector(conn, name, "b", opts)
+
+	changes := a.Observe(ctx)
+	next := func() Leadership {
+		select {
+		case l := <-changes:
+			return l
+		case <-time.After(time.Second):
+			t.Fatal("no leadership change observed")
+		}
+		return Leadership{}
+	}
+	assert.Equal(Leadership{}, next())
+
+	require.NoError(a.Campaign(ctx))
+	assert.True(a.IsLeader())
+	assert.False(b.IsLeader())
+	assert.Equal(Leadership{ID: "a", Token: 1}, next())
+
+	elected := make(chan error, 1)
+	go func() {
+		elected <- b.Campaign(ctx)
+	}()
+
+	require.NoError(a.Resign(ctx))
+	assert.False(a.IsLeader())
+	require.NoError(<-elected)
+	assert.True(b.IsLeader())
+
+	l, err := b.Leader(ctx)
+	require.NoError(err)
+	assert.Equal(Leadership{ID: "b", Token: 2}, l)
+
+	// the gap between the leaders may be missed
+	if l = next(); l.ID == "" {
+		l = next()
+	}
+	assert.Equal(Leadership{ID: "b", Token: 2}, l)
+
+	cancel()
+	for range changes {
+	}
+}
@@ -0,0 +1,356 @@
+// Package lock implements a distributed mutex kept in a Tarantool space.
+//
+// A lock is a lease: it is held for TTL and renewed by the owner in the
+// background until released. If the owner can't renew it in time, e.g.
+// it is partitioned from the server, the lease expires and the lock may be
+// acquired by another owner. The Lost channel tells the owner it must stop
+// relying on the lock, but it may learn that too late, so the writes guarded
+// by the lock should be checked against its fencing token, which grows with
+// every acquisition of the lock.
+//
+// The scripts are executed with Eval, so the user needs the execute privilege
+// on the universe. The expiration is checked with the server clock.
+package lock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+const (
+	// DefaultSpace is the name of the space to keep the locks in.
+	DefaultSpace = "_locks"
+	// DefaultTTL is the lease time of a lock.
+	DefaultTTL = 10 * time.Second
+	// DefaultRetryInterval is the interval between the attempts of Lock
+	// to acquire a busy lock.
+	DefaultRetryInterval = 100 * time.Millisecond
+)
+
+var (
+	// ErrHeld is returned by Lock and TryLock if the Mutex already holds the lock.
+	ErrHeld = errors.New("lock is already held")
+	// ErrNotHeld is returned by Unlock if the Mutex doesn't hold the lock.
+	ErrNotHeld = errors.New("lock is not held")
+	// ErrLost is returned by Unlock if the lock has expired or been taken over.
+	ErrLost = errors.New("lock is lost")
+)
+
+// luaAcquire takes the lock if it is free or expired and returns its new fencing
+// token, or 0 if the lock is busy. If the owner already holds the lock, it is
+// renewed and the token is kept, so retrying a lost response is harmless.
+// A new owner is broadcast with the key of eventKey on Tarantool >= 2.10.0.
+const luaAcquire = `
+local space, name, owner, ttl = ...
+if box.space[space] == nil then
+    box.schema.space.create(space, {
+        if_not_exists = true,
+        format = {
+            {name = 'name', type = 'string'},
+            {name = 'owner', type = 'string'},
+            {name = 'token', type = 'unsigned'},
+            {name = 'expires', type = 'number'},
+        },
+    })
+    box.space[space]:create_index('primary', {parts = {1, 'string'}, if_not_exists = true})
+end
+local s = box.space[space]
+local token, acquired = box.atomic(function()
+    local now = require('clock').time()
+    local t = s:get(name)
+    if t == nil then
+        s:insert({name, owner, 1, now + ttl})
+        return 1, true
+    end
+    if t[4] > now then
+        if t[2] ~= owner then
+            return 0
+        end
+        s:update(name, {{'=', 4, now + ttl}})
+        return t[3]
+    end
+    s:replace({name, owner, t[3] + 1, now + ttl})
+    return t[3] + 1, true
+end)
+if acquired and box.broadcast ~= nil then
+    box.broadcast('lock:' .. space .. ':' .. name, {owner, token})
+end
+return token
+`
+
+// luaRenew extends the lease, it returns false if the lock is not held
+// by the owner anymore
+const luaRenew = `
+local space, name, owner, token, ttl = ...
+local s = box.space[space]
+return box.atomic(function()
+    local now = require('clock').time()
+    local t = s:get(name)
+    if t == nil or t[2] ~= owner or t[3] ~= token or t[4] <= now then
+        return false
+    end
+    s:update(name, {{'=', 4, now + ttl}})
+    return true
+end)
+`
+
+// luaRelease frees the lock, keeping the token for the next owner, and
+// broadcasts it like luaAcquire; it returns false if the lock is not held
+// by the owner anymore
+const luaRelease = `
+local space, name, owner, token = ...
+local s = box.space[space]
+local released = box.atomic(function()
+    local t = s:get(name)
+    if t == nil or t[2] ~= owner or t[3] ~= token or t[4] <= require('clock').time() then
+        return false
+    end
+    s:update(name, {{'=', 2, ''}, {'=', 4, 0}})
+    return true
+end)
+if released and box.broadcast ~= nil then
+    box.broadcast('lock:' .. space .. ':' .. name, {'', token})
+end
+return released
+`
+
+// Options of a Mutex, zero values are replaced with the defaults.
+type Options struct {
+	// Space is the name of the space to keep the locks in,
+	// it is created on first use.
+	Space string
+	// TTL is the lease time, the lock expires if the owner doesn't renew it.
+	TTL time.Duration
+	// RenewInterval is the interval between the renewals of the lease,
+	// a third of TTL by default.
+	RenewInterval time.Duration
+	// RetryInterval is the interval between the attempts of Lock
+	// to acquire a busy lock.
+	RetryInterval time.Duration
+	// Owner identifies the holder of the lock, a random UUID by default.
+	// It must be unique among the contenders for the lock.
+	Owner string
+}
+
+// Mutex is a distributed lock identified by its name. A Mutex can hold
+// the lock once at a time, it is safe for concurrent use.
+type Mutex struct {
+	conn  tarantool.Executor
+	name  string
+	owner string
+	opts  Options
+
+	mu    sync.Mutex
+	lease *lease
+}
+
+// lease is a single acquisition of the lock
+type lease struct {
+	token uint64
+	lost  chan struct{}
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// NewMutex returns the Mutex for the lock name.
+func NewMutex(conn tarantool.Executor, name string, opts *Options) *Mutex {
+	m := &Mutex{conn: conn, name: name}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.owner = m.opts.Owner; m.owner == "" {
+		m.owner = uuid.New().String()
+	}
+	if m.opts.Space == "" {
+		m.opts.Space = DefaultSpace
+	}
+	if m.opts.TTL <= 0 {
+		m.opts.TTL = DefaultTTL
+	}
+	if m.opts.RenewInterval <= 0 {
+		m.opts.RenewInterval = m.opts.TTL / 3
+	}
+	if m.opts.RetryInterval <= 0 {
+		m.opts.RetryInterval = DefaultRetryInterval
+	}
+	return m
+}
+
+// TryLock makes a single attempt to acquire the lock. It returns false if the
+// lock is held by another owner.
+func (m *Mutex) TryLock(ctx context.Context) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.lease != nil {
+		return false, ErrHeld
+	}
+
+	token, err := m.acquire(ctx)
+	if token == 0 || err != nil {
+		return false, err
+	}
+	l := &lease{
+		token: token,
+		lost:  make(chan struct{}),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	m.lease = l
+	go m.renew(l)
+	return true, nil
+}
+
+// Lock acquires the lock, waiting until it is released or expires,
+// or ctx is done.
+func (m *Mutex) Lock(ctx context.Context) error {
+	t := time.NewTicker(m.opts.RetryInterval)
+	defer t.Stop()
+
+	for {
+		ok, err := m.TryLock(ctx)
+		if ok || err != nil {
+			return err
+		}
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return fmt.Errorf("lock %s wait: %w", m.name, ctx.Err())
+		}
+	}
+}
+
+// acquire makes an attempt to take the lock and returns its token, or 0 if it is busy
+func (m *Mutex) acquire(ctx context.Context) (uint64, error) {
+	res := m.conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaAcquire,
+		Tuple:      []interface{}{m.opts.Space, m.name, m.owner, m.opts.TTL.Seconds()},
+	})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 {
+		return 0, tarantool.ErrBadResult
+	}
+	token, ok := typeconv.IntfToUint64(res.Data[0][0])
+	if !ok {
+		return 0, fmt.Errorf("%w: token %#v", tarantool.ErrBadResult, res.Data[0][0])
+	}
+	return token, nil
+}
+
+// renew extends the lease until it is released, or lost: taken over,
+// or not renewed for TTL
+func (m *Mutex) renew(l *lease) {
+	defer close(l.done)
+
+	t := time.NewTicker(m.opts.RenewInterval)
+	defer t.Stop()
+
+	renewed := time.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-t.C:
+		}
+
+		deadline := renewed.Add(m.opts.TTL)
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		ok, err := m.call(ctx, luaRenew, l, m.opts.TTL.Seconds())
+		cancel()
+
+		switch {
+		case err == nil && ok:
+			renewed = time.Now()
+			continue
+		case err != nil && time.Now().Before(deadline):
+			// try again on the next tick while the lease may still be valid
+			continue
+		}
+		close(l.lost)
+		return
+	}
+}
+
+// call runs the renew or release script
+func (m *Mutex) call(ctx context.Context, expr string, l *lease, args ...interface{}) (bool, error) {
+	tuple := []interface{}{m.opts.Space, m.name, m.owner, l.token}
+	res := m.conn.Exec(ctx, &tarantool.Eval{Expression: expr, Tuple: append(tuple, args...)})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 {
+		return false, tarantool.ErrBadResult
+	}
+	ok, _ := res.Data[0][0].(bool)
+	return ok, nil
+}
+
+// Unlock releases the lock. It returns ErrLost if the lock has expired or been
+// acquired by another owner before, the Mutex doesn't hold it anyway then.
+func (m *Mutex) Unlock(ctx context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	l := m.lease
+	if l == nil {
+		return ErrNotHeld
+	}
+	// the lease is not renewed anymore, so it expires
+	// even if the release doesn't reach the server
+	m.lease = nil
+	close(l.stop)
+	<-l.done
+
+	select {
+	case <-l.lost:
+		return ErrLost
+	default:
+	}
+
+	ok, err := m.call(ctx, luaRelease, l)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrLost
+	}
+	return nil
+}
+
+// eventKey is the key the changes of the lock owner are broadcast with,
+// see luaAcquire
+func (m *Mutex) eventKey() string {
+	return "lock:" + m.opts.Space + ":" + m.name
+}
+
+// Token returns the fencing token of the held lock, or 0 if it is not held.
+// The tokens of the subsequent acquisitions of the lock grow, so a storage
+// can reject a write with a token lower than the one it has seen.
+func (m *Mutex) Token() uint64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lease == nil {
+		return 0
+	}
+	return m.lease.token
+}
+
+// Lost returns the channel closed when the held lock is lost, it is nil
+// if the lock is not held.
+func (m *Mutex) Lost() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.lease == nil {
+		return nil
+	}
+	return m.lease.lost
+}
@@ -0,0 +1,204 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+type fakeLock struct {
+	owner   string
+	token   uint64
+	expires time.Time
+}
+
+// fakeServer executes the lock scripts against the in-memory locks
+type fakeServer struct {
+	sync.Mutex
+	locks  map[string]*fakeLock
+	renews int
+	// watchers of the keys, see fakeWatch, and the last values broadcast
+	watchers map[string]map[*tarantool.IprotoServer]*fakeWatch
+	values   map[string]interface{}
+}
+
+// fakeWatch is the watch of a key by a connection
+type fakeWatch struct {
+	// pending is set until the last event is acknowledged
+	pending bool
+	// dirty is set if the key changes while the event is pending
+	dirty bool
+}
+
+func (s *fakeServer) handle(srv *tarantool.IprotoServer, q tarantool.Query) *tarantool.Result {
+	s.Lock()
+	defer s.Unlock()
+
+	switch q := q.(type) {
+	case *tarantool.Watch:
+		if s.watchers[q.Key] == nil {
+			s.watchers[q.Key] = map[*tarantool.IprotoServer]*fakeWatch{}
+		}
+		w := s.watchers[q.Key][srv]
+		switch {
+		case w == nil:
+			s.watchers[q.Key][srv] = &fakeWatch{pending: true}
+			srv.SendEvent(q.Key, s.values[q.Key])
+		case w.pending && w.dirty:
+			w.dirty = false
+			srv.SendEvent(q.Key, s.values[q.Key])
+		default:
+			w.pending = false
+		}
+		return nil
+	case *tarantool.Unwatch:
+		delete(s.watchers[q.Key], srv)
+		return nil
+	}
+
+	eval, ok := q.(*tarantool.Eval)
+	if !ok {
+		return &tarantool.Result{}
+	}
+
+	name := eval.Tuple[1].(string)
+	now := time.Now()
+	l := s.locks[name]
+
+	if eval.Expression == luaLeader {
+		if l == nil || !l.expires.After(now) {
+			return &tarantool.Result{}
+		}
+		return &tarantool.Result{Data: [][]interface{}{{l.owner}, {l.token}}}
+	}
+	owner := eval.Tuple[2].(string)
+
+	held := func() bool {
+		token, _ := typeconv.IntfToUint64(eval.Tuple[3])
+		return l != nil && l.owner == owner && l.token == token && l.expires.After(now)
+	}
+	ttl := func(v interface{}) time.Duration {
+		return time.Duration(v.(float64) * float64(time.Second))
+	}
+
+	var res interface{}
+	switch eval.Expression {
+	case luaAcquire:
+		switch {
+		case l == nil:
+			l = &fakeLock{token: 1}
+			s.locks[name] = l
+		case l.expires.After(now) && l.owner != owner:
+			return &tarantool.Result{Data: [][]interface{}{{uint64(0)}}}
+		case !l.expires.After(now):
+			l.token++
+		}
+		if l.owner != owner || !l.expires.After(now) {
+			s.broadcast(name, owner, l.token)
+		}
+		l.owner = owner
+		l.expires = now.Add(ttl(eval.Tuple[3]))
+		res = l.token
+	case luaRenew:
+		s.renews++
+		if res = held(); res == true {
+			l.expires = now.Add(ttl(eval.Tuple[4]))
+		}
+	case luaRelease:
+		if res = held(); res == true {
+			l.owner = ""
+			l.expires = time.Time{}
+			s.broadcast(name, "", l.token)
+		}
+	}
+	return &tarantool.Result{Data: [][]interface{}{{res}}}
+}
+
+// broadcast sends the new owner of the lock to its watchers
+func (s *fakeServer) broadcast(name, owner string, token uint64) {
+	key := "lock:" + DefaultSpace + ":" + name
+	s.values[key] = []interface{}{owner, token}
+	for srv, w := range s.watchers[key] {
+		if w.pending {
+			w.dirty = true
+			continue
+		}
+		w.pending = true
+		srv.SendEvent(key, s.values[key])
+	}
+}
+
+func newFakeServer(t *testing.T, s *fakeServer) string {
+	s.watchers = map[string]map[*tarantool.IprotoServer]*fakeWatch{}
+	s.values = map[string]interface{}{}
+	return tnttest.NewServerFunc(t, func() *tarantool.IprotoServer {
+		var srv *tarantool.IprotoServer
+		srv = tarantool.NewIprotoServer("", func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+			return s.handle(srv, q)
+		}, nil)
+		return srv
+	})
+}
+
+func TestMutex(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{locks: map[string]*fakeLock{}}
+	conn, err := tarantool.Connect(newFakeServer(t, s), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	opts := &Options{TTL: 300 * time.Millisecond, RenewInterval: 50 * time.Millisecond, RetryInterval: 10 * time.Millisecond}
+	m1 := NewMutex(conn, "job", opts)
+	m2 := NewMutex(conn, "job", opts)
+
+	assert.Equal(ErrNotHeld, m1.Unlock(ctx))
+	assert.Nil(m1.Lost())
+
+	require.NoError(m1.Lock(ctx))
+	assert.Equal(uint64(1), m1.Token())
+	assert.Equal(ErrHeld, m1.Lock(ctx))
+
+	ok, err := m2.TryLock(ctx)
+	assert.NoError(err)
+	assert.False(ok)
+	assert.Equal(uint64(0), m2.Token())
+
+	// the lease is renewed beyond its TTL
+	wctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	err = m2.Lock(wctx)
+	cancel()
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+	s.Lock()
+	assert.True(s.renews > 0)
+	s.Unlock()
+
+	require.NoError(m1.Unlock(ctx))
+	require.NoError(m2.Lock(ctx))
+	assert.Equal(uint64(2), m2.Token())
+
+	// the lock is taken over, e.g. the lease has expired while the owner was paused
+	lost := m2.Lost()
+	s.Lock()
+	s.locks["job"].owner = "other"
+	s.locks["job"].token++
+	s.Unlock()
+
+	select {
+	case <-lost:
+	case <-time.After(time.Second):
+		t.Fatal("the lost lock is not detected")
+	}
+	assert.Equal(ErrLost, m2.Unlock(ctx))
+	assert.Equal(uint64(0), m2.Token())
+}
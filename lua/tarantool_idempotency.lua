@@ -0,0 +1,161 @@
+-- Idempotency keys for stored procedures.
+--
+-- The client sends a key along with the call, see tarantool.IdempotentCall,
+-- and the function is executed at most once per key: retries of the call
+-- get the result of the first execution until the key expires.
+--
+-- Setup:
+--
+--     local idempotency = require('tarantool_idempotency')
+--     idempotency.init({ttl = 3600, expire_interval = 60})
+--     box.schema.func.create('idempotent_call', {if_not_exists = true})
+--     box.schema.user.grant('user', 'execute', 'function', 'idempotent_call', {if_not_exists = true})
+--
+-- The called function runs in the transaction which stores its result, so
+-- either both its changes and the key are committed or none of them. Thus it
+-- must not commit on its own or yield, e.g. it can't forward the call over
+-- the network. Router functions like crud.* must pass the key to the storages
+-- and call the storage procedures via idempotent_call there.
+
+local fiber = require('fiber')
+local clock = require('clock')
+
+local SPACE = '_idempotency_keys'
+
+local ttl = 3600
+local expiration
+
+-- the keys of the calls being executed, the retries wait for them to finish
+local inflight = {}
+
+-- resolve finds the global function by its name, e.g. 'module.func'.
+local function resolve(name)
+    local f = _G
+    for part in string.gmatch(name, '[^.]+') do
+        if type(f) ~= 'table' then
+            return nil
+        end
+        f = f[part]
+    end
+    if type(f) ~= 'function' then
+        return nil
+    end
+    return f
+end
+
+-- pack returns the values as an array, nils are replaced with box.NULL
+-- so the array has no holes and is stored as msgpack array.
+local function pack(...)
+    local values = {...}
+    for i = 1, select('#', ...) do
+        if values[i] == nil then
+            values[i] = box.NULL
+        end
+    end
+    return values
+end
+
+-- call executes the function name with the arguments unless it has already
+-- been executed with the key, then the stored result is returned.
+local function call(key, name, ...)
+    if type(key) ~= 'string' or key == '' then
+        box.error(box.error.PROC_LUA, 'idempotency key must be a non-empty string')
+    end
+
+    while inflight[key] ~= nil do
+        inflight[key]:wait()
+    end
+
+    local space = box.space[SPACE]
+    local t = space:get(key)
+    if t ~= nil and t[2] > clock.realtime() then
+        return unpack(t[3])
+    end
+
+    local f = resolve(name)
+    if f == nil then
+        box.error(box.error.NO_SUCH_PROC, name)
+    end
+
+    local cond = fiber.cond()
+    inflight[key] = cond
+
+    local args = {n = select('#', ...), ...}
+    local ok, res = pcall(function()
+        box.begin()
+        local result = pack(f(unpack(args, 1, args.n)))
+        space:replace({key, clock.realtime() + ttl, result})
+        box.commit()
+        return result
+    end)
+
+    inflight[key] = nil
+    cond:broadcast()
+
+    if not ok then
+        if box.is_in_txn ~= nil and box.is_in_txn() then
+            box.rollback()
+        end
+        error(res)
+    end
+    return unpack(res)
+end
+
+-- expire removes the keys which have expired, at most limit of them.
+-- It returns the number of removed keys.
+local function expire(limit)
+    local space = box.space[SPACE]
+    local keys = {}
+    for _, t in space.index.expires:pairs(clock.realtime(), {iterator = 'LT'}) do
+        if limit ~= nil and #keys >= limit then
+            break
+        end
+        table.insert(keys, t[1])
+    end
+    for _, key in ipairs(keys) do
+        space:delete(key)
+    end
+    return #keys
+end
+
+-- init creates the space for the keys and installs the idempotent_call
+-- global function. Options:
+--   ttl             - seconds to keep the keys, 3600 by default;
+--   expire_interval - seconds between the removals of the expired keys
+--                     by a background fiber, it isn't started if not set.
+local function init(opts)
+    opts = opts or {}
+    ttl = opts.ttl or ttl
+
+    local space = box.schema.space.create(SPACE, {
+        if_not_exists = true,
+        format = {
+            {name = 'key', type = 'string'},
+            {name = 'expires', type = 'number'},
+            {name = 'result', type = 'array'},
+        },
+    })
+    space:create_index('primary', {parts = {1, 'string'}, if_not_exists = true})
+    space:create_index('expires', {parts = {2, 'number'}, unique = false, if_not_exists = true})
+
+    rawset(_G, 'idempotent_call', call)
+
+    if opts.expire_interval ~= nil and expiration == nil then
+        expiration = fiber.create(function()
+            fiber.name('idempotency_expire')
+            while true do
+                fiber.sleep(opts.expire_interval)
+                local ok, err = pcall(expire, 1000)
+                if not ok then
+                    require('log').error('failed to expire idempotency keys: %s', err)
+                end
+            end
+        end)
+    end
+end
+
+return {
+    init = init,
+    call = call,
+    expire = expire,
+}
@@ -0,0 +1,153 @@
+package tarantool
+
+import (
+	"context"
+	"expvar"
+	"time"
+)
+
+// luaSlabInfo returns box.slab.info() sizes as a flat list of integers,
+// the ratios are computed on the client side
+const luaSlabInfo = `
+local s = box.slab.info()
+return {
+    tonumber64(s.items_size), tonumber64(s.items_used),
+    tonumber64(s.quota_size), tonumber64(s.quota_used),
+    tonumber64(s.arena_size), tonumber64(s.arena_used),
+}
+`
+
+// luaRuntimeInfo returns box.runtime.info() as a flat list of integers
+const luaRuntimeInfo = `
+local r = box.runtime.info()
+return {tonumber64(r.lua), tonumber64(r.used), tonumber64(r.maxalloc)}
+`
+
+// SlabInfo is the memtx memory usage reported by box.slab.info, in bytes.
+type SlabInfo struct {
+	// ItemsSize is the memory allocated for the tuples and indexes,
+	// ItemsUsed is the part of it holding the data.
+	ItemsSize uint64
+	ItemsUsed uint64
+	// QuotaSize is memtx_memory, QuotaUsed is the part of it taken by the arena.
+	QuotaSize uint64
+	QuotaUsed uint64
+	// ArenaSize is the memory allocated for the slabs,
+	// ArenaUsed is the part of it in use.
+	ArenaSize uint64
+	ArenaUsed uint64
+}
+
+// QuotaUsedRatio is the share of memtx_memory in use. Once it approaches 1
+// and ArenaUsedRatio is high as well, inserts start to fail with ER_MEMORY_ISSUE.
+func (s *SlabInfo) QuotaUsedRatio() float64 {
+	return ratio(s.QuotaUsed, s.QuotaSize)
+}
+
+// ArenaUsedRatio is the share of the allocated slabs in use.
+// A low value means the memory is fragmented.
+func (s *SlabInfo) ArenaUsedRatio() float64 {
+	return ratio(s.ArenaUsed, s.ArenaSize)
+}
+
+// ItemsUsedRatio is the share of the memory allocated for the items which holds the data.
+func (s *SlabInfo) ItemsUsedRatio() float64 {
+	return ratio(s.ItemsUsed, s.ItemsSize)
+}
+
+// RuntimeInfo is the memory used outside memtx as reported by box.runtime.info, in bytes.
+type RuntimeInfo struct {
+	// Lua is the memory used by the Lua heap.
+	Lua uint64
+	// Used is the memory used by the runtime arena,
+	// MaxAlloc is its limit.
+	Used     uint64
+	MaxAlloc uint64
+}
+
+// SlabInfo returns the memtx memory usage of the instance.
+func (conn *Connection) SlabInfo(ctx context.Context) (*SlabInfo, error) {
+	v, err := conn.evalUints(ctx, luaSlabInfo, 6)
+	if err != nil {
+		return nil, err
+	}
+	return &SlabInfo{
+		ItemsSize: v[0],
+		ItemsUsed: v[1],
+		QuotaSize: v[2],
+		QuotaUsed: v[3],
+		ArenaSize: v[4],
+		ArenaUsed: v[5],
+	}, nil
+}
+
+// RuntimeInfo returns the memory usage of the instance outside memtx.
+func (conn *Connection) RuntimeInfo(ctx context.Context) (*RuntimeInfo, error) {
+	v, err := conn.evalUints(ctx, luaRuntimeInfo, 3)
+	if err != nil {
+		return nil, err
+	}
+	return &RuntimeInfo{
+		Lua:      v[0],
+		Used:     v[1],
+		MaxAlloc: v[2],
+	}, nil
+}
+
+// MemoryInfoVar returns the expvar variable reporting the SlabInfo, its ratios
+// and the RuntimeInfo of the instance, like the PerfCount counters are:
+//
+//	expvar.Publish("tarantool_memory", conn.MemoryInfoVar(time.Second))
+//
+// The info is requested within timeout every time the variable is read,
+// a failed request is reported in the "error" field.
+func (conn *Connection) MemoryInfoVar(timeout time.Duration) expvar.Func {
+	return func() interface{} {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		slab, err := conn.SlabInfo(ctx)
+		if err != nil {
+			return map[string]interface{}{"error": err.Error()}
+		}
+		runtime, err := conn.RuntimeInfo(ctx)
+		if err != nil {
+			return map[string]interface{}{"error": err.Error()}
+		}
+		return map[string]interface{}{
+			"slab":             slab,
+			"quota_used_ratio": slab.QuotaUsedRatio(),
+			"arena_used_ratio": slab.ArenaUsedRatio(),
+			"items_used_ratio": slab.ItemsUsedRatio(),
+			"runtime":          runtime,
+		}
+	}
+}
+
+// evalUints evaluates expr returning a list of n integers.
+func (conn *Connection) evalUints(ctx context.Context, expr string, n int) ([]uint64, error) {
+	res := conn.Exec(ctx, &Eval{Expression: expr})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0]) != n {
+		return nil, ErrBadResult
+	}
+
+	v := make([]uint64, n)
+	for i, number := range res.Data[0] {
+		u, err := numberToUint64(number)
+		if err != nil {
+			return nil, ErrBadResult
+		}
+		v[i] = u
+	}
+	return v, nil
+}
+
+func ratio(used, size uint64) float64 {
+	if size == 0 {
+		return 0
+	}
+	return float64(used) / float64(size)
+}
@@ -0,0 +1,68 @@
+package tarantool
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryInfo(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		eval, ok := q.(*Eval)
+		switch {
+		case !ok:
+			return &Result{}
+		case strings.Contains(eval.Expression, "box.slab.info"):
+			return &Result{Data: [][]interface{}{{int64(100), int64(50), uint64(1 << 30), uint64(1 << 28), int64(200), int64(150)}}}
+		case strings.Contains(eval.Expression, "box.runtime.info"):
+			return &Result{Data: [][]interface{}{{int64(4096), int64(8192), uint64(1 << 31)}}}
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	slab, err := conn.SlabInfo(context.Background())
+	require.NoError(err)
+	assert.Equal(&SlabInfo{
+		ItemsSize: 100,
+		ItemsUsed: 50,
+		QuotaSize: 1 << 30,
+		QuotaUsed: 1 << 28,
+		ArenaSize: 200,
+		ArenaUsed: 150,
+	}, slab)
+	assert.Equal(0.25, slab.QuotaUsedRatio())
+	assert.Equal(0.75, slab.ArenaUsedRatio())
+	assert.Equal(0.5, slab.ItemsUsedRatio())
+	assert.Equal(0.0, (&SlabInfo{}).QuotaUsedRatio())
+
+	runtime, err := conn.RuntimeInfo(context.Background())
+	require.NoError(err)
+	assert.Equal(&RuntimeInfo{Lua: 4096, Used: 8192, MaxAlloc: 1 << 31}, runtime)
+
+	_, err = conn.evalUints(context.Background(), "return {1}", 2)
+	assert.Equal(ErrBadResult, err)
+
+	var info map[string]interface{}
+	require.NoError(json.Unmarshal([]byte(conn.MemoryInfoVar(time.Second).String()), &info))
+	assert.Equal(0.25, info["quota_used_ratio"])
+	assert.Equal(float64(1<<30), info["slab"].(map[string]interface{})["QuotaSize"])
+	assert.Equal(float64(4096), info["runtime"].(map[string]interface{})["Lua"])
+
+	conn.Close()
+	info = nil
+	require.NoError(json.Unmarshal([]byte(conn.MemoryInfoVar(time.Second).String()), &info))
+	assert.Contains(info, "error")
+	assert.NotContains(info, "slab")
+}
@@ -0,0 +1,133 @@
+// Command tarantool-migrate applies versioned Lua and SQL migrations to Tarantool.
+//
+// Usage:
+//
+//	tarantool-migrate [flags] up [version]
+//	tarantool-migrate [flags] down [version]
+//	tarantool-migrate [flags] status
+//
+// up applies the pending migrations up to the version, or all of them.
+// down reverts the applied migrations after the version, or the last one.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/migrations"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:3301", "server address, user:password@host:port")
+	dir := flag.String("dir", "migrations", "directory with the migration files")
+	space := flag.String("space", migrations.DefaultSpace, "space to record the applied versions in")
+	timeout := flag.Duration("timeout", time.Minute, "limit for a single migration")
+	dryRun := flag.Bool("dry-run", false, "print the steps without performing them")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] up [version] | down [version] | status\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*addr, *dir, *space, *timeout, *dryRun, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(addr, dir, space string, timeout time.Duration, dryRun bool, args []string) error {
+	if len(args) == 0 || len(args) > 2 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	var target uint64
+	if len(args) == 2 {
+		var err error
+		if target, err = strconv.ParseUint(args[1], 10, 64); err != nil {
+			return fmt.Errorf("bad version %q: %w", args[1], err)
+		}
+	}
+
+	list, err := migrations.LoadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	conn, err := tarantool.Connect(addr, &tarantool.Options{QueryTimeout: timeout})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	r := &migrations.Runner{Conn: conn, Space: space, DryRun: dryRun}
+	ctx := context.Background()
+
+	var steps []migrations.Step
+	switch args[0] {
+	case "up":
+		steps, err = r.Up(ctx, list, target)
+	case "down":
+		if len(args) == 1 {
+			if target, err = previousVersion(ctx, r); err != nil {
+				return err
+			}
+		}
+		steps, err = r.Down(ctx, list, target)
+	case "status":
+		return status(ctx, r, list)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	for _, step := range steps {
+		if dryRun {
+			fmt.Println("would", step)
+		} else {
+			fmt.Println(step)
+		}
+	}
+	return err
+}
+
+// previousVersion returns the version before the last applied one
+func previousVersion(ctx context.Context, r *migrations.Runner) (uint64, error) {
+	applied, err := r.Applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+	versions := make([]uint64, 0, len(applied))
+	for v := range applied {
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	if len(versions) < 2 {
+		return 0, nil
+	}
+	return versions[len(versions)-2], nil
+}
+
+func status(ctx context.Context, r *migrations.Runner, list []*migrations.Migration) error {
+	// don't create the space just to report nothing is applied
+	readOnly := *r
+	readOnly.DryRun = true
+	applied, err := readOnly.Applied(ctx)
+	if err != nil {
+		return err
+	}
+	for _, m := range list {
+		state := "pending"
+		if applied[m.Version] {
+			state = "applied"
+		}
+		fmt.Printf("%-8s %s\n", state, m)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+// Package migrations applies versioned Lua and SQL migrations to Tarantool.
+//
+// A migration is a pair of files named <version>_<name>.up.<lang> and
+// <version>_<name>.down.<lang>, where lang is lua or sql, e.g.
+//
+//	0001_create_users.up.lua
+//	0001_create_users.down.lua
+//	0002_add_email.up.sql
+//
+// The down file is optional, the migrations without it can't be reverted.
+// The applied versions are recorded in a dedicated space, see Runner.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+const (
+	LangLua = "lua"
+	LangSQL = "sql"
+)
+
+var (
+	// ErrNoDown is returned when an applied migration has to be reverted but has no down file.
+	ErrNoDown = errors.New("migration can not be reverted")
+	// ErrUnknownVersion is returned when a version recorded as applied has no migration files.
+	ErrUnknownVersion = errors.New("applied migration is unknown")
+	// ErrOutOfOrder is returned when a pending migration precedes an applied one.
+	ErrOutOfOrder = errors.New("pending migration precedes applied one")
+)
+
+// Migration is a versioned change of the database.
+type Migration struct {
+	Version uint64
+	Name    string
+	Lang    string
+	Up      string
+	Down    string // empty if the migration can not be reverted
+}
+
+func (m *Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+var fileNameRe = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.(lua|sql)$`)
+
+// LoadDir loads the migrations from the files in dir.
+func LoadDir(dir string) ([]*Migration, error) {
+	return Load(os.DirFS(dir), ".")
+}
+
+// Load loads the migrations from the files in dir of fsys, sorted by version.
+// Files with other names are ignored.
+func Load(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNameRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		name, direction, lang := match[2], match[3], match[4]
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: name, Lang: lang}
+			byVersion[version] = m
+		}
+		if m.Name != name || m.Lang != lang {
+			return nil, fmt.Errorf("%s: version %d is also used by %s.%s", entry.Name(), version, m, m.Lang)
+		}
+
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if direction == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up file", m)
+		}
+		migrations = append(migrations, m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Step is a migration to be applied or reverted.
+type Step struct {
+	Migration *Migration
+	Up        bool
+}
+
+func (s Step) String() string {
+	if s.Up {
+		return "up " + s.Migration.String()
+	}
+	return "down " + s.Migration.String()
+}
+
+// planUp returns the steps to apply the pending migrations up to target,
+// or all of them if target is 0.
+func planUp(migrations []*Migration, applied map[uint64]bool, target uint64) ([]Step, error) {
+	if err := checkApplied(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	var lastApplied uint64
+	for _, m := range migrations {
+		if applied[m.Version] {
+			lastApplied = m.Version
+		}
+	}
+
+	for _, m := range migrations {
+		if applied[m.Version] {
+			continue
+		}
+		if target != 0 && m.Version > target {
+			break
+		}
+		if m.Version < lastApplied {
+			return nil, fmt.Errorf("%w: %s is pending, %d is applied", ErrOutOfOrder, m, lastApplied)
+		}
+		steps = append(steps, Step{Migration: m, Up: true})
+	}
+	return steps, nil
+}
+
+// planDown returns the steps to revert the applied migrations
+// with the versions greater than target, the latest first.
+func planDown(migrations []*Migration, applied map[uint64]bool, target uint64) ([]Step, error) {
+	if err := checkApplied(migrations, applied); err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.Version <= target {
+			break
+		}
+		if !applied[m.Version] {
+			continue
+		}
+		if m.Down == "" {
+			return nil, fmt.Errorf("%w: %s has no down file", ErrNoDown, m)
+		}
+		steps = append(steps, Step{Migration: m, Up: false})
+	}
+	return steps, nil
+}
+
+func checkApplied(migrations []*Migration, applied map[uint64]bool) error {
+	known := make(map[uint64]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+	}
+	for version := range applied {
+		if !known[version] {
+			return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+		}
+	}
+	return nil
+}
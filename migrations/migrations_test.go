@@ -0,0 +1,258 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/lock"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+var testFS = fstest.MapFS{
+	"0001_create_users.up.lua":   {Data: []byte("box.schema.space.create('users')")},
+	"0001_create_users.down.lua": {Data: []byte("box.space.users:drop()")},
+	"0002_add_orders.up.sql":     {Data: []byte("CREATE TABLE orders (id INT PRIMARY KEY);\nINSERT INTO orders VALUES (1);\n")},
+	"0010_seed.up.lua":           {Data: []byte("box.space.users:insert{1}")},
+	"README.md":                  {Data: []byte("not a migration")},
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	list, err := Load(testFS, ".")
+	require.NoError(err)
+	require.Len(list, 3)
+
+	assert.Equal(&Migration{
+		Version: 1,
+		Name:    "create_users",
+		Lang:    LangLua,
+		Up:      "box.schema.space.create('users')",
+		Down:    "box.space.users:drop()",
+	}, list[0])
+	assert.Equal("2_add_orders", list[1].String())
+	assert.Equal(LangSQL, list[1].Lang)
+	assert.Equal(uint64(10), list[2].Version)
+	assert.Empty(list[2].Down)
+
+	_, err = Load(fstest.MapFS{"0001_a.down.lua": {}}, ".")
+	assert.Error(err)
+
+	_, err = Load(fstest.MapFS{"0001_a.up.lua": {}, "0001_b.up.lua": {}}, ".")
+	assert.Error(err)
+}
+
+func TestPlan(t *testing.T) {
+	assert := assert.New(t)
+
+	list, err := Load(testFS, ".")
+	require.NoError(t, err)
+
+	steps, err := planUp(list, map[uint64]bool{}, 0)
+	assert.NoError(err)
+	assert.Equal([]Step{{list[0], true}, {list[1], true}, {list[2], true}}, steps)
+
+	steps, err = planUp(list, map[uint64]bool{1: true}, 2)
+	assert.NoError(err)
+	assert.Equal([]Step{{list[1], true}}, steps)
+
+	_, err = planUp(list, map[uint64]bool{10: true}, 0)
+	assert.True(errors.Is(err, ErrOutOfOrder))
+
+	_, err = planUp(list, map[uint64]bool{3: true}, 0)
+	assert.True(errors.Is(err, ErrUnknownVersion))
+
+	steps, err = planDown(list, map[uint64]bool{1: true, 2: true}, 0)
+	assert.True(errors.Is(err, ErrNoDown))
+	assert.Nil(steps)
+
+	list[1].Down = "DROP TABLE orders"
+	steps, err = planDown(list, map[uint64]bool{1: true, 2: true}, 0)
+	assert.NoError(err)
+	assert.Equal([]Step{{list[1], false}, {list[0], false}}, steps)
+
+	steps, err = planDown(list, map[uint64]bool{1: true, 2: true}, 1)
+	assert.NoError(err)
+	assert.Equal([]Step{{list[1], false}}, steps)
+}
+
+func TestSplitSQL(t *testing.T) {
+	assert.Equal(t, []string{
+		"CREATE TABLE t (id INT PRIMARY KEY, s TEXT)",
+		"INSERT INTO t VALUES (1, 'a;b')",
+		"-- the comment; with a semicolon\nINSERT INTO \"t\" VALUES (2, 'c')",
+	}, splitSQL(`
+CREATE TABLE t (id INT PRIMARY KEY, s TEXT);
+INSERT INTO t VALUES (1, 'a;b');
+-- the comment; with a semicolon
+INSERT INTO "t" VALUES (2, 'c');
+-- trailing comment
+`))
+}
+
+// fakeServer executes the runner scripts against the in-memory list of versions
+type fakeServer struct {
+	sync.Mutex
+	applied map[uint64]bool
+	created bool
+	code    []interface{}
+	fail    string
+	// owner of the lock, see the lock package scripts
+	owner string
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	eval, ok := q.(*tarantool.Eval)
+	if !ok {
+		return &tarantool.Result{}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	switch eval.Expression {
+	case luaApplied:
+		if eval.Tuple[1].(bool) {
+			s.created = true
+		}
+		versions := []interface{}{}
+		for v := range s.applied {
+			versions = append(versions, v)
+		}
+		return &tarantool.Result{Data: [][]interface{}{versions}}
+	case luaRun:
+		version, _ := typeconv.IntfToUint64(eval.Tuple[1])
+		if eval.Tuple[2] == s.fail {
+			return &tarantool.Result{ErrorCode: tarantool.ErrProcLua, Error: tarantool.NewQueryError(tarantool.ErrProcLua, "failed")}
+		}
+		s.code = append(s.code, eval.Tuple[5])
+		if eval.Tuple[3].(bool) {
+			s.applied[version] = true
+		} else {
+			delete(s.applied, version)
+		}
+		return &tarantool.Result{Data: [][]interface{}{{true}}}
+	}
+	return s.handleLock(eval)
+}
+
+// handleLock emulates the acquire, renew and release scripts of the lock package
+func (s *fakeServer) handleLock(eval *tarantool.Eval) *tarantool.Result {
+	owner := eval.Tuple[2].(string)
+	switch {
+	case len(eval.Tuple) == 4 && isFloat(eval.Tuple[3]):
+		// acquire
+		if s.owner != "" && s.owner != owner {
+			return &tarantool.Result{Data: [][]interface{}{{uint64(0)}}}
+		}
+		s.owner = owner
+		return &tarantool.Result{Data: [][]interface{}{{uint64(1)}}}
+	case len(eval.Tuple) == 4:
+		// release
+		ok := s.owner == owner
+		if ok {
+			s.owner = ""
+		}
+		return &tarantool.Result{Data: [][]interface{}{{ok}}}
+	}
+	// renew
+	return &tarantool.Result{Data: [][]interface{}{{s.owner == owner}}}
+}
+
+func isFloat(v interface{}) bool {
+	switch v.(type) {
+	case float32, float64:
+		return true
+	}
+	return false
+}
+
+func newFakeServer(t *testing.T, s *fakeServer) string {
+	return tnttest.NewServer(t, s.handle)
+}
+
+func TestRunner(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	list, err := Load(testFS, ".")
+	require.NoError(err)
+	list[1].Down = "DROP TABLE orders;"
+
+	s := &fakeServer{applied: map[uint64]bool{}}
+	conn, err := tarantool.Connect(newFakeServer(t, s), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+
+	// nothing is created or applied in the dry-run mode
+	r := &Runner{Conn: conn, DryRun: true}
+	steps, err := r.Up(ctx, list, 0)
+	require.NoError(err)
+	assert.Len(steps, 3)
+	assert.False(s.created)
+	assert.Empty(s.applied)
+
+	r.DryRun = false
+	s.fail = "seed"
+	steps, err = r.Up(ctx, list, 0)
+	require.Error(err)
+	assert.Contains(err.Error(), "up 10_seed")
+	assert.Equal([]Step{{list[0], true}, {list[1], true}}, steps)
+	assert.True(s.created)
+	assert.Equal(map[uint64]bool{1: true, 2: true}, s.applied)
+	assert.Equal([]interface{}{
+		"box.schema.space.create('users')",
+		[]interface{}{"CREATE TABLE orders (id INT PRIMARY KEY)", "INSERT INTO orders VALUES (1)"},
+	}, s.code)
+
+	steps, err = r.Down(ctx, list, 1)
+	require.NoError(err)
+	assert.Equal([]Step{{list[1], false}}, steps)
+	assert.Equal(map[uint64]bool{1: true}, s.applied)
+	assert.Empty(s.owner)
+}
+
+func TestRunnerLock(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	list, err := Load(testFS, ".")
+	require.NoError(err)
+
+	s := &fakeServer{applied: map[uint64]bool{}}
+	conn := tnttest.Connect(t, newFakeServer(t, s), nil)
+
+	// another instance runs the migrations
+	other := lock.NewMutex(conn, DefaultSpace, nil)
+	require.NoError(other.Lock(context.Background()))
+
+	r := &Runner{Conn: conn, Lock: &lock.Options{RetryInterval: 10 * time.Millisecond}}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = r.Up(ctx, list, 0)
+	assert.True(errors.Is(err, context.DeadlineExceeded))
+	assert.False(s.created)
+	assert.Empty(s.applied)
+
+	// the migrations are applied once the lock is released
+	done := make(chan error, 1)
+	go func() {
+		_, err := r.Up(context.Background(), list, 0)
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	require.NoError(other.Unlock(context.Background()))
+	assert.NoError(<-done)
+	assert.Len(s.applied, 3)
+}
@@ -0,0 +1,250 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/lock"
+)
+
+// DefaultSpace is the name of the space to record the applied versions in.
+const DefaultSpace = "schema_migrations"
+
+// luaApplied returns the applied versions, nothing is created in the dry-run mode
+const luaApplied = `
+local space, create = ...
+if box.space[space] == nil then
+    if not create then
+        return {}
+    end
+    box.schema.space.create(space, {
+        if_not_exists = true,
+        format = {
+            {name = 'version', type = 'unsigned'},
+            {name = 'name', type = 'string'},
+            {name = 'applied_at', type = 'number'},
+        },
+    })
+    box.space[space]:create_index('primary', {parts = {1, 'unsigned'}, if_not_exists = true})
+end
+local versions = {}
+for _, t in box.space[space]:pairs() do
+    table.insert(versions, t[1])
+end
+return versions
+`
+
+// luaRun runs the migration code and records or removes its version
+const luaRun = `
+local space, version, name, up, lang, code = ...
+if lang == 'sql' then
+    for _, statement in ipairs(code) do
+        local _, err = box.execute(statement)
+        if err ~= nil then
+            error(err)
+        end
+    end
+else
+    local f, err = loadstring(code, name)
+    if f == nil then
+        error(err)
+    end
+    f()
+end
+if up then
+    box.space[space]:insert({version, name, require('clock').time()})
+else
+    box.space[space]:delete({version})
+end
+return true
+`
+
+// Runner applies and reverts migrations. The migrations are executed with Eval,
+// so the user needs the execute privilege on the universe, and they are
+// limited by the QueryTimeout of the connection.
+// A migration is recorded as applied only after its code succeeds, but the
+// code and the record are not atomic: a migration which fails halfway must be
+// cleaned up by hand, so keep migrations small or make their data changes
+// in box.atomic.
+//
+// Up and Down hold the lock named after the space, see the lock package,
+// while they plan and run the migrations, so the instances started at once
+// apply them one after another.
+type Runner struct {
+	Conn tarantool.Executor
+	// Space is the name of the space to record the applied versions in,
+	// DefaultSpace is used if it is empty. It is created on first use.
+	Space string
+	// DryRun makes Up and Down only return the steps they would perform,
+	// the lock is not taken then.
+	DryRun bool
+	// Lock are the options of the lock, nil for the defaults.
+	Lock *lock.Options
+}
+
+func (r *Runner) space() string {
+	if r.Space == "" {
+		return DefaultSpace
+	}
+	return r.Space
+}
+
+// Applied returns the versions of the applied migrations.
+func (r *Runner) Applied(ctx context.Context) (map[uint64]bool, error) {
+	res := r.Conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaApplied,
+		Tuple:      []interface{}{r.space(), !r.DryRun},
+	})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+
+	applied := make(map[uint64]bool)
+	if len(res.Data) == 0 {
+		return applied, nil
+	}
+	for _, v := range res.Data[0] {
+		switch version := v.(type) {
+		case uint64:
+			applied[version] = true
+		case int64:
+			applied[uint64(version)] = true
+		default:
+			return nil, fmt.Errorf("unexpected version %#v in space %s", v, r.space())
+		}
+	}
+	return applied, nil
+}
+
+// Up applies the pending migrations up to the target version in order,
+// or all of them if target is 0. It returns the steps performed, or the ones
+// to be performed in the dry-run mode. On error the steps performed before
+// the failed one are returned.
+func (r *Runner) Up(ctx context.Context, migrations []*Migration, target uint64) ([]Step, error) {
+	return r.locked(ctx, func(applied map[uint64]bool) ([]Step, error) {
+		return planUp(migrations, applied, target)
+	})
+}
+
+// Down reverts the applied migrations with versions greater than target,
+// the latest first. It returns the steps the same way as Up does.
+func (r *Runner) Down(ctx context.Context, migrations []*Migration, target uint64) ([]Step, error) {
+	return r.locked(ctx, func(applied map[uint64]bool) ([]Step, error) {
+		return planDown(migrations, applied, target)
+	})
+}
+
+// locked plans the steps with the applied versions and runs them holding the lock
+func (r *Runner) locked(ctx context.Context, plan func(applied map[uint64]bool) ([]Step, error)) (steps []Step, err error) {
+	var lost <-chan struct{}
+	if !r.DryRun {
+		mu := lock.NewMutex(r.Conn, r.space(), r.Lock)
+		if err := mu.Lock(ctx); err != nil {
+			return nil, fmt.Errorf("migrations lock: %w", err)
+		}
+		defer func() {
+			if uerr := mu.Unlock(context.Background()); uerr != nil && err == nil {
+				err = fmt.Errorf("migrations unlock: %w", uerr)
+			}
+		}()
+		lost = mu.Lost()
+	}
+
+	applied, err := r.Applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	steps, err = plan(applied)
+	if err != nil {
+		return nil, err
+	}
+	if r.DryRun {
+		return steps, nil
+	}
+	return r.run(ctx, steps, lost)
+}
+
+// run performs the steps until one fails or the lock is lost
+func (r *Runner) run(ctx context.Context, steps []Step, lost <-chan struct{}) ([]Step, error) {
+	for i, step := range steps {
+		select {
+		case <-lost:
+			return steps[:i], fmt.Errorf("%s: migrations %w", step, lock.ErrLost)
+		default:
+		}
+
+		m := step.Migration
+		code := m.Up
+		if !step.Up {
+			code = m.Down
+		}
+
+		var arg interface{} = code
+		if m.Lang == LangSQL {
+			statements := splitSQL(code)
+			list := make([]interface{}, len(statements))
+			for j, s := range statements {
+				list[j] = s
+			}
+			arg = list
+		}
+
+		res := r.Conn.Exec(ctx, &tarantool.Eval{
+			Expression: luaRun,
+			Tuple:      []interface{}{r.space(), m.Version, m.Name, step.Up, m.Lang, arg},
+		})
+		if res.Error != nil {
+			return steps[:i], fmt.Errorf("%s: %w", step, res.Error)
+		}
+	}
+	return steps, nil
+}
+
+// splitSQL splits the script into statements by semicolons
+// outside of quotes and comments.
+func splitSQL(script string) []string {
+	var statements []string
+	var quote byte
+	start := 0
+
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+		case c == ';':
+			statements = appendStatement(statements, script[start:i])
+			start = i + 1
+		}
+	}
+	return appendStatement(statements, script[start:])
+}
+
+func appendStatement(statements []string, s string) []string {
+	s = strings.TrimSpace(s)
+	if s == "" || isComment(s) {
+		return statements
+	}
+	return append(statements, s)
+}
+
+// isComment reports whether s consists of comment lines only
+func isComment(s string) bool {
+	for _, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") {
+			return false
+		}
+	}
+	return true
+}
@@ -175,7 +175,7 @@ func unmarshalOperator(data []byte) (op Operator, buf []byte, err error) {
 			return nil, buf, fmt.Errorf("unexpected number of arguments in OpInsert: %d", n)
 		}
 		opIns := &OpInsert{Before: field0}
-		if opIns.Argument, buf, err = msgp.ReadIntfBytes(buf); err != nil {
+		if opIns.Argument, buf, err = readIntfBytes(buf); err != nil {
 			return
 		}
 		op = opIns
@@ -184,7 +184,7 @@ func unmarshalOperator(data []byte) (op Operator, buf []byte, err error) {
 			return nil, buf, fmt.Errorf("unexpected number of arguments in OpAssign: %d", n)
 		}
 		opAss := &OpAssign{Field: field0}
-		if opAss.Argument, buf, err = msgp.ReadIntfBytes(buf); err != nil {
+		if opAss.Argument, buf, err = readIntfBytes(buf); err != nil {
 			return
 		}
 		op = opAss
@@ -2,6 +2,8 @@ package tarantool
 
 import (
 	"fmt"
+	"strconv"
+	"sync/atomic"
 
 	"github.com/tinylib/msgp/msgp"
 )
@@ -15,9 +17,30 @@ type packData struct {
 	packedDefaultLimit  []byte
 	packedDefaultOffset []byte
 	packedSingleKey     []byte
-	spaceMap            map[string]uint64
-	indexMap            map[uint64]map[string]uint64
-	primaryKeyMap       map[uint64][]int
+	schemaValue         atomic.Value // *schema
+}
+
+// schema is a snapshot of space and index names. It is never modified
+// once published: a reload builds a new snapshot and swaps it at once,
+// so packing requests never takes a lock.
+type schema struct {
+	spaceMap      map[string]uint64
+	indexMap      map[uint64]map[string]uint64
+	primaryKeyMap map[uint64][]int
+	// fieldMap keeps the field names of the spaces with the format
+	fieldMap map[uint64][]string
+	// fieldTypeMap keeps the field types of the spaces with the format
+	fieldTypeMap map[uint64][]string
+}
+
+func newSchema() *schema {
+	return &schema{
+		spaceMap:      make(map[string]uint64),
+		indexMap:      make(map[uint64]map[string]uint64),
+		primaryKeyMap: make(map[uint64][]int),
+		fieldMap:      make(map[uint64][]string),
+		fieldTypeMap:  make(map[uint64][]string),
+	}
 }
 
 func encodeValues2(v1, v2 interface{}) []byte {
@@ -39,7 +62,7 @@ func newPackData(defaultSpace interface{}) *packData {
 	if spaceNo, ok := defaultSpace.(uint64); ok {
 		packedDefaultSpace = encodeValues2(KeySpaceNo, spaceNo)
 	}
-	return &packData{
+	data := &packData{
 		defaultSpace:        defaultSpace,
 		packedDefaultSpace:  packedDefaultSpace,
 		packedDefaultIndex:  encodeValues2(KeyIndexNo, uint32(0)),
@@ -47,20 +70,31 @@ func newPackData(defaultSpace interface{}) *packData {
 		packedDefaultLimit:  encodeValues2(KeyLimit, DefaultLimit),
 		packedDefaultOffset: encodeValues2(KeyOffset, 0),
 		packedSingleKey:     packSelectSingleKey(),
-		spaceMap:            make(map[string]uint64),
-		indexMap:            make(map[uint64]map[string]uint64),
-		primaryKeyMap:       make(map[uint64][]int),
 	}
+	data.setSchema(newSchema())
+	return data
+}
+
+func (data *packData) schema() *schema {
+	return data.schemaValue.Load().(*schema)
+}
+
+func (data *packData) setSchema(s *schema) {
+	data.schemaValue.Store(s)
 }
 
 func (data *packData) spaceNo(space interface{}) (uint64, error) {
+	return data.schemaSpaceNo(data.schema(), space)
+}
+
+func (data *packData) schemaSpaceNo(sc *schema, space interface{}) (uint64, error) {
 	if space == nil {
 		space = data.defaultSpace
 	}
 
 	switch value := space.(type) {
 	case string:
-		spaceNo, exists := data.spaceMap[value]
+		spaceNo, exists := sc.spaceMap[value]
 		if exists {
 			return spaceNo, nil
 		}
@@ -123,12 +157,14 @@ func (data *packData) indexNo(space interface{}, index interface{}) (uint64, err
 	}
 
 	if value, ok := index.(string); ok {
-		spaceNo, err := data.spaceNo(space)
+		// resolve both names using the same snapshot
+		sc := data.schema()
+		spaceNo, err := data.schemaSpaceNo(sc, space)
 		if err != nil {
 			return 0, nil
 		}
 
-		spaceData, exists := data.indexMap[spaceNo]
+		spaceData, exists := sc.indexMap[spaceNo]
 		if !exists {
 			return 0, fmt.Errorf("no indexes defined for space %#v", space)
 		}
@@ -158,3 +194,60 @@ func (data *packData) packIndex(space interface{}, index interface{}, o []byte)
 	o = msgp.AppendUint64(o, indexNo)
 	return o, nil
 }
+
+// describe returns the space and index names for error messages,
+// falling back to the numbers if they are not in the schema.
+func (data *packData) describe(space, index interface{}) (spaceName, indexName string) {
+	sc := data.schema()
+
+	spaceNo, err := data.schemaSpaceNo(sc, space)
+	if err != nil {
+		if index != nil {
+			indexName = fmt.Sprint(index)
+		}
+		return fmt.Sprint(space), indexName
+	}
+
+	if name, ok := space.(string); ok {
+		spaceName = name
+	} else {
+		spaceName = strconv.FormatUint(spaceNo, 10)
+		for name, no := range sc.spaceMap {
+			if no == spaceNo {
+				spaceName = name
+				break
+			}
+		}
+	}
+
+	switch value := index.(type) {
+	case nil:
+	case string:
+		indexName = value
+	default:
+		indexNo, err := numberToUint64(value)
+		if err != nil {
+			return spaceName, fmt.Sprint(index)
+		}
+		indexName = strconv.FormatUint(indexNo, 10)
+		for name, no := range sc.indexMap[spaceNo] {
+			if no == indexNo {
+				indexName = name
+				break
+			}
+		}
+	}
+	return
+}
+
+// appendTuple packs the tuple the same way msgp.AppendIntf does, but the slice
+// is not boxed into an interface, which would cost an allocation per request
+func appendTuple(o []byte, tuple []interface{}) (_ []byte, err error) {
+	o = msgp.AppendArrayHeader(o, uint32(len(tuple)))
+	for _, v := range tuple {
+		if o, err = msgp.AppendIntf(o, v); err != nil {
+			return o, err
+		}
+	}
+	return o, nil
+}
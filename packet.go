@@ -12,6 +12,7 @@ type Packet struct {
 	LSN        uint64
 	requestID  uint64
 	SchemaID   uint32
+	StreamID   uint64
 	InstanceID uint32
 	Timestamp  time.Time
 	Request    Query
@@ -65,6 +66,10 @@ func (pack *Packet) UnmarshalBinaryHeader(data []byte) (buf []byte, err error) {
 			if pack.SchemaID, buf, err = msgp.ReadUint32Bytes(buf); err != nil {
 				return
 			}
+		case KeyStreamID:
+			if pack.StreamID, buf, err = msgp.ReadUint64Bytes(buf); err != nil {
+				return
+			}
 		case KeyLSN:
 			if pack.LSN, buf, err = msgp.ReadUint64Bytes(buf); err != nil {
 				return
@@ -1,8 +1,10 @@
 package tarantool
 
 import (
-	"github.com/stretchr/testify/assert"
+	"bytes"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDecodePacket(t *testing.T) {
@@ -47,3 +49,112 @@ func BenchmarkDecodeHeader(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkReadRawPacket(b *testing.B) {
+	b.ReportAllocs()
+	body := []byte("\x83\x00\xce\x00\x00\x00\x00\x01\xcf\x00\x00\x00\x00\x00\x00\x00\x03\x05\xce\x00\x00\x006\x810\xdd\x00\x00\x00\x03\x92\x01\xacFirst record\x92\x02\xa5Music\x93\x03\xa6Length]")
+	frame := append([]byte{0xce, 0, 0, 0, byte(len(body))}, body...)
+	r := bytes.NewReader(frame)
+	pp := &BinaryPacket{}
+
+	for i := 0; i < b.N; i++ {
+		r.Reset(frame)
+		requestID, _, err := pp.readRawPacket(r)
+		if err != nil || requestID != 3 {
+			b.FailNow()
+		}
+	}
+}
+
+func TestReadRawPacketLength(t *testing.T) {
+	assert := assert.New(t)
+
+	body := []byte("\x82\x00\x00\x01\x07")
+	pp := &BinaryPacket{}
+
+	for _, prefix := range [][]byte{
+		{0x05},
+		{0xcc, 0x05},
+		{0xcd, 0x00, 0x05},
+		{0xce, 0x00, 0x00, 0x00, 0x05},
+	} {
+		frame := append(append([]byte{}, prefix...), body...)
+		requestID, code, err := pp.readRawPacket(bytes.NewReader(frame))
+		if assert.NoError(err, "% x", prefix) {
+			assert.EqualValues(7, requestID, "% x", prefix)
+			assert.EqualValues(OKCommand, code, "% x", prefix)
+			assert.Equal(body, pp.body, "% x", prefix)
+		}
+	}
+
+	_, _, err := pp.readRawPacket(bytes.NewReader([]byte{0xcf, 0, 0, 0, 0, 0, 0, 0, 5}))
+	assert.Error(err)
+	_, _, err = pp.readRawPacket(bytes.NewReader([]byte{0xce, 0, 0, 0, 0}))
+	assert.Error(err)
+}
+
+func TestReadRawPacketAllocs(t *testing.T) {
+	body := []byte("\x83\x00\xce\x00\x00\x00\x00\x01\xcf\x00\x00\x00\x00\x00\x00\x00\x03\x05\xce\x00\x00\x006\x810\xdd\x00\x00\x00\x03\x92\x01\xacFirst record\x92\x02\xa5Music\x93\x03\xa6Length]")
+	frame := append([]byte{0xce, 0, 0, 0, byte(len(body))}, body...)
+	r := bytes.NewReader(frame)
+	pp := &BinaryPacket{body: make([]byte, 0, len(body))}
+
+	allocs := testing.AllocsPerRun(100, func() {
+		r.Reset(frame)
+		if _, _, err := pp.readRawPacket(r); err != nil {
+			t.Fatal(err)
+		}
+	})
+	assert.Zero(t, allocs, "reading a packet into a reused buffer must not allocate")
+}
+
+func TestReleasePacketMaxSize(t *testing.T) {
+	assert := assert.New(t)
+
+	conn := &Connection{poolMaxPacketSize: 64}
+
+	small := packetPool.Get()
+	small.body = make([]byte, 0, 64)
+	conn.releasePacket(small)
+	assert.Nil(small.pool, "small packet must be returned to the pool")
+
+	large := packetPool.Get()
+	large.body = make([]byte, 0, 128)
+	conn.releasePacket(large)
+	assert.NotNil(large.pool, "large packet must not be returned to the pool")
+}
+
+func packTestResult(tuples int) []byte {
+	data := make([][]interface{}, tuples)
+	for i := range data {
+		data[i] = []interface{}{int64(i), "name", int64(42)}
+	}
+	buf, _ := (&Result{Data: data}).MarshalMsg(nil)
+	return buf
+}
+
+func TestDecodeResultArena(t *testing.T) {
+	assert := assert.New(t)
+
+	res := &Result{}
+	_, err := res.UnmarshalMsg(packTestResult(3))
+	assert.NoError(err)
+	assert.Len(res.Data, 3)
+
+	// appending to a tuple must not overwrite the next one sharing the arena
+	res.Data[0] = append(res.Data[0], "extra")
+	assert.Equal([]interface{}{int64(1), "name", int64(42)}, res.Data[1])
+	assert.Equal([]interface{}{int64(0), "name", int64(42), "extra"}, res.Data[0])
+}
+
+func BenchmarkDecodeBulkResult(b *testing.B) {
+	b.ReportAllocs()
+	body := packTestResult(1000)
+
+	for i := 0; i < b.N; i++ {
+		res := &Result{}
+		if _, err := res.UnmarshalMsg(body); err != nil || len(res.Data) != 1000 {
+			b.FailNow()
+		}
+	}
+}
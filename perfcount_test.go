@@ -10,12 +10,10 @@ import (
 
 func TestPerfCount(t *testing.T) {
 	perf := PerfCount{
-		expvar.NewInt("net_read"),
-		expvar.NewInt("net_write"),
-		expvar.NewInt("net_packets_in"),
-		expvar.NewInt("net_packets_out"),
-		nil,
-		nil,
+		NetRead:       expvar.NewInt("net_read"),
+		NetWrite:      expvar.NewInt("net_write"),
+		NetPacketsIn:  expvar.NewInt("net_packets_in"),
+		NetPacketsOut: expvar.NewInt("net_packets_out"),
 	}
 
 	assert := assert.New(t)
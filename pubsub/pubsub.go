@@ -0,0 +1,147 @@
+// Package pubsub is a lightweight fan-out messaging over box.broadcast and
+// the watchers of Tarantool >= 2.10.0.
+//
+// A key keeps the last published value only: a slow subscriber skips the
+// intermediate values and gets the latest one, and a subscriber gets the
+// current value first, including after every reconnect. So it fits
+// notifications like configuration changes or cache invalidation rather
+// than a queue of messages, which must not be lost.
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// DefaultRetryInterval is the pause between the attempts to subscribe
+// again after the connection fails.
+const DefaultRetryInterval = time.Second
+
+// Message is the value published for the key.
+type Message struct {
+	Key string
+	// Payload is nil if nothing is published for the key.
+	Payload interface{}
+}
+
+// Publish sets the payload as the value of the key with box.broadcast,
+// so it is delivered to all the subscribers of the key on the server.
+// The user needs the execute privilege on box.broadcast.
+func Publish(ctx context.Context, conn tarantool.Executor, key string, payload interface{}) error {
+	return conn.Exec(ctx, &tarantool.Call17{
+		Name:  "box.broadcast",
+		Tuple: []interface{}{key, payload},
+	}).Error
+}
+
+// Dialer returns the connection to subscribe on, establishing it if needed.
+// It is implemented by tarantool.Connector.
+type Dialer interface {
+	Connect() (*tarantool.Connection, error)
+}
+
+// Subscriber subscribes to the keys and keeps the subscriptions
+// over the reconnects.
+type Subscriber struct {
+	Dialer Dialer
+	// RetryInterval is the pause between the attempts to subscribe,
+	// DefaultRetryInterval is used if it is 0.
+	RetryInterval time.Duration
+	// OnError is called with the errors of the attempts to subscribe.
+	OnError func(key string, err error)
+}
+
+// Subscribe is Subscriber.Subscribe with the defaults.
+func Subscribe(ctx context.Context, dialer Dialer, key string) <-chan Message {
+	s := &Subscriber{Dialer: dialer}
+	return s.Subscribe(ctx, key)
+}
+
+// Subscribe returns the channel receiving the messages of the key until ctx
+// is done, then it is closed. The next value is requested from the server
+// only after the previous one is received from the channel. Once the
+// connection is lost, the key is watched again on a new one.
+func (s *Subscriber) Subscribe(ctx context.Context, key string) <-chan Message {
+	ch := make(chan Message)
+	go func() {
+		defer close(ch)
+
+		for {
+			err := s.watch(ctx, key, ch)
+			if ctx.Err() != nil {
+				return
+			}
+			if err == nil {
+				// the connection is closed, reconnect at once
+				continue
+			}
+			if s.OnError != nil {
+				s.OnError(key, err)
+			}
+
+			t := time.NewTimer(s.retryInterval())
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func (s *Subscriber) retryInterval() time.Duration {
+	if s.RetryInterval <= 0 {
+		return DefaultRetryInterval
+	}
+	return s.RetryInterval
+}
+
+// watch delivers the messages of the key until ctx is done or the connection is closed
+func (s *Subscriber) watch(ctx context.Context, key string, ch chan<- Message) error {
+	conn, err := s.Dialer.Connect()
+	if err != nil {
+		return err
+	}
+
+	// the callback may still run once the watcher is unregistered,
+	// ch is closed after watch returns, so the callback is waited for
+	var mu sync.Mutex
+	stop := make(chan struct{})
+	defer func() {
+		close(stop)
+		mu.Lock()
+		mu.Unlock()
+	}()
+
+	w, err := conn.NewWatcherContext(ctx, key, func(e tarantool.WatchEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		select {
+		case ch <- Message{Key: e.Key, Payload: e.Value}:
+		case <-ctx.Done():
+		case <-conn.Done():
+			// the value is delivered again by the next connection
+		case <-stop:
+		}
+	})
+	if err != nil {
+		return err
+	}
+	defer w.Unregister()
+
+	select {
+	case <-ctx.Done():
+	case <-conn.Done():
+	}
+	return nil
+}
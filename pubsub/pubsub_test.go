@@ -0,0 +1,163 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+// fakeServer emulates box.broadcast and the watchers of its clients
+type fakeServer struct {
+	sync.Mutex
+	values map[string]interface{}
+	conns  map[*fakeConn]bool
+}
+
+type fakeConn struct {
+	srv *tarantool.IprotoServer
+	// watched keys, true if the last event is not acknowledged yet
+	watched map[string]bool
+	dirty   map[string]bool
+}
+
+func newFakeServer(t *testing.T) (*fakeServer, string) {
+	s := &fakeServer{values: map[string]interface{}{}, conns: map[*fakeConn]bool{}}
+
+	addr := tnttest.NewServerFunc(t, func() *tarantool.IprotoServer {
+		fc := &fakeConn{watched: map[string]bool{}, dirty: map[string]bool{}}
+		fc.srv = tarantool.NewIprotoServer("", func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+			return s.handle(fc, q)
+		}, nil)
+		s.Lock()
+		s.conns[fc] = true
+		s.Unlock()
+		return fc.srv
+	})
+	return s, addr
+}
+
+func (s *fakeServer) handle(fc *fakeConn, q tarantool.Query) *tarantool.Result {
+	switch q := q.(type) {
+	case *tarantool.Watch:
+		s.Lock()
+		defer s.Unlock()
+		pending, ok := fc.watched[q.Key]
+		if !ok || pending && fc.dirty[q.Key] {
+			fc.srv.SendEvent(q.Key, s.values[q.Key])
+			fc.watched[q.Key] = true
+		} else {
+			fc.watched[q.Key] = false
+		}
+		delete(fc.dirty, q.Key)
+		return nil
+	case *tarantool.Unwatch:
+		s.Lock()
+		defer s.Unlock()
+		delete(fc.watched, q.Key)
+		return nil
+	case *tarantool.Call17:
+		if q.Name == "box.broadcast" {
+			s.broadcast(q.Tuple[0].(string), q.Tuple[1])
+		}
+	}
+	return &tarantool.Result{}
+}
+
+func (s *fakeServer) broadcast(key string, value interface{}) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.values[key] = value
+	for fc := range s.conns {
+		pending, ok := fc.watched[key]
+		switch {
+		case !ok:
+		case pending:
+			fc.dirty[key] = true
+		default:
+			fc.srv.SendEvent(key, value)
+			fc.watched[key] = true
+		}
+	}
+}
+
+// drop closes the client connections
+func (s *fakeServer) drop() {
+	s.Lock()
+	defer s.Unlock()
+	for fc := range s.conns {
+		fc.srv.Shutdown()
+		delete(s.conns, fc)
+	}
+}
+
+type failingDialer struct {
+	*tarantool.Connector
+	fails int
+}
+
+func (d *failingDialer) Connect() (*tarantool.Connection, error) {
+	if d.fails > 0 {
+		d.fails--
+		return nil, errors.New("connection refused")
+	}
+	return d.Connector.Connect()
+}
+
+func TestPubSub(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newFakeServer(t)
+
+	publisher := tarantool.New(addr, nil)
+	defer publisher.Close()
+	connector := tarantool.New(addr, nil)
+	defer connector.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var errs []error
+	sub := &Subscriber{
+		Dialer:        &failingDialer{Connector: connector, fails: 1},
+		RetryInterval: 10 * time.Millisecond,
+		OnError:       func(key string, err error) { errs = append(errs, err) },
+	}
+	ch := sub.Subscribe(ctx, "news")
+
+	next := func() Message {
+		select {
+		case m, ok := <-ch:
+			require.True(ok)
+			return m
+		case <-time.After(time.Second):
+			t.Fatal("no message")
+		}
+		return Message{}
+	}
+
+	assert.Equal(Message{Key: "news"}, next())
+	assert.Len(errs, 1)
+
+	require.NoError(Publish(ctx, publisher, "news", "hello"))
+	assert.Equal(Message{Key: "news", Payload: "hello"}, next())
+
+	// the subscription is restored on a new connection with the current value
+	s.drop()
+	assert.Equal(Message{Key: "news", Payload: "hello"}, next())
+
+	require.NoError(Publish(ctx, publisher, "news", "again"))
+	assert.Equal(Message{Key: "news", Payload: "again"}, next())
+
+	cancel()
+	for range ch {
+	}
+}
@@ -0,0 +1,105 @@
+package tarantool
+
+import (
+	"errors"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Push is the value sent with box.session.push by the function being called,
+// it comes with the sync of the request before the reply, Tarantool >= 1.10.0.
+// Use PushExecOption or Connection.CallStream to receive them.
+type Push struct {
+	Value interface{}
+}
+
+var _ Query = (*Push)(nil)
+
+func (q *Push) GetCommandID() uint {
+	return ChunkCommand
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (q *Push) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.AppendMapHeader(b, 1)
+	o = msgp.AppendUint(o, KeyData)
+	o = msgp.AppendArrayHeader(o, 1)
+	return msgp.AppendIntf(o, q.Value)
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (q *Push) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	var l, dl uint32
+	var hasData bool
+
+	q.Value = nil
+
+	buf = data
+	if l, buf, err = readMapHeaderBytes(buf); err != nil {
+		return
+	}
+	for ; l > 0; l-- {
+		var cd uint
+		if cd, buf, err = msgp.ReadUintBytes(buf); err != nil {
+			return
+		}
+		if cd != KeyData {
+			if buf, err = msgp.Skip(buf); err != nil {
+				return
+			}
+			continue
+		}
+
+		if dl, buf, err = readArrayHeaderBytes(buf); err != nil {
+			return
+		}
+		for ; dl > 0; dl-- {
+			// only one value is pushed at a time
+			if hasData {
+				if buf, err = msgp.Skip(buf); err != nil {
+					return
+				}
+				continue
+			}
+			if q.Value, buf, err = readIntfBytes(buf); err != nil {
+				return
+			}
+			hasData = true
+		}
+	}
+	if !hasData {
+		return buf, errors.New("push data is missing")
+	}
+	return
+}
+
+type pushOption struct {
+	fn func(value interface{})
+}
+
+func (o *pushOption) apply(r *request) {
+	r.push = o.fn
+}
+
+// PushExecOption sets the function receiving the values of box.session.push
+// sent by the server while the query is executed, in order. It is called from
+// the reader goroutine of the connection, so it must not block: no replies of
+// the connection are read until it returns.
+func PushExecOption(fn func(value interface{})) ExecOption {
+	return &pushOption{fn: fn}
+}
+
+// handlePush passes the pushed value to the pending request,
+// the pushes nobody expects are dropped
+func (conn *Connection) handlePush(requestID uint64, pp *BinaryPacket) {
+	fn := conn.requests.PushFunc(requestID)
+	if fn == nil {
+		return
+	}
+	if err := pp.packet.UnmarshalBinary(pp.body); err != nil {
+		return
+	}
+	if p, ok := pp.packet.Request.(*Push); ok {
+		fn(p.Value)
+	}
+}
@@ -0,0 +1,79 @@
+package tarantool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPushMarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, q := range []*Push{
+		{Value: "progress"},
+		{Value: []interface{}{int64(1), "step"}},
+		{Value: map[string]interface{}{"done": int64(50)}},
+	} {
+		pp := packetPool.GetWithID(7)
+		require.NoError(t, pp.packMsg(q, nil))
+
+		p := Packet{Cmd: q.GetCommandID()}
+		_, err := p.UnmarshalBinaryBody(pp.body)
+		if assert.NoError(err) {
+			assert.Equal(q, p.Request)
+		}
+		pp.Release()
+	}
+
+	var p Push
+	_, err := p.UnmarshalMsg([]byte{0x80})
+	assert.Error(err)
+}
+
+// newPushServer runs the server pushing the arguments of a call one by one
+// and returning their number
+func newPushServer(t *testing.T) string {
+	return newTestServer(t, func(ctx context.Context, q Query) *Result {
+		call, ok := q.(*Call17)
+		if !ok {
+			return &Result{}
+		}
+		if call.Name == "fail" {
+			SessionPush(ctx, "started")
+			return &Result{ErrorCode: ErrProcLua, Error: NewQueryError(ErrProcLua, "job failed")}
+		}
+		for _, v := range call.Tuple {
+			if err := SessionPush(ctx, v); err != nil {
+				return &Result{ErrorCode: ErrProcLua, Error: err}
+			}
+		}
+		return &Result{Data: [][]interface{}{{int64(len(call.Tuple))}}}
+	})
+}
+
+func TestPushExecOption(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn, err := Connect(newPushServer(t), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	var pushed []interface{}
+	res := conn.Exec(context.Background(), &Call17{Name: "job", Tuple: []interface{}{"a", int64(2)}},
+		PushExecOption(func(value interface{}) {
+			pushed = append(pushed, value)
+		}))
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(2)}}, res.Data)
+	assert.Equal([]interface{}{"a", int64(2)}, pushed)
+
+	// the pushes are dropped if nobody expects them
+	res = conn.Exec(context.Background(), &Call17{Name: "job", Tuple: []interface{}{"a"}})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(1)}}, res.Data)
+
+	assert.Error(SessionPush(context.Background(), "value"))
+}
@@ -32,6 +32,24 @@ func NewQuery(cmd uint) Query {
 		return &Ping{}
 	case EvalCommand:
 		return &Eval{}
+	case ExecuteCommand:
+		return &Execute{}
+	case PrepareCommand:
+		return &Prepare{}
+	case WatchCommand:
+		return &Watch{}
+	case UnwatchCommand:
+		return &Unwatch{}
+	case EventCommand:
+		return &Event{}
+	case BeginCommand:
+		return &Begin{}
+	case CommitCommand:
+		return &Commit{}
+	case RollbackCommand:
+		return &Rollback{}
+	case ChunkCommand:
+		return &Push{}
 	default:
 		return nil
 	}
@@ -0,0 +1,181 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// DefaultPollTimeout is the time a Consumer worker waits for a task in Take,
+// half of tarantool.DefaultQueryTimeout, so an idle take returns before the
+// query times out.
+const DefaultPollTimeout = tarantool.DefaultQueryTimeout / 2
+
+// ErrBury makes the Consumer bury the task instead of releasing it, e.g. if the
+// handler can't process it on retry either. Wrap it to keep the cause:
+//
+//	return fmt.Errorf("%w: bad payload: %v", queue.ErrBury, err)
+var ErrBury = errors.New("bury the task")
+
+// Handler processes a task, the task is acked if it returns nil.
+type Handler func(ctx context.Context, task *Task) error
+
+// Consumer takes the tasks from the tube and runs the Handler for each of them.
+type Consumer struct {
+	Tube    *Tube
+	Handler Handler
+	// Workers is the number of tasks processed concurrently, 1 by default.
+	Workers int
+	// PollTimeout is the time a worker waits for a task in Take,
+	// DefaultPollTimeout is used if it is 0. It is also the pause after
+	// a failed queue request. It must be well below the QueryTimeout of the
+	// connection: an idle take timing out on the client is an error, and
+	// the task it may take on the server afterwards stays taken until the
+	// session of the connection ends.
+	PollTimeout time.Duration
+	// RetryDelay is the delay of a released task before it can be taken again.
+	RetryDelay time.Duration
+	// Retry decides what to do with a task the Handler has failed: it returns
+	// true to bury the task, or the delay to release it with otherwise.
+	// By default the errors wrapping ErrBury bury the task and the others
+	// release it after RetryDelay.
+	Retry func(task *Task, err error) (bury bool, delay time.Duration)
+	// ShutdownTimeout limits the time the handlers have to finish after Run is
+	// canceled, then their context is canceled. They are not limited if it is 0.
+	ShutdownTimeout time.Duration
+	// OnError is called with the errors of the queue requests and the handlers.
+	OnError func(task *Task, err error)
+}
+
+// Run processes the tasks until ctx is done, then it stops taking new tasks,
+// waits for the handlers of the taken ones to complete and acks, releases or
+// buries them. The handlers get their own context, which isn't canceled with
+// ctx, see ShutdownTimeout.
+func (c *Consumer) Run(ctx context.Context) error {
+	if c.Tube == nil || c.Handler == nil {
+		return errors.New("queue consumer needs Tube and Handler")
+	}
+
+	workers := c.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	handlerCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-stopped:
+			return
+		}
+		if c.ShutdownTimeout <= 0 {
+			return
+		}
+		t := time.NewTimer(c.ShutdownTimeout)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			cancel()
+		case <-stopped:
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.work(ctx, handlerCtx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+func (c *Consumer) pollTimeout() time.Duration {
+	if c.PollTimeout <= 0 {
+		return DefaultPollTimeout
+	}
+	return c.PollTimeout
+}
+
+func (c *Consumer) work(ctx, handlerCtx context.Context) {
+	for ctx.Err() == nil {
+		// a canceled take may lose the task taken on the server, so it
+		// is not canceled with ctx, the take waits for the poll timeout
+		// on the server and the query is limited by the QueryTimeout
+		task, err := c.Tube.Take(context.Background(), c.pollTimeout())
+		if err != nil {
+			c.onError(nil, fmt.Errorf("take: %w", err))
+			c.pause(ctx)
+			continue
+		}
+		if task == nil {
+			continue
+		}
+		c.process(handlerCtx, task)
+	}
+}
+
+// pause waits for the poll timeout or ctx
+func (c *Consumer) pause(ctx context.Context) {
+	t := time.NewTimer(c.pollTimeout())
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+func (c *Consumer) process(handlerCtx context.Context, task *Task) {
+	err := c.handle(handlerCtx, task)
+
+	// the task is settled even if the handler is canceled on shutdown,
+	// the requests are limited by the QueryTimeout of the connection
+	ctx := context.Background()
+	if err == nil {
+		if _, err = c.Tube.Ack(ctx, task.ID); err != nil {
+			c.onError(task, fmt.Errorf("ack: %w", err))
+		}
+		return
+	}
+	c.onError(task, err)
+
+	bury, delay := errors.Is(err, ErrBury), c.RetryDelay
+	if c.Retry != nil {
+		bury, delay = c.Retry(task, err)
+	}
+	if bury {
+		if _, err = c.Tube.Bury(ctx, task.ID); err != nil {
+			c.onError(task, fmt.Errorf("bury: %w", err))
+		}
+		return
+	}
+	if _, err = c.Tube.Release(ctx, task.ID, delay); err != nil {
+		c.onError(task, fmt.Errorf("release: %w", err))
+	}
+}
+
+// handle runs the handler, turning its panic into an error
+func (c *Consumer) handle(ctx context.Context, task *Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("handler panic: %v", r)
+		}
+	}()
+	return c.Handler(ctx, task)
+}
+
+func (c *Consumer) onError(task *Task, err error) {
+	if c.OnError != nil {
+		c.OnError(task, err)
+	}
+}
@@ -0,0 +1,168 @@
+package queue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+)
+
+func TestConsumer(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeQueue{delays: map[uint64]interface{}{}}
+	conn, err := tarantool.Connect(newFakeServer(t, s), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tube := NewTube(conn, "jobs")
+	for _, data := range []string{"ok", "retry", "bury", "panic", "slow"} {
+		_, err := tube.Put(ctx, data, nil)
+		require.NoError(err)
+	}
+
+	var mu sync.Mutex
+	attempts := map[string]int{}
+	var errs []error
+	started := make(chan struct{})
+
+	c := &Consumer{
+		Tube:        tube,
+		Workers:     2,
+		PollTimeout: 10 * time.Millisecond,
+		RetryDelay:  time.Second,
+		Handler: func(ctx context.Context, task *Task) error {
+			data := task.Data.(string)
+			mu.Lock()
+			attempts[data]++
+			n := attempts[data]
+			mu.Unlock()
+
+			switch data {
+			case "retry":
+				if n == 1 {
+					return errors.New("try again")
+				}
+			case "bury":
+				return fmt.Errorf("%w: bad data", ErrBury)
+			case "panic":
+				panic("boom")
+			case "slow":
+				close(started)
+				// graceful shutdown waits for the handler
+				<-ctx.Done()
+				return nil
+			}
+			return nil
+		},
+		ShutdownTimeout: 50 * time.Millisecond,
+		OnError: func(task *Task, err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	}
+
+	done := make(chan error)
+	go func() {
+		done <- c.Run(ctx)
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("slow task is not taken")
+	}
+	require.Eventually(func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return attempts["retry"] == 2
+	}, time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case err = <-done:
+	case <-time.After(time.Second):
+		t.Fatal("consumer is not stopped")
+	}
+	assert.Equal(context.Canceled, err)
+
+	// ok, retry, slow are acked, bury is buried and panic is released to retry
+	assert.Equal([]string{StatusDone, StatusDone, StatusBuried, StatusReady, StatusDone}, s.statuses()[:5])
+	assert.Equal(map[string]interface{}{"delay": float64(1)}, s.delays[1])
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(1, attempts["ok"])
+	assert.Equal(1, attempts["bury"])
+	var panicked bool
+	for _, err := range errs {
+		if err.Error() == "handler panic: boom" {
+			panicked = true
+		}
+	}
+	assert.True(panicked)
+}
+
+func TestConsumerDefaults(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeQueue{delays: map[uint64]interface{}{}}
+	// the connection with the default QueryTimeout
+	conn, err := tarantool.Connect(newFakeServer(t, s), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	var errs []error
+	handled := make(chan string, 1)
+
+	tube := NewTube(conn, "jobs")
+	c := &Consumer{
+		Tube: tube,
+		Handler: func(ctx context.Context, task *Task) error {
+			handled <- task.Data.(string)
+			return nil
+		},
+		OnError: func(task *Task, err error) {
+			mu.Lock()
+			errs = append(errs, err)
+			mu.Unlock()
+		},
+	}
+	done := make(chan error)
+	go func() {
+		done <- c.Run(ctx)
+	}()
+
+	// the idle takes return before the query times out
+	time.Sleep(tarantool.DefaultQueryTimeout + DefaultPollTimeout)
+	_, err = tube.Put(ctx, "late", nil)
+	require.NoError(err)
+	select {
+	case data := <-handled:
+		assert.Equal("late", data)
+	case <-time.After(tarantool.DefaultQueryTimeout):
+		t.Fatal("task is not taken")
+	}
+
+	cancel()
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Empty(errs)
+}
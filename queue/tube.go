@@ -0,0 +1,157 @@
+// Package queue is a client of the tarantool/queue module
+// (https://github.com/tarantool/queue) with a Consumer running
+// the task handlers.
+package queue
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// Task statuses.
+const (
+	StatusReady   = "r"
+	StatusTaken   = "t"
+	StatusDone    = "-"
+	StatusBuried  = "!"
+	StatusDelayed = "~"
+)
+
+// Task is a queue task.
+type Task struct {
+	ID     uint64
+	Status string
+	Data   interface{}
+}
+
+// Tube is a queue of tasks, the tube must be created on the server.
+type Tube struct {
+	conn tarantool.Executor
+	name string
+}
+
+// NewTube returns the tube by its name.
+func NewTube(conn tarantool.Executor, name string) *Tube {
+	return &Tube{conn: conn, name: name}
+}
+
+// Name returns the tube name.
+func (t *Tube) Name() string {
+	return t.name
+}
+
+// call calls the tube method and returns the task it returns, if any
+func (t *Tube) call(ctx context.Context, method string, args ...interface{}) (*Task, error) {
+	res := t.conn.Exec(ctx, &tarantool.Call17{
+		Name:  "queue.tube." + t.name + ":" + method,
+		Tuple: args,
+	})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 || res.Data[0][0] == nil {
+		return nil, nil
+	}
+	return decodeTask(res.Data[0])
+}
+
+func decodeTask(tuple []interface{}) (*Task, error) {
+	if len(tuple) < 2 {
+		return nil, fmt.Errorf("%w: task %v", tarantool.ErrBadResult, tuple)
+	}
+	id, ok := typeconv.IntfToUint64(tuple[0])
+	if !ok {
+		return nil, fmt.Errorf("%w: task id %#v", tarantool.ErrBadResult, tuple[0])
+	}
+	task := &Task{ID: id}
+	task.Status, _ = tuple[1].(string)
+	if len(tuple) > 2 {
+		task.Data = tuple[2]
+	}
+	return task, nil
+}
+
+// PutOptions are the options of a new task, the zero values are not sent.
+type PutOptions struct {
+	// Delay postpones the task.
+	Delay time.Duration
+	// TTL is the time to live of the task.
+	TTL time.Duration
+	// TTR is the time to run: the task is released if it isn't acked in time.
+	TTR time.Duration
+	// Priority of the task, for the drivers supporting it.
+	Priority int
+}
+
+// Put adds the task with the data to the tube.
+func (t *Tube) Put(ctx context.Context, data interface{}, opts *PutOptions) (*Task, error) {
+	args := []interface{}{data}
+	if opts != nil {
+		o := make(map[string]interface{})
+		if opts.Delay > 0 {
+			o["delay"] = opts.Delay.Seconds()
+		}
+		if opts.TTL > 0 {
+			o["ttl"] = opts.TTL.Seconds()
+		}
+		if opts.TTR > 0 {
+			o["ttr"] = opts.TTR.Seconds()
+		}
+		if opts.Priority != 0 {
+			o["pri"] = opts.Priority
+		}
+		args = append(args, o)
+	}
+	return t.call(ctx, "put", args...)
+}
+
+// Take takes a ready task, waiting for one for at most timeout. It returns nil
+// if there is none. The timeout must be less than the QueryTimeout of the
+// connection, otherwise the request times out before the server answers and
+// the task taken may stay taken until the connection is closed.
+func (t *Tube) Take(ctx context.Context, timeout time.Duration) (*Task, error) {
+	return t.call(ctx, "take", timeout.Seconds())
+}
+
+// Ack marks the taken task done.
+func (t *Tube) Ack(ctx context.Context, id uint64) (*Task, error) {
+	return t.call(ctx, "ack", id)
+}
+
+// Release returns the taken task to the tube, it is ready again after delay.
+func (t *Tube) Release(ctx context.Context, id uint64, delay time.Duration) (*Task, error) {
+	if delay > 0 {
+		return t.call(ctx, "release", id, map[string]interface{}{"delay": delay.Seconds()})
+	}
+	return t.call(ctx, "release", id)
+}
+
+// Bury disables the task until it is kicked.
+func (t *Tube) Bury(ctx context.Context, id uint64) (*Task, error) {
+	return t.call(ctx, "bury", id)
+}
+
+// Kick returns up to count buried tasks to the tube, it returns their number.
+func (t *Tube) Kick(ctx context.Context, count uint64) (uint64, error) {
+	res := t.conn.Exec(ctx, &tarantool.Call17{
+		Name:  "queue.tube." + t.name + ":kick",
+		Tuple: []interface{}{count},
+	})
+	if res.Error != nil {
+		return 0, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 {
+		return 0, tarantool.ErrBadResult
+	}
+	n, _ := typeconv.IntfToUint64(res.Data[0][0])
+	return n, nil
+}
+
+// Delete removes the task.
+func (t *Tube) Delete(ctx context.Context, id uint64) (*Task, error) {
+	return t.call(ctx, "delete", id)
+}
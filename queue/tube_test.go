@@ -0,0 +1,165 @@
+package queue
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// fakeQueue implements the methods of a fifo tube in memory
+type fakeQueue struct {
+	sync.Mutex
+	tasks  []*Task
+	calls  []string
+	delays map[uint64]interface{}
+}
+
+func (s *fakeQueue) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	call, ok := q.(*tarantool.Call17)
+	if !ok || !strings.HasPrefix(call.Name, "queue.tube.jobs:") {
+		return &tarantool.Result{}
+	}
+	method := strings.TrimPrefix(call.Name, "queue.tube.jobs:")
+
+	s.Lock()
+	defer s.Unlock()
+	s.calls = append(s.calls, method)
+
+	tuple := func(t *Task) *tarantool.Result {
+		if t == nil {
+			return &tarantool.Result{Data: [][]interface{}{{nil}}}
+		}
+		return &tarantool.Result{Data: [][]interface{}{{t.ID, t.Status, t.Data}}}
+	}
+	find := func() *Task {
+		id, _ := typeconv.IntfToUint64(call.Tuple[0])
+		for _, t := range s.tasks {
+			if t.ID == id {
+				return t
+			}
+		}
+		return nil
+	}
+
+	switch method {
+	case "put":
+		t := &Task{ID: uint64(len(s.tasks)), Status: StatusReady, Data: call.Tuple[0]}
+		s.tasks = append(s.tasks, t)
+		return tuple(t)
+	case "take":
+		// wait for a ready task up to the take timeout
+		timeout, _ := call.Tuple[0].(float64)
+		deadline := time.Now().Add(time.Duration(timeout * float64(time.Second)))
+		for {
+			for _, t := range s.tasks {
+				if t.Status == StatusReady {
+					t.Status = StatusTaken
+					return tuple(t)
+				}
+			}
+			if time.Now().After(deadline) {
+				return tuple(nil)
+			}
+			s.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			s.Lock()
+		}
+	case "ack":
+		t := find()
+		t.Status = StatusDone
+		return tuple(t)
+	case "release":
+		t := find()
+		t.Status = StatusReady
+		if len(call.Tuple) > 1 {
+			s.delays[t.ID] = call.Tuple[1]
+		}
+		return tuple(t)
+	case "bury":
+		t := find()
+		t.Status = StatusBuried
+		return tuple(t)
+	case "kick":
+		n := 0
+		for _, t := range s.tasks {
+			if t.Status == StatusBuried {
+				t.Status = StatusReady
+				n++
+			}
+		}
+		return &tarantool.Result{Data: [][]interface{}{{n}}}
+	}
+	return &tarantool.Result{}
+}
+
+func (s *fakeQueue) statuses() []string {
+	s.Lock()
+	defer s.Unlock()
+	var list []string
+	for _, t := range s.tasks {
+		list = append(list, t.Status)
+	}
+	return list
+}
+
+func newFakeServer(t *testing.T, s *fakeQueue) string {
+	return tnttest.NewServer(t, s.handle)
+}
+
+func TestTube(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeQueue{delays: map[uint64]interface{}{}}
+	conn, err := tarantool.Connect(newFakeServer(t, s), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+	tube := NewTube(conn, "jobs")
+	assert.Equal("jobs", tube.Name())
+
+	task, err := tube.Put(ctx, "hello", &PutOptions{TTR: time.Minute})
+	require.NoError(err)
+	assert.Equal(&Task{ID: 0, Status: StatusReady, Data: "hello"}, task)
+
+	task, err = tube.Take(ctx, time.Second)
+	require.NoError(err)
+	assert.Equal(&Task{ID: 0, Status: StatusTaken, Data: "hello"}, task)
+
+	task, err = tube.Take(ctx, 0)
+	require.NoError(err)
+	assert.Nil(task)
+
+	task, err = tube.Release(ctx, 0, 2*time.Second)
+	require.NoError(err)
+	assert.Equal(StatusReady, task.Status)
+	assert.Equal(map[string]interface{}{"delay": float64(2)}, s.delays[0])
+
+	_, err = tube.Take(ctx, 0)
+	require.NoError(err)
+	task, err = tube.Bury(ctx, 0)
+	require.NoError(err)
+	assert.Equal(StatusBuried, task.Status)
+
+	n, err := tube.Kick(ctx, 10)
+	require.NoError(err)
+	assert.Equal(uint64(1), n)
+
+	_, err = tube.Take(ctx, 0)
+	require.NoError(err)
+	task, err = tube.Ack(ctx, 0)
+	require.NoError(err)
+	assert.Equal(StatusDone, task.Status)
+
+	_, err = NewTube(conn, "missing").Take(ctx, 0)
+	assert.NoError(err)
+}
@@ -0,0 +1,190 @@
+// Package ratelimit implements a token bucket rate limiter which keeps its
+// state in a Tarantool space, so the limit is shared by all the processes
+// using the same key. The API mirrors golang.org/x/time/rate.
+//
+// Each Allow or Wait attempt is a round trip to the server, where the bucket
+// is refilled and taken from in a single transaction. The refill uses the
+// server clock. The scripts are executed with Eval, so the user needs the
+// execute privilege on the universe.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// DefaultSpace is the name of the space to keep the buckets in.
+const DefaultSpace = "_rate_limits"
+
+// Limit is the maximum rate of events per second.
+type Limit float64
+
+// Inf is the infinite rate limit, all the events are allowed
+// without asking the server.
+const Inf = Limit(math.MaxFloat64)
+
+// Every converts the minimum interval between events to a Limit.
+func Every(interval time.Duration) Limit {
+	if interval <= 0 {
+		return Inf
+	}
+	return 1 / Limit(interval.Seconds())
+}
+
+var (
+	// ErrBurst is returned if more events are requested at once than the burst allows.
+	ErrBurst = errors.New("events exceed the burst")
+	// ErrWouldExceedDeadline is returned by Wait if the events can't be allowed
+	// before the context deadline. Nothing is taken from the bucket then.
+	ErrWouldExceedDeadline = errors.New("wait would exceed the context deadline")
+)
+
+// luaTake refills the bucket and takes n tokens from it if there are enough.
+// It returns whether they are taken, and if not, the number of microseconds
+// until there are enough of them, -1 if never.
+const luaTake = `
+local space, key, rate, burst, n = ...
+if box.space[space] == nil then
+    box.schema.space.create(space, {
+        if_not_exists = true,
+        format = {
+            {name = 'key', type = 'string'},
+            {name = 'tokens', type = 'number'},
+            {name = 'updated', type = 'number'},
+        },
+    })
+    box.space[space]:create_index('primary', {parts = {1, 'string'}, if_not_exists = true})
+end
+local s = box.space[space]
+return box.atomic(function()
+    local now = require('clock').time()
+    local tokens = burst
+    local t = s:get(key)
+    if t ~= nil then
+        tokens = math.min(burst, t[2] + math.max(0, now - t[3]) * rate)
+    end
+    if tokens >= n then
+        s:replace({key, tokens - n, now})
+        return true, 0
+    end
+    if rate <= 0 then
+        return false, -1
+    end
+    return false, math.ceil((n - tokens) / rate * 1e6)
+end)
+`
+
+// Limiter allows events at rate r with bursts of at most b events for the key.
+// The limiters sharing the key must have the same rate and burst.
+// It is safe for concurrent use.
+type Limiter struct {
+	conn  tarantool.Executor
+	key   string
+	space string
+	limit Limit
+	burst int
+}
+
+// NewLimiter returns the limiter of the key. The buckets are kept in space,
+// DefaultSpace is used if it is empty. It is created on first use.
+func NewLimiter(conn tarantool.Executor, space, key string, r Limit, b int) *Limiter {
+	if space == "" {
+		space = DefaultSpace
+	}
+	return &Limiter{conn: conn, key: key, space: space, limit: r, burst: b}
+}
+
+// Limit returns the rate limit.
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Burst returns the maximum burst size.
+func (l *Limiter) Burst() int {
+	return l.burst
+}
+
+// Allow is AllowN(ctx, 1).
+func (l *Limiter) Allow(ctx context.Context) (bool, error) {
+	return l.AllowN(ctx, 1)
+}
+
+// AllowN reports whether n events may happen now, they are accounted if so.
+func (l *Limiter) AllowN(ctx context.Context, n int) (bool, error) {
+	ok, _, err := l.take(ctx, n)
+	return ok, err
+}
+
+// Wait is WaitN(ctx, 1).
+func (l *Limiter) Wait(ctx context.Context) error {
+	return l.WaitN(ctx, 1)
+}
+
+// WaitN blocks until n events are allowed. It fails at once with
+// ErrWouldExceedDeadline if they can't be allowed before the ctx deadline.
+// Unlike x/time/rate, the events are not reserved while waiting: the bucket
+// is tried again after the estimated delay, so the waiters compete for it.
+func (l *Limiter) WaitN(ctx context.Context, n int) error {
+	for {
+		ok, delay, err := l.take(ctx, n)
+		if ok || err != nil {
+			return err
+		}
+		if delay < 0 {
+			return fmt.Errorf("%w: rate is zero", ErrWouldExceedDeadline)
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Now().Add(delay).After(deadline) {
+			return ErrWouldExceedDeadline
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// take tries to take n tokens, it returns the delay until there are enough
+// of them otherwise, negative if never
+func (l *Limiter) take(ctx context.Context, n int) (bool, time.Duration, error) {
+	if n > l.burst && l.limit != Inf {
+		return false, 0, fmt.Errorf("%w: %d > %d", ErrBurst, n, l.burst)
+	}
+	if l.limit == Inf || n <= 0 {
+		return true, 0, nil
+	}
+
+	res := l.conn.Exec(ctx, &tarantool.Eval{
+		Expression: luaTake,
+		Tuple:      []interface{}{l.space, l.key, float64(l.limit), l.burst, n},
+	})
+	if res.Error != nil {
+		return false, 0, res.Error
+	}
+	if len(res.Data) < 2 || len(res.Data[0]) == 0 || len(res.Data[1]) == 0 {
+		return false, 0, tarantool.ErrBadResult
+	}
+	if ok, _ := res.Data[0][0].(bool); ok {
+		return true, 0, nil
+	}
+	us, ok := typeconv.IntfToInt64(res.Data[1][0])
+	if f, isFloat := res.Data[1][0].(float64); isFloat {
+		us, ok = int64(f), true
+	}
+	if !ok {
+		return false, 0, fmt.Errorf("%w: delay %#v", tarantool.ErrBadResult, res.Data[1][0])
+	}
+	if us < 0 {
+		return false, -1, nil
+	}
+	return false, time.Duration(us) * time.Microsecond, nil
+}
@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// fakeServer executes the limiter script against the in-memory buckets
+type fakeServer struct {
+	sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	eval, ok := q.(*tarantool.Eval)
+	if !ok || eval.Expression != luaTake {
+		return &tarantool.Result{}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	s.calls++
+
+	key := eval.Tuple[1].(string)
+	rate := eval.Tuple[2].(float64)
+	burst, _ := typeconv.IntfToInt64(eval.Tuple[3])
+	n, _ := typeconv.IntfToInt64(eval.Tuple[4])
+	now := time.Now()
+
+	tokens := float64(burst)
+	if b := s.buckets[key]; b != nil {
+		tokens = math.Min(float64(burst), b.tokens+now.Sub(b.updated).Seconds()*rate)
+	}
+	if tokens >= float64(n) {
+		s.buckets[key] = &bucket{tokens - float64(n), now}
+		return &tarantool.Result{Data: [][]interface{}{{true}, {int64(0)}}}
+	}
+	if rate <= 0 {
+		return &tarantool.Result{Data: [][]interface{}{{false}, {int64(-1)}}}
+	}
+	return &tarantool.Result{Data: [][]interface{}{{false}, {math.Ceil((float64(n) - tokens) / rate * 1e6)}}}
+}
+
+func newFakeServer(t *testing.T, s *fakeServer) string {
+	return tnttest.NewServer(t, s.handle)
+}
+
+func TestEvery(t *testing.T) {
+	assert.Equal(t, Limit(10), Every(100*time.Millisecond))
+	assert.Equal(t, Inf, Every(0))
+}
+
+func TestLimiter(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{buckets: map[string]*bucket{}}
+	conn, err := tarantool.Connect(newFakeServer(t, s), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+
+	// the limiters of the same key share the bucket
+	l1 := NewLimiter(conn, "", "api", 20, 3)
+	l2 := NewLimiter(conn, "", "api", 20, 3)
+	assert.Equal(Limit(20), l1.Limit())
+	assert.Equal(3, l1.Burst())
+
+	ok, err := l1.AllowN(ctx, 2)
+	require.NoError(err)
+	assert.True(ok)
+	ok, err = l2.Allow(ctx)
+	require.NoError(err)
+	assert.True(ok)
+	ok, err = l2.Allow(ctx)
+	require.NoError(err)
+	assert.False(ok)
+
+	_, err = l1.AllowN(ctx, 4)
+	assert.True(errors.Is(err, ErrBurst))
+
+	// a token is refilled in 50ms
+	start := time.Now()
+	require.NoError(l1.Wait(ctx))
+	assert.True(time.Since(start) >= 40*time.Millisecond)
+
+	wctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	assert.Equal(ErrWouldExceedDeadline, l1.WaitN(wctx, 3))
+
+	never := NewLimiter(conn, "", "never", 0, 1)
+	require.NoError(never.Wait(ctx))
+	assert.True(errors.Is(never.Wait(ctx), ErrWouldExceedDeadline))
+
+	// the infinite limit doesn't need the server
+	s.Lock()
+	calls := s.calls
+	s.Unlock()
+	inf := NewLimiter(conn, "", "inf", Inf, 0)
+	ok, err = inf.AllowN(ctx, 100)
+	assert.NoError(err)
+	assert.True(ok)
+	s.Lock()
+	assert.Equal(calls, s.calls)
+	s.Unlock()
+}
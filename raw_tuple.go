@@ -0,0 +1,112 @@
+package tarantool
+
+import (
+	"fmt"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// RawTuple is a msgpack encoded tuple as passed to the ExecTuples callback.
+// Its accessors decode single fields straight from the buffer,
+// without allocating interface{} values for the whole tuple.
+type RawTuple []byte
+
+// Len returns the number of fields in the tuple.
+func (t RawTuple) Len() (int, error) {
+	n, _, err := msgp.ReadArrayHeaderBytes(t)
+	return int(n), err
+}
+
+// field returns the buffer starting at the i-th field.
+func (t RawTuple) field(i int) (buf []byte, err error) {
+	var n uint32
+
+	if n, buf, err = msgp.ReadArrayHeaderBytes(t); err != nil {
+		return
+	}
+	if i < 0 || i >= int(n) {
+		return nil, fmt.Errorf("field %d is out of range, tuple has %d fields", i, n)
+	}
+
+	for ; i > 0; i-- {
+		if buf, err = msgp.Skip(buf); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// IntField returns the i-th field as int64.
+func (t RawTuple) IntField(i int) (int64, error) {
+	buf, err := t.field(i)
+	if err != nil {
+		return 0, err
+	}
+	v, _, err := msgp.ReadInt64Bytes(buf)
+	return v, err
+}
+
+// UintField returns the i-th field as uint64.
+func (t RawTuple) UintField(i int) (uint64, error) {
+	buf, err := t.field(i)
+	if err != nil {
+		return 0, err
+	}
+	v, _, err := msgp.ReadUint64Bytes(buf)
+	return v, err
+}
+
+// FloatField returns the i-th field as float64.
+func (t RawTuple) FloatField(i int) (float64, error) {
+	buf, err := t.field(i)
+	if err != nil {
+		return 0, err
+	}
+	v, _, err := msgp.ReadFloat64Bytes(buf)
+	return v, err
+}
+
+// BoolField returns the i-th field as bool.
+func (t RawTuple) BoolField(i int) (bool, error) {
+	buf, err := t.field(i)
+	if err != nil {
+		return false, err
+	}
+	v, _, err := msgp.ReadBoolBytes(buf)
+	return v, err
+}
+
+// StringField returns the i-th field as string.
+func (t RawTuple) StringField(i int) (string, error) {
+	buf, err := t.field(i)
+	if err != nil {
+		return "", err
+	}
+	v, _, err := msgp.ReadStringBytes(buf)
+	return v, err
+}
+
+// BytesField returns the i-th string or binary field without copying it.
+// The returned slice shares memory with the tuple.
+func (t RawTuple) BytesField(i int) ([]byte, error) {
+	buf, err := t.field(i)
+	if err != nil {
+		return nil, err
+	}
+	if msgp.NextType(buf) == msgp.StrType {
+		v, _, err := msgp.ReadStringZC(buf)
+		return v, err
+	}
+	v, _, err := msgp.ReadBytesZC(buf)
+	return v, err
+}
+
+// Field returns the i-th field decoded the same way as Result.Data fields.
+func (t RawTuple) Field(i int) (interface{}, error) {
+	buf, err := t.field(i)
+	if err != nil {
+		return nil, err
+	}
+	v, _, err := readIntfBytes(buf)
+	return v, err
+}
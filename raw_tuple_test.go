@@ -0,0 +1,96 @@
+package tarantool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestRawTuple(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	buf, err := msgp.AppendIntf(nil, []interface{}{int64(-1), uint64(42), "name", []byte{1, 2}, 1.5, true})
+	require.NoError(err)
+	tuple := RawTuple(buf)
+
+	n, err := tuple.Len()
+	assert.NoError(err)
+	assert.Equal(6, n)
+
+	i, err := tuple.IntField(0)
+	assert.NoError(err)
+	assert.Equal(int64(-1), i)
+
+	u, err := tuple.UintField(1)
+	assert.NoError(err)
+	assert.Equal(uint64(42), u)
+
+	s, err := tuple.StringField(2)
+	assert.NoError(err)
+	assert.Equal("name", s)
+
+	b, err := tuple.BytesField(2)
+	assert.NoError(err)
+	assert.Equal([]byte("name"), b)
+
+	b, err = tuple.BytesField(3)
+	assert.NoError(err)
+	assert.Equal([]byte{1, 2}, b)
+
+	f, err := tuple.FloatField(4)
+	assert.NoError(err)
+	assert.Equal(1.5, f)
+
+	ok, err := tuple.BoolField(5)
+	assert.NoError(err)
+	assert.True(ok)
+
+	v, err := tuple.Field(1)
+	assert.NoError(err)
+	assert.Equal(int64(42), v)
+
+	_, err = tuple.StringField(0)
+	assert.Error(err)
+
+	_, err = tuple.IntField(6)
+	assert.Error(err)
+}
+
+func BenchmarkRawTupleFields(b *testing.B) {
+	b.ReportAllocs()
+	body := packTestResult(100)
+
+	for i := 0; i < b.N; i++ {
+		var sum int64
+		err := forEachTuple(body, func(tuple []byte) error {
+			id, err := RawTuple(tuple).IntField(0)
+			sum += id
+			return err
+		})
+		if err != nil || sum != 4950 {
+			b.FailNow()
+		}
+	}
+}
+
+func BenchmarkResultFields(b *testing.B) {
+	b.ReportAllocs()
+	body := packTestResult(100)
+
+	for i := 0; i < b.N; i++ {
+		var sum int64
+		res := &Result{}
+		if _, err := res.UnmarshalMsg(body); err != nil {
+			b.FailNow()
+		}
+		for _, tuple := range res.Data {
+			sum += tuple[0].(int64)
+		}
+		if sum != 4950 {
+			b.FailNow()
+		}
+	}
+}
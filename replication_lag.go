@@ -0,0 +1,239 @@
+package tarantool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// luaReplicationLag returns the upstreams of the instance as a flat list of
+// id, uuid, status and lag of each. The lag is in microseconds, so it is not
+// subject to the float decoding.
+const luaReplicationLag = `
+local upstreams = {}
+for id, r in pairs(box.info.replication) do
+    if r.upstream ~= nil then
+        table.insert(upstreams, id)
+        table.insert(upstreams, r.uuid)
+        table.insert(upstreams, r.upstream.status)
+        table.insert(upstreams, math.floor((r.upstream.lag or 0) * 1e6))
+    end
+end
+return upstreams
+`
+
+// UpstreamFollow is the status of an upstream which is replicating normally.
+const UpstreamFollow = "follow"
+
+// UpstreamLag is the state of one upstream of a replica as reported
+// by box.info.replication.
+type UpstreamLag struct {
+	ID     uint32
+	UUID   string
+	Status string
+	// Lag is the time between the master writing the last received
+	// transaction and the replica applying it.
+	Lag time.Duration
+}
+
+// ReplicationLag returns the state of the upstreams the instance replicates from.
+// A master with no upstreams returns an empty list.
+func (conn *Connection) ReplicationLag(ctx context.Context) ([]UpstreamLag, error) {
+	return parseReplicationLag(conn.Exec(ctx, &Eval{Expression: luaReplicationLag}))
+}
+
+func parseReplicationLag(res *Result) ([]UpstreamLag, error) {
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if len(res.Data) == 0 {
+		return nil, nil
+	}
+
+	fields := res.Data[0]
+	if len(fields)%4 != 0 {
+		return nil, ErrBadResult
+	}
+
+	upstreams := make([]UpstreamLag, 0, len(fields)/4)
+	for i := 0; i < len(fields); i += 4 {
+		id, err := numberToUint64(fields[i])
+		if err != nil {
+			return nil, ErrBadResult
+		}
+		uuid, _ := fields[i+1].(string)
+		status, _ := fields[i+2].(string)
+		lag, err := numberToUint64(fields[i+3])
+		if err != nil {
+			return nil, ErrBadResult
+		}
+		upstreams = append(upstreams, UpstreamLag{
+			ID:     uint32(id),
+			UUID:   uuid,
+			Status: status,
+			Lag:    time.Duration(lag) * time.Microsecond,
+		})
+	}
+	return upstreams, nil
+}
+
+// ReplicaStats is the replication state of a replica tracked by LagMonitor.
+type ReplicaStats struct {
+	Addr      string
+	Upstreams []UpstreamLag
+	// Lag is the largest lag of the upstreams.
+	Lag time.Duration
+	// Following is false if any upstream is not in the follow status,
+	// in which case Lag does not reflect how stale the replica is.
+	Following bool
+	// Err is the error of the last poll, if any.
+	Err error
+	// UpdatedAt is the time of the last successful poll,
+	// zero if the replica has not been polled yet.
+	UpdatedAt time.Time
+}
+
+// LagMonitorOptions configures LagMonitor.
+type LagMonitorOptions struct {
+	// PollInterval is how often the replicas are polled.
+	// DefaultLagPollInterval is used if it is 0.
+	PollInterval time.Duration
+	// OnError is called with the replica address and the error of a failed poll.
+	OnError func(addr string, err error)
+}
+
+// LagMonitor polls the replication lag of a set of replicas in the background,
+// so the reads can be routed to the replicas which are not too stale:
+//
+//	m := NewLagMonitor(replicas, nil)
+//	defer m.Close()
+//	fresh := m.Replicas(time.Second)
+type LagMonitor struct {
+	replicas []*Connector
+	opts     LagMonitorOptions
+
+	mu    sync.RWMutex
+	stats []ReplicaStats
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewLagMonitor starts polling the replicas. Nil opts means the defaults.
+func NewLagMonitor(replicas []*Connector, opts *LagMonitorOptions) *LagMonitor {
+	m := &LagMonitor{
+		replicas: replicas,
+		stats:    make([]ReplicaStats, len(replicas)),
+		done:     make(chan struct{}),
+	}
+	if opts != nil {
+		m.opts = *opts
+	}
+	if m.opts.PollInterval == 0 {
+		m.opts.PollInterval = DefaultLagPollInterval
+	}
+	for i, r := range replicas {
+		m.stats[i].Addr = r.RemoteAddr
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.cancel = cancel
+	go m.run(ctx)
+	return m
+}
+
+func (m *LagMonitor) run(ctx context.Context) {
+	defer close(m.done)
+
+	ticker := time.NewTicker(m.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		m.poll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *LagMonitor) poll(ctx context.Context) {
+	pollCtx, cancel := context.WithTimeout(ctx, m.opts.PollInterval)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for i, r := range m.replicas {
+		wg.Add(1)
+		go func(i int, r *Connector) {
+			defer wg.Done()
+			upstreams, err := parseReplicationLag(r.Exec(pollCtx, &Eval{Expression: luaReplicationLag}))
+			if ctx.Err() != nil {
+				// closed, keep the last known state
+				return
+			}
+			m.update(i, upstreams, err)
+			if err != nil && m.opts.OnError != nil {
+				m.opts.OnError(r.RemoteAddr, err)
+			}
+		}(i, r)
+	}
+	wg.Wait()
+}
+
+func (m *LagMonitor) update(i int, upstreams []UpstreamLag, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s := &m.stats[i]
+	s.Err = err
+	if err != nil {
+		return
+	}
+
+	s.Upstreams = upstreams
+	s.Lag = 0
+	s.Following = true
+	for _, u := range upstreams {
+		if u.Status != UpstreamFollow {
+			s.Following = false
+		}
+		if u.Lag > s.Lag {
+			s.Lag = u.Lag
+		}
+	}
+	s.UpdatedAt = time.Now()
+}
+
+// Stats returns the last known state of the replicas in the order they were given.
+func (m *LagMonitor) Stats() []ReplicaStats {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := make([]ReplicaStats, len(m.stats))
+	copy(stats, m.stats)
+	return stats
+}
+
+// Replicas returns the replicas which follow their upstreams with the lag below
+// maxLag as of the last poll. The replicas which failed the last poll or have
+// not been polled yet are skipped.
+func (m *LagMonitor) Replicas(maxLag time.Duration) []*Connector {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var replicas []*Connector
+	for i, s := range m.stats {
+		if s.Err == nil && !s.UpdatedAt.IsZero() && s.Following && s.Lag < maxLag {
+			replicas = append(replicas, m.replicas[i])
+		}
+	}
+	return replicas
+}
+
+// Close stops polling and waits for the poll in progress to finish.
+func (m *LagMonitor) Close() {
+	m.cancel()
+	<-m.done
+}
@@ -0,0 +1,130 @@
+package tarantool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLagServer returns the address of a server reporting one upstream
+// with the status and the lag in microseconds returned by lag
+func newLagServer(t *testing.T, lag func() (string, uint64)) string {
+	return newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if _, ok := q.(*Eval); !ok {
+			return &Result{}
+		}
+		status, us := lag()
+		if status == "error" {
+			return &Result{ErrorCode: ErrProcLua, Error: NewQueryError(ErrProcLua, "box.info failed")}
+		}
+		if status == "" {
+			return &Result{Data: [][]interface{}{{}}}
+		}
+		return &Result{Data: [][]interface{}{{int64(1), "aaaaaaaa-0000-0000-0000-000000000001", status, us}}}
+	})
+}
+
+func TestReplicationLag(t *testing.T) {
+	require := require.New(t)
+
+	conn, err := Connect(newLagServer(t, func() (string, uint64) { return UpstreamFollow, 1500 }), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	upstreams, err := conn.ReplicationLag(context.Background())
+	require.NoError(err)
+	require.Equal([]UpstreamLag{{
+		ID:     1,
+		UUID:   "aaaaaaaa-0000-0000-0000-000000000001",
+		Status: UpstreamFollow,
+		Lag:    1500 * time.Microsecond,
+	}}, upstreams)
+
+	master, err := Connect(newLagServer(t, func() (string, uint64) { return "", 0 }), nil)
+	require.NoError(err)
+	defer master.Close()
+
+	upstreams, err = master.ReplicationLag(context.Background())
+	require.NoError(err)
+	require.Empty(upstreams)
+}
+
+func TestLagMonitor(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var mu sync.Mutex
+	lagging := uint64(5 * time.Second / time.Microsecond)
+	stopped := UpstreamFollow
+	failing := UpstreamFollow
+
+	fresh := New(newLagServer(t, func() (string, uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		return failing, 100
+	}), nil)
+	defer fresh.Close()
+	stale := New(newLagServer(t, func() (string, uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		return UpstreamFollow, lagging
+	}), nil)
+	defer stale.Close()
+	broken := New(newLagServer(t, func() (string, uint64) {
+		mu.Lock()
+		defer mu.Unlock()
+		return stopped, 0
+	}), nil)
+	defer broken.Close()
+
+	errs := make(chan error, 1)
+	m := NewLagMonitor([]*Connector{fresh, stale, broken}, &LagMonitorOptions{
+		PollInterval: 5 * time.Millisecond,
+		OnError: func(addr string, err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		},
+	})
+	defer m.Close()
+
+	require.Eventually(func() bool {
+		return len(m.Replicas(time.Second)) == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal([]*Connector{fresh, broken}, m.Replicas(time.Second))
+	assert.Len(m.Replicas(time.Hour), 3)
+
+	stats := m.Stats()
+	require.Len(stats, 3)
+	assert.Equal(stale.RemoteAddr, stats[1].Addr)
+	assert.Equal(5*time.Second, stats[1].Lag)
+	assert.True(stats[1].Following)
+	assert.NoError(stats[1].Err)
+	assert.False(stats[1].UpdatedAt.IsZero())
+
+	mu.Lock()
+	lagging = 10
+	stopped = "disconnected"
+	mu.Unlock()
+
+	require.Eventually(func() bool {
+		replicas := m.Replicas(time.Second)
+		return len(replicas) == 2 && replicas[1] == stale
+	}, time.Second, time.Millisecond)
+	assert.False(m.Stats()[2].Following)
+
+	// a failed poll excludes the replica
+	mu.Lock()
+	failing = "error"
+	mu.Unlock()
+	require.Eventually(func() bool {
+		return len(m.Replicas(time.Hour)) == 1
+	}, time.Second, time.Millisecond)
+	assert.Error(m.Stats()[0].Err)
+	assert.Error(<-errs)
+}
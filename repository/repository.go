@@ -0,0 +1,232 @@
+// Package repository maps the tuples of a space to Go structs and back, so
+// the CRUD code does not have to pack and unpack tuples by hand.
+//
+// The struct fields are matched to the tuple fields in order of declaration,
+// the same way tarantool.TupleDecoder.DecodeStruct does: unexported fields
+// and fields tagged with `tarantool:"-"` are skipped.
+//
+//	type User struct {
+//		ID    uint64 `tarantool:"id"`
+//		Name  string `tarantool:"name"`
+//		Email string `tarantool:"email"`
+//	}
+//
+//	users, err := repository.New(conn, "users", User{})
+//	...
+//	var u User
+//	found, err := users.GetByPK(ctx, &u, uint64(1))
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/viciious/go-tarantool"
+)
+
+var (
+	// ErrNoPrimaryKey is returned by New if the space has no unique primary
+	// index in the schema cache or the struct has fewer fields than it covers.
+	ErrNoPrimaryKey = errors.New("primary key is not known")
+	// ErrType is returned if the value passed to a method is not of the
+	// type the repository was created for.
+	ErrType = errors.New("value of unexpected type")
+)
+
+// Conn is implemented by tarantool.Connection.
+type Conn interface {
+	Exec(ctx context.Context, q tarantool.Query, options ...tarantool.ExecOption) *tarantool.Result
+	GetPrimaryKeyFields(space interface{}) ([]int, bool)
+}
+
+// Repository performs the CRUD operations on a space for one struct type.
+// It is safe for concurrent use.
+type Repository struct {
+	conn    Conn
+	space   string
+	typ     reflect.Type
+	fields  []int // struct field indexes in tuple order
+	pk      []int // tuple field numbers of the primary key
+	decoder tarantool.TupleDecoder
+}
+
+// New returns the repository of the space for the type of model, which is
+// a struct or a pointer to struct. The primary key is taken from the schema
+// cache, so the connection must be established with the schema.
+func New(conn Conn, space string, model interface{}) (*Repository, error) {
+	typ := reflect.TypeOf(model)
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("%w: %T is not a struct", ErrType, model)
+	}
+
+	r := &Repository{
+		conn:  conn,
+		space: space,
+		typ:   typ,
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" || f.Tag.Get("tarantool") == "-" {
+			continue
+		}
+		r.fields = append(r.fields, i)
+	}
+
+	pk, ok := conn.GetPrimaryKeyFields(space)
+	if !ok || len(pk) == 0 {
+		return nil, fmt.Errorf("%w: space '%s'", ErrNoPrimaryKey, space)
+	}
+	for _, n := range pk {
+		if n < 0 || n >= len(r.fields) {
+			return nil, fmt.Errorf("%w: field %d of space '%s' is not mapped by %s", ErrNoPrimaryKey, n, space, typ)
+		}
+	}
+	r.pk = pk
+	return r, nil
+}
+
+// SetStrict makes the decoding fail on missing, extra or mismatching tuple
+// fields instead of leaving them zeroed, see tarantool.TupleDecoder.
+// It must be called before the repository is used.
+func (r *Repository) SetStrict(strict bool) {
+	r.decoder.Strict = strict
+}
+
+// GetByPK decodes the tuple with the primary key into dst, a pointer to the
+// repository struct. It returns false if there is no such tuple.
+func (r *Repository) GetByPK(ctx context.Context, dst interface{}, key ...interface{}) (bool, error) {
+	if rv := reflect.ValueOf(dst); rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return false, fmt.Errorf("%w: %T, expected *%s", ErrType, dst, r.typ)
+	}
+	if _, err := r.value(dst); err != nil {
+		return false, err
+	}
+	res := r.conn.Exec(ctx, &tarantool.Select{Space: r.space, KeyTuple: key, Limit: 1})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	if len(res.Data) == 0 {
+		return false, nil
+	}
+	return true, r.decoder.DecodeStruct(res.Data[0], dst)
+}
+
+// Insert inserts v, the repository struct or a pointer to it.
+func (r *Repository) Insert(ctx context.Context, v interface{}) error {
+	rv, err := r.value(v)
+	if err != nil {
+		return err
+	}
+	return r.conn.Exec(ctx, &tarantool.Insert{Space: r.space, Tuple: r.tuple(rv)}).Error
+}
+
+// Replace inserts v or replaces the tuple with the same primary key.
+func (r *Repository) Replace(ctx context.Context, v interface{}) error {
+	rv, err := r.value(v)
+	if err != nil {
+		return err
+	}
+	return r.conn.Exec(ctx, &tarantool.Replace{Space: r.space, Tuple: r.tuple(rv)}).Error
+}
+
+// Update assigns all the fields of v but the primary key ones to the tuple
+// with the primary key of v. It returns false if there is no such tuple.
+func (r *Repository) Update(ctx context.Context, v interface{}) (bool, error) {
+	rv, err := r.value(v)
+	if err != nil {
+		return false, err
+	}
+	tuple := r.tuple(rv)
+
+	key := make([]interface{}, len(r.pk))
+	inKey := make(map[int]bool, len(r.pk))
+	for i, n := range r.pk {
+		key[i] = tuple[n]
+		inKey[n] = true
+	}
+
+	set := make([]tarantool.Operator, 0, len(tuple)-len(key))
+	for n, value := range tuple {
+		if !inKey[n] {
+			set = append(set, &tarantool.OpAssign{Field: int64(n), Argument: value})
+		}
+	}
+
+	res := r.conn.Exec(ctx, &tarantool.Update{Space: r.space, KeyTuple: key, Set: set})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return len(res.Data) > 0, nil
+}
+
+// Delete deletes the tuple with the primary key. It returns false if there
+// is no such tuple.
+func (r *Repository) Delete(ctx context.Context, key ...interface{}) (bool, error) {
+	res := r.conn.Exec(ctx, &tarantool.Delete{Space: r.space, KeyTuple: key})
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return len(res.Data) > 0, nil
+}
+
+// SelectBy decodes the tuples matching the key in the index into dst, a pointer
+// to a slice of the repository structs or of pointers to them. The index is
+// its name or number, limit of 0 means tarantool.DefaultLimit.
+func (r *Repository) SelectBy(ctx context.Context, dst interface{}, index interface{}, limit uint32, key ...interface{}) error {
+	slice := reflect.ValueOf(dst)
+	if slice.Kind() != reflect.Ptr || slice.IsNil() || slice.Elem().Kind() != reflect.Slice {
+		return fmt.Errorf("%w: %T is not a pointer to []%s", ErrType, dst, r.typ)
+	}
+	slice = slice.Elem()
+	elem := slice.Type().Elem()
+	isPtr := elem.Kind() == reflect.Ptr
+	if elem != r.typ && !(isPtr && elem.Elem() == r.typ) {
+		return fmt.Errorf("%w: %T is not a pointer to []%s", ErrType, dst, r.typ)
+	}
+
+	res := r.conn.Exec(ctx, &tarantool.Select{Space: r.space, Index: index, KeyTuple: key, Limit: limit})
+	if res.Error != nil {
+		return res.Error
+	}
+
+	out := reflect.MakeSlice(slice.Type(), 0, len(res.Data))
+	for _, tuple := range res.Data {
+		p := reflect.New(r.typ)
+		if err := r.decoder.DecodeStruct(tuple, p.Interface()); err != nil {
+			return err
+		}
+		if isPtr {
+			out = reflect.Append(out, p)
+		} else {
+			out = reflect.Append(out, p.Elem())
+		}
+	}
+	slice.Set(out)
+	return nil
+}
+
+// value returns the struct v is or points to, if it is of the repository type.
+func (r *Repository) value(v interface{}) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() || rv.Type() != r.typ {
+		return rv, fmt.Errorf("%w: %T, expected %s", ErrType, v, r.typ)
+	}
+	return rv, nil
+}
+
+// tuple returns the mapped fields of the struct in order.
+func (r *Repository) tuple(rv reflect.Value) []interface{} {
+	tuple := make([]interface{}, len(r.fields))
+	for i, f := range r.fields {
+		tuple[i] = rv.Field(f).Interface()
+	}
+	return tuple
+}
@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+type user struct {
+	ID      uint64 `tarantool:"id"`
+	Name    string `tarantool:"name"`
+	Email   string `tarantool:"email"`
+	Comment string `tarantool:"-"`
+	age     int
+}
+
+// fakeServer keeps the tuples of the users space with the primary key
+// on the first field and the non-unique email index on the third one
+type fakeServer struct {
+	sync.Mutex
+	tuples map[uint64][]interface{}
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	s.Lock()
+	defer s.Unlock()
+
+	key := func(k interface{}, tuple []interface{}) uint64 {
+		if k == nil && len(tuple) > 0 {
+			k = tuple[0]
+		}
+		id, _ := typeconv.IntfToUint64(k)
+		return id
+	}
+
+	switch q := q.(type) {
+	case *tarantool.Select:
+		switch q.Space {
+		case tarantool.ViewSpace:
+			return &tarantool.Result{Data: [][]interface{}{
+				{uint64(512), uint64(1), "users", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+			}}
+		case tarantool.ViewIndex:
+			return &tarantool.Result{Data: [][]interface{}{
+				{uint64(512), uint64(0), "primary", "tree", map[string]interface{}{"unique": true}, []interface{}{[]interface{}{uint64(0), "unsigned"}}},
+				{uint64(512), uint64(1), "email", "tree", map[string]interface{}{"unique": false}, []interface{}{[]interface{}{uint64(2), "string"}}},
+			}}
+		}
+		if q.Index == uint(1) {
+			var data [][]interface{}
+			for _, t := range s.tuples {
+				if t[2] == q.Key {
+					data = append(data, t)
+				}
+			}
+			sort.Slice(data, func(i, j int) bool { return key(nil, data[i]) < key(nil, data[j]) })
+			return &tarantool.Result{Data: data}
+		}
+		if t, ok := s.tuples[key(q.Key, q.KeyTuple)]; ok {
+			return &tarantool.Result{Data: [][]interface{}{t}}
+		}
+	case *tarantool.Insert:
+		id := key(nil, q.Tuple)
+		if _, ok := s.tuples[id]; ok {
+			return &tarantool.Result{ErrorCode: tarantool.ErrTupleFound, Error: tarantool.NewQueryError(tarantool.ErrTupleFound, "Duplicate key exists")}
+		}
+		s.tuples[id] = q.Tuple
+		return &tarantool.Result{Data: [][]interface{}{q.Tuple}}
+	case *tarantool.Replace:
+		s.tuples[key(nil, q.Tuple)] = q.Tuple
+		return &tarantool.Result{Data: [][]interface{}{q.Tuple}}
+	case *tarantool.Update:
+		t, ok := s.tuples[key(q.Key, q.KeyTuple)]
+		if !ok {
+			break
+		}
+		for _, op := range q.Set {
+			assign := op.(*tarantool.OpAssign)
+			t[assign.Field] = assign.Argument
+		}
+		return &tarantool.Result{Data: [][]interface{}{t}}
+	case *tarantool.Delete:
+		id := key(q.Key, q.KeyTuple)
+		if t, ok := s.tuples[id]; ok {
+			delete(s.tuples, id)
+			return &tarantool.Result{Data: [][]interface{}{t}}
+		}
+	}
+	return &tarantool.Result{}
+}
+
+func newFakeServer(t *testing.T, s *fakeServer) string {
+	return tnttest.NewServer(t, s.handle)
+}
+
+func TestRepository(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s := &fakeServer{tuples: map[uint64][]interface{}{}}
+	conn, err := tarantool.Connect(newFakeServer(t, s), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	_, err = New(conn, "users", 1)
+	assert.True(errors.Is(err, ErrType))
+	_, err = New(conn, "missing", user{})
+	assert.True(errors.Is(err, ErrNoPrimaryKey))
+
+	users, err := New(conn, "users", &user{})
+	require.NoError(err)
+
+	ctx := context.Background()
+
+	require.NoError(users.Insert(ctx, &user{ID: 1, Name: "alice", Email: "a@example.com", Comment: "skipped", age: 30}))
+	require.NoError(users.Insert(ctx, user{ID: 2, Name: "bob", Email: "b@example.com"}))
+	require.NoError(users.Replace(ctx, user{ID: 3, Name: "carol", Email: "a@example.com"}))
+	assert.Equal([]interface{}{int64(1), "alice", "a@example.com"}, s.tuples[1])
+
+	err = users.Insert(ctx, user{ID: 1})
+	var qe *tarantool.QueryError
+	if assert.True(errors.As(err, &qe)) {
+		assert.Equal(tarantool.ErrTupleFound, qe.Code)
+	}
+
+	var u user
+	found, err := users.GetByPK(ctx, &u, uint64(1))
+	require.NoError(err)
+	assert.True(found)
+	assert.Equal(user{ID: 1, Name: "alice", Email: "a@example.com"}, u)
+
+	found, err = users.GetByPK(ctx, &u, uint64(10))
+	assert.NoError(err)
+	assert.False(found)
+
+	found, err = users.Update(ctx, user{ID: 2, Name: "robert", Email: "b@example.com"})
+	require.NoError(err)
+	assert.True(found)
+	assert.Equal([]interface{}{int64(2), "robert", "b@example.com"}, s.tuples[2])
+
+	found, err = users.Update(ctx, user{ID: 10})
+	assert.NoError(err)
+	assert.False(found)
+
+	var list []user
+	require.NoError(users.SelectBy(ctx, &list, "email", 0, "a@example.com"))
+	assert.Equal([]user{
+		{ID: 1, Name: "alice", Email: "a@example.com"},
+		{ID: 3, Name: "carol", Email: "a@example.com"},
+	}, list)
+
+	var ptrs []*user
+	require.NoError(users.SelectBy(ctx, &ptrs, "email", 0, "b@example.com"))
+	assert.Equal([]*user{{ID: 2, Name: "robert", Email: "b@example.com"}}, ptrs)
+
+	found, err = users.Delete(ctx, uint64(1))
+	require.NoError(err)
+	assert.True(found)
+	found, err = users.Delete(ctx, uint64(1))
+	require.NoError(err)
+	assert.False(found)
+
+	// values of other types are rejected before anything is sent
+	type other struct{ ID uint64 }
+	assert.True(errors.Is(users.Insert(ctx, other{}), ErrType))
+	_, err = users.GetByPK(ctx, u, uint64(1))
+	assert.True(errors.Is(err, ErrType))
+	assert.True(errors.Is(users.SelectBy(ctx, &[]other{}, "email", 0, ""), ErrType))
+	assert.True(errors.Is(users.Insert(ctx, nil), ErrType))
+}
@@ -1,45 +1,76 @@
 package tarantool
 
-import "sync"
+import (
+	"runtime"
+	"sync"
+)
 
-const requestMapShardNum = 16
+// requestMapMinShards is the lower bound of the shard count, the actual
+// number grows with GOMAXPROCS to keep lock contention low on large machines.
+const requestMapMinShards = 16
 
 type requestMapShard struct {
 	sync.Mutex
-	data map[uint64]*request
+	data   map[uint64]*request
+	closed bool
+	// keep adjacent shards on separate cache lines
+	_ [40]byte
 }
+
+// requestMap is the table of pending requests keyed by sync (request ID).
+// Callers register requests here before handing them to the writer, and
+// the reader pops and completes them directly, so replies never pass
+// through an intermediate routing goroutine.
 type requestMap struct {
-	shard []*requestMapShard
+	shard []requestMapShard
+	mask  uint64
 }
 
 func newRequestMap() *requestMap {
-	shard := make([]*requestMapShard, requestMapShardNum)
+	return newRequestMapShards(requestMapShardNum(runtime.GOMAXPROCS(0)))
+}
 
-	for i := 0; i < requestMapShardNum; i++ {
-		shard[i] = &requestMapShard{
-			data: make(map[uint64]*request),
-		}
+// newRequestMapShards creates the map with n shards, n must be a power of two
+func newRequestMapShards(n int) *requestMap {
+	shard := make([]requestMapShard, n)
+
+	for i := range shard {
+		shard[i].data = make(map[uint64]*request)
 	}
 
 	return &requestMap{
 		shard: shard,
+		mask:  uint64(n - 1),
 	}
+}
 
+// requestMapShardNum returns the power of two not less than 4 shards per CPU
+func requestMapShardNum(procs int) int {
+	n := requestMapMinShards
+	for n < procs*4 {
+		n <<= 1
+	}
+	return n
 }
 
-// Put returns old request associated with given key
-func (m *requestMap) Put(key uint64, value *request) *request {
-	shard := m.shard[key%requestMapShardNum]
+// Put returns old request associated with given key.
+// It returns false if the map has been closed and the request is not stored.
+func (m *requestMap) Put(key uint64, value *request) (*request, bool) {
+	shard := &m.shard[key&m.mask]
 	shard.Lock()
+	if shard.closed {
+		shard.Unlock()
+		return nil, false
+	}
 	oldValue := shard.data[key]
 	shard.data[key] = value
 	shard.Unlock()
-	return oldValue
+	return oldValue, true
 }
 
 // Pop returns request associated with given key and remove it from map
 func (m *requestMap) Pop(key uint64) *request {
-	shard := m.shard[key%requestMapShardNum]
+	shard := &m.shard[key&m.mask]
 	shard.Lock()
 	value, exists := shard.data[key]
 	if exists {
@@ -49,11 +80,41 @@ func (m *requestMap) Pop(key uint64) *request {
 	return value
 }
 
+// PushFunc returns the push receiver of the pending request with given key,
+// leaving the request in the map
+func (m *requestMap) PushFunc(key uint64) func(value interface{}) {
+	shard := &m.shard[key&m.mask]
+	shard.Lock()
+	var fn func(value interface{})
+	if value := shard.data[key]; value != nil {
+		fn = value.push
+	}
+	shard.Unlock()
+	return fn
+}
+
 func (m *requestMap) CleanUp(clearCallback func(*request)) {
-	for i := 0; i < requestMapShardNum; i++ {
-		shard := m.shard[i]
+	for i := range m.shard {
+		shard := &m.shard[i]
+		shard.Lock()
+
+		for requestID, req := range shard.data {
+			delete(shard.data, requestID)
+			clearCallback(req)
+		}
+
+		shard.Unlock()
+	}
+}
+
+// Close removes all the requests like CleanUp and rejects further Puts,
+// so every request is either cleaned up here or is never stored.
+func (m *requestMap) Close(clearCallback func(*request)) {
+	for i := range m.shard {
+		shard := &m.shard[i]
 		shard.Lock()
 
+		shard.closed = true
 		for requestID, req := range shard.data {
 			delete(shard.data, requestID)
 			clearCallback(req)
@@ -0,0 +1,92 @@
+package tarantool
+
+import (
+	"runtime"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestMap(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newRequestMapShards(requestMapMinShards)
+	r1 := &request{}
+	r2 := &request{}
+
+	old, ok := m.Put(1, r1)
+	assert.True(ok)
+	assert.Nil(old)
+	old, _ = m.Put(requestMapMinShards+1, r2)
+	assert.Nil(old)
+	old, _ = m.Put(1, r1)
+	assert.Equal(r1, old)
+
+	assert.Equal(r1, m.Pop(1))
+	assert.Nil(m.Pop(1))
+
+	var cleaned []*request
+	m.CleanUp(func(req *request) {
+		cleaned = append(cleaned, req)
+	})
+	assert.Equal([]*request{r2}, cleaned)
+	assert.Nil(m.Pop(requestMapMinShards + 1))
+}
+
+func TestRequestMapClose(t *testing.T) {
+	assert := assert.New(t)
+
+	m := newRequestMap()
+	r1 := &request{}
+	m.Put(1, r1)
+
+	var cleaned []*request
+	m.Close(func(req *request) {
+		cleaned = append(cleaned, req)
+	})
+	assert.Equal([]*request{r1}, cleaned)
+
+	old, ok := m.Put(2, &request{})
+	assert.False(ok)
+	assert.Nil(old)
+	assert.Nil(m.Pop(2))
+}
+
+func TestRequestMapShardNum(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(16, requestMapShardNum(1))
+	assert.Equal(16, requestMapShardNum(4))
+	assert.Equal(32, requestMapShardNum(5))
+	assert.Equal(256, requestMapShardNum(64))
+	assert.Equal(uint64(requestMapShardNum(runtime.GOMAXPROCS(0))-1), newRequestMap().mask)
+}
+
+func TestRequestMapConcurrent(t *testing.T) {
+	const workers = 8
+	const perWorker = 1000
+
+	m := newRequestMap()
+
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(base uint64) {
+			defer wg.Done()
+			for i := uint64(0); i < perWorker; i++ {
+				req := &request{}
+				m.Put(base+i, req)
+				if m.Pop(base+i) != req {
+					t.Errorf("request %d lost", base+i)
+					return
+				}
+			}
+		}(uint64(w) * perWorker)
+	}
+	wg.Wait()
+
+	n := 0
+	m.CleanUp(func(*request) { n++ })
+	assert.Equal(t, 0, n)
+}
@@ -1,5 +1,7 @@
 package tarantool
 
+import "time"
+
 type cappedRequestPool struct {
 	queue chan *request
 	reuse bool
@@ -7,7 +9,7 @@ type cappedRequestPool struct {
 
 func newCappedRequestPool() *cappedRequestPool {
 	return &cappedRequestPool{
-		queue: make(chan *request, 1024),
+		queue: make(chan *request, poolCapacity()),
 		reuse: false,
 	}
 }
@@ -21,6 +23,10 @@ func (p *cappedRequestPool) Get() (r *request) {
 	case r = <-p.queue:
 		r.opaque = nil
 		r.replyChan = nil
+		r.expireAfter = 0
+		r.push = nil
+		r.streamID = 0
+		r.enqueuedAt = time.Time{}
 	default:
 		r = &request{}
 	}
@@ -9,6 +9,11 @@ type Result struct {
 	ErrorCode uint
 	Error     error
 	Data      [][]interface{}
+	// Metadata, SQLInfo, StmtID and BindCount are returned by Execute and Prepare
+	Metadata  []SQLColumn
+	SQLInfo   *SQLInfo
+	StmtID    uint64
+	BindCount uint64
 }
 
 func (r *Result) GetCommandID() uint {
@@ -26,7 +31,17 @@ func (r *Result) MarshalMsg(b []byte) (o []byte, err error) {
 		o = msgp.AppendUint(o, KeyError)
 		o = msgp.AppendString(o, r.Error.Error())
 	} else {
-		o = msgp.AppendMapHeader(o, 1)
+		n := uint32(1)
+		if r.Metadata != nil {
+			n++
+		}
+		if r.SQLInfo != nil {
+			n++
+		}
+		if r.StmtID != 0 {
+			n += 2
+		}
+		o = msgp.AppendMapHeader(o, n)
 		o = msgp.AppendUint(o, KeyData)
 		if r.Data != nil {
 			if o, err = msgp.AppendIntf(o, r.Data); err != nil {
@@ -35,6 +50,20 @@ func (r *Result) MarshalMsg(b []byte) (o []byte, err error) {
 		} else {
 			o = msgp.AppendArrayHeader(o, 0)
 		}
+		if r.Metadata != nil {
+			o = msgp.AppendUint(o, KeyMetadata)
+			o = appendSQLColumns(o, r.Metadata)
+		}
+		if r.SQLInfo != nil {
+			o = msgp.AppendUint(o, KeySQLInfo)
+			o = appendSQLInfo(o, r.SQLInfo)
+		}
+		if r.StmtID != 0 {
+			o = msgp.AppendUint(o, KeyStmtID)
+			o = msgp.AppendUint64(o, r.StmtID)
+			o = msgp.AppendUint(o, KeyBindCount)
+			o = msgp.AppendUint64(o, r.BindCount)
+		}
 	}
 
 	return o, nil
@@ -53,7 +82,7 @@ func (r *Result) UnmarshalMsg(data []byte) (buf []byte, err error) {
 	if len(buf) == 0 && r.ErrorCode == OKCommand {
 		return buf, nil
 	}
-	l, buf, err = msgp.ReadMapHeaderBytes(buf)
+	l, buf, err = readMapHeaderBytes(buf)
 
 	if err != nil {
 		return
@@ -70,17 +99,21 @@ func (r *Result) UnmarshalMsg(data []byte) (buf []byte, err error) {
 		case KeyData:
 			var i, j uint32
 
-			if dl, buf, err = msgp.ReadArrayHeaderBytes(buf); err != nil {
+			if dl, buf, err = readArrayHeaderBytes(buf); err != nil {
 				return
 			}
 
+			// tuple fields are sliced from a shared arena to avoid
+			// an allocation per tuple on large result sets
+			var arena []interface{}
+
 			r.Data = make([][]interface{}, dl)
 			for i = 0; i < dl; i++ {
 				obuf := buf
-				if tl, buf, err = msgp.ReadArrayHeaderBytes(buf); err != nil {
+				if tl, buf, err = readArrayHeaderBytes(buf); err != nil {
 					buf = obuf
 					if _, ok := err.(msgp.TypeError); ok {
-						if val, buf, err = msgp.ReadIntfBytes(buf); err != nil {
+						if val, buf, err = readIntfBytes(buf); err != nil {
 							return
 						}
 						r.Data[i] = []interface{}{val}
@@ -89,9 +122,13 @@ func (r *Result) UnmarshalMsg(data []byte) (buf []byte, err error) {
 					return
 				}
 
-				r.Data[i] = make([]interface{}, tl)
+				if uint32(len(arena)) < tl {
+					arena = make([]interface{}, resultArenaSize(tl, dl-i))
+				}
+				r.Data[i] = arena[:tl:tl]
+				arena = arena[tl:]
 				for j = 0; j < tl; j++ {
-					if r.Data[i][j], buf, err = msgp.ReadIntfBytes(buf); err != nil {
+					if r.Data[i][j], buf, err = readIntfBytes(buf); err != nil {
 						return
 					}
 				}
@@ -102,6 +139,22 @@ func (r *Result) UnmarshalMsg(data []byte) (buf []byte, err error) {
 				return
 			}
 			r.Error = NewQueryError(r.ErrorCode, errorMessage)
+		case KeyMetadata:
+			if r.Metadata, buf, err = readSQLColumns(buf); err != nil {
+				return
+			}
+		case KeySQLInfo:
+			if r.SQLInfo, buf, err = readSQLInfo(buf); err != nil {
+				return
+			}
+		case KeyStmtID:
+			if r.StmtID, buf, err = msgp.ReadUint64Bytes(buf); err != nil {
+				return
+			}
+		case KeyBindCount:
+			if r.BindCount, buf, err = msgp.ReadUint64Bytes(buf); err != nil {
+				return
+			}
 		default:
 			if buf, err = msgp.Skip(buf); err != nil {
 				return
@@ -111,6 +164,67 @@ func (r *Result) UnmarshalMsg(data []byte) (buf []byte, err error) {
 	return
 }
 
+// resultArenaMaxSize is the number of fields allocated at once for tuples of a result.
+const resultArenaMaxSize = 4096
+
+// resultArenaSize estimates the arena size needed for the rest of the tuples,
+// assuming they have the same field count as the current one.
+func resultArenaSize(fields, tuples uint32) uint32 {
+	if fields >= resultArenaMaxSize {
+		return fields
+	}
+	if n := uint64(fields) * uint64(tuples); n < resultArenaMaxSize {
+		return uint32(n)
+	}
+	return resultArenaMaxSize
+}
+
+// forEachTuple calls fn for every raw tuple found in the data of the reply body.
+func forEachTuple(data []byte, fn func(tuple []byte) error) (err error) {
+	var l, dl uint32
+
+	buf := data
+
+	// Tarantool >= 1.7.7 sends periodic heartbeat messages without body
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if l, buf, err = readMapHeaderBytes(buf); err != nil {
+		return
+	}
+
+	for ; l > 0; l-- {
+		var cd uint
+
+		if cd, buf, err = msgp.ReadUintBytes(buf); err != nil {
+			return
+		}
+
+		if cd != KeyData {
+			if buf, err = msgp.Skip(buf); err != nil {
+				return
+			}
+			continue
+		}
+
+		if dl, buf, err = readArrayHeaderBytes(buf); err != nil {
+			return
+		}
+
+		for ; dl > 0; dl-- {
+			tuple := buf
+			if buf, err = msgp.Skip(buf); err != nil {
+				return
+			}
+			if err = fn(tuple[:len(tuple)-len(buf)]); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
 func (r *Result) String() string {
 	switch {
 	case r == nil:
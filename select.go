@@ -62,7 +62,7 @@ func (q *Select) packMsg(data *packData, b []byte) (o []byte, err error) {
 		}
 	} else if q.KeyTuple != nil {
 		o = msgp.AppendUint(o, KeyKey)
-		if o, err = msgp.AppendIntf(o, q.KeyTuple); err != nil {
+		if o, err = appendTuple(o, q.KeyTuple); err != nil {
 			return o, err
 		}
 	} else {
@@ -122,12 +122,12 @@ func (q *Select) UnmarshalMsg(data []byte) (buf []byte, err error) {
 				return
 			}
 		case KeyKey:
-			t, buf, err = msgp.ReadIntfBytes(buf)
+			t, buf, err = readIntfBytes(buf)
 			if err != nil {
 				return buf, err
 			}
 
-			if q.KeyTuple = t.([]interface{}); q.KeyTuple == nil {
+			if q.KeyTuple, _ = t.([]interface{}); q.KeyTuple == nil {
 				return buf, errors.New("interface type is not []interface{}")
 			}
 
@@ -1,6 +1,7 @@
 package tarantool
 
 import (
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -67,7 +68,7 @@ func TestSelect(t *testing.T) {
 				if query.Index != nil {
 					switch query.Index.(type) {
 					case string:
-						assert.Equal(conn.packData.indexMap[42][query.Index.(string)], uint64(query2.Index.(uint)))
+						assert.Equal(conn.packData.schema().indexMap[42][query.Index.(string)], uint64(query2.Index.(uint)))
 					default:
 						assert.Equal(query.Index, query2.Index)
 					}
@@ -184,3 +185,35 @@ func BenchmarkSelectPack(b *testing.B) {
 		buf, _ = (&Select{Key: 3}).MarshalMsg(buf[:0])
 	}
 }
+
+func BenchmarkSelectPacket(b *testing.B) {
+	b.ReportAllocs()
+	data := newPackData(uint64(512))
+
+	for i := 0; i < b.N; i++ {
+		pp := packetPool.GetWithID(uint64(i))
+		if err := pp.packMsg(&Select{Key: int64(i)}, data); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := pp.WriteTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+		pp.Release()
+	}
+}
+
+func BenchmarkSelectNewPacket(b *testing.B) {
+	b.ReportAllocs()
+	data := newPackData(uint64(512))
+	q := &Select{Key: int64(1)}
+
+	for i := 0; i < b.N; i++ {
+		pp := &BinaryPacket{}
+		if err := pp.packMsg(q, data); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := pp.WriteTo(io.Discard); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
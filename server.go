@@ -5,6 +5,7 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -165,6 +166,68 @@ func (s *IprotoServer) greet() (err error) {
 	return s.writer.Flush()
 }
 
+// SendEvent sends the value of the watched key to the client, see Watch.
+// The handler must return nil for the Watch and Unwatch requests, as they have
+// no reply, and it is up to it to send the events in order.
+func (s *IprotoServer) SendEvent(key string, value interface{}) error {
+	pp := packetPool.GetWithID(0)
+	if err := pp.packMsg(&Event{Key: key, Value: value}, nil); err != nil {
+		pp.Release()
+		return err
+	}
+
+	select {
+	case s.output <- pp:
+		return nil
+	case <-s.ctx.Done():
+		pp.Release()
+		return s.ctx.Err()
+	}
+}
+
+type sessionKey struct{}
+
+// session is the request being handled, which the values are pushed for
+type session struct {
+	server    *IprotoServer
+	requestID uint64
+	streamID  uint64
+}
+
+// SessionStreamID returns the stream id of the request being handled,
+// 0 if it isn't executed in a stream. ctx must be the one passed to the QueryHandler.
+func SessionStreamID(ctx context.Context) uint64 {
+	if sess, ok := ctx.Value(sessionKey{}).(*session); ok {
+		return sess.streamID
+	}
+	return 0
+}
+
+// SessionPush sends the value to the client before the reply to the request
+// being handled, like box.session.push. ctx must be the one passed to the
+// QueryHandler, or derived from it.
+func SessionPush(ctx context.Context, value interface{}) error {
+	sess, ok := ctx.Value(sessionKey{}).(*session)
+	if !ok {
+		return errors.New("no request to push the value for")
+	}
+	s := sess.server
+
+	pp := packetPool.GetWithID(sess.requestID)
+	if err := pp.packMsg(&Push{Value: value}, nil); err != nil {
+		pp.Release()
+		return err
+	}
+
+	select {
+	case s.output <- pp:
+		return nil
+	case <-s.ctx.Done():
+		pp.Release()
+		return s.ctx.Err()
+	}
+}
+
 func (s *IprotoServer) loop() {
 	go s.read()
 	go s.write()
@@ -216,7 +279,13 @@ READER_LOOP:
 						break
 					}
 				} else {
-					res := s.handler(s.ctx, packet.Request)
+					ctx := context.WithValue(s.ctx, sessionKey{}, &session{s, packet.requestID, packet.StreamID})
+					res := s.handler(ctx, packet.Request)
+					if res == nil {
+						// the request has no reply, e.g. Watch
+						pp.Release()
+						return
+					}
 					if res.ErrorCode != OKCommand && res.Error == nil {
 						res.Error = ErrUnknownError
 					}
@@ -229,6 +298,7 @@ READER_LOOP:
 					}
 
 					pp.packet.SchemaID = s.schemaID
+					pp.packet.StreamID = 0
 					select {
 					case s.output <- pp:
 						return
@@ -0,0 +1,76 @@
+package tarantool
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testServerUUID = "4b2e1f36-2b9d-4c27-9f4e-9d3c1c6a6b01"
+
+// newTestServer starts IprotoServer instances on a random local port and
+// returns the address to connect to. Useful for testing the client side
+// without running tarantool.
+func newTestServer(t *testing.T, handler QueryHandler) string {
+	return newTestServerUUID(t, func() string { return testServerUUID }, handler)
+}
+
+// newTestServerUUID is newTestServer with the instance UUID
+// returned by uuid for every accepted connection.
+func newTestServerUUID(t *testing.T, uuid func() string, handler QueryHandler) string {
+	if handler == nil {
+		handler = func(context.Context, Query) *Result {
+			return &Result{}
+		}
+	}
+
+	return newTestServerFunc(t, func() *IprotoServer {
+		return NewIprotoServer(uuid(), handler, nil)
+	})
+}
+
+// newTestServerFunc is newTestServer with the server returned by newServer
+// for every accepted connection.
+func newTestServerFunc(t *testing.T, newServer func() *IprotoServer) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	serveTest(t, ln, newServer)
+	return ln.Addr().String()
+}
+
+// serveTest accepts the connections of ln with the servers returned by newServer
+// until the test is done.
+func serveTest(t *testing.T, ln net.Listener, newServer func() *IprotoServer) {
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			newServer().Accept(c)
+		}
+	}()
+}
+
+func TestIprotoServer(t *testing.T) {
+	require := require.New(t)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if eval, ok := q.(*Eval); ok {
+			return &Result{Data: [][]interface{}{{eval.Expression}}}
+		}
+		return &Result{}
+	})
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	data, err := conn.Execute(&Eval{Expression: "return 1"})
+	require.NoError(err)
+	require.Equal([][]interface{}{{"return 1"}}, data)
+}
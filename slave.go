@@ -416,9 +416,9 @@ func (s *Slave) nextFinalData() (p *Packet, err error) {
 			// assert space _schema always has str index on field one
 			// and in "cluster" tuple uuid is string too
 			// {"cluster", "ea74fc91-54fe-4f64-adae-ad2bc3eb4194"}
-			key := q.Tuple[0].(string)
+			key := tupleString(q.Tuple, 0)
 			if key == SchemaKeyClusterUUID {
-				s.ReplicaSet.UUID = q.Tuple[1].(string)
+				s.ReplicaSet.UUID = tupleString(q.Tuple, 1)
 			}
 		case SpaceCluster:
 			// fill in Replica Set from _cluster space; format:
@@ -427,10 +427,13 @@ func (s *Slave) nextFinalData() (p *Packet, err error) {
 
 			// in reality _cluster key field is decoded to uint64
 			// but we know exactly that it can be cast to uint32 without losing data
+			if len(q.Tuple) == 0 {
+				break
+			}
 			instanceIDu64, _ := typeconv.IntfToUint64(q.Tuple[0])
 			instanceID, _ := typeconv.IntfToUint32(instanceIDu64)
 			// uuid
-			s.ReplicaSet.SetInstance(instanceID, q.Tuple[1].(string))
+			s.ReplicaSet.SetInstance(instanceID, tupleString(q.Tuple, 1))
 		}
 	case OKCommand:
 		// Current vclock. This is not used now, ignore.
@@ -501,9 +504,9 @@ func (s *Slave) nextSnap() (p *Packet, err error) {
 			// assert space _schema always has str index on field one
 			// and in "cluster" tuple uuid is string too
 			// {"cluster", "ea74fc91-54fe-4f64-adae-ad2bc3eb4194"}
-			key := q.Tuple[0].(string)
+			key := tupleString(q.Tuple, 0)
 			if key == SchemaKeyClusterUUID {
-				s.ReplicaSet.UUID = q.Tuple[1].(string)
+				s.ReplicaSet.UUID = tupleString(q.Tuple, 1)
 			}
 		case SpaceCluster:
 			// fill in Replica Set from _cluster space; format:
@@ -512,10 +515,13 @@ func (s *Slave) nextSnap() (p *Packet, err error) {
 
 			// in reality _cluster key field is decoded to int64
 			// but we know exactly that it can be casted to uint32 without data loss
+			if len(q.Tuple) == 0 {
+				break
+			}
 			instanceIDu64, _ := typeconv.IntfToUint64(q.Tuple[0])
 			instanceID, _ := typeconv.IntfToUint32(instanceIDu64)
 			// uuid
-			s.ReplicaSet.SetInstance(instanceID, q.Tuple[1].(string))
+			s.ReplicaSet.SetInstance(instanceID, tupleString(q.Tuple, 1))
 		}
 	case OKCommand:
 		v := new(VClock)
@@ -595,7 +601,7 @@ func (s *Slave) connect(uri string, options Options) (err error) {
 	if err != nil {
 		return
 	}
-	conn, err := newConn(context.Background(), dsn.Scheme, dsn.Host, opts)
+	conn, err := newConn(context.Background(), dsn.Scheme, dsn.Host, opts, false)
 	if err != nil {
 		return
 	}
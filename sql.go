@@ -0,0 +1,256 @@
+package tarantool
+
+import (
+	"errors"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// the keys of the SQL info and the column metadata of the SQL replies
+const (
+	SQLInfoRowCount         = uint(0x00)
+	SQLInfoAutoincrementIDs = uint(0x01)
+
+	FieldName            = uint(0x00)
+	FieldType            = uint(0x01)
+	FieldColl            = uint(0x02)
+	FieldIsNullable      = uint(0x03)
+	FieldIsAutoincrement = uint(0x04)
+	FieldSpan            = uint(0x05)
+)
+
+// Execute query executes the SQL statement, Tarantool >= 2.1.0.
+// The rows of the statement are returned in Result.Data, their columns in
+// Result.Metadata, and the changes made by it in Result.SQLInfo.
+type Execute struct {
+	// SQL is the text of the statement, it is executed if StmtID is 0.
+	SQL string
+	// StmtID is the id of the statement prepared in the session by Prepare.
+	StmtID uint64
+	// Bind are the values of the parameters, the named ones are the maps of
+	// the name with the colon to the value, e.g. {":id": 1}.
+	Bind []interface{}
+}
+
+var _ Query = (*Execute)(nil)
+
+func (q *Execute) GetCommandID() uint {
+	return ExecuteCommand
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (q *Execute) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.AppendMapHeader(b, 3)
+	o = appendSQLStatement(o, q.SQL, q.StmtID)
+
+	o = msgp.AppendUint(o, KeySQLBind)
+	if q.Bind == nil {
+		o = msgp.AppendArrayHeader(o, 0)
+	} else if o, err = msgp.AppendIntf(o, q.Bind); err != nil {
+		return o, err
+	}
+
+	// the options are reserved by the protocol
+	o = msgp.AppendUint(o, KeyOptions)
+	o = msgp.AppendArrayHeader(o, 0)
+	return o, nil
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (q *Execute) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	var i uint32
+	var k uint
+	var t interface{}
+
+	*q = Execute{}
+
+	buf = data
+	if i, buf, err = readMapHeaderBytes(buf); err != nil {
+		return
+	}
+
+	for ; i > 0; i-- {
+		if k, buf, err = msgp.ReadUintBytes(buf); err != nil {
+			return
+		}
+
+		switch k {
+		case KeySQLText:
+			if q.SQL, buf, err = msgp.ReadStringBytes(buf); err != nil {
+				return
+			}
+		case KeyStmtID:
+			if q.StmtID, buf, err = msgp.ReadUint64Bytes(buf); err != nil {
+				return
+			}
+		case KeySQLBind:
+			if t, buf, err = readIntfBytes(buf); err != nil {
+				return
+			}
+			if q.Bind, _ = t.([]interface{}); q.Bind == nil {
+				return buf, errors.New("interface type is not []interface{}")
+			}
+			if len(q.Bind) == 0 {
+				q.Bind = nil
+			}
+		default:
+			if buf, err = msgp.Skip(buf); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// Prepare query prepares the SQL statement in the session, Tarantool >= 2.3.1.
+// The id of the statement is returned in Result.StmtID, the number of its
+// parameters in Result.BindCount and its columns in Result.Metadata. The
+// same text is prepared once in a session.
+// With StmtID instead of SQL, the statement is unprepared.
+type Prepare struct {
+	SQL    string
+	StmtID uint64
+}
+
+var _ Query = (*Prepare)(nil)
+
+func (q *Prepare) GetCommandID() uint {
+	return PrepareCommand
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (q *Prepare) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.AppendMapHeader(b, 1)
+	return appendSQLStatement(o, q.SQL, q.StmtID), nil
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (q *Prepare) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	var e Execute
+	buf, err = e.UnmarshalMsg(data)
+	q.SQL, q.StmtID = e.SQL, e.StmtID
+	return
+}
+
+// appendSQLStatement appends the statement id if it is set, and the SQL text otherwise
+func appendSQLStatement(o []byte, sql string, stmtID uint64) []byte {
+	if stmtID != 0 {
+		o = msgp.AppendUint(o, KeyStmtID)
+		return msgp.AppendUint64(o, stmtID)
+	}
+	o = msgp.AppendUint(o, KeySQLText)
+	return msgp.AppendString(o, sql)
+}
+
+// SQLColumn is the metadata of a column of the SQL statement.
+type SQLColumn struct {
+	Name string
+	// Type is the name of the type in the lower case, e.g. integer or string.
+	Type string
+}
+
+// SQLInfo is the information on the changes made by the SQL statement.
+type SQLInfo struct {
+	RowCount uint64
+	// AutoincrementIDs are the ids generated by the inserts, in their order.
+	AutoincrementIDs []int64
+}
+
+func appendSQLColumns(o []byte, columns []SQLColumn) []byte {
+	o = msgp.AppendArrayHeader(o, uint32(len(columns)))
+	for _, c := range columns {
+		o = msgp.AppendMapHeader(o, 2)
+		o = msgp.AppendUint(o, FieldName)
+		o = msgp.AppendString(o, c.Name)
+		o = msgp.AppendUint(o, FieldType)
+		o = msgp.AppendString(o, c.Type)
+	}
+	return o
+}
+
+func readSQLColumns(buf []byte) (columns []SQLColumn, o []byte, err error) {
+	var n, fields uint32
+	var k uint
+
+	o = buf
+	if n, o, err = readArrayHeaderBytes(o); err != nil {
+		return
+	}
+	columns = make([]SQLColumn, n)
+	for i := range columns {
+		if fields, o, err = readMapHeaderBytes(o); err != nil {
+			return
+		}
+		for ; fields > 0; fields-- {
+			if k, o, err = msgp.ReadUintBytes(o); err != nil {
+				return
+			}
+			switch k {
+			case FieldName:
+				columns[i].Name, o, err = msgp.ReadStringBytes(o)
+			case FieldType:
+				columns[i].Type, o, err = msgp.ReadStringBytes(o)
+			default:
+				o, err = msgp.Skip(o)
+			}
+			if err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+func appendSQLInfo(o []byte, info *SQLInfo) []byte {
+	n := uint32(1)
+	if len(info.AutoincrementIDs) > 0 {
+		n++
+	}
+	o = msgp.AppendMapHeader(o, n)
+	o = msgp.AppendUint(o, SQLInfoRowCount)
+	o = msgp.AppendUint64(o, info.RowCount)
+	if len(info.AutoincrementIDs) > 0 {
+		o = msgp.AppendUint(o, SQLInfoAutoincrementIDs)
+		o = msgp.AppendArrayHeader(o, uint32(len(info.AutoincrementIDs)))
+		for _, id := range info.AutoincrementIDs {
+			o = msgp.AppendInt64(o, id)
+		}
+	}
+	return o
+}
+
+func readSQLInfo(buf []byte) (info *SQLInfo, o []byte, err error) {
+	var n, ids uint32
+	var k uint
+
+	info = &SQLInfo{}
+	o = buf
+	if n, o, err = readMapHeaderBytes(o); err != nil {
+		return
+	}
+	for ; n > 0; n-- {
+		if k, o, err = msgp.ReadUintBytes(o); err != nil {
+			return
+		}
+		switch k {
+		case SQLInfoRowCount:
+			info.RowCount, o, err = msgp.ReadUint64Bytes(o)
+		case SQLInfoAutoincrementIDs:
+			if ids, o, err = readArrayHeaderBytes(o); err != nil {
+				return
+			}
+			info.AutoincrementIDs = make([]int64, ids)
+			for i := range info.AutoincrementIDs {
+				if info.AutoincrementIDs[i], o, err = msgp.ReadInt64Bytes(o); err != nil {
+					return
+				}
+			}
+		default:
+			o, err = msgp.Skip(o)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
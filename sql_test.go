@@ -0,0 +1,89 @@
+package tarantool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecutePackUnpack(t *testing.T) {
+	for _, q := range []*Execute{
+		{SQL: "SELECT ?", Bind: []interface{}{"a", map[string]interface{}{":b": "c"}}},
+		{StmtID: 42},
+	} {
+		buf, err := q.MarshalMsg(nil)
+		require.NoError(t, err)
+
+		qa := &Execute{}
+		_, err = qa.UnmarshalMsg(buf)
+		require.NoError(t, err)
+		assert.Equal(t, q, qa)
+	}
+
+	q := &Prepare{StmtID: 42}
+	buf, err := q.MarshalMsg(nil)
+	require.NoError(t, err)
+	qa := &Prepare{}
+	_, err = qa.UnmarshalMsg(buf)
+	require.NoError(t, err)
+	assert.Equal(t, q, qa)
+}
+
+func TestSQLResultPackUnpack(t *testing.T) {
+	for _, r := range []*Result{
+		{
+			Data:     [][]interface{}{{int64(1), "a"}},
+			Metadata: []SQLColumn{{Name: "id", Type: "integer"}, {Name: "name", Type: "string"}},
+		},
+		{Data: [][]interface{}{}, SQLInfo: &SQLInfo{RowCount: 2, AutoincrementIDs: []int64{5, 6}}},
+		{Data: [][]interface{}{}, Metadata: []SQLColumn{}, StmtID: 42, BindCount: 1},
+	} {
+		buf, err := r.MarshalMsg(nil)
+		require.NoError(t, err)
+
+		ra := &Result{}
+		_, err = ra.UnmarshalMsg(buf)
+		require.NoError(t, err)
+		assert.Equal(t, r, ra)
+	}
+}
+
+func TestSQLExecute(t *testing.T) {
+	require := require.New(t)
+	assert := assert.New(t)
+
+	config := `
+	box.execute([[CREATE TABLE t (id INTEGER PRIMARY KEY AUTOINCREMENT, name STRING)]])
+	box.schema.user.grant('guest', 'read,write', 'space', 'T')
+	box.schema.user.grant('guest', 'read', 'space', '_sequence')
+	box.schema.user.grant('guest', 'read,write', 'sequence', 'T')
+	`
+	box, err := NewBox(config, &BoxOptions{})
+	require.NoError(err)
+	defer box.Close()
+
+	tnt, err := Connect(box.Listen, &Options{})
+	require.NoError(err)
+	defer tnt.Close()
+	ctx := context.Background()
+
+	res := tnt.Exec(ctx, &Execute{SQL: "INSERT INTO t (name) VALUES (?), (?)", Bind: []interface{}{"a", "b"}})
+	require.NoError(res.Error)
+	require.NotNil(res.SQLInfo)
+	assert.Equal(uint64(2), res.SQLInfo.RowCount)
+	assert.Equal([]int64{1, 2}, res.SQLInfo.AutoincrementIDs)
+
+	prep := tnt.Exec(ctx, &Prepare{SQL: "SELECT id, name FROM t WHERE name = :name"})
+	require.NoError(prep.Error)
+	assert.NotZero(prep.StmtID)
+	assert.Equal(uint64(1), prep.BindCount)
+
+	res = tnt.Exec(ctx, &Execute{StmtID: prep.StmtID, Bind: []interface{}{map[string]interface{}{":name": "b"}}})
+	require.NoError(res.Error)
+	assert.Equal([]SQLColumn{{Name: "ID", Type: "integer"}, {Name: "NAME", Type: "string"}}, res.Metadata)
+	assert.Equal([][]interface{}{{int64(2), "b"}}, res.Data)
+
+	require.NoError(tnt.Exec(ctx, &Prepare{StmtID: prep.StmtID}).Error)
+}
@@ -0,0 +1,506 @@
+// Package sqlbuilder builds the SQL statements of the Tarantool dialect with
+// the bind arguments kept apart from the text.
+//
+// Tarantool folds the unquoted identifiers to upper case, while the spaces
+// created from Lua usually have lower case names, so the builder always
+// double-quotes the table and column names and they are taken verbatim:
+//
+//	q, args, err := sqlbuilder.Select("id", "name").
+//		From("users").
+//		Where(sqlbuilder.Eq("status", "active"), sqlbuilder.Gt("age", 18)).
+//		OrderByDesc("id").
+//		Limit(10).
+//		Build()
+//	// SELECT "id", "name" FROM "users" WHERE "status" = ? AND "age" > ? ORDER BY "id" DESC LIMIT 10
+//	// [active 18]
+package sqlbuilder
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrNoTable is returned by Build if the table name is not set.
+	ErrNoTable = errors.New("table is not set")
+	// ErrEmptyIdent is returned by Build if a table or column name is empty.
+	ErrEmptyIdent = errors.New("empty identifier")
+	// ErrValues is returned by Build if an insert has no rows or the number
+	// of values in a row differs from the number of columns or the other rows.
+	ErrValues = errors.New("mismatching number of values")
+	// ErrNoSet is returned by Build if an update assigns nothing.
+	ErrNoSet = errors.New("no columns to set")
+	// ErrOffset is returned by Build if Offset is set without Limit,
+	// which Tarantool does not support.
+	ErrOffset = errors.New("offset without limit")
+)
+
+// Builder is implemented by the statement builders of the package.
+type Builder interface {
+	// Build returns the statement text with ? placeholders and the arguments
+	// to bind to them in order.
+	Build() (string, []interface{}, error)
+}
+
+// Ident returns the name double-quoted, with the inner quotes doubled.
+func Ident(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// Expr is a piece of SQL with the arguments for its placeholders. Exprs are
+// used as conditions and can be passed instead of values to insert or set.
+type Expr struct {
+	sql  string
+	args []interface{}
+	err  error
+}
+
+// Raw returns the SQL text as is, with the arguments for its ? placeholders.
+// Identifiers in it must be quoted by the caller, see Ident.
+func Raw(sql string, args ...interface{}) Expr {
+	return Expr{sql: sql, args: args}
+}
+
+// String returns the SQL text of the expression.
+func (e Expr) String() string {
+	return e.sql
+}
+
+// Args returns the arguments of the expression placeholders.
+func (e Expr) Args() []interface{} {
+	return e.args
+}
+
+func compare(column, op string, value interface{}) Expr {
+	var w writer
+	w.ident(column)
+	w.WriteString(" " + op + " ")
+	w.value(value)
+	return w.expr()
+}
+
+// Eq is column = value, or column IS NULL if value is nil.
+func Eq(column string, value interface{}) Expr {
+	if value == nil {
+		return IsNull(column)
+	}
+	return compare(column, "=", value)
+}
+
+// Ne is column <> value, or column IS NOT NULL if value is nil.
+func Ne(column string, value interface{}) Expr {
+	if value == nil {
+		return IsNotNull(column)
+	}
+	return compare(column, "<>", value)
+}
+
+// Lt is column < value.
+func Lt(column string, value interface{}) Expr {
+	return compare(column, "<", value)
+}
+
+// Le is column <= value.
+func Le(column string, value interface{}) Expr {
+	return compare(column, "<=", value)
+}
+
+// Gt is column > value.
+func Gt(column string, value interface{}) Expr {
+	return compare(column, ">", value)
+}
+
+// Ge is column >= value.
+func Ge(column string, value interface{}) Expr {
+	return compare(column, ">=", value)
+}
+
+// Like is column LIKE pattern.
+func Like(column string, pattern interface{}) Expr {
+	return compare(column, "LIKE", pattern)
+}
+
+// IsNull is column IS NULL.
+func IsNull(column string) Expr {
+	var w writer
+	w.ident(column)
+	w.WriteString(" IS NULL")
+	return w.expr()
+}
+
+// IsNotNull is column IS NOT NULL.
+func IsNotNull(column string) Expr {
+	var w writer
+	w.ident(column)
+	w.WriteString(" IS NOT NULL")
+	return w.expr()
+}
+
+// In is column IN (values...), which is always false if there are no values.
+func In(column string, values ...interface{}) Expr {
+	if len(values) == 0 {
+		return Raw("FALSE")
+	}
+	var w writer
+	w.ident(column)
+	w.WriteString(" IN (")
+	w.values(values)
+	w.WriteString(")")
+	return w.expr()
+}
+
+func join(op string, conds []Expr) Expr {
+	if len(conds) == 1 {
+		return conds[0]
+	}
+	var w writer
+	w.WriteString("(")
+	for i, c := range conds {
+		if i > 0 {
+			w.WriteString(" " + op + " ")
+		}
+		w.value(c)
+	}
+	w.WriteString(")")
+	return w.expr()
+}
+
+// And is true if all the conditions are, or if there are none.
+func And(conds ...Expr) Expr {
+	if len(conds) == 0 {
+		return Raw("TRUE")
+	}
+	return join("AND", conds)
+}
+
+// Or is true if any of the conditions is, it is false if there are none.
+func Or(conds ...Expr) Expr {
+	if len(conds) == 0 {
+		return Raw("FALSE")
+	}
+	return join("OR", conds)
+}
+
+// Not negates the condition.
+func Not(cond Expr) Expr {
+	var w writer
+	w.WriteString("NOT (")
+	w.value(cond)
+	w.WriteString(")")
+	return w.expr()
+}
+
+// writer accumulates the statement text, its arguments and the first error
+type writer struct {
+	strings.Builder
+	args []interface{}
+	err  error
+}
+
+func (w *writer) ident(name string) {
+	if name == "" && w.err == nil {
+		w.err = ErrEmptyIdent
+	}
+	w.WriteString(Ident(name))
+}
+
+func (w *writer) idents(names []string) {
+	for i, name := range names {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.ident(name)
+	}
+}
+
+// value writes a placeholder for the value, or the expression itself
+func (w *writer) value(v interface{}) {
+	if e, ok := v.(Expr); ok {
+		if e.err != nil && w.err == nil {
+			w.err = e.err
+		}
+		w.WriteString(e.sql)
+		w.args = append(w.args, e.args...)
+		return
+	}
+	w.WriteString("?")
+	w.args = append(w.args, v)
+}
+
+func (w *writer) values(values []interface{}) {
+	for i, v := range values {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.value(v)
+	}
+}
+
+func (w *writer) table(name string) {
+	if name == "" {
+		if w.err == nil {
+			w.err = ErrNoTable
+		}
+		return
+	}
+	w.ident(name)
+}
+
+func (w *writer) where(conds []Expr) {
+	if len(conds) > 0 {
+		w.WriteString(" WHERE ")
+		// the top level conjunction needs no parentheses
+		for i, c := range conds {
+			if i > 0 {
+				w.WriteString(" AND ")
+			}
+			w.value(c)
+		}
+	}
+}
+
+func (w *writer) expr() Expr {
+	return Expr{sql: w.String(), args: w.args, err: w.err}
+}
+
+func (w *writer) build() (string, []interface{}, error) {
+	if w.err != nil {
+		return "", nil, w.err
+	}
+	return w.String(), w.args, nil
+}
+
+// SelectBuilder builds a SELECT statement.
+type SelectBuilder struct {
+	columns []string
+	table   string
+	where   []Expr
+	orderBy []string
+	desc    []bool
+	limit   int64
+	offset  int64
+}
+
+var _ Builder = (*SelectBuilder)(nil)
+
+// Select starts a SELECT of the columns, all of them if none are given.
+func Select(columns ...string) *SelectBuilder {
+	return &SelectBuilder{columns: columns, limit: -1, offset: -1}
+}
+
+// From sets the table to select from.
+func (b *SelectBuilder) From(table string) *SelectBuilder {
+	b.table = table
+	return b
+}
+
+// Where adds the conditions, all of them must be true for a row to be selected.
+func (b *SelectBuilder) Where(conds ...Expr) *SelectBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// OrderBy adds the column to sort the rows by in ascending order.
+func (b *SelectBuilder) OrderBy(column string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, column)
+	b.desc = append(b.desc, false)
+	return b
+}
+
+// OrderByDesc adds the column to sort the rows by in descending order.
+func (b *SelectBuilder) OrderByDesc(column string) *SelectBuilder {
+	b.orderBy = append(b.orderBy, column)
+	b.desc = append(b.desc, true)
+	return b
+}
+
+// Limit sets the maximum number of rows to return.
+func (b *SelectBuilder) Limit(n uint32) *SelectBuilder {
+	b.limit = int64(n)
+	return b
+}
+
+// Offset sets the number of rows to skip, it requires Limit to be set.
+func (b *SelectBuilder) Offset(n uint32) *SelectBuilder {
+	b.offset = int64(n)
+	return b
+}
+
+// Build implements Builder.
+func (b *SelectBuilder) Build() (string, []interface{}, error) {
+	var w writer
+	w.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		w.WriteString("*")
+	} else {
+		w.idents(b.columns)
+	}
+	w.WriteString(" FROM ")
+	w.table(b.table)
+	w.where(b.where)
+	for i, column := range b.orderBy {
+		if i == 0 {
+			w.WriteString(" ORDER BY ")
+		} else {
+			w.WriteString(", ")
+		}
+		w.ident(column)
+		if b.desc[i] {
+			w.WriteString(" DESC")
+		}
+	}
+	if b.limit >= 0 {
+		w.WriteString(" LIMIT " + strconv.FormatInt(b.limit, 10))
+	}
+	if b.offset >= 0 {
+		if b.limit < 0 && w.err == nil {
+			w.err = ErrOffset
+		}
+		w.WriteString(" OFFSET " + strconv.FormatInt(b.offset, 10))
+	}
+	return w.build()
+}
+
+// InsertBuilder builds an INSERT or REPLACE statement.
+type InsertBuilder struct {
+	verb    string
+	table   string
+	columns []string
+	rows    [][]interface{}
+}
+
+var _ Builder = (*InsertBuilder)(nil)
+
+// Insert starts an INSERT into the table.
+func Insert(table string) *InsertBuilder {
+	return &InsertBuilder{verb: "INSERT", table: table}
+}
+
+// Replace starts a REPLACE into the table, which overwrites the rows
+// with the same primary key.
+func Replace(table string) *InsertBuilder {
+	return &InsertBuilder{verb: "REPLACE", table: table}
+}
+
+// Columns sets the columns the values are given for, all the columns of the
+// table in order of definition are expected if it isn't called.
+func (b *InsertBuilder) Columns(columns ...string) *InsertBuilder {
+	b.columns = columns
+	return b
+}
+
+// Values adds a row.
+func (b *InsertBuilder) Values(values ...interface{}) *InsertBuilder {
+	b.rows = append(b.rows, values)
+	return b
+}
+
+// Build implements Builder.
+func (b *InsertBuilder) Build() (string, []interface{}, error) {
+	var w writer
+	w.WriteString(b.verb + " INTO ")
+	w.table(b.table)
+	if len(b.columns) > 0 {
+		w.WriteString(" (")
+		w.idents(b.columns)
+		w.WriteString(")")
+	}
+	if len(b.rows) == 0 && w.err == nil {
+		w.err = fmt.Errorf("%w: no rows", ErrValues)
+	}
+	w.WriteString(" VALUES ")
+	for i, row := range b.rows {
+		if w.err == nil && (len(row) == 0 || len(b.columns) > 0 && len(row) != len(b.columns) || len(row) != len(b.rows[0])) {
+			w.err = fmt.Errorf("%w: row %d has %d values", ErrValues, i, len(row))
+		}
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.WriteString("(")
+		w.values(row)
+		w.WriteString(")")
+	}
+	return w.build()
+}
+
+// UpdateBuilder builds an UPDATE statement.
+type UpdateBuilder struct {
+	table   string
+	columns []string
+	values  []interface{}
+	where   []Expr
+}
+
+var _ Builder = (*UpdateBuilder)(nil)
+
+// Update starts an UPDATE of the table.
+func Update(table string) *UpdateBuilder {
+	return &UpdateBuilder{table: table}
+}
+
+// Set assigns the value to the column, the value may be an Expr,
+// e.g. Raw(`"counter" + ?`, 1).
+func (b *UpdateBuilder) Set(column string, value interface{}) *UpdateBuilder {
+	b.columns = append(b.columns, column)
+	b.values = append(b.values, value)
+	return b
+}
+
+// Where adds the conditions, all of them must be true for a row to be updated.
+// All the rows are updated if there are none.
+func (b *UpdateBuilder) Where(conds ...Expr) *UpdateBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// Build implements Builder.
+func (b *UpdateBuilder) Build() (string, []interface{}, error) {
+	var w writer
+	w.WriteString("UPDATE ")
+	w.table(b.table)
+	if len(b.columns) == 0 && w.err == nil {
+		w.err = ErrNoSet
+	}
+	w.WriteString(" SET ")
+	for i, column := range b.columns {
+		if i > 0 {
+			w.WriteString(", ")
+		}
+		w.ident(column)
+		w.WriteString(" = ")
+		w.value(b.values[i])
+	}
+	w.where(b.where)
+	return w.build()
+}
+
+// DeleteBuilder builds a DELETE statement.
+type DeleteBuilder struct {
+	table string
+	where []Expr
+}
+
+var _ Builder = (*DeleteBuilder)(nil)
+
+// Delete starts a DELETE from the table.
+func Delete(table string) *DeleteBuilder {
+	return &DeleteBuilder{table: table}
+}
+
+// Where adds the conditions, all of them must be true for a row to be deleted.
+// All the rows are deleted if there are none.
+func (b *DeleteBuilder) Where(conds ...Expr) *DeleteBuilder {
+	b.where = append(b.where, conds...)
+	return b
+}
+
+// Build implements Builder.
+func (b *DeleteBuilder) Build() (string, []interface{}, error) {
+	var w writer
+	w.WriteString("DELETE FROM ")
+	w.table(b.table)
+	w.where(b.where)
+	return w.build()
+}
@@ -0,0 +1,106 @@
+package sqlbuilder
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdent(t *testing.T) {
+	assert.Equal(t, `"users"`, Ident("users"))
+	assert.Equal(t, `"a ""b"" c"`, Ident(`a "b" c`))
+}
+
+func TestBuild(t *testing.T) {
+	tests := []struct {
+		name string
+		b    Builder
+		sql  string
+		args []interface{}
+	}{
+		{
+			"select all",
+			Select().From("users"),
+			`SELECT * FROM "users"`,
+			nil,
+		},
+		{
+			"select",
+			Select("id", "name").From("users").
+				Where(Eq("status", "active"), Gt("age", 18)).
+				OrderByDesc("id").OrderBy("name").
+				Limit(10).Offset(20),
+			`SELECT "id", "name" FROM "users" WHERE "status" = ? AND "age" > ? ORDER BY "id" DESC, "name" LIMIT 10 OFFSET 20`,
+			[]interface{}{"active", 18},
+		},
+		{
+			"conditions",
+			Select("id").From("t").Where(
+				Or(Eq("a", nil), Ne("b", nil), Ne("c", 1)),
+				Not(And(Lt("d", 1), Le("e", 2), Ge("f", 3))),
+				In("g", 1, 2, 3),
+				In("h"),
+				Like("i", "x%"),
+				Raw(`"j" + ? = ?`, 1, 2),
+			),
+			`SELECT "id" FROM "t" WHERE ("a" IS NULL OR "b" IS NOT NULL OR "c" <> ?) AND NOT (("d" < ? AND "e" <= ? AND "f" >= ?)) AND "g" IN (?, ?, ?) AND FALSE AND "i" LIKE ? AND "j" + ? = ?`,
+			[]interface{}{1, 1, 2, 3, 1, 2, 3, "x%", 1, 2},
+		},
+		{
+			"insert",
+			Insert("users").Columns("id", "name").Values(1, "alice").Values(2, nil),
+			`INSERT INTO "users" ("id", "name") VALUES (?, ?), (?, ?)`,
+			[]interface{}{1, "alice", 2, nil},
+		},
+		{
+			"replace",
+			Replace("users").Values(1, Raw("UPPER(?)", "alice")),
+			`REPLACE INTO "users" VALUES (?, UPPER(?))`,
+			[]interface{}{1, "alice"},
+		},
+		{
+			"update",
+			Update("users").Set("name", "bob").Set("visits", Raw(`"visits" + ?`, 1)).Where(Eq("id", 2)),
+			`UPDATE "users" SET "name" = ?, "visits" = "visits" + ? WHERE "id" = ?`,
+			[]interface{}{"bob", 1, 2},
+		},
+		{
+			"delete",
+			Delete("users").Where(In("id", 1, 2)),
+			`DELETE FROM "users" WHERE "id" IN (?, ?)`,
+			[]interface{}{1, 2},
+		},
+	}
+
+	for _, tc := range tests {
+		sql, args, err := tc.b.Build()
+		if assert.NoError(t, err, tc.name) {
+			assert.Equal(t, tc.sql, sql, tc.name)
+			assert.Equal(t, tc.args, args, tc.name)
+		}
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	tests := []struct {
+		b   Builder
+		err error
+	}{
+		{Select(), ErrNoTable},
+		{Select("").From("t"), ErrEmptyIdent},
+		{Select().From("t").Where(Eq("", 1)), ErrEmptyIdent},
+		{Select().From("t").Offset(1), ErrOffset},
+		{Insert("t"), ErrValues},
+		{Insert("t").Values(), ErrValues},
+		{Insert("t").Columns("a", "b").Values(1), ErrValues},
+		{Insert("t").Values(1, 2).Values(1), ErrValues},
+		{Update("t").Where(Eq("id", 1)), ErrNoSet},
+		{Delete(""), ErrNoTable},
+	}
+
+	for _, tc := range tests {
+		_, _, err := tc.b.Build()
+		assert.True(t, errors.Is(err, tc.err), "%#v: %v", tc.b, err)
+	}
+}
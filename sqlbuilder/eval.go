@@ -0,0 +1,33 @@
+package sqlbuilder
+
+import (
+	"github.com/viciious/go-tarantool"
+)
+
+// luaExecute runs the statement, the rows are returned as the result tuples
+const luaExecute = `
+local sql, args = ...
+local res, err = box.execute(sql, args)
+if res == nil then
+    error(err)
+end
+if res.rows ~= nil then
+    return unpack(res.rows)
+end
+return res.row_count
+`
+
+// Eval returns the query executing the built statement with box.execute, as
+// the SQL request is not supported by the package. The result Data are the
+// selected rows, or a single tuple with the number of affected rows for other
+// statements. The user needs the execute privilege on the universe.
+func Eval(b Builder) (*tarantool.Eval, error) {
+	sql, args, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	if args == nil {
+		args = []interface{}{}
+	}
+	return &tarantool.Eval{Expression: luaExecute, Tuple: []interface{}{sql, args}}, nil
+}
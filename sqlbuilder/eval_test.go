@@ -0,0 +1,22 @@
+package sqlbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEval(t *testing.T) {
+	q, err := Eval(Delete("users"))
+	require.NoError(t, err)
+	assert.Equal(t, luaExecute, q.Expression)
+	assert.Equal(t, []interface{}{`DELETE FROM "users"`, []interface{}{}}, q.Tuple)
+
+	q, err = Eval(Select("id").From("users").Where(Eq("id", 1)))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{`SELECT "id" FROM "users" WHERE "id" = ?`, []interface{}{1}}, q.Tuple)
+
+	_, err = Eval(Select())
+	assert.Equal(t, ErrNoTable, err)
+}
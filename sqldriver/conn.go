@@ -0,0 +1,282 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+)
+
+var (
+	// ErrTxActive is returned by BeginTx if the connection already has a transaction.
+	ErrTxActive = errors.New("transaction is already started")
+	// ErrIsolation is returned by BeginTx for the isolation levels Tarantool doesn't have.
+	ErrIsolation = errors.New("isolation level is not supported")
+	// ErrReadOnly is returned by BeginTx for the read-only transactions.
+	ErrReadOnly = errors.New("read-only transactions are not supported")
+)
+
+// conn is used by database/sql from a single goroutine at a time
+type conn struct {
+	conn *tarantool.Connection
+	tx   *tarantool.Tx
+	// stmts are the numbers of the open statements by the prepared statement
+	// ids, the same SQL text is prepared once in a session
+	stmts map[uint64]int
+}
+
+var (
+	_ driver.Conn               = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+// exec executes the query in the transaction if there is one
+func (c *conn) exec(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	if c.tx != nil {
+		return c.tx.Exec(ctx, q)
+	}
+	return c.conn.Exec(ctx, q)
+}
+
+// execute executes the query, which is the SQL text or the id of the prepared statement
+func (c *conn) execute(ctx context.Context, q *tarantool.Execute, args []driver.NamedValue) (*tarantool.Result, error) {
+	q.Bind = bindArgs(args)
+	res := c.exec(ctx, q)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return res, nil
+}
+
+// Prepare implements driver.Conn
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext implements driver.ConnPrepareContext
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	res := c.conn.Exec(ctx, &tarantool.Prepare{SQL: query})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if res.StmtID == 0 {
+		return nil, errBadResponse
+	}
+
+	s := &stmt{conn: c, id: res.StmtID, params: int(res.BindCount)}
+	c.stmts[s.id]++
+	return s, nil
+}
+
+// unprepare releases the prepared statement once it isn't used by any stmt
+func (c *conn) unprepare(id uint64) error {
+	if c.stmts[id]--; c.stmts[id] > 0 {
+		return nil
+	}
+	delete(c.stmts, id)
+
+	if c.conn.IsClosed() {
+		return nil
+	}
+	return c.conn.Exec(context.Background(), &tarantool.Prepare{StmtID: id}).Error
+}
+
+// ExecContext implements driver.ExecerContext
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.execute(ctx, &tarantool.Execute{SQL: query}, args)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(res), nil
+}
+
+// QueryContext implements driver.QueryerContext
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.execute(ctx, &tarantool.Execute{SQL: query}, args)
+	if err != nil {
+		return nil, err
+	}
+	return newRows(res), nil
+}
+
+// Begin implements driver.Conn
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+// BeginTx implements driver.ConnBeginTx, the transaction is executed in
+// a stream of the connection.
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if c.tx != nil {
+		return nil, ErrTxActive
+	}
+	if opts.ReadOnly {
+		return nil, ErrReadOnly
+	}
+
+	begin := &tarantool.Begin{}
+	switch sql.IsolationLevel(opts.Isolation) {
+	case sql.LevelDefault:
+	case sql.LevelReadCommitted:
+		begin.Isolation = tarantool.TxnIsolationReadCommitted
+	default:
+		return nil, ErrIsolation
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		begin.Timeout = time.Until(deadline)
+	}
+
+	tx, err := c.conn.BeginTx(ctx, begin)
+	if err != nil {
+		return nil, err
+	}
+	c.tx = tx
+	return &connTx{conn: c}, nil
+}
+
+// Ping implements driver.Pinger
+func (c *conn) Ping(ctx context.Context) error {
+	if c.conn.IsClosed() {
+		return driver.ErrBadConn
+	}
+	return c.conn.Exec(ctx, &tarantool.Ping{}).Error
+}
+
+// ResetSession implements driver.SessionResetter
+func (c *conn) ResetSession(ctx context.Context) error {
+	if c.conn.IsClosed() {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// IsValid implements driver.Validator
+func (c *conn) IsValid() bool {
+	return !c.conn.IsClosed()
+}
+
+// CheckNamedValue implements driver.NamedValueChecker
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) (err error) {
+	switch v := nv.Value.(type) {
+	case uint64, []interface{}, map[string]interface{}:
+		return nil
+	case time.Time:
+		nv.Value = v.Format(time.RFC3339Nano)
+		return nil
+	}
+	nv.Value, err = driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	return err
+}
+
+// Close implements driver.Conn
+func (c *conn) Close() error {
+	c.conn.Close()
+	return nil
+}
+
+type connTx struct {
+	conn *conn
+}
+
+// Commit implements driver.Tx
+func (tx *connTx) Commit() error {
+	defer tx.done()
+	return tx.conn.tx.Commit(context.Background())
+}
+
+// Rollback implements driver.Tx
+func (tx *connTx) Rollback() error {
+	defer tx.done()
+	return tx.conn.tx.Rollback(context.Background())
+}
+
+func (tx *connTx) done() {
+	tx.conn.tx = nil
+}
+
+type stmt struct {
+	conn   *conn
+	id     uint64
+	params int
+	closed bool
+}
+
+var (
+	_ driver.Stmt             = (*stmt)(nil)
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+// NumInput implements driver.Stmt
+func (s *stmt) NumInput() int {
+	return s.params
+}
+
+// Exec implements driver.Stmt
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+// ExecContext implements driver.StmtExecContext
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	res, err := s.conn.execute(ctx, &tarantool.Execute{StmtID: s.id}, args)
+	if err != nil {
+		return nil, err
+	}
+	return newResult(res), nil
+}
+
+// Query implements driver.Stmt
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+// QueryContext implements driver.StmtQueryContext
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := s.conn.execute(ctx, &tarantool.Execute{StmtID: s.id}, args)
+	if err != nil {
+		return nil, err
+	}
+	return newRows(res), nil
+}
+
+// Close implements driver.Stmt
+func (s *stmt) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.conn.unprepare(s.id)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	nv := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		nv[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return nv
+}
+
+// bindArgs returns the values of the parameters, the named ones are
+// the maps of the name to the value
+func bindArgs(args []driver.NamedValue) []interface{} {
+	bind := make([]interface{}, len(args))
+	for i, a := range args {
+		if a.Name != "" {
+			bind[i] = map[string]interface{}{":" + a.Name: a.Value}
+		} else {
+			bind[i] = a.Value
+		}
+	}
+	return bind
+}
@@ -0,0 +1,81 @@
+// Package sqldriver is the database/sql driver of Tarantool SQL, registered
+// under the name "tarantool". The data source name is the one of
+// tarantool.Connect:
+//
+//	db, err := sql.Open("tarantool", "user:password@127.0.0.1:3301")
+//	...
+//	rows, err := db.QueryContext(ctx, `SELECT "id", "name" FROM "users" WHERE "age" > ?`, 18)
+//
+// The statements are executed with the EXECUTE requests, Tarantool >= 2.1.0.
+// The prepared statements are prepared with the PREPARE requests in the
+// session of the connection, Tarantool >= 2.3.1.
+// The transactions are executed in the streams, Tarantool >= 2.10.0
+// with box.cfg.memtx_use_mvcc_engine for the memtx spaces.
+//
+// The arguments are bound by position, or by name as :name. The uint64 values
+// are passed as is and time.Time as the RFC 3339 string. The integers of the
+// rows are int64, the ones which don't fit in it are the decimal strings,
+// the arrays and the maps are JSON.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// DriverName is the name the driver is registered under.
+const DriverName = "tarantool"
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+// Driver is the database/sql driver.
+type Driver struct{}
+
+var (
+	_ driver.Driver        = (*Driver)(nil)
+	_ driver.DriverContext = (*Driver)(nil)
+)
+
+// Open opens the new connection to the instance.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	c, err := d.OpenConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+// OpenConnector returns the connector of the data source.
+func (d *Driver) OpenConnector(dsn string) (driver.Connector, error) {
+	return NewConnector(dsn, nil), nil
+}
+
+type connector struct {
+	dsn  string
+	opts *tarantool.Options
+}
+
+// NewConnector returns the connector with the options of the connections,
+// to be used with sql.OpenDB. opts may be nil.
+func NewConnector(dsn string, opts *tarantool.Options) driver.Connector {
+	return &connector{dsn: dsn, opts: opts}
+}
+
+// Connect implements driver.Connector
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	tc, err := tarantool.ConnectContext(ctx, c.dsn, c.opts)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{conn: tc, stmts: make(map[uint64]int)}, nil
+}
+
+// Driver implements driver.Connector
+func (c *connector) Driver() driver.Driver {
+	return &Driver{}
+}
@@ -0,0 +1,264 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+type call struct {
+	query    string
+	stmt     interface{}
+	args     []interface{}
+	streamID uint64
+}
+
+// fakeServer answers the few statements of the tests
+type fakeServer struct {
+	mu    sync.Mutex
+	calls []call
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	streamID := tarantool.SessionStreamID(ctx)
+
+	var c call
+	switch q := q.(type) {
+	case *tarantool.Begin:
+		c.query = "begin"
+	case *tarantool.Commit:
+		c.query = "commit"
+	case *tarantool.Rollback:
+		c.query = "rollback"
+	case *tarantool.Execute:
+		c.query = "execute"
+		c.stmt = q.SQL
+		if q.StmtID != 0 {
+			c.stmt = q.StmtID
+		}
+		c.args = q.Bind
+	case *tarantool.Prepare:
+		c.query = "prepare"
+		c.stmt = q.SQL
+		if q.StmtID != 0 {
+			c.query = "unprepare"
+			c.stmt = q.StmtID
+		}
+	default:
+		return &tarantool.Result{}
+	}
+	c.streamID = streamID
+
+	s.mu.Lock()
+	s.calls = append(s.calls, c)
+	s.mu.Unlock()
+
+	switch c.query {
+	case "prepare":
+		return &tarantool.Result{StmtID: 42, BindCount: 1}
+	case "execute":
+	default:
+		return &tarantool.Result{}
+	}
+
+	stmt := c.stmt
+	if _, ok := stmt.(string); !ok {
+		stmt = "SELECT"
+	}
+	switch stmt {
+	case "SELECT":
+		return &tarantool.Result{
+			Metadata: []tarantool.SQLColumn{
+				{Name: "id", Type: "unsigned"},
+				{Name: "name", Type: "string"},
+				{Name: "tags", Type: "any"},
+			},
+			Data: [][]interface{}{
+				{uint64(1), "a", []interface{}{"x", "y"}},
+				{uint64(math.MaxUint64), nil, nil},
+			},
+		}
+	case "INSERT":
+		return &tarantool.Result{SQLInfo: &tarantool.SQLInfo{RowCount: 2, AutoincrementIDs: []int64{5, 6}}}
+	case "DELETE":
+		return &tarantool.Result{SQLInfo: &tarantool.SQLInfo{}}
+	}
+	return &tarantool.Result{
+		ErrorCode: tarantool.ErrProcLua,
+		Error:     tarantool.NewQueryError(tarantool.ErrProcLua, "Syntax error"),
+	}
+}
+
+func (s *fakeServer) Calls() []call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.calls
+	s.calls = nil
+	return calls
+}
+
+func newDB(t *testing.T) (*sql.DB, *fakeServer) {
+	s := &fakeServer{}
+	addr := tnttest.NewServer(t, s.handle)
+	db, err := sql.Open(DriverName, addr)
+	require.NoError(t, err)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, s
+}
+
+func TestQuery(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db, s := newDB(t)
+
+	rows, err := db.Query("SELECT", 1, sql.Named("name", "a"))
+	require.NoError(err)
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	require.NoError(err)
+	assert.Equal([]string{"id", "name", "tags"}, columns)
+	types, err := rows.ColumnTypes()
+	require.NoError(err)
+	assert.Equal("UNSIGNED", types[0].DatabaseTypeName())
+
+	var (
+		id   uint64
+		name sql.NullString
+		tags []byte
+	)
+	require.True(rows.Next())
+	require.NoError(rows.Scan(&id, &name, &tags))
+	assert.Equal(uint64(1), id)
+	assert.Equal(sql.NullString{String: "a", Valid: true}, name)
+	assert.Equal(`["x","y"]`, string(tags))
+
+	require.True(rows.Next())
+	require.NoError(rows.Scan(&id, &name, &tags))
+	assert.Equal(uint64(math.MaxUint64), id)
+	assert.False(name.Valid)
+	assert.Nil(tags)
+
+	assert.False(rows.Next())
+	require.NoError(rows.Err())
+
+	assert.Equal([]call{{
+		query: "execute",
+		stmt:  "SELECT",
+		args:  []interface{}{int64(1), map[string]interface{}{":name": "a"}},
+	}}, s.Calls())
+}
+
+func TestExec(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db, s := newDB(t)
+
+	at := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	res, err := db.Exec("INSERT", uint64(math.MaxUint64), at, nil)
+	require.NoError(err)
+	n, err := res.RowsAffected()
+	require.NoError(err)
+	assert.Equal(int64(2), n)
+	id, err := res.LastInsertId()
+	require.NoError(err)
+	assert.Equal(int64(6), id)
+
+	res, err = db.Exec("DELETE")
+	require.NoError(err)
+	_, err = res.LastInsertId()
+	assert.Equal(ErrNoInsertID, err)
+
+	_, err = db.Exec("SELEKT")
+	var qe *tarantool.QueryError
+	if assert.ErrorAs(err, &qe) {
+		assert.Equal(tarantool.ErrProcLua, qe.Code)
+	}
+
+	calls := s.Calls()
+	require.Len(calls, 3)
+	assert.Equal([]interface{}{uint64(math.MaxUint64), "2021-06-01T12:00:00Z", nil}, calls[0].args)
+}
+
+func TestPrepare(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db, s := newDB(t)
+
+	st, err := db.Prepare("SELECT")
+	require.NoError(err)
+	st2, err := db.Prepare("SELECT")
+	require.NoError(err)
+
+	var id uint64
+	require.NoError(st.QueryRow(7).Scan(&id, new(interface{}), new(interface{})))
+	assert.Equal(uint64(1), id)
+	_, err = st.Exec()
+	assert.Error(err, "the number of the arguments is checked")
+
+	// the statement is unprepared once both are closed
+	require.NoError(st.Close())
+	require.NoError(st2.Close())
+
+	assert.Equal([]call{
+		{query: "prepare", stmt: "SELECT"},
+		{query: "prepare", stmt: "SELECT"},
+		{query: "execute", stmt: uint64(42), args: []interface{}{int64(7)}},
+		{query: "unprepare", stmt: uint64(42)},
+	}, s.Calls())
+}
+
+func TestTx(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db, s := newDB(t)
+	ctx := context.Background()
+
+	tx, err := db.Begin()
+	require.NoError(err)
+	_, err = tx.Exec("INSERT")
+	require.NoError(err)
+	require.NoError(tx.Commit())
+
+	tx, err = db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelReadCommitted})
+	require.NoError(err)
+	_, err = tx.Exec("DELETE")
+	require.NoError(err)
+	require.NoError(tx.Rollback())
+
+	_, err = db.Exec("DELETE")
+	require.NoError(err)
+
+	_, err = db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	assert.Equal(ErrIsolation, err)
+	_, err = db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	assert.Equal(ErrReadOnly, err)
+
+	calls := s.Calls()
+	require.Len(calls, 7)
+	for i, q := range []string{"begin", "execute", "commit", "begin", "execute", "rollback", "execute"} {
+		assert.Equal(q, calls[i].query)
+	}
+
+	// the transactions are executed in their own streams
+	assert.NotZero(calls[0].streamID)
+	assert.Equal(calls[0].streamID, calls[1].streamID)
+	assert.Equal(calls[0].streamID, calls[2].streamID)
+	assert.NotZero(calls[3].streamID)
+	assert.NotEqual(calls[0].streamID, calls[3].streamID)
+	assert.Equal(calls[3].streamID, calls[5].streamID)
+	assert.Zero(calls[6].streamID)
+}
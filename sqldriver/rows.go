@@ -0,0 +1,126 @@
+package sqldriver
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+var (
+	// ErrNoInsertID is returned by Result.LastInsertId if the statement
+	// has generated no autoincrement ids.
+	ErrNoInsertID = errors.New("no autoincrement id")
+
+	errBadResponse = errors.New("unexpected response of prepare")
+)
+
+func newResult(res *tarantool.Result) driver.Result {
+	r := &result{}
+	if res.SQLInfo != nil {
+		r.rowCount = int64(res.SQLInfo.RowCount)
+		r.ids = res.SQLInfo.AutoincrementIDs
+	}
+	return r
+}
+
+func newRows(res *tarantool.Result) driver.Rows {
+	r := &rows{data: res.Data}
+	for _, col := range res.Metadata {
+		r.columns = append(r.columns, col.Name)
+		r.types = append(r.types, strings.ToUpper(col.Type))
+	}
+	return r
+}
+
+type result struct {
+	rowCount int64
+	ids      []int64
+}
+
+// LastInsertId returns the last autoincrement id generated by the statement
+func (r *result) LastInsertId() (int64, error) {
+	if len(r.ids) == 0 {
+		return 0, ErrNoInsertID
+	}
+	return r.ids[len(r.ids)-1], nil
+}
+
+// RowsAffected implements driver.Result
+func (r *result) RowsAffected() (int64, error) {
+	return r.rowCount, nil
+}
+
+type rows struct {
+	columns []string
+	types   []string
+	data    [][]interface{}
+}
+
+var (
+	_ driver.Rows                           = (*rows)(nil)
+	_ driver.RowsColumnTypeDatabaseTypeName = (*rows)(nil)
+)
+
+// Columns implements driver.Rows
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+// ColumnTypeDatabaseTypeName implements driver.RowsColumnTypeDatabaseTypeName,
+// the names are the upper case ones of Tarantool, e.g. INTEGER or STRING
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[index]
+}
+
+// Next implements driver.Rows
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.data) == 0 {
+		return io.EOF
+	}
+	row := r.data[0]
+	r.data = r.data[1:]
+
+	for i := range dest {
+		dest[i] = nil
+		if i < len(row) {
+			dest[i] = toValue(row[i])
+		}
+	}
+	return nil
+}
+
+// Close implements driver.Rows
+func (r *rows) Close() error {
+	r.data = nil
+	return nil
+}
+
+// toValue converts the decoded field to driver.Value
+func toValue(v interface{}) driver.Value {
+	switch v := v.(type) {
+	case uint64:
+		if v > math.MaxInt64 {
+			return strconv.FormatUint(v, 10)
+		}
+		return int64(v)
+	case int64, float64, bool, string, []byte, nil:
+		return v
+	case float32:
+		return float64(v)
+	case []interface{}, map[string]interface{}:
+		if data, err := json.Marshal(v); err == nil {
+			return data
+		}
+	}
+	if i, ok := typeconv.IntfToInt64(v); ok {
+		return i
+	}
+	return v
+}
@@ -0,0 +1,100 @@
+package tarantool
+
+import (
+	"context"
+	"sync"
+)
+
+// Stream is the result of CallStream: the values pushed by the function
+// followed by its return value.
+//
+//	s := conn.CallStream(ctx, "job.run", []interface{}{id})
+//	for s.Next() {
+//		fmt.Println("progress:", s.Value())
+//	}
+//	if err := s.Err(); err != nil {
+//		return err
+//	}
+//	fmt.Println("result:", s.Result().Data)
+type Stream struct {
+	mu      sync.Mutex
+	pending []interface{}
+	result  *Result
+	notify  chan struct{}
+	value   interface{}
+}
+
+// CallStream calls the function with Call17 and returns the stream of the
+// values it sends with box.session.push, Tarantool >= 1.10.0.
+// The return value of the function is available with Result once Next
+// returns false. The call is limited by ctx and QueryTimeout as a whole.
+// The pushed values are buffered until Next takes them, so a slow reader
+// doesn't hold up the other requests of the connection.
+func (conn *Connection) CallStream(ctx context.Context, name string, args []interface{}) *Stream {
+	s := &Stream{notify: make(chan struct{}, 1)}
+	go func() {
+		res := conn.Exec(ctx, &Call17{Name: name, Tuple: args}, PushExecOption(s.push))
+		s.mu.Lock()
+		s.result = res
+		s.mu.Unlock()
+		s.signal()
+	}()
+	return s
+}
+
+func (s *Stream) push(value interface{}) {
+	s.mu.Lock()
+	s.pending = append(s.pending, value)
+	s.mu.Unlock()
+	s.signal()
+}
+
+func (s *Stream) signal() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Next waits for the next pushed value, it returns false once the call
+// is complete and all the values are taken.
+func (s *Stream) Next() bool {
+	for {
+		s.mu.Lock()
+		if len(s.pending) > 0 {
+			s.value = s.pending[0]
+			s.pending[0] = nil
+			s.pending = s.pending[1:]
+			s.mu.Unlock()
+			return true
+		}
+		done := s.result != nil
+		s.mu.Unlock()
+
+		if done {
+			s.value = nil
+			return false
+		}
+		<-s.notify
+	}
+}
+
+// Value returns the value taken by Next.
+func (s *Stream) Value() interface{} {
+	return s.value
+}
+
+// Result returns the result of the call once Next has returned false, nil before that.
+func (s *Stream) Result() *Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.result
+}
+
+// Err returns the error of the call once Next has returned false.
+func (s *Stream) Err() error {
+	if res := s.Result(); res != nil {
+		return res.Error
+	}
+	return nil
+}
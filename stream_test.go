@@ -0,0 +1,37 @@
+package tarantool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCallStream(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn, err := Connect(newPushServer(t), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+
+	s := conn.CallStream(ctx, "job", []interface{}{int64(1), int64(2), int64(3)})
+	var values []interface{}
+	for s.Next() {
+		values = append(values, s.Value())
+	}
+	require.NoError(s.Err())
+	assert.Equal([]interface{}{int64(1), int64(2), int64(3)}, values)
+	assert.Equal([][]interface{}{{int64(3)}}, s.Result().Data)
+	assert.Nil(s.Value())
+	assert.False(s.Next())
+
+	s = conn.CallStream(ctx, "fail", nil)
+	require.True(s.Next())
+	assert.Equal("started", s.Value())
+	assert.False(s.Next())
+	assert.Error(s.Err())
+}
@@ -0,0 +1,178 @@
+// Package tenant scopes the queries of a multi-tenant service to one tenant.
+//
+// The tuples of all the tenants share the spaces and keep the tenant ID in the
+// first field, which is also the first part of every index used through a
+// Tenant. The Tenant prepends the ID to the keys and the tuples of the queries
+// and strips it from the returned tuples, so the code above it works with the
+// tuples as if the space belonged to the tenant alone:
+//
+//	t := tenant.New(conn, "acme")
+//	t.Exec(ctx, &tarantool.Insert{Space: "orders", Tuple: []interface{}{1, "new"}})
+//	// inserts {"acme", 1, "new"}
+//	res := t.Exec(ctx, &tarantool.Select{Space: "orders", Iterator: tarantool.IterAll})
+//	// res.Data is [[1, "new"]]
+//
+// The field numbers of the update operations are shifted past the tenant field.
+// Eval, Call and the other queries which don't address tuples are passed as is.
+package tenant
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+var (
+	// ErrIterator is returned for the iterators which can't be limited to the tenant's keys.
+	ErrIterator = errors.New("iterator can't be scoped to a tenant")
+	// ErrOperator is returned for the update operations whose field can't be shifted.
+	ErrOperator = errors.New("operator can't be scoped to a tenant")
+)
+
+// Tenant executes the queries on behalf of a tenant. It implements tarantool.Executor,
+// so it can be passed to the other packages in place of the connection.
+type Tenant struct {
+	conn tarantool.Executor
+	id   interface{}
+}
+
+// New returns the Tenant with the id executing the queries with conn.
+func New(conn tarantool.Executor, id interface{}) *Tenant {
+	return &Tenant{conn: conn, id: id}
+}
+
+// ID returns the tenant ID.
+func (t *Tenant) ID() interface{} {
+	return t.id
+}
+
+// Exec scopes q to the tenant and executes it. The query itself is not modified.
+func (t *Tenant) Exec(ctx context.Context, q tarantool.Query, options ...tarantool.ExecOption) *tarantool.Result {
+	var scoped tarantool.Query
+	var err error
+
+	switch q := q.(type) {
+	case *tarantool.Select:
+		s := *q
+		switch s.Iterator {
+		case tarantool.IterAll:
+			// a partial key with EQ selects all the tuples of the tenant
+			s.Iterator = tarantool.IterEq
+		case tarantool.IterGt, tarantool.IterLt:
+			// an empty key selects all the tuples in the order of the iterator,
+			// while GT and LT of the tenant ID alone skip all of them
+			if q.Key == nil && len(q.KeyTuple) == 0 {
+				if s.Iterator == tarantool.IterGt {
+					s.Iterator = tarantool.IterGe
+				} else {
+					s.Iterator = tarantool.IterLe
+				}
+			}
+		case tarantool.IterEq, tarantool.IterReq, tarantool.IterLe, tarantool.IterGe:
+		default:
+			err = fmt.Errorf("%w: %d", ErrIterator, s.Iterator)
+		}
+		s.Key, s.KeyTuple = nil, t.key(q.Key, q.KeyTuple)
+		scoped = &s
+	case *tarantool.Insert:
+		scoped = &tarantool.Insert{Space: q.Space, Tuple: t.tuple(q.Tuple)}
+	case *tarantool.Replace:
+		scoped = &tarantool.Replace{Space: q.Space, Tuple: t.tuple(q.Tuple)}
+	case *tarantool.Delete:
+		scoped = &tarantool.Delete{Space: q.Space, Index: q.Index, KeyTuple: t.key(q.Key, q.KeyTuple)}
+	case *tarantool.Update:
+		u := &tarantool.Update{Space: q.Space, Index: q.Index, KeyTuple: t.key(q.Key, q.KeyTuple)}
+		u.Set, err = shift(q.Set)
+		scoped = u
+	case *tarantool.Upsert:
+		u := &tarantool.Upsert{Space: q.Space, Tuple: t.tuple(q.Tuple)}
+		u.Set, err = shift(q.Set)
+		scoped = u
+	default:
+		return t.conn.Exec(ctx, q, options...)
+	}
+	if err != nil {
+		return &tarantool.Result{Error: err, ErrorCode: tarantool.ErrIllegalParams}
+	}
+
+	res := t.conn.Exec(ctx, scoped, options...)
+	if res.Error != nil {
+		return res
+	}
+
+	// the ranges of LT, GT and alike run into the neighbouring tenants
+	data := make([][]interface{}, 0, len(res.Data))
+	for _, tuple := range res.Data {
+		if len(tuple) > 0 && sameID(tuple[0], t.id) {
+			data = append(data, tuple[1:])
+		}
+	}
+	res.Data = data
+	return res
+}
+
+func (t *Tenant) key(key interface{}, keyTuple []interface{}) []interface{} {
+	if key != nil {
+		return []interface{}{t.id, key}
+	}
+	return t.tuple(keyTuple)
+}
+
+func (t *Tenant) tuple(tuple []interface{}) []interface{} {
+	return append([]interface{}{t.id}, tuple...)
+}
+
+// shift moves the fields of the operations past the tenant field,
+// the negative ones are counted from the end and are kept
+func shift(ops []tarantool.Operator) ([]tarantool.Operator, error) {
+	if ops == nil {
+		return nil, nil
+	}
+
+	next := func(field int64) int64 {
+		if field >= 0 {
+			return field + 1
+		}
+		return field
+	}
+
+	shifted := make([]tarantool.Operator, len(ops))
+	for i, op := range ops {
+		switch op := op.(type) {
+		case *tarantool.OpAdd:
+			shifted[i] = &tarantool.OpAdd{Field: next(op.Field), Argument: op.Argument}
+		case *tarantool.OpSub:
+			shifted[i] = &tarantool.OpSub{Field: next(op.Field), Argument: op.Argument}
+		case *tarantool.OpBitAND:
+			shifted[i] = &tarantool.OpBitAND{Field: next(op.Field), Argument: op.Argument}
+		case *tarantool.OpBitXOR:
+			shifted[i] = &tarantool.OpBitXOR{Field: next(op.Field), Argument: op.Argument}
+		case *tarantool.OpBitOR:
+			shifted[i] = &tarantool.OpBitOR{Field: next(op.Field), Argument: op.Argument}
+		case *tarantool.OpDelete:
+			shifted[i] = &tarantool.OpDelete{From: next(op.From), Count: op.Count}
+		case *tarantool.OpInsert:
+			shifted[i] = &tarantool.OpInsert{Before: next(op.Before), Argument: op.Argument}
+		case *tarantool.OpAssign:
+			shifted[i] = &tarantool.OpAssign{Field: next(op.Field), Argument: op.Argument}
+		case *tarantool.OpSplice:
+			shifted[i] = &tarantool.OpSplice{Field: next(op.Field), Offset: op.Offset, Position: op.Position, Argument: op.Argument}
+		default:
+			return nil, fmt.Errorf("%w: %T", ErrOperator, op)
+		}
+	}
+	return shifted, nil
+}
+
+// sameID compares the IDs regardless of the integer types they are decoded to
+func sameID(a, b interface{}) bool {
+	if x, ok := typeconv.IntfToInt64(a); ok {
+		y, ok := typeconv.IntfToInt64(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,154 @@
+package tenant
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+	"github.com/viciious/go-tarantool/typeconv"
+)
+
+// testSpace is the id of the first user space, the schema isn't loaded
+// by the test server, so the space names can't be used
+const testSpace = 512
+
+// newTestConn returns the connection to a server recording the queries
+// and answering them with the tuples returned by reply
+func newTestConn(t *testing.T, reply func(q tarantool.Query) [][]interface{}) (*tarantool.Connection, func() []tarantool.Query) {
+	var mu sync.Mutex
+	var queries []tarantool.Query
+
+	handler := func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+		if s, ok := q.(*tarantool.Select); ok {
+			if space, _ := typeconv.IntfToInt(s.Space); space < testSpace {
+				// the schema requests of Connect
+				return &tarantool.Result{}
+			}
+		}
+		mu.Lock()
+		queries = append(queries, q)
+		mu.Unlock()
+		return &tarantool.Result{Data: reply(q)}
+	}
+
+	addr := tnttest.NewServer(t, handler)
+
+	conn := tnttest.Connect(t, addr, nil)
+
+	return conn, func() []tarantool.Query {
+		mu.Lock()
+		defer mu.Unlock()
+		q := queries
+		queries = nil
+		return q
+	}
+}
+
+func TestTenantWrite(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn, received := newTestConn(t, func(q tarantool.Query) [][]interface{} {
+		return [][]interface{}{{"acme", int64(1), "new"}}
+	})
+	tn := New(conn, "acme")
+	ctx := context.Background()
+
+	res := tn.Exec(ctx, &tarantool.Insert{Space: testSpace, Tuple: []interface{}{int64(1), "new"}})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(1), "new"}}, res.Data)
+
+	require.NoError(tn.Exec(ctx, &tarantool.Replace{Space: testSpace, Tuple: []interface{}{int64(1), "new"}}).Error)
+	require.NoError(tn.Exec(ctx, &tarantool.Delete{Space: testSpace, Key: int64(1)}).Error)
+	require.NoError(tn.Exec(ctx, &tarantool.Update{
+		Space:    testSpace,
+		KeyTuple: []interface{}{int64(1)},
+		Set:      []tarantool.Operator{&tarantool.OpAssign{Field: 1, Argument: "paid"}, &tarantool.OpDelete{From: -1, Count: 1}},
+	}).Error)
+	require.NoError(tn.Exec(ctx, &tarantool.Upsert{
+		Space: testSpace,
+		Tuple: []interface{}{int64(1), "new"},
+		Set:   []tarantool.Operator{&tarantool.OpAdd{Field: 2, Argument: 1}},
+	}).Error)
+
+	queries := received()
+	require.Len(queries, 5)
+	assert.Equal([]interface{}{"acme", int64(1), "new"}, queries[0].(*tarantool.Insert).Tuple)
+	assert.Equal([]interface{}{"acme", int64(1), "new"}, queries[1].(*tarantool.Replace).Tuple)
+	assert.Equal([]interface{}{"acme", int64(1)}, queries[2].(*tarantool.Delete).KeyTuple)
+
+	update := queries[3].(*tarantool.Update)
+	assert.Equal([]interface{}{"acme", int64(1)}, update.KeyTuple)
+	assert.Equal([]tarantool.Operator{&tarantool.OpAssign{Field: 2, Argument: "paid"}, &tarantool.OpDelete{From: -1, Count: 1}}, update.Set)
+
+	upsert := queries[4].(*tarantool.Upsert)
+	assert.Equal([]interface{}{"acme", int64(1), "new"}, upsert.Tuple)
+	assert.Equal([]tarantool.Operator{&tarantool.OpAdd{Field: 3, Argument: 1}}, upsert.Set)
+}
+
+func TestTenantSelect(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	conn, received := newTestConn(t, func(q tarantool.Query) [][]interface{} {
+		if _, ok := q.(*tarantool.Eval); ok {
+			return [][]interface{}{{int64(42)}}
+		}
+		// the range runs into the next tenant
+		return [][]interface{}{
+			{uint64(7), int64(2), "b"},
+			{uint64(7), int64(3), "c"},
+			{uint64(8), int64(1), "a"},
+		}
+	})
+	tn := New(conn, 7)
+	ctx := context.Background()
+
+	res := tn.Exec(ctx, &tarantool.Select{Space: testSpace, Iterator: tarantool.IterGt, Key: int64(1), Limit: 3})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(2), "b"}, {int64(3), "c"}}, res.Data)
+
+	require.NoError(tn.Exec(ctx, &tarantool.Select{Space: testSpace, Index: 1, Iterator: tarantool.IterAll, Limit: 10}).Error)
+
+	// the queries not addressing tuples are passed as is
+	res = tn.Exec(ctx, &tarantool.Eval{Expression: "return 42"})
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(42)}}, res.Data)
+
+	// GT and LT with an empty key select all the tuples of the tenant
+	res = tn.Exec(ctx, &tarantool.Select{Space: testSpace, Iterator: tarantool.IterGt, Limit: 3})
+	require.NoError(res.Error)
+	assert.Len(res.Data, 2)
+	require.NoError(tn.Exec(ctx, &tarantool.Select{Space: testSpace, Iterator: tarantool.IterLt, KeyTuple: []interface{}{}, Limit: 3}).Error)
+
+	queries := received()
+	require.Len(queries, 5)
+	ge := queries[3].(*tarantool.Select)
+	assert.Equal(tarantool.IterGe, ge.Iterator)
+	assert.Equal(int64(7), ge.Key)
+	assert.Equal(tarantool.IterLe, queries[4].(*tarantool.Select).Iterator)
+	gt := queries[0].(*tarantool.Select)
+	assert.Equal(tarantool.IterGt, gt.Iterator)
+	assert.Equal([]interface{}{int64(7), int64(1)}, gt.KeyTuple)
+	all := queries[1].(*tarantool.Select)
+	assert.Equal(tarantool.IterEq, all.Iterator)
+	assert.Equal(uint(1), all.Index)
+	assert.Equal(int64(7), all.Key)
+
+	res = tn.Exec(ctx, &tarantool.Select{Space: testSpace, Iterator: tarantool.IterBitsAllSet, Key: uint64(1)})
+	assert.True(errors.Is(res.Error, ErrIterator))
+	res = tn.Exec(ctx, &tarantool.Update{Space: testSpace, Key: int64(1), Set: []tarantool.Operator{customOp{}}})
+	assert.True(errors.Is(res.Error, ErrOperator))
+	assert.Empty(received())
+}
+
+type customOp struct{}
+
+func (customOp) AsTuple() []interface{} {
+	return []interface{}{"=", int64(1), "x"}
+}
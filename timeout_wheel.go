@@ -0,0 +1,140 @@
+package tarantool
+
+import (
+	"sync"
+	"time"
+)
+
+var timeouts = newTimeoutWheel(10*time.Millisecond, 6000)
+
+// timeoutWheel is a coarse timing wheel shared by all connections. It hands out
+// channels which are closed when the timeout expires and runs the callbacks,
+// so a pending request costs neither a timer nor a goroutine. The wheel only
+// ticks while there are pending timeouts.
+type timeoutWheel struct {
+	sync.Mutex
+	tick    time.Duration
+	slots   []chan struct{}
+	funcs   [][]wheelFunc
+	pos     int
+	pending int
+	running bool
+}
+
+// wheelFunc is a callback which runs after the wheel passes its slot rounds more times
+type wheelFunc struct {
+	f      func()
+	rounds int
+}
+
+func newTimeoutWheel(tick time.Duration, size int) *timeoutWheel {
+	return &timeoutWheel{
+		tick:  tick,
+		slots: make([]chan struct{}, size),
+		funcs: make([][]wheelFunc, size),
+	}
+}
+
+// After returns a channel which is closed not earlier than d passes.
+// Timeouts are rounded up to the wheel tick. If d doesn't fit into the wheel span,
+// nil is returned and the caller should fall back to a regular timer.
+func (w *timeoutWheel) After(d time.Duration) <-chan struct{} {
+	// one extra tick, because the current one is partially elapsed
+	n := int((d+w.tick-1)/w.tick) + 1
+	if n >= len(w.slots) {
+		return nil
+	}
+
+	w.Lock()
+	defer w.Unlock()
+
+	i := (w.pos + n) % len(w.slots)
+	ch := w.slots[i]
+	if ch == nil {
+		ch = make(chan struct{})
+		w.slots[i] = ch
+		// the slot is pending already if it has callbacks
+		if len(w.funcs[i]) == 0 {
+			w.pending++
+		}
+	}
+
+	w.start()
+	return ch
+}
+
+// AfterFunc runs f in the goroutine of the wheel not earlier than d passes.
+// Unlike After, it takes any d, the long ones wait for several rounds of the
+// wheel. The callbacks can't be removed, so f must do nothing if it is
+// not needed anymore, and it must not block.
+func (w *timeoutWheel) AfterFunc(d time.Duration, f func()) {
+	n := int((d+w.tick-1)/w.tick) + 1
+
+	w.Lock()
+	defer w.Unlock()
+
+	i := (w.pos + n) % len(w.slots)
+	if w.slots[i] == nil && len(w.funcs[i]) == 0 {
+		w.pending++
+	}
+	w.funcs[i] = append(w.funcs[i], wheelFunc{f: f, rounds: (n - 1) / len(w.slots)})
+	w.start()
+}
+
+// start runs the wheel if it is stopped, w must be locked
+func (w *timeoutWheel) start() {
+	if !w.running {
+		w.running = true
+		go w.run()
+	}
+}
+
+func (w *timeoutWheel) run() {
+	ticker := time.NewTicker(w.tick)
+	defer ticker.Stop()
+
+	var due []func()
+	for range ticker.C {
+		w.Lock()
+		w.pos = (w.pos + 1) % len(w.slots)
+		ch, funcs := w.slots[w.pos], w.funcs[w.pos]
+		if ch != nil {
+			w.slots[w.pos] = nil
+			close(ch)
+		}
+		// the callbacks of the later rounds stay in the slot
+		due = due[:0]
+		kept := funcs[:0]
+		for _, wf := range funcs {
+			if wf.rounds == 0 {
+				due = append(due, wf.f)
+			} else {
+				wf.rounds--
+				kept = append(kept, wf)
+			}
+		}
+		for i := len(kept); i < len(funcs); i++ {
+			funcs[i] = wheelFunc{}
+		}
+		if len(kept) == 0 {
+			kept = nil
+		}
+		w.funcs[w.pos] = kept
+		if (ch != nil || len(funcs) > 0) && len(kept) == 0 {
+			w.pending--
+		}
+		stop := w.pending == 0
+		if stop {
+			w.running = false
+		}
+		w.Unlock()
+
+		for i, f := range due {
+			f()
+			due[i] = nil
+		}
+		if stop {
+			return
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package tarantool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeoutWheel(t *testing.T) {
+	assert := assert.New(t)
+
+	w := newTimeoutWheel(5*time.Millisecond, 100)
+
+	assert.Nil(w.After(time.Second))
+
+	started := time.Now()
+	ch1 := w.After(20 * time.Millisecond)
+	ch2 := w.After(20 * time.Millisecond)
+	ch3 := w.After(40 * time.Millisecond)
+
+	<-ch1
+	assert.True(time.Since(started) >= 20*time.Millisecond)
+
+	select {
+	case <-ch2:
+	default:
+		t.Error("timeouts within the same tick must expire together")
+	}
+
+	<-ch3
+	assert.True(time.Since(started) >= 40*time.Millisecond)
+
+	// the wheel stops ticking when there is nothing pending
+	time.Sleep(20 * time.Millisecond)
+	w.Lock()
+	assert.False(w.running)
+	w.Unlock()
+}
+
+func TestTimeoutWheelAfterFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	w := newTimeoutWheel(5*time.Millisecond, 10)
+
+	started := time.Now()
+	short := make(chan time.Duration, 1)
+	long := make(chan time.Duration, 1)
+	// longer than the wheel span of 50ms
+	w.AfterFunc(120*time.Millisecond, func() { long <- time.Since(started) })
+	w.AfterFunc(20*time.Millisecond, func() { short <- time.Since(started) })
+
+	assert.True(<-short >= 20*time.Millisecond)
+	assert.True(<-long >= 120*time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	w.Lock()
+	assert.False(w.running)
+	assert.Equal(0, w.pending)
+	w.Unlock()
+}
+
+func TestTimeoutWheelSharedSlot(t *testing.T) {
+	w := newTimeoutWheel(5*time.Millisecond, 10)
+
+	// the callbacks of both rounds and the channel are in the same slot
+	done := make(chan struct{}, 2)
+	w.AfterFunc(70*time.Millisecond, func() { done <- struct{}{} })
+	w.AfterFunc(20*time.Millisecond, func() { done <- struct{}{} })
+	ch := w.After(20 * time.Millisecond)
+	w.Lock()
+	assert.Equal(t, 1, w.pending)
+	w.Unlock()
+
+	<-ch
+	<-done
+	<-done
+
+	assert.Eventually(t, func() bool {
+		w.Lock()
+		defer w.Unlock()
+		return !w.running && w.pending == 0
+	}, time.Second, 5*time.Millisecond)
+}
+
+func BenchmarkTimeoutWheel(b *testing.B) {
+	b.ReportAllocs()
+	w := newTimeoutWheel(10*time.Millisecond, 6000)
+
+	for i := 0; i < b.N; i++ {
+		w.After(time.Second)
+	}
+}
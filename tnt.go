@@ -20,10 +20,28 @@ type request struct {
 	replyChan chan *AsyncResult
 	packet    *BinaryPacket
 	startedAt time.Time
+	// enqueuedAt is the time the request is queued for writing,
+	// it is only set if PerfCount.QueueDelay is
+	enqueuedAt time.Time
+	// async requests are failed by the timeout wheel if no reply arrives in expireAfter
+	expireAfter time.Duration
+	// push receives the values of box.session.push sent before the reply
+	push func(value interface{})
+	// streamID is the stream the query is executed in, 0 for none
+	streamID uint64
 }
 
 type QueryCompleteFn func(interface{}, time.Duration)
 
+// QueueDelayFn receives the time a request has waited in the write queue.
+type QueueDelayFn func(time.Duration)
+
+// OrphanReplyFn receives the sync of a reply which nobody waits for.
+type OrphanReplyFn func(requestID uint64)
+
+// AsyncResult is the reply to a query sent with ExecAsync.
+// BinaryPacket holds the raw reply body: call BinaryPacket.Unmarshal to decode it
+// and BinaryPacket.Release when it is no longer needed.
 type AsyncResult struct {
 	ErrorCode    uint
 	Error        error
@@ -39,6 +57,18 @@ type PerfCount struct {
 	NetPacketsOut *expvar.Int
 	QueryTimeouts *expvar.Int
 	QueryComplete QueryCompleteFn
+	// SyncCollisions counts requests failed with ErrSyncCollision
+	SyncCollisions *expvar.Int
+	// OrphanReplies counts replies with a sync of no pending request,
+	// e.g. late replies to timed out requests
+	OrphanReplies *expvar.Int
+	// OrphanReply is called by the reader for every orphan reply, e.g. to log it
+	OrphanReply OrphanReplyFn
+	// QueueDelay is called by the writer with the time every request has spent
+	// in the write queue, from the enqueue to the write to the socket buffer.
+	// The delay growing while WriteQueueLen stays high means the connection
+	// can't keep up with the callers. It must not block.
+	QueueDelay QueueDelayFn
 }
 
 // ReplicaSet is used to store params of the Replica Set.
@@ -111,6 +141,20 @@ func (vc VectorClock) LSN() uint64 {
 	return result
 }
 
+// Covers reports whether all the changes of other have been applied according to vc.
+// The zero index is not compared.
+func (vc VectorClock) Covers(other VectorClock) bool {
+	for id := 1; id < len(other); id++ {
+		if other[id] == 0 {
+			continue
+		}
+		if id >= len(vc) || vc[id] < other[id] {
+			return false
+		}
+	}
+	return true
+}
+
 // Has VectorClock specified ID?
 func (vc VectorClock) Has(id uint32) bool {
 	return id < uint32(len(vc))
@@ -0,0 +1,312 @@
+// Package chaos injects network faults into the connections to verify the timeout,
+// retry and reconnect behavior of the code using them:
+//
+//	d := &chaos.Dialer{Schedule: chaos.Nth(chaos.OpRead, 3, chaos.Fault{Drop: true})}
+//	conn := tarantool.New(addr, &tarantool.Options{Dialer: d})
+//
+// The Schedule decides the fault of every dial, read and write, the operations
+// are numbered, so the faults are reproducible.
+package chaos
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// ErrInjected is returned by the operations failed by a Fault.
+var ErrInjected = errors.New("injected fault")
+
+// OpKind is the kind of the network operation.
+type OpKind int
+
+const (
+	OpDial OpKind = iota
+	OpRead
+	OpWrite
+)
+
+func (k OpKind) String() string {
+	switch k {
+	case OpDial:
+		return "dial"
+	case OpRead:
+		return "read"
+	case OpWrite:
+		return "write"
+	}
+	return "unknown"
+}
+
+// Op is the network operation the fault is scheduled for.
+type Op struct {
+	// Conn is the number of the connection starting with 1, the failed dials are counted as well.
+	Conn int
+	Kind OpKind
+	// N is the number of the operation of the kind on the connection starting with 1.
+	N int
+}
+
+// Fault is the failure injected into the operation.
+type Fault struct {
+	// Latency delays the operation.
+	Latency time.Duration
+	// Drop closes the connection and fails the operation with ErrInjected.
+	// A dropped dial fails without connecting.
+	Drop bool
+	// MaxRead limits the number of bytes returned by the read.
+	MaxRead int
+	// Corrupt inverts the first byte read or written.
+	Corrupt bool
+}
+
+// Schedule returns the fault of the operation, the zero Fault means no fault.
+// It is called concurrently by the reads and the writes.
+type Schedule func(op Op) Fault
+
+// Always injects f into all the operations.
+func Always(f Fault) Schedule {
+	return func(Op) Fault {
+		return f
+	}
+}
+
+// Nth injects f into the n-th operation of the kind of every connection.
+func Nth(kind OpKind, n int, f Fault) Schedule {
+	return func(op Op) Fault {
+		if op.Kind == kind && op.N == n {
+			return f
+		}
+		return Fault{}
+	}
+}
+
+// OnConn applies s to the conn-th connection only.
+func OnConn(conn int, s Schedule) Schedule {
+	return func(op Op) Fault {
+		if op.Conn == conn {
+			return s(op)
+		}
+		return Fault{}
+	}
+}
+
+// Random injects f into the operations with the probability p. The faults
+// are the same for the same seed and the same sequence of operations.
+func Random(seed int64, p float64, f Fault) Schedule {
+	var mu sync.Mutex
+	rnd := rand.New(rand.NewSource(seed))
+	return func(Op) Fault {
+		mu.Lock()
+		defer mu.Unlock()
+		if rnd.Float64() < p {
+			return f
+		}
+		return Fault{}
+	}
+}
+
+// Combine merges the faults of the schedules: the latencies are summed,
+// the smallest MaxRead is taken and Drop and Corrupt are set if any of them is set.
+func Combine(schedules ...Schedule) Schedule {
+	return func(op Op) Fault {
+		var f Fault
+		for _, s := range schedules {
+			sf := s(op)
+			f.Latency += sf.Latency
+			f.Drop = f.Drop || sf.Drop
+			f.Corrupt = f.Corrupt || sf.Corrupt
+			if sf.MaxRead > 0 && (f.MaxRead == 0 || sf.MaxRead < f.MaxRead) {
+				f.MaxRead = sf.MaxRead
+			}
+		}
+		return f
+	}
+}
+
+// Dialer dials the connections injecting the scheduled faults, it implements
+// tarantool.Dialer.
+type Dialer struct {
+	// Dialer establishes the connections, a net.Dialer is used if it is nil.
+	Dialer tarantool.Dialer
+	// Schedule decides the faults, there are none if it is nil.
+	// Use SetSchedule to change it while the connections are in use.
+	Schedule Schedule
+
+	mu    sync.Mutex
+	conns int
+	live  map[*Conn]struct{}
+}
+
+// DialContext dials the address and returns the connection injecting the faults.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d.mu.Lock()
+	d.conns++
+	id := d.conns
+	d.mu.Unlock()
+
+	f := d.fault(Op{Conn: id, Kind: OpDial, N: 1})
+	if err := sleep(ctx.Done(), f.Latency); err != nil {
+		return nil, ctx.Err()
+	}
+	if f.Drop {
+		return nil, &net.OpError{Op: "dial", Net: network, Err: ErrInjected}
+	}
+
+	dialer := d.Dialer
+	if dialer == nil {
+		dialer = &net.Dialer{}
+	}
+	nc, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Conn{Conn: nc, dialer: d, id: id, closed: make(chan struct{})}
+	d.mu.Lock()
+	if d.live == nil {
+		d.live = make(map[*Conn]struct{})
+	}
+	d.live[c] = struct{}{}
+	d.mu.Unlock()
+	return c, nil
+}
+
+// Dials returns the number of the dials made, including the failed ones.
+func (d *Dialer) Dials() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.conns
+}
+
+// Drop closes all the open connections, like a network partition or a server restart would.
+func (d *Dialer) Drop() {
+	d.mu.Lock()
+	conns := make([]*Conn, 0, len(d.live))
+	for c := range d.live {
+		conns = append(conns, c)
+	}
+	d.mu.Unlock()
+
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// SetSchedule replaces the schedule of the new and the open connections.
+func (d *Dialer) SetSchedule(s Schedule) {
+	d.mu.Lock()
+	d.Schedule = s
+	d.mu.Unlock()
+}
+
+func (d *Dialer) fault(op Op) Fault {
+	d.mu.Lock()
+	s := d.Schedule
+	d.mu.Unlock()
+	if s == nil {
+		return Fault{}
+	}
+	return s(op)
+}
+
+// Conn is the connection injecting the faults into the reads and the writes.
+type Conn struct {
+	net.Conn
+	dialer *Dialer
+	id     int
+
+	mu     sync.Mutex
+	reads  int
+	writes int
+
+	closeOnce sync.Once
+	closed    chan struct{}
+}
+
+func (c *Conn) fault(kind OpKind) Fault {
+	c.mu.Lock()
+	op := Op{Conn: c.id, Kind: kind}
+	if kind == OpRead {
+		c.reads++
+		op.N = c.reads
+	} else {
+		c.writes++
+		op.N = c.writes
+	}
+	c.mu.Unlock()
+	return c.dialer.fault(op)
+}
+
+// inject delays the operation and drops the connection according to f
+func (c *Conn) inject(kind OpKind, f Fault) error {
+	if err := sleep(c.closed, f.Latency); err != nil {
+		return &net.OpError{Op: kind.String(), Net: c.LocalAddr().Network(), Err: net.ErrClosed}
+	}
+	if f.Drop {
+		c.Close()
+		return &net.OpError{Op: kind.String(), Net: c.LocalAddr().Network(), Err: ErrInjected}
+	}
+	return nil
+}
+
+// Read reads from the connection injecting the scheduled fault.
+func (c *Conn) Read(b []byte) (int, error) {
+	f := c.fault(OpRead)
+	if err := c.inject(OpRead, f); err != nil {
+		return 0, err
+	}
+	if f.MaxRead > 0 && len(b) > f.MaxRead {
+		b = b[:f.MaxRead]
+	}
+	n, err := c.Conn.Read(b)
+	if f.Corrupt && n > 0 {
+		b[0] ^= 0xff
+	}
+	return n, err
+}
+
+// Write writes to the connection injecting the scheduled fault.
+func (c *Conn) Write(b []byte) (int, error) {
+	f := c.fault(OpWrite)
+	if err := c.inject(OpWrite, f); err != nil {
+		return 0, err
+	}
+	if f.Corrupt && len(b) > 0 {
+		// the caller's buffer is not modified
+		b = append([]byte(nil), b...)
+		b[0] ^= 0xff
+	}
+	return c.Conn.Write(b)
+}
+
+// Close closes the connection.
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		c.dialer.mu.Lock()
+		delete(c.dialer.live, c)
+		c.dialer.mu.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+// sleep waits for d, it fails if done is closed first
+func sleep(done <-chan struct{}, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-done:
+		return net.ErrClosed
+	}
+}
@@ -0,0 +1,150 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+// newTestServer returns the address of a server answering the evaluations with 1
+func newTestServer(t *testing.T) string {
+	handler := func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+		if _, ok := q.(*tarantool.Eval); ok {
+			return &tarantool.Result{Data: [][]interface{}{{int64(1)}}}
+		}
+		return &tarantool.Result{}
+	}
+
+	return tnttest.NewServer(t, handler)
+}
+
+var eval = &tarantool.Eval{Expression: "return 1"}
+
+func TestLatency(t *testing.T) {
+	d := &Dialer{}
+	conn, err := tarantool.Connect(newTestServer(t), &tarantool.Options{
+		Dialer:       d,
+		QueryTimeout: 100 * time.Millisecond,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the next reads are slower than the query timeout
+	d.SetSchedule(Always(Fault{Latency: 300 * time.Millisecond}))
+	res := conn.Exec(context.Background(), eval)
+	assert.True(t, errors.Is(res.Error, tarantool.ErrRequestTimeout), "%v", res.Error)
+}
+
+func TestDrop(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	d := &Dialer{}
+	c := tarantool.New(newTestServer(t), &tarantool.Options{Dialer: d})
+	defer c.Close()
+
+	ctx := context.Background()
+	require.NoError(c.Exec(ctx, eval).Error)
+	require.Equal(1, d.Dials())
+
+	d.Drop()
+	// the idempotent query is retried on the new connection
+	res := c.Exec(ctx, eval, tarantool.IdempotentExecOption())
+	require.NoError(res.Error)
+	assert.Equal([][]interface{}{{int64(1)}}, res.Data)
+	assert.Equal(2, d.Dials())
+
+	// the first write of the third connection fails it, the fourth one succeeds
+	d.SetSchedule(OnConn(3, Nth(OpWrite, 1, Fault{Drop: true})))
+	d.Drop()
+	res = c.Exec(ctx, eval)
+	assert.True(errors.Is(res.Error, ErrInjected), "%v", res.Error)
+	require.NoError(c.Exec(ctx, eval).Error)
+	assert.Equal(4, d.Dials())
+}
+
+func TestDialFault(t *testing.T) {
+	d := &Dialer{Schedule: OnConn(1, Always(Fault{Drop: true}))}
+	addr := newTestServer(t)
+
+	_, err := tarantool.Connect(addr, &tarantool.Options{Dialer: d})
+	assert.True(t, errors.Is(err, ErrInjected), "%v", err)
+
+	conn, err := tarantool.Connect(addr, &tarantool.Options{Dialer: d})
+	require.NoError(t, err)
+	conn.Close()
+
+	d.SetSchedule(Always(Fault{Latency: time.Second}))
+	start := time.Now()
+	_, err = tarantool.Connect(addr, &tarantool.Options{Dialer: d, ConnectTimeout: 50 * time.Millisecond})
+	assert.Error(t, err)
+	assert.Less(t, int64(time.Since(start)), int64(time.Second))
+}
+
+func TestPartialReads(t *testing.T) {
+	d := &Dialer{Schedule: Always(Fault{MaxRead: 1})}
+	conn, err := tarantool.Connect(newTestServer(t), &tarantool.Options{Dialer: d})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	res := conn.Exec(context.Background(), eval)
+	require.NoError(t, res.Error)
+	assert.Equal(t, [][]interface{}{{int64(1)}}, res.Data)
+}
+
+func TestCorrupt(t *testing.T) {
+	d := &Dialer{}
+	conn, err := tarantool.Connect(newTestServer(t), &tarantool.Options{
+		Dialer:       d,
+		QueryTimeout: time.Second,
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// the response is read by byte, the sixth one starts the header after
+	// the frame length, and corrupting it breaks the frame
+	reads := 0
+	d.SetSchedule(func(op Op) Fault {
+		if op.Kind != OpRead {
+			return Fault{}
+		}
+		reads++
+		return Fault{MaxRead: 1, Corrupt: reads == 6}
+	})
+	res := conn.Exec(context.Background(), eval)
+	assert.Error(t, res.Error)
+	assert.Eventually(t, conn.IsClosed, time.Second, 10*time.Millisecond)
+}
+
+func TestSchedules(t *testing.T) {
+	assert := assert.New(t)
+
+	op := Op{Conn: 2, Kind: OpRead, N: 3}
+	assert.Equal(Fault{Drop: true}, Nth(OpRead, 3, Fault{Drop: true})(op))
+	assert.Equal(Fault{}, Nth(OpWrite, 3, Fault{Drop: true})(op))
+	assert.Equal(Fault{}, OnConn(1, Always(Fault{Drop: true}))(op))
+
+	f := Combine(
+		Always(Fault{Latency: time.Millisecond, MaxRead: 8}),
+		Always(Fault{Latency: time.Millisecond, MaxRead: 4, Corrupt: true}),
+	)(op)
+	assert.Equal(Fault{Latency: 2 * time.Millisecond, MaxRead: 4, Corrupt: true}, f)
+
+	faults := func(s Schedule) (n int) {
+		for i := 1; i <= 1000; i++ {
+			if s(Op{Conn: 1, Kind: OpRead, N: i}).Drop {
+				n++
+			}
+		}
+		return n
+	}
+	n := faults(Random(1, 0.1, Fault{Drop: true}))
+	assert.Equal(n, faults(Random(1, 0.1, Fault{Drop: true})))
+	assert.InDelta(100, n, 50)
+}
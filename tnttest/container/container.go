@@ -0,0 +1,316 @@
+// Package container starts Tarantool in a docker container for the integration
+// tests and returns the connection to it:
+//
+//	func TestUsers(t *testing.T) {
+//		c := container.New(t, &container.Options{Bootstrap: `box.schema.space.create('users')`})
+//		res := c.Conn.Exec(ctx, &tarantool.Insert{Space: "users", Tuple: []interface{}{1}})
+//		...
+//	}
+//
+// The container is run with the docker command, so only the docker CLI is
+// needed, and removed when the test completes. The instance listens on
+// a random port of the loopback interface.
+package container
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/viciious/go-tarantool"
+)
+
+const (
+	// DefaultImage is the docker image of Tarantool.
+	DefaultImage = "tarantool/tarantool"
+	// DefaultVersion is the tag of the image.
+	DefaultVersion = "2.10"
+	// DefaultStartTimeout limits the start of the container and the bootstrap.
+	DefaultStartTimeout = time.Minute
+)
+
+// ErrNoDocker is returned by Start if the docker command is not found.
+var ErrNoDocker = errors.New("docker command is not found")
+
+// port is the port the instance listens on in the container
+const port = "3301/tcp"
+
+// luaInit configures the instance, creates the user and runs the bootstrap
+// script, which is read from the environment as well
+const luaInit = `
+box.cfg{listen = 3301}
+box.once('tnttest', function()
+    local user, password = os.getenv('TNTTEST_USER'), os.getenv('TNTTEST_PASSWORD')
+    if user == nil or user == '' or user == 'guest' then
+        box.schema.user.grant('guest', 'super', nil, nil, {if_not_exists = true})
+    else
+        box.schema.user.create(user, {password = password, if_not_exists = true})
+        box.schema.user.grant(user, 'super', nil, nil, {if_not_exists = true})
+    end
+    local bootstrap = os.getenv('TNTTEST_BOOTSTRAP')
+    if bootstrap ~= nil and bootstrap ~= '' then
+        assert(loadstring(bootstrap, 'bootstrap'))()
+    end
+end)
+rawset(_G, 'tnttest_ready', true)
+`
+
+// luaReady checks that the bootstrap is done
+const luaReady = `return box.info.status == 'running' and rawget(_G, 'tnttest_ready') == true`
+
+// Options are the options of the container.
+type Options struct {
+	// Image is the docker image, DefaultImage is used if it is empty.
+	Image string
+	// Version is the tag of the image, DefaultVersion is used if it is empty.
+	Version string
+	// Bootstrap is the Lua script run once the instance is configured,
+	// e.g. creating the spaces.
+	Bootstrap string
+	// User is created with the super role and the password,
+	// the guest gets the role if it is empty.
+	User     string
+	Password string
+	// StartTimeout limits the start of the container and the bootstrap,
+	// DefaultStartTimeout is used if it is 0.
+	StartTimeout time.Duration
+	// Docker is the path of the docker command, it is looked up in PATH if empty.
+	Docker string
+	// ConnOptions are the options of the connection, the user and
+	// the password are set from the ones above.
+	ConnOptions *tarantool.Options
+}
+
+// Container is the running Tarantool container.
+type Container struct {
+	// ID is the id of the docker container.
+	ID string
+	// Addr is the address the instance is reachable at.
+	Addr string
+	// Conn is the connection to the instance.
+	Conn *tarantool.Connection
+
+	docker string
+}
+
+// New starts the container, which is removed when the test completes.
+// The test fails if the container doesn't start, and it is skipped
+// if docker is not installed. opts may be nil.
+func New(t testing.TB, opts *Options) *Container {
+	t.Helper()
+
+	c, err := Start(context.Background(), opts)
+	if errors.Is(err, ErrNoDocker) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := c.Terminate(context.Background()); err != nil {
+			t.Error(err)
+		}
+	})
+	return c
+}
+
+// Start starts the container and waits until the instance is bootstrapped.
+// The container is removed if it fails. opts may be nil.
+func Start(ctx context.Context, opts *Options) (*Container, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	timeout := opts.StartTimeout
+	if timeout <= 0 {
+		timeout = DefaultStartTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	docker, err := lookDocker(opts.Docker)
+	if err != nil {
+		return nil, err
+	}
+	c := &Container{docker: docker}
+
+	if err = c.create(ctx, opts); err != nil {
+		return nil, err
+	}
+	if err = c.start(ctx, opts); err != nil {
+		err = c.withLogs(err)
+		c.remove()
+		return nil, err
+	}
+	return c, nil
+}
+
+func lookDocker(docker string) (string, error) {
+	if docker == "" {
+		docker = "docker"
+	}
+	path, err := exec.LookPath(docker)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrNoDocker, err)
+	}
+	return path, nil
+}
+
+// create creates the container with the init script
+func (c *Container) create(ctx context.Context, opts *Options) error {
+	image, version := opts.Image, opts.Version
+	if image == "" {
+		image = DefaultImage
+	}
+	if version == "" {
+		version = DefaultVersion
+	}
+
+	id, err := c.run(ctx, "create",
+		"-p", "127.0.0.1::"+port,
+		"-e", "TNTTEST_USER="+opts.User,
+		"-e", "TNTTEST_PASSWORD="+opts.Password,
+		"-e", "TNTTEST_BOOTSTRAP="+opts.Bootstrap,
+		"--entrypoint", "tarantool",
+		image+":"+version,
+		"/opt/tnttest/init.lua")
+	if err != nil {
+		return err
+	}
+	c.ID = id
+
+	dir, err := os.MkdirTemp("", "tnttest")
+	if err != nil {
+		c.remove()
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	if err = os.WriteFile(filepath.Join(dir, "init.lua"), []byte(luaInit), 0644); err != nil {
+		c.remove()
+		return err
+	}
+	if _, err = c.run(ctx, "cp", dir, c.ID+":/opt/tnttest"); err != nil {
+		c.remove()
+		return err
+	}
+	return nil
+}
+
+// start starts the container and connects to it once it's ready
+func (c *Container) start(ctx context.Context, opts *Options) error {
+	if _, err := c.run(ctx, "start", c.ID); err != nil {
+		return err
+	}
+
+	out, err := c.run(ctx, "port", c.ID, port)
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(out, "\n") {
+		if _, _, err := net.SplitHostPort(strings.TrimSpace(line)); err == nil {
+			c.Addr = strings.TrimSpace(line)
+			break
+		}
+	}
+	if c.Addr == "" {
+		return fmt.Errorf("no address of port %s in %q", port, out)
+	}
+
+	connOpts := tarantool.Options{}
+	if opts.ConnOptions != nil {
+		connOpts = *opts.ConnOptions
+	}
+	connOpts.User, connOpts.Password = opts.User, opts.Password
+
+	for {
+		if c.Conn, err = c.connect(ctx, &connOpts); err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("instance is not ready: %w", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
+
+// connect returns the connection once the bootstrap is done
+func (c *Container) connect(ctx context.Context, opts *tarantool.Options) (*tarantool.Connection, error) {
+	conn, err := tarantool.ConnectContext(ctx, c.Addr, opts)
+	if err != nil {
+		return nil, err
+	}
+	res := conn.Exec(ctx, &tarantool.Eval{Expression: luaReady})
+	if res.Error != nil {
+		conn.Close()
+		return nil, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0]) == 0 || res.Data[0][0] != true {
+		conn.Close()
+		return nil, errors.New("bootstrap is not done")
+	}
+	return conn, nil
+}
+
+// Logs returns the output of the instance, which logs to stderr.
+func (c *Container) Logs(ctx context.Context) (string, error) {
+	out, err := exec.CommandContext(ctx, c.docker, "logs", c.ID).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("docker logs: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// withLogs adds the tail of the logs to the error of the start
+func (c *Container) withLogs(err error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	logs, lerr := c.Logs(ctx)
+	if lerr != nil || logs == "" {
+		return err
+	}
+	lines := strings.Split(logs, "\n")
+	if len(lines) > 20 {
+		lines = lines[len(lines)-20:]
+	}
+	return fmt.Errorf("%w\n%s", err, strings.Join(lines, "\n"))
+}
+
+// Terminate closes the connection and removes the container.
+func (c *Container) Terminate(ctx context.Context) error {
+	if c.Conn != nil {
+		c.Conn.Close()
+	}
+	_, err := c.run(ctx, "rm", "-f", "-v", c.ID)
+	return err
+}
+
+func (c *Container) remove() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	c.Terminate(ctx)
+}
+
+// run runs the docker command and returns its trimmed output
+func (c *Container) run(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.docker, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("docker %s: %s", args[0], msg)
+		}
+		return "", fmt.Errorf("docker %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,144 @@
+package container
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+// fakeDocker writes the docker command emulating a container with the
+// instance listening at addr, the commands are logged to the returned file
+func fakeDocker(t *testing.T, addr, start string) (string, string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake docker command is a shell script")
+	}
+
+	dir := t.TempDir()
+	log := filepath.Join(dir, "log")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "$@" >> %[1]s
+case "$1" in
+create) echo c0ffee ;;
+cp) cp "$2/init.lua" %[2]s ;;
+start) %[3]s ;;
+port) echo "%[4]s"; echo "[::1]:1" ;;
+logs) echo "tarantool is starting" >&2 ;;
+esac
+`, log, filepath.Join(dir, "init.lua"), start, addr)
+
+	docker := filepath.Join(dir, "docker")
+	require.NoError(t, os.WriteFile(docker, []byte(script), 0755))
+	return docker, log
+}
+
+// newFakeServer runs the instance which is ready after a few checks
+func newFakeServer(t *testing.T) string {
+	var checks int32
+	handler := func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+		if eval, ok := q.(*tarantool.Eval); ok && eval.Expression == luaReady {
+			return &tarantool.Result{Data: [][]interface{}{{atomic.AddInt32(&checks, 1) > 2}}}
+		}
+		return &tarantool.Result{}
+	}
+
+	return tnttest.NewServer(t, handler)
+}
+
+func readLog(t *testing.T, path string) []string {
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	return strings.Split(strings.TrimSpace(string(data)), "\n")
+}
+
+func TestStart(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	addr := newFakeServer(t)
+	docker, log := fakeDocker(t, addr, "")
+
+	c, err := Start(context.Background(), &Options{
+		Version:   "2.11",
+		Bootstrap: "box.schema.space.create('users')",
+		Docker:    docker,
+	})
+	require.NoError(err)
+	assert.Equal("c0ffee", c.ID)
+	assert.Equal(addr, c.Addr)
+	require.NoError(c.Conn.Exec(context.Background(), &tarantool.Ping{}).Error)
+
+	logs, err := c.Logs(context.Background())
+	require.NoError(err)
+	assert.Equal("tarantool is starting", logs)
+
+	require.NoError(c.Terminate(context.Background()))
+	assert.True(c.Conn.IsClosed())
+
+	init, err := os.ReadFile(filepath.Join(filepath.Dir(log), "init.lua"))
+	require.NoError(err)
+	assert.Equal(luaInit, string(init))
+
+	cmds := readLog(t, log)
+	require.Len(cmds, 6)
+	assert.Equal("create -p 127.0.0.1::3301/tcp -e TNTTEST_USER= -e TNTTEST_PASSWORD= "+
+		"-e TNTTEST_BOOTSTRAP=box.schema.space.create('users') --entrypoint tarantool "+
+		"tarantool/tarantool:2.11 /opt/tnttest/init.lua", cmds[0])
+	assert.True(strings.HasPrefix(cmds[1], "cp "))
+	assert.True(strings.HasSuffix(cmds[1], " c0ffee:/opt/tnttest"))
+	assert.Equal([]string{"start c0ffee", "port c0ffee 3301/tcp", "logs c0ffee", "rm -f -v c0ffee"}, cmds[2:])
+}
+
+func TestStartError(t *testing.T) {
+	assert := assert.New(t)
+
+	docker, log := fakeDocker(t, "", "echo 'no such image' >&2; exit 1")
+
+	_, err := Start(context.Background(), &Options{Docker: docker})
+	if assert.Error(err) {
+		assert.Equal("docker start: no such image\ntarantool is starting", err.Error())
+	}
+	// the container is removed
+	cmds := readLog(t, log)
+	assert.Equal("rm -f -v c0ffee", cmds[len(cmds)-1])
+
+	_, err = Start(context.Background(), &Options{Docker: filepath.Join(t.TempDir(), "docker")})
+	assert.ErrorIs(err, ErrNoDocker)
+}
+
+func TestStartTimeout(t *testing.T) {
+	assert := assert.New(t)
+
+	// nothing listens at the address
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	ln.Close()
+
+	docker, _ := fakeDocker(t, addr, "")
+	started := time.Now()
+	_, err = Start(context.Background(), &Options{Docker: docker, StartTimeout: 300 * time.Millisecond})
+	if assert.Error(err) {
+		assert.Contains(err.Error(), "instance is not ready")
+	}
+	assert.Less(int64(time.Since(started)), int64(5*time.Second))
+}
+
+func TestNewSkip(t *testing.T) {
+	ok := t.Run("skip", func(t *testing.T) {
+		New(t, &Options{Docker: filepath.Join(os.TempDir(), "no-such-docker")})
+		t.Error("the test is not skipped")
+	})
+	assert.True(t, ok)
+}
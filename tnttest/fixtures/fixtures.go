@@ -0,0 +1,322 @@
+// Package fixtures seeds the spaces from the YAML or JSON files and resets
+// them between the integration tests.
+//
+// A fixture file maps the space names to their rows, a row is the array of
+// the fields or the object of them named by the space format:
+//
+//	users:
+//	  - {id: 1, name: alice}
+//	  - [2, bob]
+//	orders:
+//	  - {id: 10, user_id: 1}
+//
+// The spaces are loaded in the order of the file, the values are converted to
+// the field types of the format from the schema cache, e.g. unsigned or varbinary.
+//
+//	f, err := fixtures.ReadFiles("testdata/users.yml")
+//	...
+//	err = f.Load(ctx, conn)
+//
+// Isolate snapshots the spaces on the server and restores them once the test
+// completes, which is faster than loading them again for every test.
+package fixtures
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/viciious/go-tarantool"
+	"gopkg.in/yaml.v3"
+)
+
+// luaTruncate truncates the spaces
+const luaTruncate = `
+for _, name in ipairs({...}) do
+    local s = box.space[name]
+    if s == nil then
+        error(string.format("space '%s' does not exist", name), 0)
+    end
+    s:truncate()
+end
+`
+
+// luaInsert inserts the rows into the space in a transaction
+const luaInsert = `
+local name, rows = ...
+local s = box.space[name]
+if s == nil then
+    error(string.format("space '%s' does not exist", name), 0)
+end
+box.begin()
+for _, row in ipairs(rows) do
+    local ok, err = pcall(s.insert, s, row)
+    if not ok then
+        box.rollback()
+        error(err)
+    end
+end
+box.commit()
+`
+
+// Conn is the connection with the schema cache, it is implemented by tarantool.Connection.
+type Conn interface {
+	tarantool.Executor
+	GetSpaceFields(space interface{}) ([]string, bool)
+	GetSpaceFieldTypes(space interface{}) ([]string, bool)
+}
+
+// Fixtures are the rows of the spaces.
+type Fixtures struct {
+	spaces []spaceRows
+}
+
+type spaceRows struct {
+	space string
+	rows  []interface{}
+}
+
+// ReadFiles reads the fixture files, the rows of a space in several files
+// are concatenated.
+func ReadFiles(paths ...string) (*Fixtures, error) {
+	f := &Fixtures{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err = f.parse(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return f, nil
+}
+
+// Parse parses the fixtures, JSON is parsed as YAML.
+func Parse(data []byte) (*Fixtures, error) {
+	f := &Fixtures{}
+	if err := f.parse(data); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *Fixtures) parse(data []byte) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	// the mapping node is decoded pair by pair to keep the order of the spaces
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("line %d: the spaces must be a mapping", root.Line)
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		var rows []interface{}
+		if err := root.Content[i+1].Decode(&rows); err != nil {
+			return fmt.Errorf("space '%s': %w", root.Content[i].Value, err)
+		}
+		f.add(root.Content[i].Value, rows)
+	}
+	return nil
+}
+
+func (f *Fixtures) add(space string, rows []interface{}) {
+	for i := range f.spaces {
+		if f.spaces[i].space == space {
+			f.spaces[i].rows = append(f.spaces[i].rows, rows...)
+			return
+		}
+	}
+	f.spaces = append(f.spaces, spaceRows{space: space, rows: rows})
+}
+
+// Spaces returns the names of the spaces in the order they are loaded.
+func (f *Fixtures) Spaces() []string {
+	spaces := make([]string, len(f.spaces))
+	for i, s := range f.spaces {
+		spaces[i] = s.space
+	}
+	return spaces
+}
+
+// Load truncates the spaces and inserts the rows, the rows of a space
+// are inserted in a transaction.
+func (f *Fixtures) Load(ctx context.Context, conn Conn) error {
+	tuples := make([][]interface{}, len(f.spaces))
+	for i, s := range f.spaces {
+		var err error
+		if tuples[i], err = makeTuples(conn, s.space, s.rows); err != nil {
+			return err
+		}
+	}
+
+	if err := Truncate(ctx, conn, f.Spaces()...); err != nil {
+		return err
+	}
+	for i, s := range f.spaces {
+		if len(tuples[i]) == 0 {
+			continue
+		}
+		res := conn.Exec(ctx, &tarantool.Eval{Expression: luaInsert, Tuple: []interface{}{s.space, tuples[i]}})
+		if res.Error != nil {
+			return fmt.Errorf("space '%s': %w", s.space, res.Error)
+		}
+	}
+	return nil
+}
+
+// Truncate deletes all the tuples of the spaces.
+func Truncate(ctx context.Context, conn tarantool.Executor, spaces ...string) error {
+	if len(spaces) == 0 {
+		return nil
+	}
+	args := make([]interface{}, len(spaces))
+	for i, s := range spaces {
+		args[i] = s
+	}
+	return conn.Exec(ctx, &tarantool.Eval{Expression: luaTruncate, Tuple: args}).Error
+}
+
+// makeTuples converts the rows to the tuples of the space format
+func makeTuples(conn Conn, space string, rows []interface{}) ([]interface{}, error) {
+	names, _ := conn.GetSpaceFields(space)
+	types, _ := conn.GetSpaceFieldTypes(space)
+
+	tuples := make([]interface{}, len(rows))
+	for i, r := range rows {
+		tuple, err := makeTuple(r, names, types)
+		if err != nil {
+			return nil, fmt.Errorf("space '%s' row %d: %w", space, i+1, err)
+		}
+		tuples[i] = tuple
+	}
+	return tuples, nil
+}
+
+func makeTuple(row interface{}, names, types []string) ([]interface{}, error) {
+	var tuple []interface{}
+
+	switch r := row.(type) {
+	case []interface{}:
+		tuple = append(tuple, r...)
+	case map[string]interface{}:
+		if len(names) == 0 {
+			return nil, fmt.Errorf("the space has no format for the named fields")
+		}
+		index := make(map[string]int, len(names))
+		for i, name := range names {
+			index[name] = i
+		}
+		keys := make([]string, 0, len(r))
+		for k := range r {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			i, ok := index[k]
+			if !ok {
+				return nil, fmt.Errorf("unknown field '%s'", k)
+			}
+			for len(tuple) <= i {
+				tuple = append(tuple, nil)
+			}
+			tuple[i] = r[k]
+		}
+	default:
+		return nil, fmt.Errorf("the row must be an array or a mapping, got %T", row)
+	}
+
+	for i, v := range tuple {
+		v = normalize(v)
+		tuple[i] = v
+		if i >= len(types) || v == nil {
+			continue
+		}
+		var err error
+		if tuple[i], err = convert(v, types[i]); err != nil {
+			if i < len(names) && names[i] != "" {
+				return nil, fmt.Errorf("field '%s': %w", names[i], err)
+			}
+			return nil, fmt.Errorf("field %d: %w", i+1, err)
+		}
+	}
+	return tuple, nil
+}
+
+// convert converts the decoded value to the field type
+func convert(v interface{}, typ string) (interface{}, error) {
+	switch strings.ToLower(typ) {
+	case "unsigned":
+		switch n := v.(type) {
+		case int:
+			if n < 0 {
+				return nil, fmt.Errorf("%d is not unsigned", n)
+			}
+			return uint64(n), nil
+		case uint64:
+			return n, nil
+		case float64:
+			if n < 0 || n != math.Trunc(n) {
+				return nil, fmt.Errorf("%v is not unsigned", n)
+			}
+			return uint64(n), nil
+		}
+	case "integer":
+		switch n := v.(type) {
+		case int:
+			return int64(n), nil
+		case uint64:
+			return n, nil
+		case float64:
+			if n != math.Trunc(n) {
+				return nil, fmt.Errorf("%v is not integer", n)
+			}
+			return int64(n), nil
+		}
+	case "double":
+		switch n := v.(type) {
+		case int:
+			return float64(n), nil
+		case uint64:
+			return float64(n), nil
+		}
+	case "number", "scalar", "any":
+		if n, ok := v.(int); ok {
+			return int64(n), nil
+		}
+	case "varbinary":
+		if s, ok := v.(string); ok {
+			return []byte(s), nil
+		}
+	}
+	return v, nil
+}
+
+// normalize converts the mappings with the non-string keys, which can't be encoded
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = normalize(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = normalize(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = normalize(e)
+		}
+	}
+	return v
+}
@@ -0,0 +1,229 @@
+package fixtures
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+// fakeServer keeps the tuples of the spaces and executes the scripts of the package
+type fakeServer struct {
+	mu        sync.Mutex
+	spaces    map[string][]interface{}
+	snapshots map[string]map[string][]interface{}
+}
+
+func (s *fakeServer) handle(ctx context.Context, q tarantool.Query) *tarantool.Result {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch q := q.(type) {
+	case *tarantool.Select:
+		if q.Space != tarantool.ViewSpace {
+			return &tarantool.Result{}
+		}
+		return &tarantool.Result{Data: [][]interface{}{
+			{uint64(512), uint64(1), "users", "memtx", uint64(0), map[string]interface{}{}, []interface{}{
+				map[string]interface{}{"name": "id", "type": "unsigned"},
+				map[string]interface{}{"name": "name", "type": "string"},
+				map[string]interface{}{"name": "avatar", "type": "varbinary", "is_nullable": true},
+				map[string]interface{}{"name": "score", "type": "double", "is_nullable": true},
+			}},
+			{uint64(513), uint64(1), "events", "memtx", uint64(0), map[string]interface{}{}, []interface{}{}},
+		}}
+	case *tarantool.Eval:
+		return s.eval(q)
+	}
+	return &tarantool.Result{}
+}
+
+func (s *fakeServer) eval(q *tarantool.Eval) *tarantool.Result {
+	switch q.Expression {
+	case luaTruncate:
+		for _, name := range q.Tuple {
+			if _, ok := s.spaces[name.(string)]; !ok {
+				return fail("space '" + name.(string) + "' does not exist")
+			}
+			s.spaces[name.(string)] = nil
+		}
+	case luaInsert:
+		name := q.Tuple[0].(string)
+		seen := map[interface{}]bool{}
+		for _, t := range s.spaces[name] {
+			seen[t.([]interface{})[0]] = true
+		}
+		for _, t := range q.Tuple[1].([]interface{}) {
+			if seen[t.([]interface{})[0]] {
+				return fail("Duplicate key exists in unique index 'primary'")
+			}
+			seen[t.([]interface{})[0]] = true
+		}
+		s.spaces[name] = append(s.spaces[name], q.Tuple[1].([]interface{})...)
+	case luaSnapshot:
+		snapshot := map[string][]interface{}{}
+		for _, name := range q.Tuple[1].([]interface{}) {
+			snapshot[name.(string)] = append([]interface{}(nil), s.spaces[name.(string)]...)
+		}
+		s.snapshots[q.Tuple[0].(string)] = snapshot
+	case luaRestore:
+		snapshot, ok := s.snapshots[q.Tuple[0].(string)]
+		if !ok {
+			return fail("snapshot does not exist")
+		}
+		for name, tuples := range snapshot {
+			s.spaces[name] = append([]interface{}(nil), tuples...)
+		}
+	case luaRelease:
+		delete(s.snapshots, q.Tuple[0].(string))
+	}
+	return &tarantool.Result{}
+}
+
+func fail(msg string) *tarantool.Result {
+	return &tarantool.Result{ErrorCode: tarantool.ErrProcLua, Error: tarantool.NewQueryError(tarantool.ErrProcLua, msg)}
+}
+
+func (s *fakeServer) tuples(space string) []interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.spaces[space]
+}
+
+func newConn(t *testing.T) (*tarantool.Connection, *fakeServer) {
+	s := &fakeServer{
+		spaces:    map[string][]interface{}{"users": nil, "events": nil},
+		snapshots: map[string]map[string][]interface{}{},
+	}
+	addr := tnttest.NewServer(t, s.handle)
+
+	conn := tnttest.Connect(t, addr, nil)
+	return conn, s
+}
+
+func TestLoad(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir := t.TempDir()
+	users := filepath.Join(dir, "users.yml")
+	require.NoError(os.WriteFile(users, []byte(`
+users:
+  - {id: 1, name: alice, avatar: png, score: 5}
+  - [2, bob]
+events:
+  - [1, login, {ip: 127.0.0.1, tags: [a, b]}]
+`), 0644))
+	more := filepath.Join(dir, "more.json")
+	require.NoError(os.WriteFile(more, []byte(`{"users": [{"name": "carol", "id": 3}]}`), 0644))
+
+	f, err := ReadFiles(users, more)
+	require.NoError(err)
+	assert.Equal([]string{"users", "events"}, f.Spaces())
+
+	conn, s := newConn(t)
+	ctx := context.Background()
+	require.NoError(f.Load(ctx, conn))
+
+	assert.Equal([]interface{}{
+		[]interface{}{int64(1), "alice", []byte("png"), float64(5)},
+		[]interface{}{int64(2), "bob"},
+		[]interface{}{int64(3), "carol"},
+	}, s.tuples("users"))
+	assert.Equal([]interface{}{
+		[]interface{}{int64(1), "login", map[string]interface{}{"ip": "127.0.0.1", "tags": []interface{}{"a", "b"}}},
+	}, s.tuples("events"))
+
+	// the spaces are truncated before the load
+	require.NoError(f.Load(ctx, conn))
+	assert.Len(s.tuples("users"), 3)
+}
+
+func TestLoadErrors(t *testing.T) {
+	conn, _ := newConn(t)
+	ctx := context.Background()
+
+	for _, tc := range []struct {
+		data, err string
+	}{
+		{`[1, 2]`, "line 1: the spaces must be a mapping"},
+		{`users: [{id: 1, nick: a}]`, "space 'users' row 1: unknown field 'nick'"},
+		{`users: [{id: -1}]`, "space 'users' row 1: field 'id': -1 is not unsigned"},
+		{`events: [{id: 1}]`, "space 'events' row 1: the space has no format for the named fields"},
+		{`users: [1]`, "space 'users' row 1: the row must be an array or a mapping, got int"},
+		{`users: [[1, a], [1, b]]`, "space 'users': Duplicate key exists in unique index 'primary'"},
+		{`missing: [[1]]`, "space 'missing' does not exist"},
+	} {
+		f, err := Parse([]byte(tc.data))
+		if err == nil {
+			err = f.Load(ctx, conn)
+		}
+		if assert.Error(t, err, tc.data) {
+			assert.Contains(t, err.Error(), tc.err, tc.data)
+		}
+	}
+}
+
+func TestIsolate(t *testing.T) {
+	conn, s := newConn(t)
+	ctx := context.Background()
+
+	f, err := Parse([]byte(`users: [[1, alice]]`))
+	require.NoError(t, err)
+	require.NoError(t, f.Load(ctx, conn))
+
+	t.Run("changes", func(t *testing.T) {
+		Isolate(t, conn, "users", "events")
+
+		f, err := Parse([]byte(`users: [[2, bob]]`))
+		require.NoError(t, err)
+		require.NoError(t, f.Load(ctx, conn))
+		require.Equal(t, []interface{}{[]interface{}{int64(2), "bob"}}, s.tuples("users"))
+	})
+
+	require.Equal(t, []interface{}{[]interface{}{int64(1), "alice"}}, s.tuples("users"))
+	require.Empty(t, s.snapshots)
+
+	snapshot, err := TakeSnapshot(ctx, conn, "users")
+	require.NoError(t, err)
+	require.NoError(t, Truncate(ctx, conn, "users"))
+	require.Empty(t, s.tuples("users"))
+	require.NoError(t, snapshot.Restore(ctx))
+	require.NoError(t, snapshot.Restore(ctx))
+	require.Len(t, s.tuples("users"), 1)
+	require.NoError(t, snapshot.Release(ctx))
+	require.Error(t, snapshot.Restore(ctx))
+}
+
+func TestMakeTuple(t *testing.T) {
+	assert := assert.New(t)
+
+	names := []string{"id", "balance", "ratio", "data", "extra"}
+	types := []string{"unsigned", "integer", "double", "varbinary", "any"}
+
+	tuple, err := makeTuple(map[string]interface{}{
+		"id":      1,
+		"balance": -5,
+		"ratio":   2,
+		"data":    "raw",
+		"extra":   map[interface{}]interface{}{1: "one"},
+	}, names, types)
+	if assert.NoError(err) {
+		assert.Equal([]interface{}{uint64(1), int64(-5), float64(2), []byte("raw"), map[string]interface{}{"1": "one"}}, tuple)
+	}
+
+	// the fields beyond the format are kept as is
+	tuple, err = makeTuple([]interface{}{1.0, nil, 1.5, nil, 3, "tail"}, names, types)
+	if assert.NoError(err) {
+		assert.Equal([]interface{}{uint64(1), nil, 1.5, nil, int64(3), "tail"}, tuple)
+	}
+
+	_, err = makeTuple([]interface{}{1, 1.5}, names, types)
+	assert.EqualError(err, "field 'balance': 1.5 is not integer")
+}
@@ -0,0 +1,118 @@
+package fixtures
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/viciious/go-tarantool"
+)
+
+// luaSnapshot keeps the tuples of the spaces in the memory of the server,
+// the tuples are immutable, so only the references to them are kept
+const luaSnapshot = `
+local id, spaces = ...
+local snapshot = {}
+for i, name in ipairs(spaces) do
+    local s = box.space[name]
+    if s == nil then
+        error(string.format("space '%s' does not exist", name), 0)
+    end
+    snapshot[i] = {name, s:select({}, {iterator = 'ALL'})}
+end
+local snapshots = rawget(_G, '__tnttest_snapshots')
+if snapshots == nil then
+    snapshots = {}
+    rawset(_G, '__tnttest_snapshots', snapshots)
+end
+snapshots[id] = snapshot
+`
+
+// luaRestore truncates the spaces and inserts the tuples of the snapshot
+const luaRestore = `
+local id = ...
+local snapshots = rawget(_G, '__tnttest_snapshots')
+local snapshot = snapshots and snapshots[id]
+if snapshot == nil then
+    error(string.format("snapshot '%s' does not exist", id), 0)
+end
+for _, space in ipairs(snapshot) do
+    box.space[space[1]]:truncate()
+end
+box.begin()
+for _, space in ipairs(snapshot) do
+    local s = box.space[space[1]]
+    for _, t in ipairs(space[2]) do
+        local ok, err = pcall(s.insert, s, t)
+        if not ok then
+            box.rollback()
+            error(err)
+        end
+    end
+end
+box.commit()
+`
+
+// luaRelease drops the snapshot
+const luaRelease = `
+local snapshots = rawget(_G, '__tnttest_snapshots')
+if snapshots ~= nil then
+    snapshots[...] = nil
+end
+`
+
+// DefaultRestoreTimeout limits the restore of the snapshot by Isolate.
+const DefaultRestoreTimeout = 30 * time.Second
+
+// Snapshot is the state of the spaces kept on the server.
+type Snapshot struct {
+	conn tarantool.Executor
+	id   string
+}
+
+// TakeSnapshot keeps the tuples of the spaces on the server until
+// the snapshot is released.
+func TakeSnapshot(ctx context.Context, conn tarantool.Executor, spaces ...string) (*Snapshot, error) {
+	s := &Snapshot{conn: conn, id: uuid.NewString()}
+	if spaces == nil {
+		spaces = []string{}
+	}
+	res := conn.Exec(ctx, &tarantool.Eval{Expression: luaSnapshot, Tuple: []interface{}{s.id, spaces}})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return s, nil
+}
+
+// Restore brings the spaces back to the state of the snapshot,
+// it may be called any number of times.
+func (s *Snapshot) Restore(ctx context.Context) error {
+	return s.conn.Exec(ctx, &tarantool.Eval{Expression: luaRestore, Tuple: []interface{}{s.id}}).Error
+}
+
+// Release frees the memory of the snapshot.
+func (s *Snapshot) Release(ctx context.Context) error {
+	return s.conn.Exec(ctx, &tarantool.Eval{Expression: luaRelease, Tuple: []interface{}{s.id}}).Error
+}
+
+// Isolate snapshots the spaces and restores them once the test completes,
+// so the changes made by the test don't affect the other ones.
+func Isolate(t testing.TB, conn tarantool.Executor, spaces ...string) {
+	t.Helper()
+
+	s, err := TakeSnapshot(context.Background(), conn, spaces...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), DefaultRestoreTimeout)
+		defer cancel()
+		if err := s.Restore(ctx); err != nil {
+			t.Error(err)
+		}
+		if err := s.Release(ctx); err != nil {
+			t.Error(err)
+		}
+	})
+}
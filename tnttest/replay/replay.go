@@ -0,0 +1,248 @@
+// Package replay records the queries and the responses of a live server to
+// golden files and serves them back, so the tests of complex data flows are
+// deterministic and don't need the server:
+//
+//	func TestOrders(t *testing.T) {
+//		conn := replay.Golden(t, "testdata/orders.json", func() replay.Executor {
+//			return tarantool.New("127.0.0.1:3301", nil)
+//		})
+//		...
+//	}
+//
+// The test records the golden file if TNTTEST_RECORD is set and replays it
+// otherwise. The queries are matched by type and content, a query recorded several
+// times gets the responses in the recorded order.
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// RecordEnv is the environment variable switching Golden to the record mode.
+const RecordEnv = "TNTTEST_RECORD"
+
+// ErrNotRecorded is returned by Replayer for the queries missing from the recording.
+var ErrNotRecorded = errors.New("query is not recorded")
+
+// Interaction is a recorded query and its response.
+type Interaction struct {
+	// Type is the Go type of the query, e.g. *tarantool.Select.
+	Type  string          `json:"type"`
+	Query json.RawMessage `json:"query"`
+	// Error is the error message for reading, the response carries it as well.
+	Error     string `json:"error,omitempty"`
+	ErrorCode uint   `json:"error_code,omitempty"`
+	// Response is the msgpack encoded result, so the replayed data
+	// is decoded to the same types as the live one.
+	Response []byte `json:"response"`
+}
+
+func (i *Interaction) matches(typ string, query []byte) bool {
+	return i.Type == typ && string(i.Query) == string(query)
+}
+
+func encodeQuery(q tarantool.Query) (string, []byte, error) {
+	body, err := json.Marshal(q)
+	if err != nil {
+		return "", nil, fmt.Errorf("encode %T: %w", q, err)
+	}
+	return fmt.Sprintf("%T", q), body, nil
+}
+
+// Recorder executes the queries with the connection and records them with the responses.
+type Recorder struct {
+	conn tarantool.Executor
+
+	mu           sync.Mutex
+	interactions []Interaction
+	err          error
+}
+
+// NewRecorder returns the Recorder executing the queries with conn.
+func NewRecorder(conn tarantool.Executor) *Recorder {
+	return &Recorder{conn: conn}
+}
+
+// Exec executes q and records it with the result. The errors other than
+// the server ones, e.g. the network errors, are not recorded.
+func (r *Recorder) Exec(ctx context.Context, q tarantool.Query, options ...tarantool.ExecOption) *tarantool.Result {
+	res := r.conn.Exec(ctx, q, options...)
+
+	var qerr *tarantool.QueryError
+	if res.Error != nil && !(errors.As(res.Error, &qerr) && qerr.Request != nil) {
+		return res
+	}
+
+	i := Interaction{ErrorCode: res.ErrorCode}
+	typ, query, err := encodeQuery(q)
+	if err == nil {
+		i.Type, i.Query = typ, query
+		recorded := res
+		if qerr != nil {
+			// the client annotates the server error with the request details,
+			// only the server message is replayed
+			server := *qerr
+			server.Request = nil
+			i.Error = server.Error()
+			recorded = &tarantool.Result{ErrorCode: server.Code, Error: &server}
+		}
+		i.Response, err = recorded.MarshalMsg(nil)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		if r.err == nil {
+			r.err = err
+		}
+		return res
+	}
+	r.interactions = append(r.interactions, i)
+	return res
+}
+
+// Interactions returns the recorded interactions in the order of the responses.
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.interactions...)
+}
+
+// Save writes the recording to the file, creating its directory. It fails
+// if any query or result could not be encoded.
+func (r *Recorder) Save(path string) error {
+	r.mu.Lock()
+	err := r.err
+	r.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(r.Interactions(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Replayer serves the recorded responses to the matching queries.
+type Replayer struct {
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer returns the Replayer of the interactions.
+func NewReplayer(interactions []Interaction) *Replayer {
+	return &Replayer{
+		interactions: interactions,
+		used:         make([]bool, len(interactions)),
+	}
+}
+
+// Load returns the Replayer of the recording saved to the file.
+func Load(path string) (*Replayer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var interactions []Interaction
+	if err := json.Unmarshal(data, &interactions); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// the queries are indented in the file and matched in the compact form
+	for n := range interactions {
+		var query bytes.Buffer
+		if err := json.Compact(&query, interactions[n].Query); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		interactions[n].Query = query.Bytes()
+	}
+	return NewReplayer(interactions), nil
+}
+
+// Exec returns the response recorded for the first not yet replayed query matching q.
+func (p *Replayer) Exec(ctx context.Context, q tarantool.Query, options ...tarantool.ExecOption) *tarantool.Result {
+	typ, query, err := encodeQuery(q)
+	if err != nil {
+		return &tarantool.Result{Error: err, ErrorCode: tarantool.ErrIllegalParams}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for n := range p.interactions {
+		i := &p.interactions[n]
+		if p.used[n] || !i.matches(typ, query) {
+			continue
+		}
+		p.used[n] = true
+
+		res := &tarantool.Result{ErrorCode: i.ErrorCode}
+		if _, err := res.UnmarshalMsg(i.Response); err != nil {
+			return &tarantool.Result{Error: err, ErrorCode: tarantool.ErrInvalidMsgpack}
+		}
+		return res
+	}
+	return &tarantool.Result{
+		Error:     fmt.Errorf("%w: %s %s", ErrNotRecorded, typ, query),
+		ErrorCode: tarantool.ErrIllegalParams,
+	}
+}
+
+// Unused returns the interactions which have not been replayed.
+func (p *Replayer) Unused() []Interaction {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var unused []Interaction
+	for n, used := range p.used {
+		if !used {
+			unused = append(unused, p.interactions[n])
+		}
+	}
+	return unused
+}
+
+// Golden returns the tarantool.Executor for the test. If RecordEnv is set, it executes
+// the queries with the connection returned by connect and saves them to the
+// golden file when the test ends. Otherwise it replays the golden file and
+// fails the test if some of the recorded queries have not been executed.
+func Golden(t testing.TB, path string, connect func() tarantool.Executor) tarantool.Executor {
+	t.Helper()
+
+	if os.Getenv(RecordEnv) != "" {
+		r := NewRecorder(connect())
+		t.Cleanup(func() {
+			if err := r.Save(path); err != nil {
+				t.Errorf("save %s: %s", path, err)
+			}
+		})
+		return r
+	}
+
+	p, err := Load(path)
+	if err != nil {
+		t.Fatalf("load %s: %s, set %s to record it", path, err, RecordEnv)
+	}
+	t.Cleanup(func() {
+		if unused := p.Unused(); len(unused) != 0 {
+			t.Errorf("%s: %d recorded queries have not been executed, the first is %s %s",
+				path, len(unused), unused[0].Type, unused[0].Query)
+		}
+	})
+	return p
+}
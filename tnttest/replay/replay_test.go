@@ -0,0 +1,114 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/viciious/go-tarantool"
+	"github.com/viciious/go-tarantool/tnttest"
+)
+
+// newTestConn returns the connection to a server answering the evaluations
+// with a counter of them, and failing "error()"
+func newTestConn(t *testing.T) *tarantool.Connection {
+	var mu sync.Mutex
+	var n int64
+
+	handler := func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+		eval, ok := q.(*tarantool.Eval)
+		if !ok {
+			return &tarantool.Result{}
+		}
+		if eval.Expression == "error()" {
+			return &tarantool.Result{ErrorCode: tarantool.ErrProcLua, Error: tarantool.NewQueryError(tarantool.ErrProcLua, "boom")}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		n++
+		return &tarantool.Result{Data: [][]interface{}{{n, uint64(1) << 63, "text", []byte("bin"), map[string]interface{}{"k": []interface{}{1.5}}}}}
+	}
+
+	addr := tnttest.NewServer(t, handler)
+
+	conn := tnttest.Connect(t, addr, nil)
+	return conn
+}
+
+func TestRecordReplay(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "golden", "eval.json")
+
+	r := NewRecorder(newTestConn(t))
+	var live []*tarantool.Result
+	for _, q := range []tarantool.Query{
+		&tarantool.Eval{Expression: "return ..."},
+		&tarantool.Eval{Expression: "return ...", Tuple: []interface{}{"arg"}},
+		&tarantool.Eval{Expression: "return ..."},
+		&tarantool.Eval{Expression: "error()"},
+	} {
+		live = append(live, r.Exec(ctx, q))
+	}
+	require.NoError(live[0].Error)
+	require.Error(live[3].Error)
+	require.Len(r.Interactions(), 4)
+	assert.Equal("boom", r.Interactions()[3].Error)
+	require.NoError(r.Save(path))
+
+	p, err := Load(path)
+	require.NoError(err)
+
+	// the repeated query gets the responses in the recorded order
+	res := p.Exec(ctx, &tarantool.Eval{Expression: "return ..."})
+	require.NoError(res.Error)
+	assert.Equal(live[0].Data, res.Data)
+	res = p.Exec(ctx, &tarantool.Eval{Expression: "return ..."})
+	require.NoError(res.Error)
+	assert.Equal(live[2].Data, res.Data)
+
+	res = p.Exec(ctx, &tarantool.Eval{Expression: "error()"})
+	var qerr *tarantool.QueryError
+	require.True(errors.As(res.Error, &qerr))
+	assert.Equal(tarantool.ErrProcLua, qerr.Code)
+	assert.EqualError(qerr, "boom")
+	assert.Equal(tarantool.ErrProcLua, res.ErrorCode)
+
+	res = p.Exec(ctx, &tarantool.Eval{Expression: "return ..."})
+	assert.True(errors.Is(res.Error, ErrNotRecorded))
+
+	unused := p.Unused()
+	require.Len(unused, 1)
+	assert.JSONEq(`{"Expression": "return ...", "Tuple": ["arg"]}`, string(unused[0].Query))
+}
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "golden.json")
+	var recorded [][]interface{}
+
+	os.Setenv(RecordEnv, "1")
+	t.Run("record", func(t *testing.T) {
+		conn := Golden(t, path, func() tarantool.Executor { return newTestConn(t) })
+		res := conn.Exec(context.Background(), &tarantool.Eval{Expression: "return 1"})
+		require.NoError(t, res.Error)
+		recorded = res.Data
+	})
+	os.Unsetenv(RecordEnv)
+
+	t.Run("replay", func(t *testing.T) {
+		conn := Golden(t, path, func() tarantool.Executor {
+			t.Fatal("the server is not needed for replay")
+			return nil
+		})
+		res := conn.Exec(context.Background(), &tarantool.Eval{Expression: "return 1"})
+		require.NoError(t, res.Error)
+		assert.Equal(t, recorded, res.Data)
+	})
+}
@@ -0,0 +1,66 @@
+// Package tnttest runs the fake servers for the tests of the code using the
+// connector without Tarantool:
+//
+//	addr := tnttest.NewServer(t, func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+//		return &tarantool.Result{Data: [][]interface{}{{int64(1)}}}
+//	})
+//	conn := tnttest.Connect(t, addr, nil)
+//
+// The servers answer the schema requests of Connect with the handler too,
+// an empty Result for them leaves the schema cache empty.
+//
+// The subpackages seed the spaces of the integration tests, replay the recorded
+// replies, inject the network faults and run Tarantool in a container.
+package tnttest
+
+import (
+	"net"
+	"testing"
+
+	"github.com/viciious/go-tarantool"
+)
+
+// NewServer runs the server answering the queries with handler on a local port
+// and returns its address. The server is closed with the test.
+func NewServer(t testing.TB, handler tarantool.QueryHandler) string {
+	t.Helper()
+	return NewServerFunc(t, func() *tarantool.IprotoServer {
+		return tarantool.NewIprotoServer("", handler, nil)
+	})
+}
+
+// NewServerFunc is NewServer with the server returned by newServer for every
+// accepted connection, e.g. to send the events to the connection.
+func NewServerFunc(t testing.TB, newServer func() *tarantool.IprotoServer) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			newServer().Accept(c)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+// Connect connects to the server with the options, which may be nil.
+// The connection is closed with the test.
+func Connect(t testing.TB, addr string, opts *tarantool.Options) *tarantool.Connection {
+	t.Helper()
+
+	conn, err := tarantool.Connect(addr, opts)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(conn.Close)
+	return conn
+}
@@ -0,0 +1,28 @@
+package tnttest
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/viciious/go-tarantool"
+)
+
+func TestNewServer(t *testing.T) {
+	assert := assert.New(t)
+
+	addr := NewServer(t, func(ctx context.Context, q tarantool.Query) *tarantool.Result {
+		if eval, ok := q.(*tarantool.Eval); ok {
+			return &tarantool.Result{Data: [][]interface{}{{eval.Expression}}}
+		}
+		return &tarantool.Result{}
+	})
+
+	for i := 0; i < 2; i++ {
+		conn := Connect(t, addr, nil)
+		res := conn.Exec(context.Background(), &tarantool.Eval{Expression: "return 1"})
+		if assert.NoError(res.Error) {
+			assert.Equal([][]interface{}{{"return 1"}}, res.Data)
+		}
+	}
+}
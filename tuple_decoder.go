@@ -0,0 +1,239 @@
+package tarantool
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var (
+	errFieldMissing  = errors.New("field is missing")
+	errFieldExtra    = errors.New("unexpected extra field")
+	errFieldNil      = errors.New("unexpected nil")
+	errFieldOverflow = errors.New("value overflows the destination")
+)
+
+// FieldError describes the tuple field which can not be decoded.
+type FieldError struct {
+	Index int
+	Name  string // struct field name, if decoded into a struct
+	Err   error
+}
+
+func (e *FieldError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("field %d (%s): %s", e.Index, e.Name, e.Err)
+	}
+	return fmt.Sprintf("field %d: %s", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// TupleDecoder decodes tuple fields into typed destinations.
+// By default missing fields, nil values and values of a mismatching type leave
+// the destination zeroed, and extra fields are ignored. In Strict mode all of
+// them fail the decoding with a *FieldError, so schema drift is caught early.
+// Integers fit into any integer destination as long as they don't overflow it,
+// and into floats.
+type TupleDecoder struct {
+	Strict bool
+}
+
+// Decode assigns the tuple fields to the dest pointers in order.
+func (d TupleDecoder) Decode(tuple []interface{}, dest ...interface{}) error {
+	values := make([]reflect.Value, len(dest))
+	for i, p := range dest {
+		v := reflect.ValueOf(p)
+		if v.Kind() != reflect.Ptr || v.IsNil() {
+			return fmt.Errorf("destination %d is not a non-nil pointer", i)
+		}
+		values[i] = v.Elem()
+	}
+	return d.decode(tuple, values, nil)
+}
+
+// DecodeRaw is the same as Decode for a raw msgpack tuple.
+func (d TupleDecoder) DecodeRaw(t RawTuple, dest ...interface{}) error {
+	v, _, err := readIntfBytes(t)
+	if err != nil {
+		return err
+	}
+	tuple, ok := v.([]interface{})
+	if !ok {
+		return ErrBadResult
+	}
+	return d.Decode(tuple, dest...)
+}
+
+// DecodeStruct assigns the tuple fields to the exported fields of the struct
+// pointed by v in order of declaration. Fields tagged with `tarantool:"-"` are
+// skipped, the tag value is used as the field name in errors otherwise.
+func (d TupleDecoder) DecodeStruct(tuple []interface{}, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("destination is not a non-nil pointer to struct")
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var values []reflect.Value
+	var names []string
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Tag.Get("tarantool")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		values = append(values, rv.Field(i))
+		names = append(names, name)
+	}
+	return d.decode(tuple, values, names)
+}
+
+func (d TupleDecoder) decode(tuple []interface{}, values []reflect.Value, names []string) error {
+	fieldError := func(i int, err error) error {
+		fe := &FieldError{Index: i, Err: err}
+		if i < len(names) {
+			fe.Name = names[i]
+		}
+		return fe
+	}
+
+	if d.Strict && len(tuple) > len(values) {
+		return fieldError(len(values), errFieldExtra)
+	}
+
+	for i, dst := range values {
+		if i >= len(tuple) {
+			if d.Strict {
+				return fieldError(i, errFieldMissing)
+			}
+			dst.Set(reflect.Zero(dst.Type()))
+			continue
+		}
+		if err := d.assign(dst, tuple[i]); err != nil {
+			return fieldError(i, err)
+		}
+	}
+	return nil
+}
+
+// assign stores the decoded msgpack value into dst
+func (d TupleDecoder) assign(dst reflect.Value, v interface{}) error {
+	if v == nil {
+		switch dst.Kind() {
+		case reflect.Interface, reflect.Ptr, reflect.Slice, reflect.Map:
+		default:
+			if d.Strict {
+				return errFieldNil
+			}
+		}
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+
+	var err error
+
+	switch dst.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch n := v.(type) {
+		case int64:
+			err = setInt(dst, n)
+		case uint64:
+			if n > 1<<63-1 {
+				err = errFieldOverflow
+			} else {
+				err = setInt(dst, int64(n))
+			}
+		default:
+			err = mismatch(dst, v)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch n := v.(type) {
+		case uint64:
+			err = setUint(dst, n)
+		case int64:
+			if n < 0 {
+				err = errFieldOverflow
+			} else {
+				err = setUint(dst, uint64(n))
+			}
+		default:
+			err = mismatch(dst, v)
+		}
+	case reflect.Float32, reflect.Float64:
+		switch n := v.(type) {
+		case float64:
+			dst.SetFloat(n)
+		case float32:
+			dst.SetFloat(float64(n))
+		case int64:
+			dst.SetFloat(float64(n))
+		case uint64:
+			dst.SetFloat(float64(n))
+		default:
+			err = mismatch(dst, v)
+		}
+	case reflect.String:
+		if s, ok := v.(string); ok {
+			dst.SetString(s)
+		} else {
+			err = mismatch(dst, v)
+		}
+	case reflect.Bool:
+		if b, ok := v.(bool); ok {
+			dst.SetBool(b)
+		} else {
+			err = mismatch(dst, v)
+		}
+	default:
+		if dst.Kind() == reflect.Slice && dst.Type().Elem().Kind() == reflect.Uint8 {
+			// strings are accepted for binary destinations since
+			// binary data is often stored in string fields
+			if s, ok := v.(string); ok {
+				dst.SetBytes([]byte(s))
+				return nil
+			}
+		}
+		if rv := reflect.ValueOf(v); rv.Type().AssignableTo(dst.Type()) {
+			dst.Set(rv)
+		} else {
+			err = mismatch(dst, v)
+		}
+	}
+
+	if err != nil && !d.Strict {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	return err
+}
+
+func setInt(dst reflect.Value, n int64) error {
+	if dst.OverflowInt(n) {
+		return errFieldOverflow
+	}
+	dst.SetInt(n)
+	return nil
+}
+
+func setUint(dst reflect.Value, n uint64) error {
+	if dst.OverflowUint(n) {
+		return errFieldOverflow
+	}
+	dst.SetUint(n)
+	return nil
+}
+
+func mismatch(dst reflect.Value, v interface{}) error {
+	return fmt.Errorf("can not decode %T into %s", v, dst.Type())
+}
@@ -0,0 +1,76 @@
+package tarantool
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tinylib/msgp/msgp"
+)
+
+type decoderUser struct {
+	ID      uint32
+	Name    string `tarantool:"name"`
+	Score   float64
+	skipped int
+	Ignored string `tarantool:"-"`
+	Active  bool
+}
+
+func TestTupleDecoder(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	tuple := []interface{}{int64(1), "alice", int64(10), true}
+
+	var u decoderUser
+	require.NoError(TupleDecoder{Strict: true}.DecodeStruct(tuple, &u))
+	assert.Equal(decoderUser{ID: 1, Name: "alice", Score: 10, Active: true}, u)
+
+	var id int
+	var name string
+	var rest interface{}
+	require.NoError(TupleDecoder{}.Decode(tuple, &id, &name))
+	assert.Equal(1, id)
+	assert.Equal("alice", name)
+
+	data, err := msgp.AppendIntf(nil, tuple)
+	require.NoError(err)
+	require.NoError(TupleDecoder{Strict: true}.DecodeRaw(data, &id, &name, &rest, &u.Active))
+	assert.Equal(int64(10), rest)
+}
+
+func TestTupleDecoderStrict(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		name  string
+		tuple []interface{}
+		err   string
+	}{
+		{"type", []interface{}{"1", "alice", 1.5, true}, "field 0 (ID): can not decode string into uint32"},
+		{"missing", []interface{}{int64(1), "alice"}, "field 2 (Score): field is missing"},
+		{"extra", []interface{}{int64(1), "alice", 1.5, true, "x"}, "field 4: unexpected extra field"},
+		{"nil", []interface{}{int64(1), nil, 1.5, true}, "field 1 (name): unexpected nil"},
+		{"overflow", []interface{}{int64(-1), "alice", 1.5, true}, "field 0 (ID): value overflows the destination"},
+	}
+
+	for _, tc := range tests {
+		var u decoderUser
+		err := TupleDecoder{Strict: true}.DecodeStruct(tc.tuple, &u)
+		if assert.Error(err, tc.name) {
+			assert.Equal(tc.err, err.Error(), tc.name)
+			var fe *FieldError
+			assert.True(errors.As(err, &fe), tc.name)
+		}
+
+		// the lenient mode leaves such fields zeroed
+		u = decoderUser{Name: "bob"}
+		assert.NoError(TupleDecoder{}.DecodeStruct(tc.tuple, &u), tc.name)
+	}
+
+	var u decoderUser
+	assert.NoError(TupleDecoder{}.DecodeStruct([]interface{}{"1", nil}, &u))
+	assert.Equal(decoderUser{}, u)
+}
@@ -0,0 +1,215 @@
+package tarantool
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// TxnIsolation is the isolation level of a transaction, Tarantool >= 2.10.0.
+type TxnIsolation uint
+
+const (
+	// TxnIsolationDefault is the level set by box.cfg.txn_isolation.
+	TxnIsolationDefault TxnIsolation = iota
+	TxnIsolationReadCommitted
+	TxnIsolationReadConfirmed
+	TxnIsolationBestEffort
+)
+
+// ErrTxDone is returned by the methods of a committed or rolled back Tx.
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// Begin starts the interactive transaction of the stream, Tarantool >= 2.10.0.
+// The memtx spaces need box.cfg.memtx_use_mvcc_engine for it.
+type Begin struct {
+	// Timeout rolls the transaction back if it isn't committed in time,
+	// box.cfg.txn_timeout is used if it is 0.
+	Timeout   time.Duration
+	Isolation TxnIsolation
+}
+
+var _ Query = (*Begin)(nil)
+
+func (q *Begin) GetCommandID() uint {
+	return BeginCommand
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (q *Begin) MarshalMsg(b []byte) ([]byte, error) {
+	var n uint32
+	if q.Timeout != 0 {
+		n++
+	}
+	if q.Isolation != TxnIsolationDefault {
+		n++
+	}
+
+	o := msgp.AppendMapHeader(b, n)
+	if q.Timeout != 0 {
+		o = msgp.AppendUint(o, KeyTimeout)
+		o = msgp.AppendFloat64(o, q.Timeout.Seconds())
+	}
+	if q.Isolation != TxnIsolationDefault {
+		o = msgp.AppendUint(o, KeyTxnIsolation)
+		o = msgp.AppendUint(o, uint(q.Isolation))
+	}
+	return o, nil
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (q *Begin) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	var l uint32
+
+	*q = Begin{}
+	buf = data
+	if len(buf) == 0 {
+		return buf, nil
+	}
+	if l, buf, err = msgp.ReadMapHeaderBytes(buf); err != nil {
+		return
+	}
+
+	for ; l > 0; l-- {
+		var k uint
+		if k, buf, err = msgp.ReadUintBytes(buf); err != nil {
+			return
+		}
+
+		switch k {
+		case KeyTimeout:
+			var t float64
+			if t, buf, err = msgp.ReadFloat64Bytes(buf); err != nil {
+				return
+			}
+			q.Timeout = time.Duration(t * float64(time.Second))
+		case KeyTxnIsolation:
+			var i uint
+			if i, buf, err = msgp.ReadUintBytes(buf); err != nil {
+				return
+			}
+			q.Isolation = TxnIsolation(i)
+		default:
+			if buf, err = msgp.Skip(buf); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
+
+// Commit commits the transaction of the stream, Tarantool >= 2.10.0.
+type Commit struct {
+}
+
+var _ Query = (*Commit)(nil)
+
+func (q *Commit) GetCommandID() uint {
+	return CommitCommand
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (q *Commit) MarshalMsg(b []byte) ([]byte, error) {
+	return msgp.AppendMapHeader(b, 0), nil
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (q *Commit) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	return msgp.Skip(data)
+}
+
+// Rollback rolls the transaction of the stream back, Tarantool >= 2.10.0.
+type Rollback struct {
+}
+
+var _ Query = (*Rollback)(nil)
+
+func (q *Rollback) GetCommandID() uint {
+	return RollbackCommand
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (q *Rollback) MarshalMsg(b []byte) ([]byte, error) {
+	return msgp.AppendMapHeader(b, 0), nil
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (q *Rollback) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	return msgp.Skip(data)
+}
+
+// Tx is the interactive transaction executed in a stream of the connection.
+//
+//	tx, err := conn.BeginTx(ctx, nil)
+//	if err != nil {
+//		return err
+//	}
+//	defer tx.Rollback(ctx)
+//	if res := tx.Exec(ctx, &Insert{Space: "accounts", Tuple: tuple}); res.Error != nil {
+//		return res.Error
+//	}
+//	return tx.Commit(ctx)
+type Tx struct {
+	conn     *Connection
+	streamID uint64
+	done     bool
+}
+
+// BeginTx starts the transaction in a new stream, Tarantool >= 2.10.0.
+// opts may be nil.
+func (conn *Connection) BeginTx(ctx context.Context, opts *Begin) (*Tx, error) {
+	if opts == nil {
+		opts = &Begin{}
+	}
+	tx := &Tx{conn: conn, streamID: conn.NewStreamID()}
+	if res := conn.Exec(ctx, opts, StreamExecOption(tx.streamID)); res.Error != nil {
+		return nil, res.Error
+	}
+	return tx, nil
+}
+
+// StreamID returns the id of the stream of the transaction.
+func (tx *Tx) StreamID() uint64 {
+	return tx.streamID
+}
+
+// Exec executes the query in the transaction.
+func (tx *Tx) Exec(ctx context.Context, q Query, options ...ExecOption) *Result {
+	if tx.done {
+		return &Result{Error: ErrTxDone, ErrorCode: ErrNoActiveTransaction}
+	}
+	// the options of the caller are copied, so the stream is not appended to its array
+	opts := make([]ExecOption, len(options), len(options)+1)
+	copy(opts, options)
+	return tx.conn.Exec(ctx, q, append(opts, StreamExecOption(tx.streamID))...)
+}
+
+// Commit commits the transaction.
+func (tx *Tx) Commit(ctx context.Context) error {
+	return tx.finish(ctx, &Commit{})
+}
+
+// Rollback rolls the transaction back, it does nothing if the transaction
+// has already been committed or rolled back.
+func (tx *Tx) Rollback(ctx context.Context) error {
+	if tx.done {
+		return nil
+	}
+	return tx.finish(ctx, &Rollback{})
+}
+
+func (tx *Tx) finish(ctx context.Context, q Query) error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	return tx.conn.Exec(ctx, q, StreamExecOption(tx.streamID)).Error
+}
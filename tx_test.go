@@ -0,0 +1,110 @@
+package tarantool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxMarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, q := range []Query{
+		&Begin{},
+		&Begin{Timeout: 1500 * time.Millisecond, Isolation: TxnIsolationReadCommitted},
+		&Commit{},
+		&Rollback{},
+	} {
+		pp := packetPool.GetWithID(0)
+		require.NoError(t, pp.packMsg(q, nil))
+
+		p := Packet{Cmd: q.GetCommandID()}
+		_, err := p.UnmarshalBinaryBody(pp.body)
+		if assert.NoError(err) {
+			assert.Equal(q, p.Request)
+		}
+		pp.Release()
+	}
+}
+
+type txCall struct {
+	query    Query
+	streamID uint64
+}
+
+// newTxServer runs the server recording the queries and their streams
+func newTxServer(t *testing.T) (string, func() []txCall) {
+	var (
+		mu    sync.Mutex
+		calls []txCall
+	)
+
+	addr := newTestServer(t, func(ctx context.Context, q Query) *Result {
+		// the schema is selected on connect
+		if _, ok := q.(*Select); ok {
+			return &Result{}
+		}
+
+		mu.Lock()
+		calls = append(calls, txCall{q, SessionStreamID(ctx)})
+		mu.Unlock()
+
+		if eval, ok := q.(*Eval); ok && eval.Expression == "error()" {
+			return &Result{ErrorCode: ErrProcLua, Error: NewQueryError(ErrProcLua, "failed")}
+		}
+		return &Result{}
+	})
+	return addr, func() []txCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]txCall(nil), calls...)
+	}
+}
+
+func TestTx(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	addr, calls := newTxServer(t)
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	ctx := context.Background()
+
+	tx, err := conn.BeginTx(ctx, &Begin{Timeout: time.Second})
+	require.NoError(err)
+	// the spare capacity of the options of the caller is not written to
+	options := make([]ExecOption, 0, 1)
+	require.NoError(tx.Exec(ctx, &Eval{Expression: "return 1"}, options...).Error)
+	assert.Nil(options[:1][0])
+	require.NoError(tx.Commit(ctx))
+	assert.Equal(ErrTxDone, tx.Commit(ctx))
+	assert.NoError(tx.Rollback(ctx))
+	assert.Equal(ErrTxDone, tx.Exec(ctx, &Eval{Expression: "return 1"}).Error)
+
+	// the streams of the transactions differ
+	tx2, err := conn.BeginTx(ctx, nil)
+	require.NoError(err)
+	assert.Error(tx2.Exec(ctx, &Eval{Expression: "error()"}).Error)
+	require.NoError(tx2.Rollback(ctx))
+	assert.NotEqual(tx.StreamID(), tx2.StreamID())
+
+	// the queries outside of the transactions have no stream
+	require.NoError(conn.Exec(ctx, &Eval{Expression: "return 2"}).Error)
+
+	id, id2 := tx.StreamID(), tx2.StreamID()
+	assert.Equal([]txCall{
+		{&Begin{Timeout: time.Second}, id},
+		{&Eval{Expression: "return 1"}, id},
+		{&Commit{}, id},
+		{&Begin{}, id2},
+		{&Eval{Expression: "error()"}, id2},
+		{&Rollback{}, id2},
+		{&Eval{Expression: "return 2"}, 0},
+	}, calls())
+}
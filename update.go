@@ -39,7 +39,7 @@ func (q *Update) packMsg(data *packData, b []byte) (o []byte, err error) {
 		}
 	} else if q.KeyTuple != nil {
 		o = msgp.AppendUint(o, KeyKey)
-		if o, err = msgp.AppendIntf(o, q.KeyTuple); err != nil {
+		if o, err = appendTuple(o, q.KeyTuple); err != nil {
 			return o, err
 		}
 	}
@@ -89,12 +89,12 @@ func (q *Update) UnmarshalMsg(data []byte) (buf []byte, err error) {
 				return
 			}
 		case KeyKey:
-			t, buf, err = msgp.ReadIntfBytes(buf)
+			t, buf, err = readIntfBytes(buf)
 			if err != nil {
 				return
 			}
 
-			if q.KeyTuple = t.([]interface{}); q.KeyTuple == nil {
+			if q.KeyTuple, _ = t.([]interface{}); q.KeyTuple == nil {
 				return buf, errors.New("interface type is not []interface{}")
 			}
 
@@ -104,7 +104,7 @@ func (q *Update) UnmarshalMsg(data []byte) (buf []byte, err error) {
 			}
 		case KeyTuple:
 			var len uint32
-			if len, buf, err = msgp.ReadArrayHeaderBytes(buf); err != nil {
+			if len, buf, err = readArrayHeaderBytes(buf); err != nil {
 				return
 			}
 
@@ -66,7 +66,7 @@ func TestUpdate(t *testing.T) {
 				if query.Index != nil {
 					switch query.Index.(type) {
 					case string:
-						assert.Equal(conn.packData.indexMap[512][query.Index.(string)], uint64(query2.Index.(uint)))
+						assert.Equal(conn.packData.schema().indexMap[512][query.Index.(string)], uint64(query2.Index.(uint)))
 					default:
 						assert.Equal(query.Index, query2.Index)
 					}
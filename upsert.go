@@ -27,7 +27,7 @@ func (q *Upsert) packMsg(data *packData, b []byte) (o []byte, err error) {
 	}
 
 	o = msgp.AppendUint(o, KeyTuple)
-	if o, err = msgp.AppendIntf(o, q.Tuple); err != nil {
+	if o, err = appendTuple(o, q.Tuple); err != nil {
 		return o, err
 	}
 
@@ -71,17 +71,17 @@ func (q *Upsert) UnmarshalMsg(data []byte) (buf []byte, err error) {
 				return
 			}
 		case KeyTuple:
-			t, buf, err = msgp.ReadIntfBytes(buf)
+			t, buf, err = readIntfBytes(buf)
 			if err != nil {
 				return
 			}
 
-			if q.Tuple = t.([]interface{}); q.Tuple == nil {
+			if q.Tuple, _ = t.([]interface{}); q.Tuple == nil {
 				return buf, errors.New("interface type is not []interface{}")
 			}
 		case KeyDefTuple:
 			var len uint32
-			if len, buf, err = msgp.ReadArrayHeaderBytes(buf); err != nil {
+			if len, buf, err = readArrayHeaderBytes(buf); err != nil {
 				return
 			}
 
@@ -0,0 +1,120 @@
+package tarantool
+
+import (
+	"context"
+	"time"
+)
+
+// luaVClock returns the vclock as a flat list of id, lsn pairs,
+// skipping the local changes which are not replicated
+const luaVClock = `
+local vclock = {}
+for id, lsn in pairs(box.info.vclock) do
+    if id ~= 0 then
+        table.insert(vclock, id)
+        table.insert(vclock, lsn)
+    end
+end
+return vclock
+`
+
+// VClock returns the current vector clock of the instance. Taken on the master
+// after a write, it identifies the changes a replica must have applied for
+// the write to be visible there, see WaitVClock.
+func (conn *Connection) VClock(ctx context.Context) (VectorClock, error) {
+	res := conn.Exec(ctx, &Eval{Expression: luaVClock})
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	if len(res.Data) == 0 || len(res.Data[0])%2 != 0 {
+		return nil, ErrBadResult
+	}
+
+	pairs := res.Data[0]
+	vc := NewVectorClock()
+	for i := 0; i < len(pairs); i += 2 {
+		id, err := numberToUint64(pairs[i])
+		if err != nil {
+			return nil, ErrBadResult
+		}
+		lsn, err := numberToUint64(pairs[i+1])
+		if err != nil {
+			return nil, ErrBadResult
+		}
+		if id >= VClockMax || !vc.Follow(uint32(id), lsn) {
+			return nil, ErrVectorClock
+		}
+	}
+	return vc, nil
+}
+
+// WaitVClock polls the instance every interval until it has applied all
+// the changes up to vc, so the reads from it observe them. It returns the
+// context error if it does not catch up in time.
+// DefaultVClockPollInterval is used if interval is 0.
+func (conn *Connection) WaitVClock(ctx context.Context, vc VectorClock, interval time.Duration) error {
+	if interval == 0 {
+		interval = DefaultVClockPollInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		current, err := conn.VClock(ctx)
+		if err != nil {
+			return err
+		}
+		if current.Covers(vc) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// ReadAfter waits until any of the replicas has applied all the changes up to
+// vc and returns it, so the reads routed there observe the writes made before
+// vc was taken:
+//
+//	res := master.Exec(ctx, &Insert{...})
+//	vc, err := master.VClock(ctx)
+//	replica, err := ReadAfter(ctx, vc, 0, replicas...)
+//	res = replica.Exec(ctx, &Select{...})
+//
+// The replicas are polled concurrently every interval. If none of them catches
+// up before ctx is done, the last error is returned.
+func ReadAfter(ctx context.Context, vc VectorClock, interval time.Duration, replicas ...*Connection) (*Connection, error) {
+	if len(replicas) == 0 {
+		return nil, ErrNoReplicas
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type caughtUp struct {
+		conn *Connection
+		err  error
+	}
+
+	results := make(chan caughtUp, len(replicas))
+	for _, r := range replicas {
+		go func(conn *Connection) {
+			results <- caughtUp{conn: conn, err: conn.WaitVClock(ctx, vc, interval)}
+		}(r)
+	}
+
+	var err error
+	for range replicas {
+		r := <-results
+		if r.err == nil {
+			return r.conn, nil
+		}
+		err = r.err
+	}
+	return nil, err
+}
@@ -0,0 +1,77 @@
+package tarantool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVectorClockCovers(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(NewVectorClock(10, 20).Covers(NewVectorClock(10, 20)))
+	assert.True(NewVectorClock(11, 20, 1).Covers(NewVectorClock(10, 20)))
+	assert.False(NewVectorClock(10, 19).Covers(NewVectorClock(10, 20)))
+	assert.False(NewVectorClock(10).Covers(NewVectorClock(10, 20)))
+	// the instances without changes don't matter
+	assert.True(NewVectorClock(10).Covers(NewVectorClock(10, 0)))
+	assert.True(NewVectorClock().Covers(NewVectorClock()))
+}
+
+// newVClockServer returns the address of a server whose vclock of the second
+// instance grows by one on every request
+func newVClockServer(t *testing.T, lsn uint64) string {
+	var mu sync.Mutex
+	return newTestServer(t, func(ctx context.Context, q Query) *Result {
+		if _, ok := q.(*Eval); !ok {
+			return &Result{}
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		lsn++
+		return &Result{Data: [][]interface{}{{uint64(1), uint64(10), uint64(2), lsn}}}
+	})
+}
+
+func TestWaitVClock(t *testing.T) {
+	require := require.New(t)
+
+	conn, err := Connect(newVClockServer(t, 0), nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	vc, err := conn.VClock(context.Background())
+	require.NoError(err)
+	require.Equal(NewVectorClock(10, 1), vc)
+
+	require.NoError(conn.WaitVClock(context.Background(), NewVectorClock(10, 5), time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = conn.WaitVClock(ctx, NewVectorClock(11, 5), time.Millisecond)
+	require.True(errors.Is(err, context.DeadlineExceeded))
+}
+
+func TestReadAfter(t *testing.T) {
+	require := require.New(t)
+
+	lagging, err := Connect(newVClockServer(t, 0), nil)
+	require.NoError(err)
+	defer lagging.Close()
+
+	upToDate, err := Connect(newVClockServer(t, 1000), nil)
+	require.NoError(err)
+	defer upToDate.Close()
+
+	replica, err := ReadAfter(context.Background(), NewVectorClock(10, 1000), time.Hour, lagging, upToDate)
+	require.NoError(err)
+	require.True(replica == upToDate)
+
+	_, err = ReadAfter(context.Background(), NewVectorClock(10, 1000), 0)
+	require.Equal(ErrNoReplicas, err)
+}
@@ -0,0 +1,129 @@
+package tarantool
+
+import (
+	"errors"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Watch subscribes to the changes of the key, Tarantool >= 2.10.0.
+// The server doesn't reply to it, it sends an Event with the current value of
+// the key instead, and then the next Event once the key changes and the
+// previous one is acknowledged with another Watch. Use Connection.NewWatcher,
+// which takes care of that.
+type Watch struct {
+	Key string
+}
+
+var _ Query = (*Watch)(nil)
+
+func (q *Watch) GetCommandID() uint {
+	return WatchCommand
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (q *Watch) MarshalMsg(b []byte) ([]byte, error) {
+	return marshalEventKey(q.Key, b), nil
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (q *Watch) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	var e Event
+	buf, err = e.UnmarshalMsg(data)
+	q.Key = e.Key
+	return
+}
+
+// Unwatch cancels the Watch of the key, the server doesn't reply to it either.
+type Unwatch struct {
+	Key string
+}
+
+var _ Query = (*Unwatch)(nil)
+
+func (q *Unwatch) GetCommandID() uint {
+	return UnwatchCommand
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (q *Unwatch) MarshalMsg(b []byte) ([]byte, error) {
+	return marshalEventKey(q.Key, b), nil
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (q *Unwatch) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	var e Event
+	buf, err = e.UnmarshalMsg(data)
+	q.Key = e.Key
+	return
+}
+
+// Event is the value of a watched key sent by the server with sync 0.
+// Value is nil if the key is not set.
+type Event struct {
+	Key   string
+	Value interface{}
+}
+
+var _ Query = (*Event)(nil)
+
+func (q *Event) GetCommandID() uint {
+	return EventCommand
+}
+
+func marshalEventKey(key string, b []byte) []byte {
+	o := msgp.AppendMapHeader(b, 1)
+	o = msgp.AppendUint(o, KeyEventKey)
+	return msgp.AppendString(o, key)
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (q *Event) MarshalMsg(b []byte) (o []byte, err error) {
+	if q.Value == nil {
+		return marshalEventKey(q.Key, b), nil
+	}
+	o = msgp.AppendMapHeader(b, 2)
+	o = msgp.AppendUint(o, KeyEventKey)
+	o = msgp.AppendString(o, q.Key)
+	o = msgp.AppendUint(o, KeyEventData)
+	return msgp.AppendIntf(o, q.Value)
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (q *Event) UnmarshalMsg(data []byte) (buf []byte, err error) {
+	var l uint32
+	var hasKey bool
+
+	q.Key = ""
+	q.Value = nil
+
+	buf = data
+	if l, buf, err = readMapHeaderBytes(buf); err != nil {
+		return
+	}
+	for ; l > 0; l-- {
+		var cd uint
+		if cd, buf, err = msgp.ReadUintBytes(buf); err != nil {
+			return
+		}
+		switch cd {
+		case KeyEventKey:
+			if q.Key, buf, err = msgp.ReadStringBytes(buf); err != nil {
+				return
+			}
+			hasKey = true
+		case KeyEventData:
+			if q.Value, buf, err = readIntfBytes(buf); err != nil {
+				return
+			}
+		default:
+			if buf, err = msgp.Skip(buf); err != nil {
+				return
+			}
+		}
+	}
+	if !hasKey {
+		return buf, errors.New("event key is missing")
+	}
+	return
+}
@@ -0,0 +1,33 @@
+package tarantool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchMarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, q := range []Query{
+		&Watch{Key: "config"},
+		&Unwatch{Key: "config"},
+		&Event{Key: "config"},
+		&Event{Key: "config", Value: map[string]interface{}{"version": int64(2)}},
+	} {
+		pp := packetPool.GetWithID(0)
+		require.NoError(t, pp.packMsg(q, nil))
+
+		p := Packet{Cmd: q.GetCommandID()}
+		_, err := p.UnmarshalBinaryBody(pp.body)
+		if assert.NoError(err) {
+			assert.Equal(q, p.Request)
+		}
+		pp.Release()
+	}
+
+	var e Event
+	_, err := e.UnmarshalMsg([]byte{0x80})
+	assert.Error(err)
+}
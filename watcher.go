@@ -0,0 +1,275 @@
+package tarantool
+
+import "context"
+
+// WatchEvent is delivered to the watchers of a key.
+type WatchEvent struct {
+	Conn *Connection
+	Key  string
+	// Value is the value of the key, nil if it is not set.
+	Value interface{}
+}
+
+// WatchCallback receives the events of a Watcher one at a time.
+type WatchCallback func(event WatchEvent)
+
+// Watcher is a callback registered for the changes of a key.
+type Watcher struct {
+	conn      *Connection
+	watch     *watch
+	callback  WatchCallback
+	delivered uint64 // the version of the watch delivered to the callback
+}
+
+// watch is the server-side subscription to a key shared by its watchers
+type watch struct {
+	key      string
+	value    interface{}
+	version  uint64 // number of events received
+	acked    uint64 // the version acknowledged to the server
+	watchers map[*Watcher]struct{}
+	notify   chan struct{}
+	done     chan struct{}
+}
+
+// NewWatcher registers the callback for the changes of the key, Tarantool >= 2.10.0.
+// The callback is called with the current value of the key first, and then
+// with every change of it. The callbacks of a key are called sequentially
+// from a goroutine of the key, the next change is requested from the server
+// only after they all return. Changes which happen in the meantime are
+// collapsed: only the latest value is delivered.
+// Watchers don't survive the connection, they must be registered again on
+// a new one, see the pubsub package.
+// The wait for the watch request to be handed over to the writer is limited
+// by SendTimeout or QueryTimeout, see NewWatcherContext.
+func (conn *Connection) NewWatcher(key string, callback WatchCallback) (*Watcher, error) {
+	return conn.NewWatcherContext(context.Background(), key, callback)
+}
+
+// NewWatcherContext is NewWatcher with the wait for the watch request to be
+// handed over to the writer limited by ctx as well.
+func (conn *Connection) NewWatcherContext(ctx context.Context, key string, callback WatchCallback) (*Watcher, error) {
+	if conn.IsClosed() {
+		return nil, ConnectionClosedError(conn)
+	}
+
+	var sent chan error
+
+	conn.watchLock.Lock()
+	if conn.watches == nil {
+		conn.watches = make(map[string]*watch)
+	}
+
+	w := conn.watches[key]
+	if w == nil {
+		w = &watch{
+			key:      key,
+			watchers: make(map[*Watcher]struct{}),
+			notify:   make(chan struct{}, 1),
+			done:     make(chan struct{}),
+		}
+		// the queue keeps the Watch and Unwatch requests of a key in order
+		sent = make(chan error, 1)
+		if err := conn.queueNoReply(&Watch{Key: key}, sent); err != nil {
+			conn.watchLock.Unlock()
+			return nil, err
+		}
+		conn.watches[key] = w
+		go conn.deliverEvents(w)
+	}
+
+	watcher := &Watcher{conn: conn, watch: w, callback: callback}
+	w.watchers[watcher] = struct{}{}
+	if w.version > 0 {
+		w.signal()
+	}
+	conn.watchLock.Unlock()
+
+	if sent == nil {
+		return watcher, nil
+	}
+
+	sendCtx, timeout, cancel := conn.sendDeadline(ctx)
+	defer cancel()
+
+	var err error
+	select {
+	case err = <-sent:
+	case <-sendCtx.Done():
+		err = conn.errors.send(sendCtx.Err())
+	case <-timeout:
+		err = conn.errors.send(context.DeadlineExceeded)
+	}
+	if err != nil {
+		// the Unwatch is queued after the Watch, so the server ends up unwatching the key
+		watcher.Unregister()
+		return nil, err
+	}
+	return watcher, nil
+}
+
+// Unregister removes the watcher. A callback which has already started
+// may still be running. The key is unwatched on the server when its last
+// watcher is removed.
+func (watcher *Watcher) Unregister() {
+	conn := watcher.conn
+	conn.watchLock.Lock()
+	defer conn.watchLock.Unlock()
+
+	w := watcher.watch
+	if _, ok := w.watchers[watcher]; !ok {
+		return
+	}
+	delete(w.watchers, watcher)
+	if len(w.watchers) > 0 {
+		return
+	}
+
+	delete(conn.watches, w.key)
+	close(w.done)
+	// the connection may be closed by now, then there is nothing to unwatch
+	conn.queueNoReply(&Unwatch{Key: w.key}, nil)
+}
+
+func (w *watch) signal() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// handleEvent passes the event to the watchers of its key
+func (conn *Connection) handleEvent(e *Event) {
+	conn.watchLock.Lock()
+	defer conn.watchLock.Unlock()
+
+	if w := conn.watches[e.Key]; w != nil {
+		w.value = e.Value
+		w.version++
+		w.signal()
+	}
+}
+
+// deliverEvents calls the callbacks of the watchers which haven't seen the
+// latest value and acknowledges the event to get the next one
+func (conn *Connection) deliverEvents(w *watch) {
+	var pending []*Watcher
+
+	for {
+		select {
+		case <-w.notify:
+		case <-w.done:
+			return
+		case <-conn.exit:
+			return
+		}
+
+		conn.watchLock.Lock()
+		event := WatchEvent{Conn: conn, Key: w.key, Value: w.value}
+		pending = pending[:0]
+		for watcher := range w.watchers {
+			if watcher.delivered < w.version {
+				watcher.delivered = w.version
+				pending = append(pending, watcher)
+			}
+		}
+		conn.watchLock.Unlock()
+
+		for _, watcher := range pending {
+			watcher.callback(event)
+		}
+
+		conn.watchLock.Lock()
+		if conn.watches[w.key] == w && w.acked < w.version {
+			w.acked = w.version
+			conn.queueNoReply(&Watch{Key: w.key}, nil)
+		}
+		conn.watchLock.Unlock()
+	}
+}
+
+// watchSend is a queued request the server doesn't reply to
+type watchSend struct {
+	pp *BinaryPacket
+	// sent receives the result of the hand over to the writer if it is not nil
+	sent chan error
+}
+
+func (ws *watchSend) done(err error) {
+	if ws.sent != nil {
+		ws.sent <- err
+	}
+}
+
+// queueNoReply packs the request the server doesn't reply to and queues it
+// for sendWatches without blocking. The requests are written in the order
+// they are queued.
+func (conn *Connection) queueNoReply(q Query, sent chan error) error {
+	pp := packetPool.GetWithID(conn.nextID())
+	if err := pp.packMsg(q, conn.packData); err != nil {
+		conn.releasePacket(pp)
+		return err
+	}
+
+	conn.watchSendLock.Lock()
+	defer conn.watchSendLock.Unlock()
+
+	if conn.watchSendWake == nil {
+		conn.watchSendWake = make(chan struct{}, 1)
+		go conn.sendWatches()
+	}
+	conn.watchSends = append(conn.watchSends, &watchSend{pp: pp, sent: sent})
+
+	select {
+	case conn.watchSendWake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// sendWatches hands the queued requests over to the writer until the connection is closed
+func (conn *Connection) sendWatches() {
+	for {
+		select {
+		case <-conn.watchSendWake:
+		case <-conn.exit:
+			conn.failWatchSends()
+			return
+		}
+
+		for {
+			conn.watchSendLock.Lock()
+			if len(conn.watchSends) == 0 {
+				conn.watchSendLock.Unlock()
+				break
+			}
+			ws := conn.watchSends[0]
+			conn.watchSends[0] = nil
+			conn.watchSends = conn.watchSends[1:]
+			conn.watchSendLock.Unlock()
+
+			select {
+			case conn.writeChan <- &request{packet: ws.pp}:
+				ws.done(nil)
+			case <-conn.exit:
+				conn.releasePacket(ws.pp)
+				ws.done(ConnectionClosedError(conn))
+				conn.failWatchSends()
+				return
+			}
+		}
+	}
+}
+
+// failWatchSends releases the requests which have not been sent before the connection closed
+func (conn *Connection) failWatchSends() {
+	conn.watchSendLock.Lock()
+	pending := conn.watchSends
+	conn.watchSends = nil
+	conn.watchSendLock.Unlock()
+
+	for _, ws := range pending {
+		conn.releasePacket(ws.pp)
+		ws.done(ConnectionClosedError(conn))
+	}
+}
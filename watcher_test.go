@@ -0,0 +1,259 @@
+package tarantool
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// watchServer emulates box.broadcast and the watchers of its clients
+type watchServer struct {
+	sync.Mutex
+	values map[string]interface{}
+	conns  []*watchServerConn
+}
+
+type watchServerConn struct {
+	srv *IprotoServer
+	// watched keys, true if the last event is not acknowledged yet
+	watched map[string]bool
+	// keys changed while the event is not acknowledged
+	dirty map[string]bool
+}
+
+func newWatchServer(t *testing.T) (*watchServer, string) {
+	s := &watchServer{values: make(map[string]interface{})}
+
+	addr := newTestServerFunc(t, func() *IprotoServer {
+		wc := &watchServerConn{watched: make(map[string]bool), dirty: make(map[string]bool)}
+		wc.srv = NewIprotoServer(testServerUUID, func(ctx context.Context, q Query) *Result {
+			return s.handle(wc, q)
+		}, nil)
+		s.Lock()
+		s.conns = append(s.conns, wc)
+		s.Unlock()
+		return wc.srv
+	})
+	return s, addr
+}
+
+func (s *watchServer) handle(wc *watchServerConn, q Query) *Result {
+	s.Lock()
+	defer s.Unlock()
+
+	switch q := q.(type) {
+	case *Watch:
+		pending, ok := wc.watched[q.Key]
+		if !ok || pending && wc.dirty[q.Key] {
+			wc.srv.SendEvent(q.Key, s.values[q.Key])
+			wc.watched[q.Key] = true
+		} else {
+			wc.watched[q.Key] = false
+		}
+		delete(wc.dirty, q.Key)
+		return nil
+	case *Unwatch:
+		delete(wc.watched, q.Key)
+		delete(wc.dirty, q.Key)
+		return nil
+	}
+	return &Result{}
+}
+
+func (s *watchServer) broadcast(key string, value interface{}) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.values[key] = value
+	for _, wc := range s.conns {
+		pending, ok := wc.watched[key]
+		switch {
+		case !ok:
+		case pending:
+			wc.dirty[key] = true
+		default:
+			wc.srv.SendEvent(key, value)
+			wc.watched[key] = true
+		}
+	}
+}
+
+func (s *watchServer) watched(key string) bool {
+	s.Lock()
+	defer s.Unlock()
+	for _, wc := range s.conns {
+		if _, ok := wc.watched[key]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func TestWatcher(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newWatchServer(t)
+	s.broadcast("config", "v1")
+
+	conn, err := Connect(addr, nil)
+	require.NoError(err)
+	defer conn.Close()
+
+	next := func(ch chan WatchEvent) interface{} {
+		select {
+		case e := <-ch:
+			assert.Equal(conn, e.Conn)
+			assert.Equal("config", e.Key)
+			return e.Value
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+		}
+		return nil
+	}
+	none := func(ch chan WatchEvent) {
+		select {
+		case e := <-ch:
+			t.Fatalf("unexpected event %v", e)
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+
+	events1 := make(chan WatchEvent, 10)
+	w1, err := conn.NewWatcher("config", func(e WatchEvent) { events1 <- e })
+	require.NoError(err)
+	assert.Equal("v1", next(events1))
+
+	s.broadcast("config", "v2")
+	assert.Equal("v2", next(events1))
+
+	// a new watcher gets the current value, the others don't get it again
+	events2 := make(chan WatchEvent, 10)
+	w2, err := conn.NewWatcher("config", func(e WatchEvent) { events2 <- e })
+	require.NoError(err)
+	assert.Equal("v2", next(events2))
+	none(events1)
+
+	s.broadcast("config", "v3")
+	assert.Equal("v3", next(events1))
+	assert.Equal("v3", next(events2))
+
+	w1.Unregister()
+	w1.Unregister()
+	assert.True(s.watched("config"))
+	s.broadcast("config", "v4")
+	assert.Equal("v4", next(events2))
+	none(events1)
+
+	w2.Unregister()
+	require.Eventually(func() bool { return !s.watched("config") }, time.Second, 10*time.Millisecond)
+
+	// the key which is not set
+	events3 := make(chan WatchEvent, 10)
+	_, err = conn.NewWatcher("missing", func(e WatchEvent) { events3 <- e })
+	require.NoError(err)
+	select {
+	case e := <-events3:
+		assert.Nil(e.Value)
+	case <-time.After(time.Second):
+		t.Fatal("no event")
+	}
+
+	conn.Close()
+	_, err = conn.NewWatcher("config", func(WatchEvent) {})
+	assert.Error(err)
+}
+
+// stallDialer dials the connections whose writes block while stalled
+type stallDialer struct {
+	net.Dialer
+	stalled sync.RWMutex
+	// writing, if set, is notified when a write starts
+	writing chan struct{}
+}
+
+type stallConn struct {
+	net.Conn
+	d *stallDialer
+}
+
+func (d *stallDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	c, err := d.Dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &stallConn{Conn: c, d: d}, nil
+}
+
+func (c *stallConn) Write(b []byte) (int, error) {
+	if c.d.writing != nil {
+		select {
+		case c.d.writing <- struct{}{}:
+		default:
+		}
+	}
+	c.d.stalled.RLock()
+	c.d.stalled.RUnlock()
+	return c.Conn.Write(b)
+}
+
+func TestWatcherStalledWrites(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	s, addr := newWatchServer(t)
+	s.broadcast("config", "v1")
+
+	d := &stallDialer{}
+	conn, err := Connect(addr, &Options{Dialer: d, WriteQueueSize: 1})
+	require.NoError(err)
+	defer conn.Close()
+
+	events := make(chan WatchEvent, 10)
+	_, err = conn.NewWatcher("config", func(e WatchEvent) { events <- e })
+	require.NoError(err)
+	<-events
+	// the event is acknowledged
+	require.Eventually(func() bool {
+		s.Lock()
+		defer s.Unlock()
+		return !s.conns[0].watched["config"]
+	}, time.Second, 10*time.Millisecond)
+
+	d.stalled.Lock()
+	defer d.stalled.Unlock()
+
+	// the requests fill the buffer of the writer and the queue,
+	// then the hand over times out
+	var err2 error
+	for i := 0; i < 10 && err2 == nil; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		_, err2 = conn.NewWatcherContext(ctx, fmt.Sprintf("key%d", i), func(WatchEvent) {})
+		cancel()
+	}
+	assert.Error(err2)
+
+	// the events are still read and delivered while the writes are stalled,
+	// the acknowledgement doesn't block the other watchers
+	s.broadcast("config", "v2")
+	next := func(ch chan WatchEvent) interface{} {
+		select {
+		case e := <-ch:
+			return e.Value
+		case <-time.After(time.Second):
+			t.Fatal("no event")
+		}
+		return nil
+	}
+	assert.Equal("v2", next(events))
+	events2 := make(chan WatchEvent, 10)
+	_, err = conn.NewWatcher("config", func(e WatchEvent) { events2 <- e })
+	require.NoError(err)
+	assert.Equal("v2", next(events2))
+}